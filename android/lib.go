@@ -11,7 +11,6 @@ import (
 
 	"github.com/projectqai/hydra/engine"
 	"github.com/projectqai/hydra/view"
-	"github.com/rs/cors"
 	"golang.org/x/net/http2"
 	"golang.org/x/net/http2/h2c"
 )
@@ -57,15 +56,11 @@ func StartEngine() string {
 		mux.Handle("/", webServer)
 	}
 
-	corsHandler := cors.New(cors.Options{
-		AllowedOrigins: []string{"*"},
-		AllowedMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders: []string{"*"},
-	})
-
+	// the app loads the view from the engine, so other pages don't need access
+	var corsConfig *engine.CORSConfig
 	service.server = &http.Server{
 		Addr:    ":50051",
-		Handler: h2c.NewHandler(corsHandler.Handler(mux), &http2.Server{}),
+		Handler: h2c.NewHandler(corsConfig.Handler(mux), &http2.Server{}),
 	}
 
 	go func() {
@@ -87,6 +82,9 @@ func StopEngine() string {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	// let watching clients know, so they back off instead of reconnecting right away
+	globalService.engine.DrainWatchers(ctx, false)
+
 	if err := globalService.server.Shutdown(ctx); err != nil {
 		return fmt.Sprintf("Error stopping engine: %v", err)
 	}
@@ -6,9 +6,11 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/projectqai/hydra/builtin"
 	pb "github.com/projectqai/proto/go"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
@@ -34,6 +36,8 @@ type ADSBAircraft struct {
 type FlexibleInt struct {
 	Value int
 	Valid bool
+	// Ground is set for the "ground" altitude of aircraft on the surface
+	Ground bool
 }
 
 func (f *FlexibleInt) UnmarshalJSON(data []byte) error {
@@ -47,6 +51,7 @@ func (f *FlexibleInt) UnmarshalJSON(data []byte) error {
 	var s string
 	if err := json.Unmarshal(data, &s); err == nil {
 		f.Valid = false
+		f.Ground = s == "ground"
 		return nil
 	}
 
@@ -60,6 +65,29 @@ type ADSBResponse struct {
 	Message string         `json:"msg"`
 }
 
+// defaultRetryAfter is used when a 429 response has no usable Retry-After header
+const defaultRetryAfter = time.Minute
+
+// RateLimitedError is returned when the API answers 429 Too Many Requests
+type RateLimitedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitedError) Error() string {
+	return fmt.Sprintf("rate limited, retry after %s", e.RetryAfter)
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as HTTP date
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	if seconds, err := strconv.Atoi(v); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return defaultRetryAfter
+}
+
 type ADSBClient struct {
 	httpClient *http.Client
 }
@@ -104,6 +132,10 @@ func (c *ADSBClient) fetchAircraft(ctx context.Context, url string) ([]ADSBAircr
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, &RateLimitedError{RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())}
+	}
+
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
 		return nil, fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
@@ -122,12 +154,12 @@ func (c *ADSBClient) fetchAircraft(ctx context.Context, url string) ([]ADSBAircr
 	return adsbResp.AC, nil
 }
 
-func ADSBAircraftToEntity(aircraft ADSBAircraft, controllerID string, expires time.Duration) *pb.Entity {
+func ADSBAircraftToEntity(aircraft ADSBAircraft, idPrefix, controllerID string, expires time.Duration) *pb.Entity {
 	if aircraft.Lat == nil || aircraft.Lon == nil {
 		return nil
 	}
 
-	entityID := fmt.Sprintf("adsblol-%s", aircraft.Hex)
+	entityID := builtin.PrefixID(idPrefix, fmt.Sprintf("adsblol-%s", aircraft.Hex))
 
 	label := strings.TrimSpace(aircraft.Callsign)
 	if label == "" {
@@ -0,0 +1,45 @@
+package adsblol
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestFetchAircraft_RateLimited(t *testing.T) {
+	now := time.Now()
+	for _, tc := range []struct {
+		name       string
+		retryAfter string
+		want       time.Duration
+	}{
+		{"seconds", "7", 7 * time.Second},
+		{"http date", now.Add(90 * time.Second).UTC().Format(http.TimeFormat), 90 * time.Second},
+		{"missing", "", defaultRetryAfter},
+		{"date in the past", now.Add(-time.Hour).UTC().Format(http.TimeFormat), defaultRetryAfter},
+		{"garbage", "soon", defaultRetryAfter},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tc.retryAfter != "" {
+					w.Header().Set("Retry-After", tc.retryAfter)
+				}
+				w.WriteHeader(http.StatusTooManyRequests)
+			}))
+			defer srv.Close()
+
+			_, err := NewADSBClient().fetchAircraft(context.Background(), srv.URL)
+			var rateLimited *RateLimitedError
+			if !errors.As(err, &rateLimited) {
+				t.Fatalf("expected a RateLimitedError, got %v", err)
+			}
+			// the http date has second precision and the clock moves on during the request
+			if d := rateLimited.RetryAfter - tc.want; d > 0 || d < -2*time.Second {
+				t.Errorf("retry after %v, want %v", rateLimited.RetryAfter, tc.want)
+			}
+		})
+	}
+}
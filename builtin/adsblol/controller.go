@@ -2,12 +2,16 @@ package adsblol
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"math/rand/v2"
 	"time"
 
 	"github.com/projectqai/hydra/builtin"
 	"github.com/projectqai/hydra/builtin/controller"
+	"github.com/projectqai/hydra/builtin/terrain"
+	"github.com/projectqai/hydra/goclient"
 	pb "github.com/projectqai/proto/go"
 )
 
@@ -19,6 +23,36 @@ type PollerConfig struct {
 	Callsign        string
 	ICAO            string
 	IntervalSeconds int
+	// IDPrefix namespaces the aircraft of this poller, see builtin.PrefixID
+	IDPrefix string
+	// TerrainURL is an OpenTopoData dataset URL. If set, aircraft on the ground
+	// get the terrain elevation as altitude instead of 0.
+	TerrainURL string
+	// LabelTemplate formats aircraft labels from the fields in aircraftLabelFields,
+	// e.g. "{callsign} ({squawk})". Unset, the callsign, registration or hex is used.
+	LabelTemplate string
+	labels        *builtin.LabelTemplate
+}
+
+// confidence of aircraft positions, they are reported by the aircraft themselves
+const confidence = 0.9
+
+// aircraftLabelFields are the fields of an aircraft available to LabelTemplate
+var aircraftLabelFields = []string{"callsign", "registration", "hex", "squawk", "type", "category"}
+
+// applyLabel renders the label template of the poller for aircraft, if any
+func (c *PollerConfig) applyLabel(entity *pb.Entity, aircraft ADSBAircraft) {
+	label := c.labels.Render(map[string]string{
+		"callsign":     aircraft.Callsign,
+		"registration": aircraft.Registration,
+		"hex":          aircraft.Hex,
+		"squawk":       aircraft.Squawk,
+		"type":         aircraft.Type,
+		"category":     aircraft.Category,
+	})
+	if label != "" {
+		entity.Label = &label
+	}
 }
 
 func Run(ctx context.Context, logger *slog.Logger, _ string) error {
@@ -31,7 +65,37 @@ func Run(ctx context.Context, logger *slog.Logger, _ string) error {
 		},
 	}, func(ctx context.Context, entity *pb.Entity) error {
 		return runPoller(ctx, logger, entity)
-	})
+	}, controller.WithValidator(validatePoller))
+}
+
+func validatePoller(entity *pb.Entity) error {
+	config, err := parsePollerConfig(entity.Config)
+	if err != nil {
+		return err
+	}
+	switch config.ConfigKey {
+	case "adsblol.location.v0":
+		if config.Latitude < -90 || config.Latitude > 90 || config.Longitude < -180 || config.Longitude > 180 {
+			return fmt.Errorf("invalid location %v,%v", config.Latitude, config.Longitude)
+		}
+	case "adsblol.military.v0":
+	case "adsblol.callsign.v0":
+		if config.Callsign == "" {
+			return fmt.Errorf("callsign is required")
+		}
+	case "adsblol.icao.v0":
+		if config.ICAO == "" {
+			return fmt.Errorf("icao is required")
+		}
+	default:
+		return fmt.Errorf("unknown config key: %s", config.ConfigKey)
+	}
+	if config.TerrainURL != "" {
+		if _, err := terrain.NewHTTPSource(config.TerrainURL); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func runPoller(ctx context.Context, logger *slog.Logger, entity *pb.Entity) error {
@@ -49,6 +113,13 @@ func runPoller(ctx context.Context, logger *slog.Logger, entity *pb.Entity) erro
 
 	adsbClient := NewADSBClient()
 
+	var ground terrain.Source
+	if pollerConfig.TerrainURL != "" {
+		if ground, err = terrain.NewHTTPSource(pollerConfig.TerrainURL); err != nil {
+			return err
+		}
+	}
+
 	grpcConn, err := builtin.BuiltinClientConn()
 	if err != nil {
 		return fmt.Errorf("gRPC connection: %w", err)
@@ -57,27 +128,55 @@ func runPoller(ctx context.Context, logger *slog.Logger, entity *pb.Entity) erro
 
 	worldClient := pb.NewWorldServiceClient(grpcConn)
 
-	ticker := time.NewTicker(time.Duration(pollerConfig.IntervalSeconds) * time.Second)
-	defer ticker.Stop()
+	interval := time.Duration(pollerConfig.IntervalSeconds) * time.Second
+
+	// spread pollers over the interval so they don't hit the API at the same time
+	if !sleepCtx(ctx, rand.N(interval)) {
+		return ctx.Err()
+	}
 
-	// Initial poll
-	pollAndPush(ctx, logger, entity.Id, pollerConfig, adsbClient, worldClient)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
 
 	for {
+		err := pollAndPush(ctx, logger, entity.Id, pollerConfig, adsbClient, ground, worldClient)
+
+		var rateLimited *RateLimitedError
+		if errors.As(err, &rateLimited) {
+			logger.Warn("Rate limited by adsb.lol, consider a larger interval_seconds", "entityID", entity.Id, "interval", interval, "retryAfter", rateLimited.RetryAfter)
+			if !sleepCtx(ctx, rateLimited.RetryAfter) {
+				return ctx.Err()
+			}
+			ticker.Reset(interval)
+			continue
+		}
+
 		select {
 		case <-ctx.Done():
 			logger.Info("Poller shutting down", "entityID", entity.Id)
 			return ctx.Err()
 		case <-ticker.C:
-			pollAndPush(ctx, logger, entity.Id, pollerConfig, adsbClient, worldClient)
 		}
 	}
 }
 
-func pollAndPush(ctx context.Context, logger *slog.Logger, entityID string, config *PollerConfig, adsbClient *ADSBClient, worldClient pb.WorldServiceClient) {
+// sleepCtx waits for d and reports false if ctx was cancelled first
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+// pollAndPush fetches aircraft once and pushes them. Errors are logged and
+// reported, the fetch error is also returned so the caller can back off.
+// Aircraft on the ground are placed on the terrain if ground is not nil.
+func pollAndPush(ctx context.Context, logger *slog.Logger, entityID string, config *PollerConfig, adsbClient *ADSBClient, ground terrain.Source, worldClient pb.WorldServiceClient) error {
 	select {
 	case <-ctx.Done():
-		return
+		return ctx.Err()
 	default:
 	}
 
@@ -100,46 +199,62 @@ func pollAndPush(ctx context.Context, logger *slog.Logger, entityID string, conf
 	case "adsblol.callsign.v0":
 		if config.Callsign == "" {
 			logger.Error("Callsign query requires callsign field", "entityID", entityID)
-			return
+			return nil
 		}
 		aircraft, err = adsbClient.FetchByCallsign(requestCtx, config.Callsign)
 
 	case "adsblol.icao.v0":
 		if config.ICAO == "" {
 			logger.Error("ICAO query requires icao field", "entityID", entityID)
-			return
+			return nil
 		}
 		aircraft, err = adsbClient.FetchByICAO(requestCtx, config.ICAO)
 
 	default:
 		logger.Error("Unknown config key", "entityID", entityID, "configKey", config.ConfigKey)
-		return
+		return nil
 	}
 
 	if err != nil {
 		logger.Error("Failed to fetch aircraft data", "entityID", entityID, "error", err)
-		return
+		controller.ReportError(ctx, err)
+		return err
 	}
 
-	var entities []*pb.Entity
+	var entities, onGround []*pb.Entity
 	for _, ac := range aircraft {
-		entity := ADSBAircraftToEntity(ac, entityID, time.Duration(config.IntervalSeconds))
+		entity := ADSBAircraftToEntity(ac, config.IDPrefix, entityID, time.Duration(config.IntervalSeconds))
 		if entity != nil {
+			config.applyLabel(entity, ac)
 			entities = append(entities, entity)
+			if ac.AltBaro != nil && ac.AltBaro.Ground {
+				onGround = append(onGround, entity)
+			}
+		}
+	}
+
+	if ground != nil && len(onGround) > 0 {
+		// without terrain they stay at 0, which is better than not pushing them
+		if err := terrain.Ground(requestCtx, ground, onGround); err != nil {
+			logger.Warn("Failed to look up terrain", "entityID", entityID, "error", err)
 		}
 	}
 
 	if len(entities) == 0 {
-		return
+		controller.ReportSuccess(ctx, 0)
+		return nil
 	}
 
-	_, err = worldClient.Push(ctx, &pb.EntityChangeRequest{
+	_, err = worldClient.Push(goclient.WithConfidence(ctx, confidence), &pb.EntityChangeRequest{
 		Changes: entities,
 	})
 	if err != nil {
 		logger.Error("Failed to push entities", "entityID", entityID, "error", err)
-		return
+		controller.ReportError(ctx, err)
+		return nil
 	}
+	controller.ReportSuccess(ctx, len(entities))
+	return nil
 }
 
 func parsePollerConfig(config *pb.ConfigurationComponent) (*PollerConfig, error) {
@@ -170,6 +285,22 @@ func parsePollerConfig(config *pb.ConfigurationComponent) (*PollerConfig, error)
 	if v, ok := fields["interval_seconds"]; ok {
 		pollerConfig.IntervalSeconds = int(v.GetNumberValue())
 	}
+	if v, ok := fields["id_prefix"]; ok {
+		pollerConfig.IDPrefix = v.GetStringValue()
+		if err := builtin.CheckIDPrefix(pollerConfig.IDPrefix); err != nil {
+			return nil, err
+		}
+	}
+	if v, ok := fields["terrain_url"]; ok {
+		pollerConfig.TerrainURL = v.GetStringValue()
+	}
+	if v, ok := fields["label_template"]; ok {
+		pollerConfig.LabelTemplate = v.GetStringValue()
+		var err error
+		if pollerConfig.labels, err = builtin.ParseLabelTemplate(pollerConfig.LabelTemplate, aircraftLabelFields...); err != nil {
+			return nil, err
+		}
+	}
 
 	return pollerConfig, nil
 }
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log/slog"
 	"net"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -16,6 +17,7 @@ import (
 	"github.com/paulmach/orb/geo"
 	"github.com/projectqai/hydra/builtin"
 	"github.com/projectqai/hydra/builtin/controller"
+	"github.com/projectqai/hydra/builtin/terrain"
 	pb "github.com/projectqai/proto/go"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
@@ -26,19 +28,73 @@ type MessageFragment struct {
 	timestamp time.Time
 }
 
+// Source is one AIS TCP feed
+type Source struct {
+	Host string `json:"host"`
+	Port int    `json:"port"`
+}
+
+func (s Source) Addr() string {
+	return net.JoinHostPort(s.Host, strconv.Itoa(s.Port))
+}
+
 type StreamConfig struct {
-	Host                string   `json:"host"`
-	Port                int      `json:"port"`
+	// Sources are read in parallel and reconnect independently. A single
+	// host and port are accepted as shorthand for one source.
+	Sources             []Source `json:"sources"`
 	EntityExpirySeconds int      `json:"entity_expiry_seconds"`
 	Latitude            *float64 `json:"latitude"`
 	Longitude           *float64 `json:"longitude"`
 	RadiusKM            *float64 `json:"radius_km"`
+	// IDPrefix namespaces the entities of this stream, see builtin.PrefixID
+	IDPrefix string `json:"id_prefix"`
+	// FixedExpirySeconds is the lifetime of base stations and aids to navigation,
+	// which don't move and report less often than vessels
+	FixedExpirySeconds int `json:"fixed_expiry_seconds"`
+	// DefaultSIDC is the symbol of vessels, defaulting to a friendly surface vessel.
+	// Like all symbols it may reference an icon instead, see goclient.IconPrefix.
+	DefaultSIDC string `json:"default_sidc"`
+	// LabelTemplate formats vessel labels from the fields in vesselLabelFields,
+	// e.g. "{name|mmsi} ({callsign})". Unset, the name, callsign or MMSI is used.
+	LabelTemplate string `json:"label_template"`
+	labels        *builtin.LabelTemplate
 
 	// Self position (receiver position from GPS RMC sentences)
 	SelfEntityID     string `json:"self_entity_id"`
 	SelfLabel        string `json:"self_label"`
 	SelfSIDC         string `json:"self_sidc"`
 	SelfAllowInvalid bool   `json:"self_allow_invalid"`
+
+	// TerrainURL is an OpenTopoData dataset URL. If set, vessels get the elevation
+	// of the water surface as altitude, e.g. on inland waterways, instead of 0.
+	// Lookups are cached, but a new area delays the stream until it is loaded.
+	TerrainURL string `json:"terrain_url"`
+	ground     terrain.Source
+}
+
+// vesselLabelFields are the fields of a vessel available to LabelTemplate
+var vesselLabelFields = []string{"mmsi", "name", "callsign"}
+
+// applyLabel renders the label template of the stream for vessel, if any
+func (c *StreamConfig) applyLabel(entity *pb.Entity, vessel *AISVessel) {
+	label := c.labels.Render(map[string]string{
+		"mmsi":     strconv.FormatUint(uint64(vessel.MMSI), 10),
+		"name":     vessel.Name,
+		"callsign": vessel.Callsign,
+	})
+	if label != "" {
+		entity.Label = &label
+	}
+}
+
+// placeOnGround sets the altitude of entity to the terrain if configured
+func (c *StreamConfig) placeOnGround(ctx context.Context, logger *slog.Logger, entity *pb.Entity) {
+	if c.ground == nil {
+		return
+	}
+	if err := terrain.Ground(ctx, c.ground, []*pb.Entity{entity}); err != nil {
+		logger.Warn("Failed to look up terrain", "entityID", entity.Id, "error", err)
+	}
 }
 
 type AISVessel struct {
@@ -64,7 +120,37 @@ func Run(ctx context.Context, logger *slog.Logger, _ string) error {
 		},
 	}, func(ctx context.Context, entity *pb.Entity) error {
 		return runStream(ctx, logger, entity)
-	})
+	}, controller.WithValidator(validateStream))
+}
+
+func validateStream(entity *pb.Entity) error {
+	if entity.Config.Key != "ais.stream.v0" {
+		return fmt.Errorf("unknown config key: %s", entity.Config.Key)
+	}
+	config, err := parseStreamConfig(entity.Config)
+	if err != nil {
+		return err
+	}
+	if len(config.Sources) == 0 {
+		return fmt.Errorf("host and port or sources are required")
+	}
+	for i, source := range config.Sources {
+		if source.Host == "" {
+			return fmt.Errorf("source %d: host is required", i)
+		}
+		if source.Port <= 0 || source.Port > 65535 {
+			return fmt.Errorf("source %d: port must be between 1 and 65535, got %d", i, source.Port)
+		}
+	}
+	if (config.Latitude == nil) != (config.Longitude == nil) {
+		return fmt.Errorf("latitude and longitude must be given together")
+	}
+	if config.TerrainURL != "" {
+		if _, err := terrain.NewHTTPSource(config.TerrainURL); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func runStream(ctx context.Context, logger *slog.Logger, entity *pb.Entity) error {
@@ -78,16 +164,22 @@ func runStream(ctx context.Context, logger *slog.Logger, entity *pb.Entity) erro
 		return fmt.Errorf("parse config: %w", err)
 	}
 
-	if streamConfig.Host == "" || streamConfig.Port == 0 {
+	if len(streamConfig.Sources) == 0 {
 		return fmt.Errorf("host and port are required")
 	}
 
 	if streamConfig.EntityExpirySeconds <= 0 {
 		streamConfig.EntityExpirySeconds = 300
 	}
+	if streamConfig.FixedExpirySeconds <= 0 {
+		streamConfig.FixedExpirySeconds = 3600
+	}
 
-	addr := fmt.Sprintf("%s:%d", streamConfig.Host, streamConfig.Port)
-	logger.Info("Starting AIS stream", "entityID", entity.Id, "address", addr)
+	if streamConfig.TerrainURL != "" {
+		if streamConfig.ground, err = terrain.NewHTTPSource(streamConfig.TerrainURL); err != nil {
+			return err
+		}
+	}
 
 	grpcConn, err := builtin.BuiltinClientConn()
 	if err != nil {
@@ -96,20 +188,43 @@ func runStream(ctx context.Context, logger *slog.Logger, entity *pb.Entity) erro
 	defer grpcConn.Close()
 
 	worldClient := pb.NewWorldServiceClient(grpcConn)
+	seen := newDedup()
+
+	var wg sync.WaitGroup
+	for _, source := range streamConfig.Sources {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			runSource(ctx, logger.With("address", source.Addr()), source, worldClient, entity.Id, streamConfig, seen)
+		}()
+	}
+	wg.Wait()
+
+	return ctx.Err()
+}
+
+// reconnectDelay is the wait before a source that closed its connection is dialed again
+var reconnectDelay = 2 * time.Second
+
+// runSource reads one feed and reconnects it until ctx is done
+func runSource(ctx context.Context, logger *slog.Logger, source Source, worldClient pb.WorldServiceClient, controllerID string, config *StreamConfig, seen *dedup) {
+	logger.Info("Starting AIS stream", "entityID", controllerID)
+
 	aisDecoder := ais.CodecNew(false, false)
 	aisDecoder.DropSpace = true
 
 	for {
 		select {
 		case <-ctx.Done():
-			return ctx.Err()
+			return
 		default:
 		}
 
-		conn, err := net.DialTimeout("tcp", addr, 10*time.Second)
+		conn, err := net.DialTimeout("tcp", source.Addr(), 10*time.Second)
 		if err != nil {
 			logger.Error("Failed to connect", "error", err)
-			time.Sleep(5 * time.Second)
+			controller.ReportError(ctx, err)
+			sleepCtx(ctx, 5*time.Second)
 			continue
 		}
 
@@ -123,23 +238,74 @@ func runStream(ctx context.Context, logger *slog.Logger, entity *pb.Entity) erro
 			select {
 			case <-ctx.Done():
 				conn.Close()
-				return ctx.Err()
+				return
 			default:
 			}
-			processAISLine(ctx, logger, scanner.Text(), aisDecoder, worldClient, entity.Id, streamConfig, fragmentStore, &fragmentMu)
+			processAISLine(ctx, logger, scanner.Text(), aisDecoder, worldClient, controllerID, config, fragmentStore, &fragmentMu, seen)
+			controller.ReportSuccess(ctx, 1)
 		}
 
 		if err := scanner.Err(); err != nil {
 			logger.Error("Stream read error", "error", err)
+			controller.ReportError(ctx, err)
 		}
 
 		conn.Close()
-		logger.Warn("Connection closed, reconnecting...", "entityID", entity.Id)
-		time.Sleep(2 * time.Second)
+		logger.Warn("Connection closed, reconnecting...", "entityID", controllerID)
+		sleepCtx(ctx, reconnectDelay)
 	}
 }
 
-func processAISLine(ctx context.Context, logger *slog.Logger, line string, aisDecoder *ais.Codec, worldClient pb.WorldServiceClient, controllerID string, config *StreamConfig, fragmentStore map[int64]*MessageFragment, fragmentMu *sync.Mutex) bool {
+func sleepCtx(ctx context.Context, d time.Duration) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(d):
+	}
+}
+
+// dedupWindow is how long an identical report of a vessel from another source is dropped
+const dedupWindow = 5 * time.Second
+
+type lastReport struct {
+	latitude  float64
+	longitude float64
+	at        time.Time
+}
+
+// dedup drops position reports of a vessel that another source already delivered
+type dedup struct {
+	mu   sync.Mutex
+	last map[uint32]lastReport
+}
+
+func newDedup() *dedup {
+	return &dedup{last: make(map[uint32]lastReport)}
+}
+
+// fresh reports whether the vessel position was not pushed recently and records it
+func (d *dedup) fresh(vessel *AISVessel) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	if prev, ok := d.last[vessel.MMSI]; ok && now.Sub(prev.at) < dedupWindow &&
+		prev.latitude == vessel.Latitude && prev.longitude == vessel.Longitude {
+		return false
+	}
+	d.last[vessel.MMSI] = lastReport{latitude: vessel.Latitude, longitude: vessel.Longitude, at: now}
+
+	// forget vessels that went silent so the map doesn't grow forever
+	if len(d.last) > 10000 {
+		for mmsi, r := range d.last {
+			if now.Sub(r.at) > dedupWindow {
+				delete(d.last, mmsi)
+			}
+		}
+	}
+	return true
+}
+
+func processAISLine(ctx context.Context, logger *slog.Logger, line string, aisDecoder *ais.Codec, worldClient pb.WorldServiceClient, controllerID string, config *StreamConfig, fragmentStore map[int64]*MessageFragment, fragmentMu *sync.Mutex, seen *dedup) bool {
 	if idx := strings.Index(line, "!"); idx >= 0 {
 		line = line[idx:]
 	} else if idx := strings.Index(line, "$"); idx >= 0 {
@@ -199,7 +365,7 @@ func processAISLine(ctx context.Context, logger *slog.Logger, line string, aisDe
 			return false
 		}
 
-		return processAISPacket(ctx, logger, packet, worldClient, controllerID, config)
+		return processAISPacket(ctx, logger, packet, worldClient, controllerID, config, seen)
 	}
 
 	packet := aisDecoder.DecodePacket(vdm.Payload)
@@ -207,7 +373,7 @@ func processAISLine(ctx context.Context, logger *slog.Logger, line string, aisDe
 		return false
 	}
 
-	return processAISPacket(ctx, logger, packet, worldClient, controllerID, config)
+	return processAISPacket(ctx, logger, packet, worldClient, controllerID, config, seen)
 }
 
 func processRMC(ctx context.Context, logger *slog.Logger, rmc nmea.RMC, worldClient pb.WorldServiceClient, controllerID string, config *StreamConfig) bool {
@@ -233,6 +399,7 @@ func processRMC(ctx context.Context, logger *slog.Logger, rmc nmea.RMC, worldCli
 	if entity == nil {
 		return false
 	}
+	config.placeOnGround(ctx, logger, entity)
 
 	_, err := worldClient.Push(ctx, &pb.EntityChangeRequest{
 		Changes: []*pb.Entity{entity},
@@ -245,7 +412,7 @@ func processRMC(ctx context.Context, logger *slog.Logger, rmc nmea.RMC, worldCli
 	return true
 }
 
-func processAISPacket(ctx context.Context, logger *slog.Logger, packet ais.Packet, worldClient pb.WorldServiceClient, controllerID string, config *StreamConfig) bool {
+func processAISPacket(ctx context.Context, logger *slog.Logger, packet ais.Packet, worldClient pb.WorldServiceClient, controllerID string, config *StreamConfig, seen *dedup) bool {
 	switch msg := packet.(type) {
 	case ais.PositionReport:
 		mmsi := msg.UserID
@@ -263,14 +430,16 @@ func processAISPacket(ctx context.Context, logger *slog.Logger, packet ais.Packe
 			LastSeen:  time.Now(),
 		}
 
-		if !checkGeoFilter(vessel, config) {
+		if !checkGeoFilter(vessel, config) || !seen.fresh(vessel) {
 			return false
 		}
 
-		entity := VesselToEntity(vessel, controllerID, time.Duration(config.EntityExpirySeconds))
+		entity := VesselToEntity(vessel, config.IDPrefix, controllerID, time.Duration(config.EntityExpirySeconds), config.DefaultSIDC)
 		if entity == nil {
 			return false
 		}
+		config.applyLabel(entity, vessel)
+		config.placeOnGround(ctx, logger, entity)
 
 		_, err := worldClient.Push(ctx, &pb.EntityChangeRequest{
 			Changes: []*pb.Entity{entity},
@@ -298,14 +467,16 @@ func processAISPacket(ctx context.Context, logger *slog.Logger, packet ais.Packe
 			LastSeen:  time.Now(),
 		}
 
-		if !checkGeoFilter(vessel, config) {
+		if !checkGeoFilter(vessel, config) || !seen.fresh(vessel) {
 			return false
 		}
 
-		entity := VesselToEntity(vessel, controllerID, time.Duration(config.EntityExpirySeconds))
+		entity := VesselToEntity(vessel, config.IDPrefix, controllerID, time.Duration(config.EntityExpirySeconds), config.DefaultSIDC)
 		if entity == nil {
 			return false
 		}
+		config.applyLabel(entity, vessel)
+		config.placeOnGround(ctx, logger, entity)
 
 		_, err := worldClient.Push(ctx, &pb.EntityChangeRequest{
 			Changes: []*pb.Entity{entity},
@@ -335,14 +506,16 @@ func processAISPacket(ctx context.Context, logger *slog.Logger, packet ais.Packe
 			LastSeen:  time.Now(),
 		}
 
-		if !checkGeoFilter(vessel, config) {
+		if !checkGeoFilter(vessel, config) || !seen.fresh(vessel) {
 			return false
 		}
 
-		entity := VesselToEntity(vessel, controllerID, time.Duration(config.EntityExpirySeconds))
+		entity := VesselToEntity(vessel, config.IDPrefix, controllerID, time.Duration(config.EntityExpirySeconds), config.DefaultSIDC)
 		if entity == nil {
 			return false
 		}
+		config.applyLabel(entity, vessel)
+		config.placeOnGround(ctx, logger, entity)
 
 		_, err := worldClient.Push(ctx, &pb.EntityChangeRequest{
 			Changes: []*pb.Entity{entity},
@@ -353,6 +526,25 @@ func processAISPacket(ctx context.Context, logger *slog.Logger, packet ais.Packe
 		}
 
 		return true
+
+	case ais.BaseStationReport:
+		station := &AISVessel{
+			MMSI:      msg.UserID,
+			Latitude:  float64(msg.Latitude),
+			Longitude: float64(msg.Longitude),
+			LastSeen:  time.Now(),
+		}
+		return processStation(ctx, logger, station, baseStation, worldClient, controllerID, config, seen)
+
+	case ais.AidsToNavigationReport:
+		station := &AISVessel{
+			MMSI:      msg.UserID,
+			Latitude:  float64(msg.Latitude),
+			Longitude: float64(msg.Longitude),
+			Name:      strings.TrimSpace(msg.Name + msg.NameExtension),
+			LastSeen:  time.Now(),
+		}
+		return processStation(ctx, logger, station, aidToNavigation, worldClient, controllerID, config, seen)
 	}
 	return false
 }
@@ -368,8 +560,8 @@ func checkGeoFilter(vessel *AISVessel, config *StreamConfig) bool {
 	return distanceKM <= *config.RadiusKM
 }
 
-func VesselToEntity(vessel *AISVessel, controllerID string, expires time.Duration) *pb.Entity {
-	entityID := fmt.Sprintf("ais-%d", vessel.MMSI)
+func VesselToEntity(vessel *AISVessel, idPrefix, controllerID string, expires time.Duration, fallbackSIDC string) *pb.Entity {
+	entityID := builtin.PrefixID(idPrefix, fmt.Sprintf("ais-%d", vessel.MMSI))
 	label := vessel.Name
 	if label == "" {
 		label = vessel.Callsign
@@ -379,7 +571,7 @@ func VesselToEntity(vessel *AISVessel, controllerID string, expires time.Duratio
 	}
 
 	altitude := 0.0
-	sidc := vesselTypeToSIDC(vessel.Type)
+	sidc := vesselTypeToSIDC(vessel.Type, fallbackSIDC)
 
 	entity := &pb.Entity{
 		Id:    entityID,
@@ -403,16 +595,31 @@ func VesselToEntity(vessel *AISVessel, controllerID string, expires time.Duratio
 		Track: &pb.TrackComponent{},
 	}
 
-	if vessel.Course > 0 {
-		course := vessel.Course
+	if azimuth, ok := vessel.bearing(); ok {
 		entity.Bearing = &pb.BearingComponent{
-			Azimuth: &course,
+			Azimuth: &azimuth,
 		}
 	}
 
 	return entity
 }
 
+// courseNotAvailable is the course over ground of vessels that don't report one,
+// vessels without a true heading report 511
+const courseNotAvailable = 360
+
+// bearing returns where the vessel points, its true heading or else its course
+// over ground. ok is false if it reports neither, instead of the sentinels.
+func (v *AISVessel) bearing() (azimuth float64, ok bool) {
+	if v.Heading >= 0 && v.Heading < 360 {
+		return float64(v.Heading), true
+	}
+	if v.Course >= 0 && v.Course < courseNotAvailable {
+		return v.Course, true
+	}
+	return 0, false
+}
+
 func SelfToEntity(rmc nmea.RMC, controllerID string, config *StreamConfig) *pb.Entity {
 	entityID := config.SelfEntityID
 	if entityID == "" {
@@ -426,7 +633,7 @@ func SelfToEntity(rmc nmea.RMC, controllerID string, config *StreamConfig) *pb.E
 
 	sidc := config.SelfSIDC
 	if sidc == "" {
-		sidc = "SFSPXM----*****"
+		sidc = defaultSIDC
 	}
 
 	altitude := 0.0
@@ -463,8 +670,14 @@ func SelfToEntity(rmc nmea.RMC, controllerID string, config *StreamConfig) *pb.E
 	return entity
 }
 
-func vesselTypeToSIDC(shipType uint8) string {
-	return "SFSPXM----*****"
+// defaultSIDC is a friendly surface vessel
+const defaultSIDC = "SFSPXM----*****"
+
+func vesselTypeToSIDC(shipType uint8, fallback string) string {
+	if fallback == "" {
+		return defaultSIDC
+	}
+	return fallback
 }
 
 func parseStreamConfig(config *pb.ConfigurationComponent) (*StreamConfig, error) {
@@ -475,15 +688,37 @@ func parseStreamConfig(config *pb.ConfigurationComponent) (*StreamConfig, error)
 	fields := config.Value.Fields
 	streamConfig := &StreamConfig{}
 
+	var single Source
 	if v, ok := fields["host"]; ok {
-		streamConfig.Host = v.GetStringValue()
+		single.Host = v.GetStringValue()
 	}
 	if v, ok := fields["port"]; ok {
-		streamConfig.Port = int(v.GetNumberValue())
+		single.Port = int(v.GetNumberValue())
+	}
+	if single.Host != "" || single.Port != 0 {
+		streamConfig.Sources = append(streamConfig.Sources, single)
+	}
+	if v, ok := fields["sources"]; ok {
+		for _, item := range v.GetListValue().GetValues() {
+			sourceFields := item.GetStructValue().GetFields()
+			streamConfig.Sources = append(streamConfig.Sources, Source{
+				Host: sourceFields["host"].GetStringValue(),
+				Port: int(sourceFields["port"].GetNumberValue()),
+			})
+		}
 	}
 	if v, ok := fields["entity_expiry_seconds"]; ok {
 		streamConfig.EntityExpirySeconds = int(v.GetNumberValue())
 	}
+	if v, ok := fields["id_prefix"]; ok {
+		streamConfig.IDPrefix = v.GetStringValue()
+		if err := builtin.CheckIDPrefix(streamConfig.IDPrefix); err != nil {
+			return nil, err
+		}
+	}
+	if v, ok := fields["fixed_expiry_seconds"]; ok {
+		streamConfig.FixedExpirySeconds = int(v.GetNumberValue())
+	}
 	if v, ok := fields["latitude"]; ok {
 		lat := v.GetNumberValue()
 		streamConfig.Latitude = &lat
@@ -502,12 +737,25 @@ func parseStreamConfig(config *pb.ConfigurationComponent) (*StreamConfig, error)
 	if v, ok := fields["self_label"]; ok {
 		streamConfig.SelfLabel = v.GetStringValue()
 	}
+	if v, ok := fields["default_sidc"]; ok {
+		streamConfig.DefaultSIDC = v.GetStringValue()
+	}
 	if v, ok := fields["self_sidc"]; ok {
 		streamConfig.SelfSIDC = v.GetStringValue()
 	}
 	if v, ok := fields["self_allow_invalid"]; ok {
 		streamConfig.SelfAllowInvalid = v.GetBoolValue()
 	}
+	if v, ok := fields["terrain_url"]; ok {
+		streamConfig.TerrainURL = v.GetStringValue()
+	}
+	if v, ok := fields["label_template"]; ok {
+		streamConfig.LabelTemplate = v.GetStringValue()
+		var err error
+		if streamConfig.labels, err = builtin.ParseLabelTemplate(streamConfig.LabelTemplate, vesselLabelFields...); err != nil {
+			return nil, err
+		}
+	}
 
 	return streamConfig, nil
 }
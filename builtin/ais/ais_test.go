@@ -0,0 +1,236 @@
+package ais
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/BertoldVdb/go-ais"
+	"github.com/projectqai/hydra/builtin"
+	"github.com/projectqai/hydra/engine"
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/grpc"
+)
+
+func TestProcessAISLine_PushesVessel(t *testing.T) {
+	server := engine.NewTestServer(t)
+
+	labels, err := builtin.ParseLabelTemplate("MMSI {mmsi}", vesselLabelFields...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &StreamConfig{EntityExpirySeconds: 60, labels: labels}
+	decoder := ais.CodecNew(false, false)
+	var mu sync.Mutex
+
+	line := "!AIVDM,1,1,,B,15M67FC000G?ufbE`FepT@3n00Sa,0*5C"
+	if !processAISLine(context.Background(), slog.Default(), line, decoder, server.Client, "ais-test", config, map[int64]*MessageFragment{}, &mu, newDedup()) {
+		t.Fatal("expected the position report to be pushed")
+	}
+
+	list, err := server.Client.ListEntities(context.Background(), &pb.ListEntitiesRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Entities) != 1 {
+		t.Fatalf("expected one vessel, got %d entities", len(list.Entities))
+	}
+	vessel := list.Entities[0]
+	if vessel.GetController().GetName() != "ais" || vessel.GetLabel() != "MMSI "+vessel.Id[len("ais-"):] {
+		t.Fatalf("unexpected vessel %v", vessel)
+	}
+}
+
+func TestProcessAISPacket_HeadingNotAvailable(t *testing.T) {
+	server := engine.NewTestServer(t)
+	config := &StreamConfig{EntityExpirySeconds: 60}
+
+	report := func(mmsi uint32, heading uint16, cog float64) ais.PositionReport {
+		return ais.PositionReport{
+			Header:      ais.Header{MessageID: 1, UserID: mmsi},
+			Valid:       true,
+			Latitude:    53.54,
+			Longitude:   9.98,
+			Cog:         ais.Field10(cog),
+			TrueHeading: heading,
+		}
+	}
+	for _, packet := range []ais.PositionReport{
+		report(211000001, 511, 360),
+		report(211000002, 511, 45),
+		report(211000003, 90, 45),
+		report(211000004, 511, 0),
+	} {
+		if !processAISPacket(context.Background(), slog.Default(), packet, server.Client, "ais-test", config, newDedup()) {
+			t.Fatalf("expected the position report of %d to be pushed", packet.UserID)
+		}
+	}
+
+	for id, want := range map[string]*float64{
+		"ais-211000001": nil,
+		"ais-211000002": ptr(45.0),
+		"ais-211000003": ptr(90.0),
+		"ais-211000004": ptr(0.0),
+	} {
+		resp, err := server.Client.GetEntity(context.Background(), &pb.GetEntityRequest{Id: id})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := resp.Entity.GetBearing()
+		switch {
+		case want == nil && got != nil:
+			t.Errorf("%s: bearing %v for a vessel without heading or course, want none", id, got.GetAzimuth())
+		case want != nil && (got == nil || got.GetAzimuth() != *want):
+			t.Errorf("%s: bearing %v, want %v", id, got.GetAzimuth(), *want)
+		}
+	}
+}
+
+func ptr[T any](v T) *T { return &v }
+
+func TestProcessAISPacket_FixedStations(t *testing.T) {
+	server := engine.NewTestServer(t)
+	config := &StreamConfig{EntityExpirySeconds: 60, FixedExpirySeconds: 3600}
+
+	packets := []ais.Packet{
+		ais.BaseStationReport{Header: ais.Header{MessageID: 4, UserID: 2111240}, Valid: true, Latitude: 53.54, Longitude: 9.98},
+		ais.AidsToNavigationReport{Header: ais.Header{MessageID: 21, UserID: 992111234}, Valid: true, Type: 22, Name: "ELBE 1", Latitude: 54.0, Longitude: 8.11},
+		ais.AidsToNavigationReport{Header: ais.Header{MessageID: 21, UserID: 992115678}, Valid: true, Latitude: 91, Longitude: 181},
+	}
+	for _, packet := range packets[:2] {
+		if !processAISPacket(context.Background(), slog.Default(), packet, server.Client, "ais-test", config, newDedup()) {
+			t.Fatalf("expected %T to be pushed", packet)
+		}
+	}
+	if processAISPacket(context.Background(), slog.Default(), packets[2], server.Client, "ais-test", config, newDedup()) {
+		t.Fatal("expected a station without position to be dropped")
+	}
+
+	for id, label := range map[string]string{
+		"ais-base-2111240":   "Base station 2111240",
+		"ais-aton-992111234": "ELBE 1",
+	} {
+		resp, err := server.Client.GetEntity(context.Background(), &pb.GetEntityRequest{Id: id})
+		if err != nil {
+			t.Fatal(err)
+		}
+		station := resp.Entity
+		if station.GetLabel() != label || station.Track != nil {
+			t.Errorf("%s: got label %q and track %v, want %q and no track", id, station.GetLabel(), station.Track, label)
+		}
+		if ttl := time.Until(station.Lifetime.Until.AsTime()); ttl < 59*time.Minute {
+			t.Errorf("%s: expires in %v, want the fixed expiry", id, ttl)
+		}
+	}
+}
+
+func TestProcessAISPacket_IDPrefix(t *testing.T) {
+	server := engine.NewTestServer(t)
+
+	report := ais.PositionReport{Header: ais.Header{MessageID: 1, UserID: 211000001}, Valid: true, Latitude: 53.54, Longitude: 9.98}
+	for _, prefix := range []string{"region1", "region2"} {
+		config := &StreamConfig{EntityExpirySeconds: 60, IDPrefix: prefix}
+		if !processAISPacket(context.Background(), slog.Default(), report, server.Client, "ais-"+prefix, config, newDedup()) {
+			t.Fatalf("expected the position report of %s to be pushed", prefix)
+		}
+	}
+
+	for _, id := range []string{"region1-ais-211000001", "region2-ais-211000001"} {
+		resp, err := server.Client.GetEntity(context.Background(), &pb.GetEntityRequest{Id: id})
+		if err != nil {
+			t.Fatalf("%s: %v", id, err)
+		}
+		if want := "ais-" + id[:len("region1")]; resp.Entity.GetController().GetId() != want {
+			t.Errorf("%s: pushed by %s, want %s", id, resp.Entity.GetController().GetId(), want)
+		}
+	}
+}
+
+// pushCounter is a world client that counts the entities pushed to it by id
+type pushCounter struct {
+	pb.WorldServiceClient
+	mu     sync.Mutex
+	pushes map[string]int
+}
+
+func (c *pushCounter) Push(ctx context.Context, req *pb.EntityChangeRequest, _ ...grpc.CallOption) (*pb.EntityChangeResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, e := range req.Changes {
+		c.pushes[e.Id]++
+	}
+	return &pb.EntityChangeResponse{}, nil
+}
+
+// feed serves line to every connection and closes it, counting the connections
+func feed(t *testing.T, line string) (Source, *atomic.Int32) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	var conns atomic.Int32
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			conns.Add(1)
+			conn.Write([]byte(line + "\r\n"))
+			conn.Close()
+		}
+	}()
+	addr := l.Addr().(*net.TCPAddr)
+	return Source{Host: "127.0.0.1", Port: addr.Port}, &conns
+}
+
+func TestRunSource_DedupsAcrossSourcesAndReconnects(t *testing.T) {
+	reconnectDelay = 100 * time.Millisecond
+	defer func() { reconnectDelay = 2 * time.Second }()
+
+	line := "!AIVDM,1,1,,B,15M67FC000G?ufbE`FepT@3n00Sa,0*5C"
+	a, connsA := feed(t, line)
+	b, connsB := feed(t, line)
+
+	client := &pushCounter{pushes: make(map[string]int)}
+	config := &StreamConfig{EntityExpirySeconds: 60}
+	seen := newDedup()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	for _, source := range []Source{a, b} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			runSource(ctx, slog.Default(), source, client, "ais-test", config, seen)
+		}()
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for (connsA.Load() < 2 || connsB.Load() < 2) && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	cancel()
+	wg.Wait()
+
+	if connsA.Load() < 2 || connsB.Load() < 2 {
+		t.Fatalf("expected both sources to reconnect after their connection closed, got %d and %d connections", connsA.Load(), connsB.Load())
+	}
+	var pushed int
+	for id, n := range client.pushes {
+		if strings.HasPrefix(id, "ais-") {
+			pushed += n
+		}
+	}
+	if pushed != 1 {
+		t.Errorf("expected the same report from both sources and reconnects to be pushed once, got %d pushes", pushed)
+	}
+}
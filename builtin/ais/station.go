@@ -0,0 +1,80 @@
+package ais
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/projectqai/hydra/builtin"
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// stationKind is a fixed object that reports over AIS instead of a vessel
+type stationKind struct {
+	idPrefix string
+	label    string
+	sidc     string
+}
+
+var (
+	// baseStation is a shore station, type 4 reports
+	baseStation = stationKind{idPrefix: "ais-base", label: "Base station", sidc: "SFGPIUT---H****"}
+	// aidToNavigation is a buoy, beacon or lighthouse, type 21 reports. 2525C
+	// has no symbol for them, they are shown as a neutral installation.
+	aidToNavigation = stationKind{idPrefix: "ais-aton", label: "Aid to navigation", sidc: "SNGPI-----H****"}
+)
+
+// processStation pushes a base station or aid to navigation
+func processStation(ctx context.Context, logger *slog.Logger, station *AISVessel, kind stationKind, worldClient pb.WorldServiceClient, controllerID string, config *StreamConfig, seen *dedup) bool {
+	// 91 and 181 mean the position is not available
+	if station.MMSI == 0 || station.Latitude > 90 || station.Longitude > 180 {
+		return false
+	}
+	if !checkGeoFilter(station, config) || !seen.fresh(station) {
+		return false
+	}
+
+	entity := stationToEntity(station, kind, config.IDPrefix, controllerID, time.Duration(config.FixedExpirySeconds)*time.Second)
+	config.placeOnGround(ctx, logger, entity)
+
+	_, err := worldClient.Push(ctx, &pb.EntityChangeRequest{
+		Changes: []*pb.Entity{entity},
+	})
+	if err != nil {
+		logger.Error("Failed to push station", "kind", kind.label, "error", err)
+		return false
+	}
+	return true
+}
+
+// stationToEntity returns a fixed object with an id that stays the same for its MMSI
+func stationToEntity(station *AISVessel, kind stationKind, idPrefix, controllerID string, expires time.Duration) *pb.Entity {
+	label := station.Name
+	if label == "" {
+		label = fmt.Sprintf("%s %d", kind.label, station.MMSI)
+	}
+	altitude := 0.0
+
+	return &pb.Entity{
+		Id:    builtin.PrefixID(idPrefix, fmt.Sprintf("%s-%d", kind.idPrefix, station.MMSI)),
+		Label: &label,
+		Lifetime: &pb.Lifetime{
+			From:  timestamppb.Now(),
+			Until: timestamppb.New(time.Now().Add(expires)),
+		},
+		Geo: &pb.GeoSpatialComponent{
+			Latitude:  station.Latitude,
+			Longitude: station.Longitude,
+			Altitude:  &altitude,
+		},
+		Symbol: &pb.SymbolComponent{
+			MilStd2525C: kind.sidc,
+		},
+		Controller: &pb.ControllerRef{
+			Id:   controllerID,
+			Name: "ais",
+		},
+	}
+}
@@ -12,8 +12,12 @@ import (
 
 const feetToMeters = 0.3048
 
-// TrackToEntity converts an ASTERIX CAT62 track to a Hydra entity.
-func TrackToEntity(track *cat62.Track, sourcePrefix string, controllerID string) (*pb.Entity, error) {
+// DefaultSIDC is the symbol of tracks unless configured otherwise: Unknown, Air, Platform, Manned
+const DefaultSIDC = "SUAPM---------*"
+
+// TrackToEntity converts an ASTERIX CAT62 track to a Hydra entity with the
+// symbol sidc, since CAT62 carries no affiliation.
+func TrackToEntity(track *cat62.Track, sourcePrefix string, controllerID string, sidc string) (*pb.Entity, error) {
 	// Track must have at least track number and position
 	if track.TrackNumber == nil {
 		return nil, fmt.Errorf("track missing track number")
@@ -67,7 +71,7 @@ func TrackToEntity(track *cat62.Track, sourcePrefix string, controllerID string)
 			Altitude:  altitude,
 		},
 		Symbol: &pb.SymbolComponent{
-			MilStd2525C: "SUAPM---------*", // Unknown, Air, Platform, Manned
+			MilStd2525C: sidc,
 		},
 		Controller: &pb.ControllerRef{
 			Id:   controllerID,
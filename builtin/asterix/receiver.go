@@ -18,6 +18,7 @@ func runReceiver(ctx context.Context, logger *slog.Logger, entity *pb.Entity) er
 	listenAddr := ":8600"
 	category := 62
 	sourcePrefix := entity.Id
+	sidc := DefaultSIDC
 
 	if config.Value != nil && config.Value.Fields != nil {
 		if v, ok := config.Value.Fields["listen"]; ok {
@@ -29,6 +30,9 @@ func runReceiver(ctx context.Context, logger *slog.Logger, entity *pb.Entity) er
 		if v, ok := config.Value.Fields["source_prefix"]; ok {
 			sourcePrefix = v.GetStringValue()
 		}
+		if v, ok := config.Value.Fields["default_sidc"]; ok && v.GetStringValue() != "" {
+			sidc = v.GetStringValue()
+		}
 	}
 
 	logger.Info("Starting ASTERIX receiver", "listenAddr", listenAddr, "category", category)
@@ -99,7 +103,7 @@ func runReceiver(ctx context.Context, logger *slog.Logger, entity *pb.Entity) er
 			case cat62.Category:
 				tracks := block.Cat62Tracks()
 				for _, track := range tracks {
-					e, err := TrackToEntity(track, sourcePrefix, entity.Id)
+					e, err := TrackToEntity(track, sourcePrefix, entity.Id, sidc)
 					if err != nil {
 						logger.Debug("Skip track", "error", err)
 						continue
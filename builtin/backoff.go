@@ -0,0 +1,59 @@
+package builtin
+
+import (
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"github.com/projectqai/hydra/goclient"
+)
+
+// Backoff computes restart delays that double after every failure up to Max,
+// with jitter so restarts of many connectors don't line up. A run that lasted
+// at least Reset counts as success and starts over at Min.
+type Backoff struct {
+	Min   time.Duration
+	Max   time.Duration
+	Reset time.Duration
+
+	attempt int
+}
+
+// Next returns the delay before the next attempt, given how long the last one ran
+func (b *Backoff) Next(ranFor time.Duration) time.Duration {
+	if ranFor >= b.Reset {
+		b.attempt = 0
+	}
+
+	d := b.Min
+	for i := 0; i < b.attempt && d < b.Max; i++ {
+		d *= 2
+	}
+	if d > b.Max {
+		d = b.Max
+	}
+	b.attempt++
+	return jitter(d)
+}
+
+// jitter returns equal jitter: somewhere between half and the full delay
+func jitter(d time.Duration) time.Duration {
+	half := d / 2
+	return half + rand.N(half+1)
+}
+
+// After is Next for a run that ended with err. A world that is unavailable, e.g.
+// restarting, is retried after Min, one that rejected the client after Max. It
+// reports false for errors of requests that won't ever succeed, see goclient.ErrInvalid.
+func (b *Backoff) After(ranFor time.Duration, err error) (time.Duration, bool) {
+	switch {
+	case errors.Is(err, goclient.ErrInvalid):
+		return 0, false
+	case errors.Is(err, goclient.ErrUnauthenticated):
+		b.attempt++
+		return jitter(b.Max), true
+	case errors.Is(err, goclient.ErrUnavailable):
+		return b.Next(b.Reset), true
+	}
+	return b.Next(ranFor), true
+}
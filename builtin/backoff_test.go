@@ -0,0 +1,46 @@
+package builtin
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/projectqai/hydra/goclient"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestBackoff(t *testing.T) {
+	b := &Backoff{Min: time.Second, Max: 8 * time.Second, Reset: time.Minute}
+
+	for i, want := range []time.Duration{1, 2, 4, 8, 8} {
+		want *= time.Second
+		d := b.Next(0)
+		if d < want/2 || d > want {
+			t.Errorf("attempt %d: expected delay in [%s, %s], got %s", i, want/2, want, d)
+		}
+	}
+
+	if d := b.Next(time.Minute); d > time.Second {
+		t.Errorf("expected reset to Min after a long run, got %s", d)
+	}
+}
+
+func TestBackoff_AfterError(t *testing.T) {
+	b := &Backoff{Min: time.Second, Max: 8 * time.Second, Reset: time.Minute}
+	b.Next(0)
+	b.Next(0)
+
+	if d, ok := b.After(0, goclient.WrapError(status.Error(codes.Unavailable, "connection refused"))); !ok || d > time.Second {
+		t.Errorf("expected a retry within Min for an unavailable world, got %s %v", d, ok)
+	}
+	if d, ok := b.After(0, goclient.WrapError(status.Error(codes.PermissionDenied, "denied"))); !ok || d < 4*time.Second {
+		t.Errorf("expected a retry after about Max for a rejected client, got %s %v", d, ok)
+	}
+	if _, ok := b.After(0, goclient.WrapError(status.Error(codes.Unimplemented, "unknown method"))); ok {
+		t.Error("expected no retry for an invalid request")
+	}
+	if _, ok := b.After(0, errors.New("connection reset")); !ok {
+		t.Error("expected a retry for other errors")
+	}
+}
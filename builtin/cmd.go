@@ -2,42 +2,100 @@ package builtin
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net"
+	"os"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/projectqai/hydra/goclient"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/test/bufconn"
 )
 
-var ServerURL string = "localhost:50051"
+// Builtins run in one of two modes. In process, the default, the engine starts
+// them with StartAll and they reach it through an in-memory listener, which
+// policies see as policy.BuiltinAddr. Out of process, e.g. an AIS ingest
+// container next to a central engine, "hydra builtins run" starts them after
+// UseRemote and they connect to ServerURL over the network like any client.
+
+// ServerURL is the engine builtins connect to out of process, $HYDRA_SERVER if set
+var ServerURL string = DefaultServerURL()
+
+// DefaultServerURL returns $HYDRA_SERVER, or the engine on this machine
+func DefaultServerURL() string {
+	if v := os.Getenv("HYDRA_SERVER"); v != "" {
+		return v
+	}
+	return "localhost:50051"
+}
 
 const bufSize = 1024 * 1024
 
 var (
+	builtinMu       sync.Mutex
 	builtinListener *bufconn.Listener
-	builtinOnce     sync.Once
+	// remote makes builtins connect to ServerURL instead of builtinListener
+	remote bool
 )
 
+// UseRemote makes builtins connect to the engine at serverURL from now on,
+// to run them out of process
+func UseRemote(serverURL string) {
+	builtinMu.Lock()
+	defer builtinMu.Unlock()
+	ServerURL = serverURL
+	remote = true
+}
+
 func GetBuiltinListener() *bufconn.Listener {
-	builtinOnce.Do(func() {
+	builtinMu.Lock()
+	defer builtinMu.Unlock()
+	if builtinListener == nil {
 		builtinListener = bufconn.Listen(bufSize)
-	})
+	}
 	return builtinListener
 }
 
+// SwapBuiltinListener makes builtins dial l from now on and returns the listener
+// they dialed before, so tests can serve a world of their own, see engine.NewTestServer
+func SwapBuiltinListener(l *bufconn.Listener) *bufconn.Listener {
+	builtinMu.Lock()
+	defer builtinMu.Unlock()
+	previous := builtinListener
+	builtinListener = l
+	return previous
+}
+
 func BuiltinDialer() grpc.DialOption {
 	return grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
 		return GetBuiltinListener().DialContext(ctx)
 	})
 }
 
+// BuiltinClientConn connects to the engine the builtins run with, see UseRemote
 func BuiltinClientConn() (*grpc.ClientConn, error) {
+	builtinMu.Lock()
+	serverURL, isRemote := ServerURL, remote
+	builtinMu.Unlock()
+	if isRemote {
+		conn, err := goclient.Connect(serverURL)
+		if err != nil {
+			return nil, err
+		}
+		return conn.ClientConn, nil
+	}
+
 	return grpc.NewClient(
 		"passthrough:///bufconn",
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithChainUnaryInterceptor(goclient.UnaryErrorInterceptor),
+		grpc.WithChainStreamInterceptor(goclient.StreamErrorInterceptor),
 		BuiltinDialer(),
 	)
 }
@@ -47,11 +105,50 @@ type Builtin struct {
 	Run  func(ctx context.Context, logger *slog.Logger, serverURL string) error
 }
 
+// Builtin states reported by Statuses
+const (
+	StateStopped    = "stopped"
+	StateRunning    = "running"
+	StateRestarting = "restarting"
+)
+
+// Status is the state of a registered builtin. The connectors it runs report
+// their own status as entities, see controller.StatusKey.
+type Status = goclient.BuiltinStatus
+
 var (
 	mu       sync.RWMutex
 	builtins []Builtin
+	statuses = make(map[string]*Status)
 )
 
+// Statuses returns the state of all registered builtins in registration order
+func Statuses() []Status {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	result := make([]Status, 0, len(builtins))
+	for _, b := range builtins {
+		if s, ok := statuses[b.Name]; ok {
+			result = append(result, *s)
+		} else {
+			result = append(result, Status{Name: b.Name, State: StateStopped})
+		}
+	}
+	return result
+}
+
+func setStatus(name string, fn func(s *Status)) {
+	mu.Lock()
+	defer mu.Unlock()
+	s, ok := statuses[name]
+	if !ok {
+		s = &Status{Name: name}
+		statuses[name] = s
+	}
+	fn(s)
+}
+
 func Register(name string, run func(ctx context.Context, logger *slog.Logger, serverURL string) error) {
 	builtins = append(builtins, Builtin{
 		Name: name,
@@ -59,37 +156,102 @@ func Register(name string, run func(ctx context.Context, logger *slog.Logger, se
 	})
 }
 
-func StartAll(ctx context.Context, serverURL string) {
+// StartAll starts all builtins but those named in except, e.g. because they run out of process
+func StartAll(ctx context.Context, serverURL string, except ...string) {
 	for _, b := range builtins {
-		builtin := b // capture loop variable
+		if !slices.Contains(except, b.Name) {
+			start(ctx, b, serverURL)
+		}
+	}
+}
+
+// RunNamed runs the builtins with the given names, e.g. one of them out of
+// process, until ctx is done or all of them failed for good. It returns their
+// errors, so a container can exit and be restarted by its orchestrator.
+func RunNamed(ctx context.Context, serverURL string, names ...string) error {
+	var selected []Builtin
+	for _, name := range names {
+		i := slices.IndexFunc(builtins, func(b Builtin) bool { return b.Name == name })
+		if i < 0 {
+			known := make([]string, len(builtins))
+			for j, b := range builtins {
+				known[j] = b.Name
+			}
+			return fmt.Errorf("unknown builtin %q, expected one of %s", name, strings.Join(known, ", "))
+		}
+		selected = append(selected, builtins[i])
+	}
+
+	errs := make([]error, len(selected))
+	var wg sync.WaitGroup
+	for i, b := range selected {
+		wg.Add(1)
 		go func() {
-			// Create a logger with module prefix for this builtin
-			logger := slog.Default().With("module", builtin.Name)
-
-			for {
-				select {
-				case <-ctx.Done():
-					logger.Info("Stopping (context cancelled)")
-					return
-				default:
-				}
-
-				err := builtin.Run(ctx, logger, serverURL)
-
-				if ctx.Err() != nil {
-					// Context cancelled, don't restart
-					return
-				}
-
-				logger.Error("Crashed, restarting in 1 second", "error", err)
-
-				select {
-				case <-ctx.Done():
-					return
-				case <-time.After(1 * time.Second):
-					// Continue to restart
-				}
+			defer wg.Done()
+			if err := run(ctx, b, serverURL); err != nil {
+				errs[i] = fmt.Errorf("%s: %w", b.Name, err)
 			}
 		}()
 	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// start runs a builtin in the background, see run
+func start(ctx context.Context, builtin Builtin, serverURL string) {
+	go run(ctx, builtin, serverURL)
+}
+
+// run runs a builtin until ctx is done, restarting it with a backoff when it
+// fails. It returns the error the builtin failed with for good.
+func run(ctx context.Context, builtin Builtin, serverURL string) error {
+	// Create a logger with module prefix for this builtin
+	logger := slog.Default().With("module", builtin.Name)
+	backoff := &Backoff{Min: time.Second, Max: 5 * time.Minute, Reset: time.Minute}
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("Stopping (context cancelled)")
+			setStatus(builtin.Name, func(s *Status) { s.State = StateStopped })
+			return nil
+		default:
+		}
+
+		setStatus(builtin.Name, func(s *Status) { s.State = StateRunning })
+		started := time.Now()
+		err := builtin.Run(ctx, logger, serverURL)
+
+		if ctx.Err() != nil {
+			// Context cancelled, don't restart
+			setStatus(builtin.Name, func(s *Status) { s.State = StateStopped })
+			return nil
+		}
+
+		delay, retry := backoff.After(time.Since(started), err)
+		if !retry {
+			logger.Error("Failed, not restarting", "error", err)
+			setStatus(builtin.Name, func(s *Status) {
+				s.State = StateStopped
+				s.LastError = err.Error()
+			})
+			return err
+		}
+		logger.Error("Crashed, restarting", "error", err, "in", delay)
+		setStatus(builtin.Name, func(s *Status) {
+			s.State = StateRestarting
+			s.Restarts++
+			if err != nil {
+				s.LastError = err.Error()
+			}
+		})
+
+		select {
+		case <-ctx.Done():
+			setStatus(builtin.Name, func(s *Status) { s.State = StateStopped })
+			return nil
+		case <-time.After(delay):
+			// Continue to restart
+		}
+	}
 }
@@ -0,0 +1,25 @@
+package builtin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"testing"
+
+	"github.com/projectqai/hydra/goclient"
+)
+
+func TestRunNamed_ReturnsWhenFailedForGood(t *testing.T) {
+	Register("test-invalid", func(ctx context.Context, logger *slog.Logger, serverURL string) error {
+		return fmt.Errorf("bad config: %w", goclient.ErrInvalid)
+	})
+
+	err := RunNamed(context.Background(), "localhost:0", "test-invalid")
+	if !errors.Is(err, goclient.ErrInvalid) {
+		t.Errorf("expected the builtin's error, got %v", err)
+	}
+	if err := RunNamed(context.Background(), "localhost:0", "no-such-builtin"); err == nil {
+		t.Error("expected an unknown builtin to be rejected")
+	}
+}
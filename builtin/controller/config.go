@@ -0,0 +1,55 @@
+package controller
+
+import (
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// ParseEntityFilter reads an EntityFilter from a config value, e.g.
+// {id, label, component: [11], config: {controller, key}}
+func ParseEntityFilter(v *structpb.Value) *pb.EntityFilter {
+	if v == nil {
+		return nil
+	}
+
+	s := v.GetStructValue()
+	if s == nil {
+		return nil
+	}
+
+	filter := &pb.EntityFilter{}
+
+	if id, ok := s.Fields["id"]; ok {
+		idStr := id.GetStringValue()
+		filter.Id = &idStr
+	}
+
+	if label, ok := s.Fields["label"]; ok {
+		labelStr := label.GetStringValue()
+		filter.Label = &labelStr
+	}
+
+	if components, ok := s.Fields["component"]; ok {
+		if list := components.GetListValue(); list != nil {
+			for _, c := range list.Values {
+				filter.Component = append(filter.Component, uint32(c.GetNumberValue()))
+			}
+		}
+	}
+
+	if configFilter, ok := s.Fields["config"]; ok {
+		if cs := configFilter.GetStructValue(); cs != nil {
+			filter.Config = &pb.ConfigurationFilter{}
+			if ctrl, ok := cs.Fields["controller"]; ok {
+				ctrlStr := ctrl.GetStringValue()
+				filter.Config.Controller = &ctrlStr
+			}
+			if key, ok := cs.Fields["key"]; ok {
+				keyStr := key.GetStringValue()
+				filter.Config.Key = &keyStr
+			}
+		}
+	}
+
+	return filter
+}
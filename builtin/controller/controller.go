@@ -4,6 +4,7 @@ package controller
 import (
 	"context"
 	"log/slog"
+	"slices"
 	"sync"
 	"time"
 
@@ -19,19 +20,54 @@ import (
 // It will always be restarted until the context is cancelled.
 type RunFunc func(ctx context.Context, entity *pb.Entity) error
 
+// ValidateFunc checks the config of an entity before a connector is run for it.
+type ValidateFunc func(entity *pb.Entity) error
+
+// Option configures Run1to1.
+type Option func(*controller)
+
+// WithValidator checks every entity before running its connector. An entity that
+// fails validation is not run or restarted until it is updated, and the error is
+// reported in its status entity.
+func WithValidator(validate ValidateFunc) Option {
+	return func(c *controller) {
+		c.validate = validate
+	}
+}
+
+// WithDataKeys marks config keys of entities that carry data of the controller
+// rather than connector config, e.g. device details of TAK clients. Like status
+// entities, they are never run as connectors.
+func WithDataKeys(keys ...string) Option {
+	return func(c *controller) {
+		c.dataKeys = append(c.dataKeys, keys...)
+	}
+}
+
 type controller struct {
 	run        RunFunc
+	validate   ValidateFunc
+	dataKeys   []string
 	mu         sync.Mutex
 	connectors map[string]context.CancelFunc
+
+	// name and client are used to push status entities, no status is pushed if client is nil
+	name   string
+	client pb.WorldServiceClient
+	// statusDone is closed once the status of the previous connector for an id was expired
+	statusDone map[string]chan struct{}
 }
 
 // Run1to1 watches for entities matching the filter and runs exactly one connector for each entity
 // It blocks until the context is cancelled or an error occurs.
-func Run1to1(ctx context.Context, forEntity *pb.EntityFilter, run RunFunc) error {
+func Run1to1(ctx context.Context, forEntity *pb.EntityFilter, run RunFunc, opts ...Option) error {
 	c := &controller{
 		run:        run,
 		connectors: make(map[string]context.CancelFunc),
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
 
 	grpcConn, err := builtin.BuiltinClientConn()
 	if err != nil {
@@ -41,6 +77,12 @@ func Run1to1(ctx context.Context, forEntity *pb.EntityFilter, run RunFunc) error
 
 	client := pb.NewWorldServiceClient(grpcConn)
 
+	c.client = client
+	c.statusDone = make(map[string]chan struct{})
+	if forEntity.Config != nil && forEntity.Config.Controller != nil {
+		c.name = *forEntity.Config.Controller
+	}
+
 	stream, err := goclient.WatchEntitiesWithRetry(ctx, client, &pb.ListEntitiesRequest{
 		Filter: forEntity,
 	})
@@ -57,6 +99,9 @@ func Run1to1(ctx context.Context, forEntity *pb.EntityFilter, run RunFunc) error
 		if event.Entity == nil {
 			continue
 		}
+		if event.Entity.Config != nil && (event.Entity.Config.Key == StatusKey || slices.Contains(c.dataKeys, event.Entity.Config.Key)) {
+			continue
+		}
 
 		entity := event.Entity
 
@@ -106,24 +151,78 @@ func (c *controller) runConnector(ctx context.Context, entity *pb.Entity) {
 		c.mu.Unlock()
 	}()
 
+	backoff := &builtin.Backoff{Min: 5 * time.Second, Max: 5 * time.Minute, Reset: time.Minute}
+
+	st := &status{state: StateStarting, changed: make(chan struct{}, 1)}
+	ctx = context.WithValue(ctx, statusCtxKey{}, st)
+	if c.client != nil {
+		c.mu.Lock()
+		prev := c.statusDone[entity.Id]
+		done := make(chan struct{})
+		c.statusDone[entity.Id] = done
+		c.mu.Unlock()
+
+		go func() {
+			defer func() {
+				close(done)
+				c.mu.Lock()
+				if c.statusDone[entity.Id] == done {
+					delete(c.statusDone, entity.Id)
+				}
+				c.mu.Unlock()
+			}()
+			// don't let the previous connector expire the status of this one
+			if prev != nil {
+				<-prev
+			}
+			c.reportStatus(ctx, st, entity)
+		}()
+	}
+
+	if c.validate != nil {
+		if err := c.validate(entity); err != nil {
+			slog.Error("invalid connector config, not starting", "entityID", entity.Id, "error", err)
+			st.update(func(s *status) {
+				s.state = StateInvalid
+				s.lastError = err.Error()
+			})
+			<-ctx.Done()
+			return
+		}
+	}
+
 	for {
 		if ctx.Err() != nil {
 			return
 		}
 
+		started := time.Now()
 		err := c.run(ctx, entity)
 		if ctx.Err() != nil {
 			return
 		}
 
+		delay, retry := backoff.After(time.Since(started), err)
+		if !retry {
+			// like an invalid config, the connector runs again once its entity is updated
+			slog.Error("connector failed, not restarting", "entityID", entity.Id, "error", err)
+			st.update(func(s *status) {
+				s.state = StateInvalid
+				s.lastError = err.Error()
+			})
+			<-ctx.Done()
+			return
+		}
 		if err != nil {
-			slog.Error("connector error, restarting", "entityID", entity.Id, "error", err)
+			slog.Error("connector error, restarting", "entityID", entity.Id, "error", err, "in", delay)
+			ReportError(ctx, err)
 		}
+		st.update(func(s *status) { s.restarts++ })
 
 		select {
 		case <-ctx.Done():
 			return
-		case <-time.After(5 * time.Second):
+		case <-time.After(delay):
 		}
 	}
 }
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	pb "github.com/projectqai/proto/go"
+	"google.golang.org/grpc"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
@@ -222,7 +223,7 @@ func TestControllerRestartsOnError(t *testing.T) {
 	entity := &pb.Entity{Id: "test-entity-6"}
 	c.handleUpdate(ctx, entity)
 
-	// Wait for restarts (5s backoff means we need to wait a bit)
+	// Wait for restarts (backoff starts at 5s so we need to wait a bit)
 	// But we can cancel early after confirming restart behavior
 	time.Sleep(100 * time.Millisecond)
 
@@ -311,3 +312,108 @@ func TestControllerParentContextCancellation(t *testing.T) {
 		t.Error("expected connector to be cancelled when parent context is cancelled")
 	}
 }
+
+func TestControllerSkipsInvalidConfig(t *testing.T) {
+	var runCount atomic.Int32
+
+	c := &controller{
+		run: func(ctx context.Context, entity *pb.Entity) error {
+			runCount.Add(1)
+			<-ctx.Done()
+			return ctx.Err()
+		},
+		connectors: make(map[string]context.CancelFunc),
+	}
+	WithValidator(func(entity *pb.Entity) error {
+		if entity.Label == nil {
+			return errors.New("label is required")
+		}
+		return nil
+	})(c)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c.handleUpdate(ctx, &pb.Entity{Id: "test-entity-8"})
+	time.Sleep(50 * time.Millisecond)
+
+	if runCount.Load() != 0 {
+		t.Error("expected invalid config not to run")
+	}
+
+	// fixing the config starts the connector
+	label := "fixed"
+	c.handleUpdate(ctx, &pb.Entity{Id: "test-entity-8", Label: &label})
+	time.Sleep(50 * time.Millisecond)
+
+	if runCount.Load() != 1 {
+		t.Errorf("expected fixed config to run once, got %d", runCount.Load())
+	}
+}
+
+// pushRecorder is a world client that records the entities pushed to it
+type pushRecorder struct {
+	pb.WorldServiceClient
+	mu     sync.Mutex
+	pushed []*pb.Entity
+}
+
+func (r *pushRecorder) Push(ctx context.Context, req *pb.EntityChangeRequest, _ ...grpc.CallOption) (*pb.EntityChangeResponse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pushed = append(r.pushed, req.Changes...)
+	return &pb.EntityChangeResponse{}, nil
+}
+
+func (r *pushRecorder) last() *pb.Entity {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.pushed) == 0 {
+		return nil
+	}
+	return r.pushed[len(r.pushed)-1]
+}
+
+func TestControllerStatusLifecycle(t *testing.T) {
+	recorder := &pushRecorder{}
+	c := &controller{
+		run: func(ctx context.Context, entity *pb.Entity) error {
+			ReportSuccess(ctx, 3)
+			<-ctx.Done()
+			return ctx.Err()
+		},
+		connectors: make(map[string]context.CancelFunc),
+		name:       "test",
+		client:     recorder,
+		statusDone: make(map[string]chan struct{}),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	entity := &pb.Entity{Id: "test-entity-9"}
+	c.handleUpdate(ctx, entity)
+	time.Sleep(50 * time.Millisecond)
+
+	st := recorder.last()
+	if st == nil || st.Id != "test-entity-9-status" || st.Config.Key != StatusKey {
+		t.Fatalf("expected a status entity for the connector, got %v", st)
+	}
+	fields := st.Config.Value.Fields
+	if fields["state"].GetStringValue() != StateConnected || fields["count"].GetNumberValue() != 3 {
+		t.Errorf("expected connected with 3 items, got %v", fields)
+	}
+	if !st.Lifetime.Until.AsTime().After(time.Now().Add(statusInterval)) {
+		t.Errorf("expected the status to outlive the push interval, until %v", st.Lifetime.Until.AsTime())
+	}
+
+	// removing the connector expires its status
+	entity.Lifetime = &pb.Lifetime{Until: timestamppb.Now()}
+	c.handleUpdate(ctx, entity)
+	time.Sleep(50 * time.Millisecond)
+
+	st = recorder.last()
+	if st.Lifetime.Until.AsTime().After(time.Now()) {
+		t.Errorf("expected the status to expire with the connector, until %v", st.Lifetime.Until.AsTime())
+	}
+}
@@ -0,0 +1,150 @@
+package controller
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// StatusKey is the config key of the status entities pushed for every running connector.
+// Status entities carry the controller name, so they show up next to the connector
+// config, e.g. in `hydra ec ls --config-controller ais`. They are never run as connectors.
+const StatusKey = "controller.status.v0"
+
+// Connector states reported in the status entity
+const (
+	StateStarting  = "starting"
+	StateConnected = "connected"
+	StateError     = "error"
+	// StateInvalid is reported when the config failed validation, see WithValidator
+	StateInvalid = "invalid"
+)
+
+const (
+	// statusInterval is how often the status is pushed even if nothing changed
+	statusInterval = 30 * time.Second
+	// statusTTL expires the status entity if the process dies without removing it
+	statusTTL = 3 * statusInterval
+)
+
+type status struct {
+	mu          sync.Mutex
+	state       string
+	lastError   string
+	lastSuccess time.Time
+	count       int64
+	restarts    int
+
+	// changed is signalled when the state changes, so it is pushed right away
+	changed chan struct{}
+}
+
+type statusCtxKey struct{}
+
+func statusFrom(ctx context.Context) *status {
+	s, _ := ctx.Value(statusCtxKey{}).(*status)
+	return s
+}
+
+func (s *status) update(fn func(s *status)) {
+	s.mu.Lock()
+	before := s.state
+	fn(s)
+	after := s.state
+	s.mu.Unlock()
+
+	if before != after {
+		select {
+		case s.changed <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// ReportSuccess marks the connector running under ctx as connected and adds n
+// to the number of items it processed.
+func ReportSuccess(ctx context.Context, n int) {
+	if s := statusFrom(ctx); s != nil {
+		s.update(func(s *status) {
+			s.state = StateConnected
+			s.lastSuccess = time.Now()
+			s.count += int64(n)
+		})
+	}
+}
+
+// ReportError marks the connector running under ctx as failing, for errors
+// it handles itself without returning.
+func ReportError(ctx context.Context, err error) {
+	if s := statusFrom(ctx); s != nil {
+		s.update(func(s *status) {
+			s.state = StateError
+			s.lastError = err.Error()
+		})
+	}
+}
+
+func (s *status) entity(name string, connector *pb.Entity, until time.Time) *pb.Entity {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fields := map[string]any{
+		"connector": connector.Id,
+		"state":     s.state,
+		"count":     float64(s.count),
+		"restarts":  float64(s.restarts),
+	}
+	if s.lastError != "" {
+		fields["last_error"] = s.lastError
+	}
+	if !s.lastSuccess.IsZero() {
+		fields["last_success"] = s.lastSuccess.UTC().Format(time.RFC3339)
+	}
+	value, _ := structpb.NewStruct(fields)
+
+	label := name + " status"
+	return &pb.Entity{
+		Id:         connector.Id + "-status",
+		Label:      &label,
+		Controller: &pb.ControllerRef{Id: connector.Id, Name: name},
+		Lifetime: &pb.Lifetime{
+			From:  timestamppb.Now(),
+			Until: timestamppb.New(until),
+		},
+		Config: &pb.ConfigurationComponent{
+			Controller: name,
+			Key:        StatusKey,
+			Value:      value,
+		},
+	}
+}
+
+// reportStatus pushes the status of a connector until ctx is done, then expires it
+func (c *controller) reportStatus(ctx context.Context, s *status, connector *pb.Entity) {
+	push := func(ctx context.Context, until time.Time) {
+		_, _ = c.client.Push(ctx, &pb.EntityChangeRequest{
+			Changes: []*pb.Entity{s.entity(c.name, connector, until)},
+		})
+	}
+
+	ticker := time.NewTicker(statusInterval)
+	defer ticker.Stop()
+
+	for {
+		push(ctx, time.Now().Add(statusTTL))
+
+		select {
+		case <-ctx.Done():
+			expireCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			push(expireCtx, time.Now())
+			cancel()
+			return
+		case <-ticker.C:
+		case <-s.changed:
+		}
+	}
+}
@@ -2,9 +2,12 @@ package federation
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"net/netip"
+	"time"
 
 	"github.com/projectqai/hydra/builtin"
 	"github.com/projectqai/hydra/builtin/controller"
@@ -72,7 +75,7 @@ func runInstance(ctx context.Context, logger *slog.Logger, serverURL string, ent
 		}
 
 		if v, ok := config.Value.Fields["filter"]; ok {
-			filter = parseEntityFilter(v)
+			filter = controller.ParseEntityFilter(v)
 		}
 
 		if v, ok := config.Value.Fields["limiter"]; ok {
@@ -113,15 +116,42 @@ func runInstance(ctx context.Context, logger *slog.Logger, serverURL string, ent
 
 func (i *Instance) connectToRemote() (*goclient.Connection, error) {
 	if i.wgConfig != nil {
-		conn, tunnel, err := goclient.ConnectViaWireGuard(i.remote, i.wgConfig)
-		if err != nil {
-			return nil, err
-		}
-		return &goclient.Connection{ClientConn: conn, Tunnel: tunnel}, nil
+		return goclient.ConnectWithWireGuardConfig(i.remote, i.wgConfig)
 	}
 	return goclient.Connect(i.remote)
 }
 
+// handshake compares the builds of both engines before any entity is exchanged.
+// Differences are logged, an incompatible remote is refused. Remotes that
+// predate the handshake, whose /info is not found, are accepted with a warning.
+func (i *Instance) handshake(ctx context.Context, localConn, remoteConn *goclient.Connection) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	local, err := localConn.ServerInfo(ctx)
+	if err != nil {
+		return fmt.Errorf("local server info: %w", err)
+	}
+	remote, err := remoteConn.ServerInfo(ctx)
+	var httpErr *goclient.HTTPError
+	if errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusNotFound {
+		i.logger.Warn("remote has no server info, it may be an older version", "entityID", i.entityID, "remote", i.remote)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("remote server info: %w", err)
+	}
+
+	warnings, err := goclient.Compatible(local, remote)
+	if err != nil {
+		return fmt.Errorf("refusing to federate with %s: %w", i.remote, err)
+	}
+	for _, w := range warnings {
+		i.logger.Warn("remote differs", "entityID", i.entityID, "remote", i.remote, "difference", w)
+	}
+	return nil
+}
+
 func (i *Instance) runPull(ctx context.Context) error {
 	localConn, err := goclient.Connect(i.serverURL)
 	if err != nil {
@@ -135,6 +165,10 @@ func (i *Instance) runPull(ctx context.Context) error {
 	}
 	defer remoteConn.Close()
 
+	if err := i.handshake(ctx, localConn, remoteConn); err != nil {
+		return err
+	}
+
 	localClient := pb.NewWorldServiceClient(localConn)
 	remoteClient := pb.NewWorldServiceClient(remoteConn)
 
@@ -196,6 +230,10 @@ func (i *Instance) runPush(ctx context.Context) error {
 	}
 	defer remoteConn.Close()
 
+	if err := i.handshake(ctx, localConn, remoteConn); err != nil {
+		return err
+	}
+
 	localClient := pb.NewWorldServiceClient(localConn)
 	remoteClient := pb.NewWorldServiceClient(remoteConn)
 
@@ -279,53 +317,6 @@ func parseWireGuardConfig(v *structpb.Value) *goclient.WireGuardConfig {
 	return cfg
 }
 
-func parseEntityFilter(v *structpb.Value) *pb.EntityFilter {
-	if v == nil {
-		return nil
-	}
-
-	s := v.GetStructValue()
-	if s == nil {
-		return nil
-	}
-
-	filter := &pb.EntityFilter{}
-
-	if id, ok := s.Fields["id"]; ok {
-		idStr := id.GetStringValue()
-		filter.Id = &idStr
-	}
-
-	if label, ok := s.Fields["label"]; ok {
-		labelStr := label.GetStringValue()
-		filter.Label = &labelStr
-	}
-
-	if components, ok := s.Fields["component"]; ok {
-		if list := components.GetListValue(); list != nil {
-			for _, c := range list.Values {
-				filter.Component = append(filter.Component, uint32(c.GetNumberValue()))
-			}
-		}
-	}
-
-	if configFilter, ok := s.Fields["config"]; ok {
-		if cs := configFilter.GetStructValue(); cs != nil {
-			filter.Config = &pb.ConfigurationFilter{}
-			if ctrl, ok := cs.Fields["controller"]; ok {
-				ctrlStr := ctrl.GetStringValue()
-				filter.Config.Controller = &ctrlStr
-			}
-			if key, ok := cs.Fields["key"]; ok {
-				keyStr := key.GetStringValue()
-				filter.Config.Key = &keyStr
-			}
-		}
-	}
-
-	return filter
-}
-
 func parseWatchLimiter(v *structpb.Value) *pb.WatchLimiter {
 	if v == nil {
 		return nil
@@ -0,0 +1,162 @@
+package geofence
+
+import (
+	"time"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/planar"
+	"github.com/projectqai/hydra/goclient"
+)
+
+type Transition string
+
+const (
+	TransitionEnter Transition = "enter"
+	TransitionExit  Transition = "exit"
+)
+
+type memberState struct {
+	inside bool
+	// left is set once the entity moved out of the area watched for the fence.
+	// It is forgotten as soon as it is confirmed outside.
+	left bool
+
+	// candidate is the side the entity was last seen on while it differs from
+	// inside. It is confirmed once the entity stayed there for the dwell time.
+	pending bool
+	since   time.Time
+	point   orb.Point
+}
+
+// Fence tracks on which side of a polygon each entity is.
+// Transitions are only reported after an entity stayed on the new side for dwell,
+// so tracks jittering along the boundary don't flap.
+type Fence struct {
+	polygon orb.Polygon
+	buffer  float64
+	dwell   time.Duration
+	members map[string]*memberState
+}
+
+type Event struct {
+	EntityID   string
+	Transition Transition
+	Point      orb.Point
+	Time       time.Time
+}
+
+func NewFence(polygon orb.Polygon, dwell time.Duration) *Fence {
+	return &Fence{
+		polygon: polygon,
+		dwell:   dwell,
+		members: make(map[string]*memberState),
+	}
+}
+
+// SetBuffer makes points within meters of the polygon edge count as inside
+func (f *Fence) SetBuffer(meters float64) {
+	f.buffer = meters
+}
+
+func (f *Fence) Contains(p orb.Point) bool {
+	if planar.PolygonContains(f.polygon, p) {
+		return true
+	}
+	return f.buffer > 0 && goclient.DistanceToGeometry(p, f.polygon) <= f.buffer
+}
+
+// Observe records a position of an entity. Entities never seen before are
+// considered outside, so appearing inside the fence counts as entering it.
+func (f *Fence) Observe(id string, p orb.Point, now time.Time) *Event {
+	m, ok := f.members[id]
+	if !ok {
+		m = &memberState{}
+		f.members[id] = m
+	}
+	m.left = false
+	return f.observe(id, m, p, now)
+}
+
+// Leave records that an entity moved out of the area watched for the fence, to p.
+// Like with Observe its exit is only reported after the dwell time, in case it
+// comes right back, and it is forgotten once it is confirmed outside.
+func (f *Fence) Leave(id string, p orb.Point, now time.Time) *Event {
+	m, ok := f.members[id]
+	if !ok {
+		return nil
+	}
+	m.left = true
+	ev := f.observe(id, m, p, now)
+	f.forgetLeft(id, m)
+	return ev
+}
+
+func (f *Fence) observe(id string, m *memberState, p orb.Point, now time.Time) *Event {
+	m.point = p
+
+	inside := f.Contains(p)
+	if inside == m.inside {
+		m.pending = false
+		return nil
+	}
+	if !m.pending {
+		m.pending = true
+		m.since = now
+	}
+	return f.confirm(id, m, now)
+}
+
+// Tracks reports whether the entity is inside the fence or about to change sides
+func (f *Fence) Tracks(id string) bool {
+	m, ok := f.members[id]
+	return ok && (m.inside || m.pending)
+}
+
+// Remove forgets an entity, reporting an exit if it was inside
+func (f *Fence) Remove(id string, now time.Time) *Event {
+	m, ok := f.members[id]
+	if !ok {
+		return nil
+	}
+	delete(f.members, id)
+	if !m.inside {
+		return nil
+	}
+	return &Event{EntityID: id, Transition: TransitionExit, Point: m.point, Time: now}
+}
+
+// Tick confirms pending transitions whose dwell time has passed without a new observation
+func (f *Fence) Tick(now time.Time) []Event {
+	var events []Event
+	for id, m := range f.members {
+		if !m.pending {
+			continue
+		}
+		if ev := f.confirm(id, m, now); ev != nil {
+			events = append(events, *ev)
+		}
+		f.forgetLeft(id, m)
+	}
+	return events
+}
+
+// forgetLeft drops an entity that left the watched area once it is settled outside
+func (f *Fence) forgetLeft(id string, m *memberState) {
+	if m.left && !m.inside && !m.pending {
+		delete(f.members, id)
+	}
+}
+
+func (f *Fence) confirm(id string, m *memberState, now time.Time) *Event {
+	if now.Sub(m.since) < f.dwell {
+		return nil
+	}
+	m.pending = false
+	m.inside = !m.inside
+
+	t := TransitionExit
+	if m.inside {
+		t = TransitionEnter
+	}
+	return &Event{EntityID: id, Transition: t, Point: m.point, Time: now}
+}
@@ -0,0 +1,120 @@
+package geofence
+
+import (
+	"testing"
+	"time"
+
+	"github.com/paulmach/orb"
+)
+
+func testFence(dwell time.Duration) *Fence {
+	return NewFence(orb.Polygon{{{0, 0}, {1, 0}, {1, 1}, {0, 1}, {0, 0}}}, dwell)
+}
+
+var (
+	inside  = orb.Point{0.5, 0.5}
+	outside = orb.Point{2, 2}
+)
+
+func TestFence_EnterExitWithoutDwell(t *testing.T) {
+	f := testFence(0)
+	now := time.Now()
+
+	if ev := f.Observe("e1", outside, now); ev != nil {
+		t.Fatalf("expected no event outside, got %v", ev.Transition)
+	}
+	if ev := f.Observe("e1", inside, now); ev == nil || ev.Transition != TransitionEnter {
+		t.Fatalf("expected enter, got %v", ev)
+	}
+	if ev := f.Observe("e1", inside, now); ev != nil {
+		t.Fatalf("expected no repeated enter, got %v", ev.Transition)
+	}
+	if ev := f.Observe("e1", outside, now); ev == nil || ev.Transition != TransitionExit {
+		t.Fatalf("expected exit, got %v", ev)
+	}
+}
+
+func TestFence_DwellSuppressesFlapping(t *testing.T) {
+	f := testFence(10 * time.Second)
+	now := time.Now()
+
+	// flapping across the boundary faster than the dwell time
+	for i := 0; i < 5; i++ {
+		p := inside
+		if i%2 == 1 {
+			p = outside
+		}
+		if ev := f.Observe("e1", p, now.Add(time.Duration(i)*time.Second)); ev != nil {
+			t.Fatalf("unexpected %v while flapping", ev.Transition)
+		}
+	}
+
+	// the last observation (inside, t+4s) is confirmed once the dwell passed
+	if events := f.Tick(now.Add(13 * time.Second)); len(events) != 0 {
+		t.Fatalf("expected no event before dwell, got %d", len(events))
+	}
+	events := f.Tick(now.Add(14 * time.Second))
+	if len(events) != 1 || events[0].Transition != TransitionEnter {
+		t.Fatalf("expected one enter after dwell, got %v", events)
+	}
+}
+
+func TestFence_RemoveInsideIsExit(t *testing.T) {
+	f := testFence(0)
+	now := time.Now()
+
+	f.Observe("e1", inside, now)
+	if ev := f.Remove("e1", now); ev == nil || ev.Transition != TransitionExit {
+		t.Fatalf("expected exit on removal, got %v", ev)
+	}
+	if ev := f.Remove("e1", now); ev != nil {
+		t.Fatalf("expected nothing for unknown entity, got %v", ev.Transition)
+	}
+}
+
+func TestFence_LeaveForgetsAfterDwell(t *testing.T) {
+	f := testFence(10 * time.Second)
+	now := time.Now()
+
+	f.Observe("e1", inside, now)
+	f.Tick(now.Add(10 * time.Second))
+	if ev := f.Leave("e1", outside, now.Add(11*time.Second)); ev != nil {
+		t.Fatalf("expected the exit to wait for the dwell time, got %v", ev.Transition)
+	}
+	if !f.Tracks("e1") {
+		t.Fatal("expected the entity to be tracked until its exit is confirmed")
+	}
+	events := f.Tick(now.Add(21 * time.Second))
+	if len(events) != 1 || events[0].Transition != TransitionExit {
+		t.Fatalf("expected one exit after dwell, got %v", events)
+	}
+	if _, ok := f.members["e1"]; ok {
+		t.Error("expected the entity to be forgotten once it left")
+	}
+
+	// never inside, so there is nothing to remember
+	f.Observe("e2", outside, now)
+	if f.Tracks("e2") {
+		t.Error("expected an entity outside not to be tracked")
+	}
+	f.Leave("e2", outside, now)
+	if _, ok := f.members["e2"]; ok {
+		t.Error("expected an entity that left from outside to be forgotten")
+	}
+}
+
+func TestFence_Buffer(t *testing.T) {
+	f := testFence(0)
+	near := orb.Point{1.005, 0.5} // ~550m east of the edge
+
+	if f.Contains(near) {
+		t.Fatal("expected point outside the polygon without buffer")
+	}
+	f.SetBuffer(1000)
+	if !f.Contains(near) {
+		t.Error("expected point within the buffer to count as inside")
+	}
+	if f.Contains(outside) {
+		t.Error("expected far point to stay outside")
+	}
+}
@@ -0,0 +1,356 @@
+// Package geofence alerts when entities enter or leave a configured polygon.
+package geofence
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/encoding/wkb"
+	"github.com/paulmach/orb/geo"
+	"github.com/projectqai/hydra/builtin"
+	"github.com/projectqai/hydra/builtin/controller"
+	"github.com/projectqai/hydra/goclient"
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const controllerName = "geofence"
+
+type FenceConfig struct {
+	Polygon orb.Polygon
+	// Buffer extends the fence by this many meters beyond the polygon edge
+	Buffer float64
+	// Dwell is how long an entity must stay on the other side before a transition is reported
+	Dwell time.Duration
+	// Webhook receives a JSON POST for every transition, if set
+	Webhook string
+	// AlertEntities pushes an alert entity into the world for every transition
+	AlertEntities bool
+	AlertTTL      time.Duration
+}
+
+type webhookPayload struct {
+	Fence      string     `json:"fence"`
+	EntityID   string     `json:"entity_id"`
+	Label      string     `json:"label,omitempty"`
+	Transition Transition `json:"transition"`
+	Time       time.Time  `json:"time"`
+	Longitude  float64    `json:"longitude"`
+	Latitude   float64    `json:"latitude"`
+}
+
+func Run(ctx context.Context, logger *slog.Logger, _ string) error {
+	name := controllerName
+
+	return controller.Run1to1(ctx, &pb.EntityFilter{
+		Component: []uint32{31},
+		Config: &pb.ConfigurationFilter{
+			Controller: &name,
+		},
+	}, func(ctx context.Context, entity *pb.Entity) error {
+		return runFence(ctx, logger, entity)
+	}, controller.WithValidator(validateFence))
+}
+
+func validateFence(entity *pb.Entity) error {
+	if entity.Config.Key != "geofence.v0" {
+		return fmt.Errorf("unknown config key: %s", entity.Config.Key)
+	}
+	_, err := parseFenceConfig(entity.Config)
+	return err
+}
+
+func runFence(ctx context.Context, logger *slog.Logger, entity *pb.Entity) error {
+	if entity.Config.Key != "geofence.v0" {
+		return fmt.Errorf("unknown config key: %s", entity.Config.Key)
+	}
+
+	config, err := parseFenceConfig(entity.Config)
+	if err != nil {
+		return fmt.Errorf("parse config: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	grpcConn, err := builtin.BuiltinClientConn()
+	if err != nil {
+		return fmt.Errorf("gRPC connection: %w", err)
+	}
+	defer grpcConn.Close()
+
+	client := pb.NewWorldServiceClient(grpcConn)
+
+	// Only entities around the fence are watched. The engine tells when one moves
+	// out of the area with EntityChangeUnobserved, which the fence sees as leaving.
+	area, err := wkb.Marshal(geo.BoundPad(config.Polygon.Bound(), config.Buffer).ToPolygon())
+	if err != nil {
+		return fmt.Errorf("encode fence area: %w", err)
+	}
+	stream, err := goclient.WatchEntitiesWithRetry(goclient.WithWatchTransitions(ctx), client, &pb.ListEntitiesRequest{
+		Filter: &pb.EntityFilter{
+			Component: []uint32{11},
+			Geo:       &pb.GeoFilter{Geo: &pb.GeoFilter_Geometry{Geometry: &pb.Geometry{Wkb: area}}},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	events := make(chan *pb.EntityChangeEvent)
+	errc := make(chan error, 1)
+	go func() {
+		for {
+			event, err := stream.Recv()
+			if err != nil {
+				errc <- err
+				return
+			}
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	logger.Info("Watching geofence", "entityID", entity.Id, "dwell", config.Dwell)
+
+	fence := NewFence(config.Polygon, config.Dwell)
+	fence.SetBuffer(config.Buffer)
+	// labels of the entities the fence tracks, for the alerts of their transitions
+	labels := make(map[string]string)
+
+	var hooks chan webhookPayload
+	if config.Webhook != "" {
+		hooks = make(chan webhookPayload, webhookQueue)
+		go postWebhooks(ctx, logger.With("entityID", entity.Id), config.Webhook, hooks)
+	}
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		var transitions []Event
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-errc:
+			return err
+		case now := <-ticker.C:
+			transitions = fence.Tick(now)
+		case event := <-events:
+			e := event.Entity
+			if e == nil || e.Id == entity.Id || event.T == pb.EntityChange_EntityChangeInvalid {
+				continue
+			}
+			// don't react to our own (or any other fence's) alerts
+			if e.Controller != nil && e.Controller.Name == controllerName {
+				continue
+			}
+
+			now := time.Now()
+			var ev *Event
+			switch {
+			case event.T == pb.EntityChange_EntityChangeExpired || e.Geo == nil:
+				ev = fence.Remove(e.Id, now)
+			case event.T == pb.EntityChange_EntityChangeUnobserved:
+				ev = fence.Leave(e.Id, orb.Point{e.Geo.Longitude, e.Geo.Latitude}, now)
+			default:
+				ev = fence.Observe(e.Id, orb.Point{e.Geo.Longitude, e.Geo.Latitude}, now)
+			}
+			if e.Label != nil && (fence.Tracks(e.Id) || ev != nil) {
+				labels[e.Id] = *e.Label
+			}
+			if ev != nil {
+				transitions = append(transitions, *ev)
+			} else if !fence.Tracks(e.Id) {
+				delete(labels, e.Id)
+			}
+		}
+
+		for _, t := range transitions {
+			label := labels[t.EntityID]
+			if !fence.Tracks(t.EntityID) {
+				delete(labels, t.EntityID)
+			}
+			logger.Info("Geofence transition", "entityID", entity.Id, "target", t.EntityID, "label", label, "transition", t.Transition)
+
+			if config.AlertEntities {
+				alert := alertEntity(entity, t, label, config.AlertTTL)
+				if _, err := client.Push(ctx, &pb.EntityChangeRequest{Changes: []*pb.Entity{alert}}); err != nil {
+					logger.Error("Failed to push alert", "entityID", entity.Id, "error", err)
+				}
+			}
+			if hooks != nil {
+				select {
+				case hooks <- webhookPayload{
+					Fence:      entity.Id,
+					EntityID:   t.EntityID,
+					Label:      label,
+					Transition: t.Transition,
+					Time:       t.Time,
+					Longitude:  t.Point[0],
+					Latitude:   t.Point[1],
+				}:
+				default:
+					logger.Warn("Webhook is too slow, dropping transition", "entityID", entity.Id, "target", t.EntityID, "transition", t.Transition)
+				}
+			}
+		}
+	}
+}
+
+// alertEntity describes a transition. There is one alert per fence and target,
+// detected by the fence so it can be listed with `ec ls --parent <fence>`.
+func alertEntity(fence *pb.Entity, t Event, label string, ttl time.Duration) *pb.Entity {
+	if label == "" {
+		label = t.EntityID
+	}
+	fenceLabel := fence.Id
+	if fence.Label != nil {
+		fenceLabel = *fence.Label
+	}
+	text := fmt.Sprintf("%s entered %s", label, fenceLabel)
+	if t.Transition == TransitionExit {
+		text = fmt.Sprintf("%s left %s", label, fenceLabel)
+	}
+	priority := pb.Priority_PriorityImmediate
+
+	return &pb.Entity{
+		Id:       fmt.Sprintf("%s-alert-%s", fence.Id, t.EntityID),
+		Label:    &text,
+		Priority: &priority,
+		Lifetime: &pb.Lifetime{
+			From:  timestamppb.New(t.Time),
+			Until: timestamppb.New(t.Time.Add(ttl)),
+		},
+		Geo: &pb.GeoSpatialComponent{
+			Longitude: t.Point[0],
+			Latitude:  t.Point[1],
+		},
+		Controller: &pb.ControllerRef{
+			Id:   fence.Id,
+			Name: controllerName,
+		},
+		Detection: &pb.DetectionComponent{
+			DetectorEntityId: &fence.Id,
+			LastMeasured:     timestamppb.New(t.Time),
+		},
+	}
+}
+
+// webhookQueue is how many transitions wait for a slow webhook before new ones are dropped
+const webhookQueue = 100
+
+// postWebhooks posts the queued transitions one after another, so a slow
+// endpoint doesn't hold up the fence
+func postWebhooks(ctx context.Context, logger *slog.Logger, url string, queue <-chan webhookPayload) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case payload := <-queue:
+			if err := postWebhook(ctx, url, payload); err != nil {
+				logger.Error("Failed to post webhook", "target", payload.EntityID, "error", err)
+			}
+		}
+	}
+}
+
+func postWebhook(ctx context.Context, url string, payload webhookPayload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func parseFenceConfig(config *pb.ConfigurationComponent) (*FenceConfig, error) {
+	if config.Value == nil || config.Value.Fields == nil {
+		return nil, fmt.Errorf("empty config value")
+	}
+
+	fields := config.Value.Fields
+	fenceConfig := &FenceConfig{
+		Dwell:         5 * time.Second,
+		AlertEntities: true,
+		AlertTTL:      5 * time.Minute,
+	}
+
+	polygon, err := parsePolygon(fields["polygon"])
+	if err != nil {
+		return nil, err
+	}
+	fenceConfig.Polygon = polygon
+
+	if v, ok := fields["buffer_meters"]; ok {
+		fenceConfig.Buffer = v.GetNumberValue()
+	}
+	if v, ok := fields["dwell_seconds"]; ok {
+		fenceConfig.Dwell = time.Duration(v.GetNumberValue() * float64(time.Second))
+	}
+	if v, ok := fields["webhook"]; ok {
+		fenceConfig.Webhook = v.GetStringValue()
+	}
+	if v, ok := fields["alert_entities"]; ok {
+		fenceConfig.AlertEntities = v.GetBoolValue()
+	}
+	if v, ok := fields["alert_ttl_seconds"]; ok {
+		fenceConfig.AlertTTL = time.Duration(v.GetNumberValue() * float64(time.Second))
+	}
+
+	return fenceConfig, nil
+}
+
+// parsePolygon reads a list of [lon, lat] pairs
+func parsePolygon(v *structpb.Value) (orb.Polygon, error) {
+	points := v.GetListValue().GetValues()
+	if len(points) < 3 {
+		return nil, fmt.Errorf("polygon needs at least 3 [lon, lat] points")
+	}
+
+	ring := make(orb.Ring, 0, len(points)+1)
+	for i, p := range points {
+		coords := p.GetListValue().GetValues()
+		if len(coords) < 2 {
+			return nil, fmt.Errorf("polygon point %d: expected [lon, lat]", i)
+		}
+		ring = append(ring, orb.Point{coords[0].GetNumberValue(), coords[1].GetNumberValue()})
+	}
+	if !ring.Closed() {
+		ring = append(ring, ring[0])
+	}
+	return orb.Polygon{ring}, nil
+}
+
+func init() {
+	builtin.Register(controllerName, Run)
+}
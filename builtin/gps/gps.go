@@ -0,0 +1,270 @@
+// Package gps keeps a self entity at the position of a GPS receiver attached to
+// the engine host, read as NMEA 0183 from a serial device or a TCP stream.
+package gps
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/adrianmo/go-nmea"
+	"github.com/projectqai/hydra/builtin"
+	"github.com/projectqai/hydra/builtin/controller"
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const configKey = "gps.nmea.v0"
+
+type ReceiverConfig struct {
+	// Device is a serial device like /dev/ttyACM0. It is read as a file, so the
+	// baud rate has to be set up front if the receiver needs one, e.g. with stty.
+	Device string
+	// Address is host:port of a TCP stream of NMEA sentences, instead of Device
+	Address string
+
+	// EntityID defaults to "self", the id of the engine's own self entity,
+	// which the GPS position then takes over
+	EntityID            string
+	Label               string
+	SIDC                string
+	EntityExpirySeconds int
+}
+
+// fix is the last known position, combined from RMC and GGA sentences
+type fix struct {
+	latitude  float64
+	longitude float64
+	altitude  *float64
+	course    *float64
+	at        time.Time
+}
+
+func Run(ctx context.Context, logger *slog.Logger, _ string) error {
+	controllerName := "gps"
+
+	return controller.Run1to1(ctx, &pb.EntityFilter{
+		Component: []uint32{31},
+		Config: &pb.ConfigurationFilter{
+			Controller: &controllerName,
+		},
+	}, func(ctx context.Context, entity *pb.Entity) error {
+		return runReceiver(ctx, logger, entity)
+	}, controller.WithValidator(validateReceiver))
+}
+
+func validateReceiver(entity *pb.Entity) error {
+	if entity.Config.Key != configKey {
+		return fmt.Errorf("unknown config key: %s", entity.Config.Key)
+	}
+	config, err := parseReceiverConfig(entity.Config)
+	if err != nil {
+		return err
+	}
+	if (config.Device == "") == (config.Address == "") {
+		return fmt.Errorf("either device or address is required")
+	}
+	return nil
+}
+
+func runReceiver(ctx context.Context, logger *slog.Logger, entity *pb.Entity) error {
+	config, err := parseReceiverConfig(entity.Config)
+	if err != nil {
+		return fmt.Errorf("parse config: %w", err)
+	}
+	if config.EntityID == "" {
+		config.EntityID = "self"
+	}
+	if config.Label == "" {
+		config.Label = "Self"
+	}
+	if config.SIDC == "" {
+		config.SIDC = "SFGPU-----*****"
+	}
+	if config.EntityExpirySeconds <= 0 {
+		config.EntityExpirySeconds = 30
+	}
+
+	grpcConn, err := builtin.BuiltinClientConn()
+	if err != nil {
+		return fmt.Errorf("gRPC connection: %w", err)
+	}
+	defer grpcConn.Close()
+
+	worldClient := pb.NewWorldServiceClient(grpcConn)
+	source := config.Device
+	if source == "" {
+		source = config.Address
+	}
+	logger = logger.With("entityID", entity.Id, "source", source)
+
+	// receivers get unplugged and plugged in again in the field, so keep trying
+	backoff := &builtin.Backoff{Min: time.Second, Max: 30 * time.Second, Reset: time.Minute}
+	for {
+		start := time.Now()
+		err := readReceiver(ctx, logger, config, entity.Id, worldClient)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		logger.Warn("GPS receiver lost, reconnecting", "error", err)
+		controller.ReportError(ctx, err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff.Next(time.Since(start))):
+		}
+	}
+}
+
+func open(ctx context.Context, config *ReceiverConfig) (io.ReadCloser, error) {
+	if config.Device != "" {
+		return os.Open(config.Device)
+	}
+	var d net.Dialer
+	return d.DialContext(ctx, "tcp", config.Address)
+}
+
+// readReceiver pushes the position until the source fails or ctx is done
+func readReceiver(ctx context.Context, logger *slog.Logger, config *ReceiverConfig, controllerID string, worldClient pb.WorldServiceClient) error {
+	r, err := open(ctx, config)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	// unblock the read when the connector stops
+	stop := context.AfterFunc(ctx, func() { r.Close() })
+	defer stop()
+
+	logger.Info("Reading GPS receiver")
+
+	var last fix
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "$") {
+			continue
+		}
+		s, err := nmea.Parse(line)
+		if err != nil {
+			continue
+		}
+		if !update(&last, s) {
+			continue
+		}
+
+		_, err = worldClient.Push(ctx, &pb.EntityChangeRequest{
+			Changes: []*pb.Entity{selfEntity(last, config, controllerID)},
+		})
+		if err != nil {
+			logger.Error("Failed to push position", "error", err)
+			controller.ReportError(ctx, err)
+			continue
+		}
+		controller.ReportSuccess(ctx, 1)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return io.EOF
+}
+
+// update applies a sentence to the fix and reports whether it carried a valid
+// position. Every valid one is pushed, so the entity is refreshed even when
+// the receiver is stationary.
+func update(f *fix, s nmea.Sentence) bool {
+	switch s := s.(type) {
+	case nmea.RMC:
+		if s.Validity != nmea.ValidRMC {
+			return false
+		}
+		f.latitude, f.longitude = s.Latitude, s.Longitude
+		if s.Speed > 0 {
+			course := s.Course
+			f.course = &course
+		} else {
+			f.course = nil
+		}
+	case nmea.GGA:
+		if s.FixQuality == nmea.Invalid {
+			return false
+		}
+		f.latitude, f.longitude = s.Latitude, s.Longitude
+		// GGA gives the altitude above mean sea level, entities are above the ellipsoid
+		alt := s.Altitude + s.Separation
+		f.altitude = &alt
+	default:
+		return false
+	}
+	f.at = time.Now()
+	return true
+}
+
+func selfEntity(f fix, config *ReceiverConfig, controllerID string) *pb.Entity {
+	label := config.Label
+	entity := &pb.Entity{
+		Id:    config.EntityID,
+		Label: &label,
+		Lifetime: &pb.Lifetime{
+			From:  timestamppb.New(f.at),
+			Until: timestamppb.New(f.at.Add(time.Duration(config.EntityExpirySeconds) * time.Second)),
+		},
+		Geo: &pb.GeoSpatialComponent{
+			Latitude:  f.latitude,
+			Longitude: f.longitude,
+			Altitude:  f.altitude,
+		},
+		Symbol: &pb.SymbolComponent{
+			MilStd2525C: config.SIDC,
+		},
+		Controller: &pb.ControllerRef{
+			Id:   controllerID,
+			Name: "gps",
+		},
+	}
+	if f.course != nil {
+		entity.Bearing = &pb.BearingComponent{Azimuth: f.course}
+	}
+	return entity
+}
+
+func parseReceiverConfig(config *pb.ConfigurationComponent) (*ReceiverConfig, error) {
+	if config.Value == nil || config.Value.Fields == nil {
+		return nil, fmt.Errorf("empty config value")
+	}
+
+	fields := config.Value.Fields
+	receiverConfig := &ReceiverConfig{}
+
+	if v, ok := fields["device"]; ok {
+		receiverConfig.Device = v.GetStringValue()
+	}
+	if v, ok := fields["address"]; ok {
+		receiverConfig.Address = v.GetStringValue()
+	}
+	if v, ok := fields["entity_id"]; ok {
+		receiverConfig.EntityID = v.GetStringValue()
+	}
+	if v, ok := fields["label"]; ok {
+		receiverConfig.Label = v.GetStringValue()
+	}
+	if v, ok := fields["sidc"]; ok {
+		receiverConfig.SIDC = v.GetStringValue()
+	}
+	if v, ok := fields["entity_expiry_seconds"]; ok {
+		receiverConfig.EntityExpirySeconds = int(v.GetNumberValue())
+	}
+
+	return receiverConfig, nil
+}
+
+func init() {
+	builtin.Register("gps", Run)
+}
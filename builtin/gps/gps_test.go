@@ -0,0 +1,90 @@
+package gps
+
+import (
+	"math"
+	"testing"
+
+	"github.com/adrianmo/go-nmea"
+)
+
+func TestUpdate_Sentences(t *testing.T) {
+	ptr := func(v float64) *float64 { return &v }
+
+	// applied in order to the same fix
+	steps := []struct {
+		name     string
+		sentence string
+		want     bool
+		lat, lon float64
+		altitude *float64
+		course   *float64
+	}{
+		{
+			name:     "moving rmc sets the course",
+			sentence: "$GPRMC,123519,A,4807.038,N,01131.000,E,022.4,084.4,230394,003.1,W*6A",
+			want:     true,
+			lat:      48.1173, lon: 11.516667,
+			course: ptr(84.4),
+		},
+		{
+			name:     "gga adds the geoid separation to the altitude",
+			sentence: "$GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*47",
+			want:     true,
+			lat:      48.1173, lon: 11.516667,
+			altitude: ptr(545.4 + 46.9), course: ptr(84.4),
+		},
+		{
+			name:     "void rmc is ignored",
+			sentence: "$GPRMC,123520,V,4807.038,N,01131.000,E,022.4,084.4,230394,003.1,W*77",
+			lat:      48.1173, lon: 11.516667,
+			altitude: ptr(545.4 + 46.9), course: ptr(84.4),
+		},
+		{
+			name:     "gga without fix is ignored",
+			sentence: "$GPGGA,123520,4807.038,N,01131.000,E,0,00,,,M,,M,,*58",
+			lat:      48.1173, lon: 11.516667,
+			altitude: ptr(545.4 + 46.9), course: ptr(84.4),
+		},
+		{
+			name:     "stationary rmc clears the course",
+			sentence: "$GPRMC,123521,A,4807.100,N,01131.000,E,000.0,084.4,230394,003.1,W*6F",
+			want:     true,
+			lat:      48.118333, lon: 11.516667,
+			altitude: ptr(545.4 + 46.9),
+		},
+		{
+			name:     "other sentences are ignored",
+			sentence: "$GPGSA,A,3,04,05,,09,12,,,24,,,,,2.5,1.3,2.1*39",
+			lat:      48.118333, lon: 11.516667,
+			altitude: ptr(545.4 + 46.9),
+		},
+	}
+
+	near := func(a, b float64) bool { return math.Abs(a-b) < 1e-6 }
+	var f fix
+	for _, step := range steps {
+		s, err := nmea.Parse(step.sentence)
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if got := update(&f, s); got != step.want {
+			t.Errorf("%s: update returned %v, want %v", step.name, got, step.want)
+		}
+		if !near(f.latitude, step.lat) || !near(f.longitude, step.lon) {
+			t.Errorf("%s: position %f,%f, want %f,%f", step.name, f.latitude, f.longitude, step.lat, step.lon)
+		}
+		if (f.altitude == nil) != (step.altitude == nil) || f.altitude != nil && !near(*f.altitude, *step.altitude) {
+			t.Errorf("%s: altitude %v, want %v", step.name, deref(f.altitude), deref(step.altitude))
+		}
+		if (f.course == nil) != (step.course == nil) || f.course != nil && !near(*f.course, *step.course) {
+			t.Errorf("%s: course %v, want %v", step.name, deref(f.course), deref(step.course))
+		}
+	}
+}
+
+func deref(v *float64) any {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
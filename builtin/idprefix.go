@@ -0,0 +1,27 @@
+package builtin
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// PrefixID namespaces the id of an entity a builtin derives from its feed, e.g.
+// ais-<mmsi>, with the id_prefix of its config, so two instances of a builtin
+// for two regions become region1-ais-123 and region2-ais-123 instead of
+// overwriting each other. Without prefix the id is unchanged.
+func PrefixID(prefix, id string) string {
+	if prefix == "" {
+		return id
+	}
+	return prefix + "-" + id
+}
+
+// CheckIDPrefix rejects an id_prefix that would make ids hard to use on the
+// command line
+func CheckIDPrefix(prefix string) error {
+	if strings.IndexFunc(prefix, unicode.IsSpace) >= 0 {
+		return fmt.Errorf("id_prefix %q must not contain spaces", prefix)
+	}
+	return nil
+}
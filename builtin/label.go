@@ -0,0 +1,87 @@
+package builtin
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// LabelTemplate renders entity labels from fields of a feed, configured by
+// operators as e.g. label_template: "{callsign} ({squawk})". A placeholder may
+// list alternatives, the first field that is set is used: "{name|callsign|mmsi}".
+// Brackets left empty by unset fields are dropped.
+type LabelTemplate struct {
+	parts []labelPart
+}
+
+// labelPart is literal text, or a placeholder if fields is set
+type labelPart struct {
+	text   string
+	fields []string
+}
+
+// ParseLabelTemplate parses a template, only the known fields may be used in it
+func ParseLabelTemplate(template string, known ...string) (*LabelTemplate, error) {
+	t := &LabelTemplate{}
+	rest := template
+	for rest != "" {
+		open := strings.IndexByte(rest, '{')
+		if open < 0 {
+			break
+		}
+		end := strings.IndexByte(rest[open:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("label template %q: unclosed {", template)
+		}
+		if open > 0 {
+			t.parts = append(t.parts, labelPart{text: rest[:open]})
+		}
+
+		fields := strings.Split(rest[open+1:open+end], "|")
+		for i, f := range fields {
+			fields[i] = strings.TrimSpace(f)
+			if !slices.Contains(known, fields[i]) {
+				return nil, fmt.Errorf("label template %q: unknown field %q, expected one of %s", template, fields[i], strings.Join(known, ", "))
+			}
+		}
+		t.parts = append(t.parts, labelPart{fields: fields})
+		rest = rest[open+end+1:]
+	}
+	if strings.ContainsRune(rest, '}') {
+		return nil, fmt.Errorf("label template %q: unexpected }", template)
+	}
+	if rest != "" {
+		t.parts = append(t.parts, labelPart{text: rest})
+	}
+	return t, nil
+}
+
+// Render fills in the fields. It returns "" if t is nil or none of its
+// placeholders is set, callers then keep their own label.
+func (t *LabelTemplate) Render(fields map[string]string) string {
+	if t == nil {
+		return ""
+	}
+
+	var b strings.Builder
+	set := false
+	for _, p := range t.parts {
+		if p.fields == nil {
+			b.WriteString(p.text)
+			continue
+		}
+		for _, f := range p.fields {
+			if v := strings.TrimSpace(fields[f]); v != "" {
+				b.WriteString(v)
+				set = true
+				break
+			}
+		}
+	}
+	if !set {
+		return ""
+	}
+
+	label := strings.NewReplacer("()", "", "[]", "").Replace(b.String())
+	return strings.Join(strings.Fields(label), " ")
+}
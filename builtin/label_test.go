@@ -0,0 +1,33 @@
+package builtin
+
+import "testing"
+
+func TestLabelTemplate(t *testing.T) {
+	tmpl, err := ParseLabelTemplate("{callsign|registration} ({squawk})", "callsign", "registration", "squawk")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		fields map[string]string
+		want   string
+	}{
+		{map[string]string{"callsign": "DLH4AB ", "squawk": "7700"}, "DLH4AB (7700)"},
+		{map[string]string{"registration": "D-AIBA"}, "D-AIBA"},
+		{map[string]string{}, ""},
+	} {
+		if got := tmpl.Render(tc.fields); got != tc.want {
+			t.Errorf("Render(%v) = %q, want %q", tc.fields, got, tc.want)
+		}
+	}
+
+	if got := (*LabelTemplate)(nil).Render(map[string]string{"callsign": "X"}); got != "" {
+		t.Errorf("expected a nil template to render nothing, got %q", got)
+	}
+
+	for _, bad := range []string{"{callsign", "callsign}", "{mmsi}"} {
+		if _, err := ParseLabelTemplate(bad, "callsign"); err == nil {
+			t.Errorf("expected an error for %q", bad)
+		}
+	}
+}
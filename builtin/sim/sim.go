@@ -0,0 +1,309 @@
+// Package sim moves synthetic entities around, for demos and load tests without a real feed.
+package sim
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geo"
+	"github.com/projectqai/hydra/builtin"
+	"github.com/projectqai/hydra/builtin/controller"
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const controllerName = "sim"
+
+// maxCount bounds the entities of one simulation, a typo shouldn't take the engine down
+const maxCount = 1_000_000
+
+// profile is a preset of speeds and symbol for a kind of mover
+type profile struct {
+	minSpeed, maxSpeed float64 // m/s
+	sidc               string
+}
+
+var profiles = map[string]profile{
+	"pedestrian": {minSpeed: 0.5, maxSpeed: 2, sidc: "SFGPUCI----"},
+	"vehicle":    {minSpeed: 5, maxSpeed: 30, sidc: "SFGPEV-----"},
+	"vessel":     {minSpeed: 2, maxSpeed: 15, sidc: "SFSP-------"},
+	"aircraft":   {minSpeed: 60, maxSpeed: 250, sidc: "SFAPMF-----"},
+}
+
+type SimConfig struct {
+	// Count is the number of entities, default 10
+	Count int
+	// Bounds is the area the entities move in
+	Bounds orb.Bound
+	// Profile names a preset of speeds and symbol, default vehicle
+	Profile string
+	// MinSpeed and MaxSpeed override the speeds of the profile in m/s,
+	// each entity moves at a random speed between them
+	MinSpeed float64
+	MaxSpeed float64
+	// Mode is "random_walk", entities turn a little every step, default, or
+	// "waypoints", entities head for random points in Bounds one after another
+	Mode string
+	// Interval is how often all entities are moved and pushed, default 1s
+	Interval time.Duration
+	// SIDC overrides the symbol of the profile
+	SIDC string
+	// LabelPrefix is followed by the number of the entity, default "SIM-"
+	LabelPrefix string
+	// Seed makes runs repeatable, zero is random
+	Seed uint64
+}
+
+func Run(ctx context.Context, logger *slog.Logger, _ string) error {
+	name := controllerName
+
+	return controller.Run1to1(ctx, &pb.EntityFilter{
+		Component: []uint32{31},
+		Config: &pb.ConfigurationFilter{
+			Controller: &name,
+		},
+	}, func(ctx context.Context, entity *pb.Entity) error {
+		return runSim(ctx, logger, entity)
+	}, controller.WithValidator(validateSim))
+}
+
+func validateSim(entity *pb.Entity) error {
+	if entity.Config.Key != "sim.v0" {
+		return fmt.Errorf("unknown config key: %s", entity.Config.Key)
+	}
+	_, err := parseSimConfig(entity.Config)
+	return err
+}
+
+func runSim(ctx context.Context, logger *slog.Logger, entity *pb.Entity) error {
+	config, err := parseSimConfig(entity.Config)
+	if err != nil {
+		return fmt.Errorf("parse config: %w", err)
+	}
+
+	grpcConn, err := builtin.BuiltinClientConn()
+	if err != nil {
+		return fmt.Errorf("gRPC connection: %w", err)
+	}
+	defer grpcConn.Close()
+
+	worldClient := pb.NewWorldServiceClient(grpcConn)
+
+	seed := config.Seed
+	if seed == 0 {
+		seed = rand.Uint64()
+	}
+	world := newWorld(config, rand.New(rand.NewPCG(seed, seed)))
+
+	logger.Info("Starting simulation", "entityID", entity.Id, "count", config.Count, "mode", config.Mode, "interval", config.Interval)
+
+	ticker := time.NewTicker(config.Interval)
+	defer ticker.Stop()
+
+	last := time.Now()
+	for {
+		now := time.Now()
+		world.step(now.Sub(last).Seconds())
+		last = now
+
+		changes := world.entities(entity.Id, config, now)
+		if _, err := worldClient.Push(ctx, &pb.EntityChangeRequest{Changes: changes}); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			logger.Error("Failed to push entities", "entityID", entity.Id, "error", err)
+			controller.ReportError(ctx, err)
+		} else {
+			controller.ReportSuccess(ctx, len(changes))
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// mover is one simulated entity
+type mover struct {
+	pos      orb.Point
+	heading  float64 // degrees from north
+	speed    float64 // m/s
+	waypoint orb.Point
+}
+
+// simWorld moves all entities of a simulation
+type simWorld struct {
+	bounds orb.Bound
+	mode   string
+	rng    *rand.Rand
+	movers []mover
+}
+
+func newWorld(config *SimConfig, rng *rand.Rand) *simWorld {
+	w := &simWorld{bounds: config.Bounds, mode: config.Mode, rng: rng, movers: make([]mover, config.Count)}
+	for i := range w.movers {
+		m := &w.movers[i]
+		m.pos = w.randomPoint()
+		m.heading = rng.Float64() * 360
+		m.speed = config.MinSpeed + rng.Float64()*(config.MaxSpeed-config.MinSpeed)
+		m.waypoint = w.randomPoint()
+	}
+	return w
+}
+
+func (w *simWorld) randomPoint() orb.Point {
+	return orb.Point{
+		w.bounds.Min[0] + w.rng.Float64()*(w.bounds.Max[0]-w.bounds.Min[0]),
+		w.bounds.Min[1] + w.rng.Float64()*(w.bounds.Max[1]-w.bounds.Min[1]),
+	}
+}
+
+// step moves all entities by dt seconds. They turn back when they would leave the bounds.
+func (w *simWorld) step(dt float64) {
+	for i := range w.movers {
+		m := &w.movers[i]
+		distance := m.speed * dt
+
+		switch w.mode {
+		case "waypoints":
+			if geo.Distance(m.pos, m.waypoint) <= distance {
+				m.pos = m.waypoint
+				m.waypoint = w.randomPoint()
+			}
+			m.heading = geo.Bearing(m.pos, m.waypoint)
+		default:
+			m.heading += (w.rng.Float64() - 0.5) * 30
+		}
+
+		next := geo.PointAtBearingAndDistance(m.pos, m.heading, distance)
+		if !w.bounds.Contains(next) {
+			m.heading = geo.Bearing(m.pos, w.bounds.Center())
+			next = geo.PointAtBearingAndDistance(m.pos, m.heading, distance)
+		}
+		m.pos = next
+		m.heading = math.Mod(m.heading+360, 360)
+	}
+}
+
+// entities returns the current state of all entities. They expire a few
+// intervals after the simulation stops.
+func (w *simWorld) entities(sourceID string, config *SimConfig, now time.Time) []*pb.Entity {
+	lifetime := &pb.Lifetime{
+		From:  timestamppb.New(now),
+		Until: timestamppb.New(now.Add(3 * config.Interval)),
+	}
+	entities := make([]*pb.Entity, len(w.movers))
+	for i, m := range w.movers {
+		label := fmt.Sprintf("%s%d", config.LabelPrefix, i+1)
+		rad := m.heading * math.Pi / 180
+		east, north, up := m.speed*math.Sin(rad), m.speed*math.Cos(rad), 0.0
+		entities[i] = &pb.Entity{
+			Id:         fmt.Sprintf("%s-%d", sourceID, i+1),
+			Label:      &label,
+			Controller: &pb.ControllerRef{Id: sourceID, Name: controllerName},
+			Lifetime:   lifetime,
+			Geo:        &pb.GeoSpatialComponent{Longitude: m.pos[0], Latitude: m.pos[1]},
+			Symbol:     &pb.SymbolComponent{MilStd2525C: config.SIDC},
+			Kinematics: &pb.KinematicsComponent{
+				VelocityEnu: &pb.KinematicsEnu{East: &east, North: &north, Up: &up},
+			},
+		}
+	}
+	return entities
+}
+
+func parseSimConfig(config *pb.ConfigurationComponent) (*SimConfig, error) {
+	if config.Value == nil || config.Value.Fields == nil {
+		return nil, fmt.Errorf("empty config value")
+	}
+
+	fields := config.Value.Fields
+	simConfig := &SimConfig{
+		Count:       10,
+		Profile:     "vehicle",
+		Mode:        "random_walk",
+		Interval:    time.Second,
+		LabelPrefix: "SIM-",
+	}
+
+	bounds, err := parseBBox(fields["bbox"])
+	if err != nil {
+		return nil, err
+	}
+	simConfig.Bounds = bounds
+
+	if v, ok := fields["count"]; ok {
+		simConfig.Count = int(v.GetNumberValue())
+	}
+	if simConfig.Count < 1 || simConfig.Count > maxCount {
+		return nil, fmt.Errorf("count must be between 1 and %d", maxCount)
+	}
+	if v, ok := fields["profile"]; ok {
+		simConfig.Profile = v.GetStringValue()
+	}
+	p, ok := profiles[simConfig.Profile]
+	if !ok {
+		return nil, fmt.Errorf("unknown profile %q, expected pedestrian, vehicle, vessel or aircraft", simConfig.Profile)
+	}
+	simConfig.MinSpeed, simConfig.MaxSpeed, simConfig.SIDC = p.minSpeed, p.maxSpeed, p.sidc
+	if v, ok := fields["min_speed_mps"]; ok {
+		simConfig.MinSpeed = v.GetNumberValue()
+	}
+	if v, ok := fields["max_speed_mps"]; ok {
+		simConfig.MaxSpeed = v.GetNumberValue()
+	}
+	if simConfig.MinSpeed < 0 || simConfig.MaxSpeed < simConfig.MinSpeed {
+		return nil, fmt.Errorf("invalid speeds %v..%v m/s", simConfig.MinSpeed, simConfig.MaxSpeed)
+	}
+	if v, ok := fields["mode"]; ok {
+		simConfig.Mode = v.GetStringValue()
+	}
+	if simConfig.Mode != "random_walk" && simConfig.Mode != "waypoints" {
+		return nil, fmt.Errorf("unknown mode %q, expected random_walk or waypoints", simConfig.Mode)
+	}
+	if v, ok := fields["interval_seconds"]; ok {
+		simConfig.Interval = time.Duration(v.GetNumberValue() * float64(time.Second))
+	}
+	if simConfig.Interval < 10*time.Millisecond {
+		return nil, fmt.Errorf("interval_seconds must be at least 0.01")
+	}
+	if v, ok := fields["sidc"]; ok {
+		simConfig.SIDC = v.GetStringValue()
+	}
+	if v, ok := fields["label_prefix"]; ok {
+		simConfig.LabelPrefix = v.GetStringValue()
+	}
+	if v, ok := fields["seed"]; ok {
+		simConfig.Seed = uint64(v.GetNumberValue())
+	}
+
+	return simConfig, nil
+}
+
+// parseBBox reads [min_lon, min_lat, max_lon, max_lat]
+func parseBBox(v *structpb.Value) (orb.Bound, error) {
+	values := v.GetListValue().GetValues()
+	if len(values) != 4 {
+		return orb.Bound{}, fmt.Errorf("bbox must be [min_lon, min_lat, max_lon, max_lat]")
+	}
+	b := orb.Bound{
+		Min: orb.Point{values[0].GetNumberValue(), values[1].GetNumberValue()},
+		Max: orb.Point{values[2].GetNumberValue(), values[3].GetNumberValue()},
+	}
+	if b.Min[0] >= b.Max[0] || b.Min[1] >= b.Max[1] || b.Min[0] < -180 || b.Max[0] > 180 || b.Min[1] < -90 || b.Max[1] > 90 {
+		return orb.Bound{}, fmt.Errorf("invalid bbox %v", b)
+	}
+	return b, nil
+}
+
+func init() {
+	builtin.Register(controllerName, Run)
+}
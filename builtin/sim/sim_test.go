@@ -0,0 +1,36 @@
+package sim
+
+import (
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/paulmach/orb"
+)
+
+func TestStep_StaysInBounds(t *testing.T) {
+	for _, mode := range []string{"random_walk", "waypoints"} {
+		config := &SimConfig{
+			Count:    50,
+			Bounds:   orb.Bound{Min: orb.Point{9.9, 53.5}, Max: orb.Point{10.0, 53.6}},
+			MinSpeed: 100,
+			MaxSpeed: 300,
+			Mode:     mode,
+			Interval: time.Second,
+		}
+		w := newWorld(config, rand.New(rand.NewPCG(1, 1)))
+		start := w.movers[0].pos
+
+		for range 500 {
+			w.step(1)
+			for i, m := range w.movers {
+				if !config.Bounds.Contains(m.pos) {
+					t.Fatalf("%s: mover %d left the bounds at %v", mode, i, m.pos)
+				}
+			}
+		}
+		if w.movers[0].pos == start {
+			t.Fatalf("%s: expected movers to move", mode)
+		}
+	}
+}
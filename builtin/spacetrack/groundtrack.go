@@ -0,0 +1,66 @@
+package spacetrack
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/akhenakh/sgp4"
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// groundTrack propagates tle from now over horizon in steps and returns the
+// line below the satellite. Longitudes are unwrapped where the track crosses
+// the antimeridian, e.g. 179 to 181 instead of -179, so the line stays in one
+// piece instead of spanning the whole map.
+func groundTrack(tle *sgp4.TLE, now time.Time, horizon, step time.Duration) (*pb.GeoShapeComponent, error) {
+	if step <= 0 || horizon < step {
+		return nil, fmt.Errorf("ground track step %v must be positive and within the horizon %v", step, horizon)
+	}
+
+	points := make([]*pb.PlanarPoint, 0, int(horizon/step)+1)
+	var offset float64
+	for at := time.Duration(0); at <= horizon; at += step {
+		position, err := calculatePosition(tle, now.Add(at))
+		if err != nil {
+			return nil, err
+		}
+		lon := position.Longitude + offset
+		if n := len(points); n > 0 {
+			for lon-points[n-1].Longitude > 180 {
+				lon -= 360
+				offset -= 360
+			}
+			for lon-points[n-1].Longitude < -180 {
+				lon += 360
+				offset += 360
+			}
+		}
+		altitude := position.Altitude
+		points = append(points, &pb.PlanarPoint{Longitude: lon, Latitude: position.Latitude, Altitude: &altitude})
+	}
+
+	return &pb.GeoShapeComponent{Geometry: &pb.Geometry{Planar: &pb.PlanarGeometry{
+		Plane: &pb.PlanarGeometry_Line{Line: &pb.PlanarRing{Points: points}},
+	}}}, nil
+}
+
+// groundTrackEntity returns the upcoming ground track of the satellite entityID,
+// located at it so it expires with the satellite
+func groundTrackEntity(shape *pb.GeoShapeComponent, entityID, label string, expires time.Duration, controllerID string) *pb.Entity {
+	label += " ground track"
+	return &pb.Entity{
+		Id:    entityID + "-groundtrack",
+		Label: &label,
+		Lifetime: &pb.Lifetime{
+			From:  timestamppb.Now(),
+			Until: timestamppb.New(time.Now().Add(expires * 2)),
+		},
+		Shape:   shape,
+		Locator: &pb.LocatorComponent{LocatedEntityId: entityID},
+		Controller: &pb.ControllerRef{
+			Id:   controllerID,
+			Name: "spacetrack",
+		},
+	}
+}
@@ -0,0 +1,49 @@
+package spacetrack
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/akhenakh/sgp4"
+)
+
+func TestGroundTrack_ContinuousOverAntimeridian(t *testing.T) {
+	tle, err := sgp4.ParseTLE(`STARLINK-2511
+1 48332U 21036BJ  26008.30093631  .01564128  18636-2  14068-2 0  9997
+2 48332  53.0399 337.5810 0008348 116.0415 244.1481 16.12439623259422`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// one orbit is about 90 minutes, two cross the antimeridian at least once
+	now := tle.EpochTime().Add(time.Hour)
+	shape, err := groundTrack(tle, now, 3*time.Hour, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	points := shape.Geometry.Planar.GetLine().Points
+	if len(points) != 181 {
+		t.Fatalf("got %d points, want 181", len(points))
+	}
+
+	first, err := calculatePosition(tle, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if points[0].Longitude != first.Longitude || points[0].Latitude != first.Latitude {
+		t.Errorf("track starts at %v/%v, want the current position %v/%v", points[0].Longitude, points[0].Latitude, first.Longitude, first.Latitude)
+	}
+	for i := 1; i < len(points); i++ {
+		if d := math.Abs(points[i].Longitude - points[i-1].Longitude); d > 180 {
+			t.Fatalf("track jumps %v degrees of longitude at point %d", d, i)
+		}
+	}
+	if span := math.Abs(points[len(points)-1].Longitude - points[0].Longitude); span < 360 {
+		t.Errorf("track spans %v degrees of longitude, want it unwrapped past 360", span)
+	}
+
+	if _, err := groundTrack(tle, now, time.Minute, time.Hour); err == nil {
+		t.Error("step beyond the horizon should fail")
+	}
+}
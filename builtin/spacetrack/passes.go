@@ -0,0 +1,49 @@
+package spacetrack
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/akhenakh/sgp4"
+	pb "github.com/projectqai/proto/go"
+)
+
+// passStep is how often passes are searched for, the edges are then refined by bisection
+const passStep = 30 * time.Second
+
+// TrackedTLE loads the TLE the tracker configured by configEntity pushes as
+// entityID, the same way the tracker loads it
+func TrackedTLE(ctx context.Context, configEntity *pb.Entity, entityID string) (*sgp4.TLE, error) {
+	if configEntity.GetConfig().GetKey() != "spacetrack.orbit.v0" {
+		return nil, fmt.Errorf("%s is not a spacetrack config", configEntity.GetId())
+	}
+	config, err := parseTrackerConfig(configEntity.Config)
+	if err != nil {
+		return nil, fmt.Errorf("parse config: %w", err)
+	}
+	tles, err := loadTLEs(ctx, config)
+	if err != nil {
+		return nil, fmt.Errorf("load TLEs: %w", err)
+	}
+	for _, tle := range tles {
+		if id, _ := generateIDAndLabel(configEntity.Id, config, tle, len(tles)); id == entityID {
+			return tle, nil
+		}
+	}
+	return nil, fmt.Errorf("%s tracks no satellite %s", configEntity.Id, entityID)
+}
+
+// Passes predicts the passes of tle over the observer at lat/lon and altitude
+// in meters between from and until, that rise higher than minElevation degrees.
+// AOS and LOS are where the satellite crosses the horizon.
+func Passes(tle *sgp4.TLE, lat, lon, altitude float64, from, until time.Time, minElevation float64) ([]sgp4.PassDetails, error) {
+	passes, err := tle.GeneratePasses(lat, lon, altitude, from, until, int(passStep.Seconds()))
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(passes, func(p sgp4.PassDetails) bool {
+		return p.MaxElevation < minElevation
+	}), nil
+}
@@ -0,0 +1,53 @@
+package spacetrack
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestPasses_TrackedTLEAboveMinElevation(t *testing.T) {
+	value, err := structpb.NewStruct(map[string]any{
+		"tle": "ISS (ZARYA)\n" +
+			"1 25544U 98067A   26289.50000000  .00016717  00000-0  30571-3 0  9993\n" +
+			"2 25544  51.6416 247.4627 0006703 130.5360 325.0288 15.49815308432158",
+		"id": "iss",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &pb.Entity{Id: "iss-config", Config: &pb.ConfigurationComponent{Key: "spacetrack.orbit.v0", Value: value}}
+
+	if _, err := TrackedTLE(context.Background(), config, "hubble"); err == nil {
+		t.Error("satellite of another tracker should not be found")
+	}
+	tle, err := TrackedTLE(context.Background(), config, "iss")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	from := tle.EpochTime()
+	until := from.Add(24 * time.Hour)
+	all, err := Passes(tle, 52.52, 13.40, 0, from, until, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	high, err := Passes(tle, 52.52, 13.40, 0, from, until, 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(high) == 0 || len(high) >= len(all) {
+		t.Fatalf("got %d passes above 30° of %d, want some but not all", len(high), len(all))
+	}
+	for _, p := range high {
+		if p.MaxElevation < 30 {
+			t.Errorf("pass at %v peaks at %.1f°, below the minimum", p.AOS, p.MaxElevation)
+		}
+		if !p.AOS.Before(p.MaxElevationTime) || !p.MaxElevationTime.Before(p.LOS) {
+			t.Errorf("pass at %v peaks at %v outside of AOS to LOS %v", p.AOS, p.MaxElevationTime, p.LOS)
+		}
+	}
+}
@@ -25,12 +25,91 @@ type TrackerConfig struct {
 	TLERefreshSeconds int     `json:"tle_refresh_seconds"`
 	Username          string  `json:"username"`
 	Password          string  `json:"password"`
+	// MaxTLEAgeHours is how old a TLE epoch may get before SGP4 positions are
+	// considered unreliable, they drift by kilometers per day
+	MaxTLEAgeHours float64 `json:"max_tle_age_hours"`
+	// SkipStale stops pushing satellites with a TLE older than MaxTLEAgeHours,
+	// otherwise they are only warned about
+	SkipStale bool `json:"skip_stale"`
+	// GroundTrackMinutes pushes the ground track of the next minutes of each
+	// satellite as a line entity next to it, 0 disables it
+	GroundTrackMinutes float64 `json:"ground_track_minutes"`
+	// GroundTrackStepSeconds is the time between the points of the ground track
+	GroundTrackStepSeconds float64 `json:"ground_track_step_seconds"`
+}
+
+func (c *TrackerConfig) maxTLEAge() time.Duration {
+	return time.Duration(c.MaxTLEAgeHours * float64(time.Hour))
+}
+
+func (c *TrackerConfig) groundTrackHorizon() time.Duration {
+	return time.Duration(c.GroundTrackMinutes * float64(time.Minute))
+}
+
+func (c *TrackerConfig) groundTrackStep() time.Duration {
+	return time.Duration(c.GroundTrackStepSeconds * float64(time.Second))
+}
+
+// epochAge is how long ago the orbit of tle was measured
+func epochAge(tle *sgp4.TLE, now time.Time) time.Duration {
+	return now.Sub(tle.EpochTime())
+}
+
+// satelliteName is the name of tle, or its catalog number for TLEs without a name line
+func satelliteName(tle *sgp4.TLE) string {
+	if tle.Name != "" {
+		return tle.Name
+	}
+	return fmt.Sprintf("%05d", tle.SatelliteNumber)
+}
+
+// warnStale logs how many TLEs are older than the config allows, once per load
+func warnStale(logger *slog.Logger, configEntityID string, tles []*sgp4.TLE, config *TrackerConfig) {
+	now := time.Now()
+	var stale int
+	var oldest *sgp4.TLE
+	for _, tle := range tles {
+		if epochAge(tle, now) <= config.maxTLEAge() {
+			continue
+		}
+		stale++
+		if oldest == nil || tle.EpochTime().Before(oldest.EpochTime()) {
+			oldest = tle
+		}
+	}
+	if stale == 0 {
+		return
+	}
+	logger.Warn("Stale TLEs, positions may be far off",
+		"configEntityID", configEntityID,
+		"count", stale,
+		"oldest", satelliteName(oldest),
+		"epochAge", epochAge(oldest, now).Round(time.Hour),
+		"maxAge", config.maxTLEAge(),
+		"skipped", config.SkipStale)
 }
 
 type SatellitePosition struct {
 	Latitude  float64
 	Longitude float64
 	Altitude  float64
+	// Sunlit is false while the satellite is in the shadow of the earth
+	Sunlit bool
+	// SunSynchronous is true for orbits that pass over places at the same local time
+	SunSynchronous bool
+}
+
+// illumination describes a satellite at position for Detection.Classification,
+// e.g. "sunlit, sun-synchronous", so displays can tell passes that can image
+func (p *SatellitePosition) illumination() string {
+	status := "eclipse"
+	if p.Sunlit {
+		status = "sunlit"
+	}
+	if p.SunSynchronous {
+		status += ", sun-synchronous"
+	}
+	return status
 }
 
 func isURL(source string) bool {
@@ -106,6 +185,20 @@ func fetchMultipleTLEs(ctx context.Context, url, username, password string) ([]*
 	return tles, nil
 }
 
+// loadTLEs fetches the TLEs of a URL source or parses the inline TLE of config
+func loadTLEs(ctx context.Context, config *TrackerConfig) ([]*sgp4.TLE, error) {
+	if !isURL(config.TLESource) {
+		tle, err := parseInlineTLE(config.TLESource)
+		if err != nil {
+			return nil, err
+		}
+		return []*sgp4.TLE{tle}, nil
+	}
+	fetchCtx, fetchCancel := context.WithTimeout(ctx, 30*time.Second)
+	defer fetchCancel()
+	return fetchMultipleTLEs(fetchCtx, config.TLESource, config.Username, config.Password)
+}
+
 func calculatePosition(tle *sgp4.TLE, t time.Time) (*SatellitePosition, error) {
 	eciState, err := tle.FindPositionAtTime(t)
 	if err != nil {
@@ -115,9 +208,11 @@ func calculatePosition(tle *sgp4.TLE, t time.Time) (*SatellitePosition, error) {
 	lat, lon, alt := eciState.ToGeodetic()
 
 	return &SatellitePosition{
-		Latitude:  lat,
-		Longitude: lon,
-		Altitude:  alt * 1000,
+		Latitude:       lat,
+		Longitude:      lon,
+		Altitude:       alt * 1000,
+		Sunlit:         sunlit(eciState.Position, t),
+		SunSynchronous: sunSynchronous(tle),
 	}, nil
 }
 
@@ -162,27 +257,16 @@ func runTracker(ctx context.Context, logger *slog.Logger, entity *pb.Entity) err
 	defer ticker.Stop()
 
 	isURLSource := isURL(trackerConfig.TLESource)
-	var tles []*sgp4.TLE
 	tleTicker := time.NewTicker(time.Duration(trackerConfig.TLERefreshSeconds) * time.Second)
 	defer tleTicker.Stop()
 
-	fetchCtx, fetchCancel := context.WithTimeout(ctx, 30*time.Second)
-	if isURLSource {
-		tles, err = fetchMultipleTLEs(fetchCtx, trackerConfig.TLESource, trackerConfig.Username, trackerConfig.Password)
-	} else {
-		var tle *sgp4.TLE
-		tle, err = parseInlineTLE(trackerConfig.TLESource)
-		if err == nil {
-			tles = []*sgp4.TLE{tle}
-		}
-	}
-	fetchCancel()
-
+	tles, err := loadTLEs(ctx, trackerConfig)
 	if err != nil {
 		return fmt.Errorf("load initial TLE: %w", err)
 	}
 
 	logger.Info("Loaded TLEs", "configEntityID", entity.Id, "count", len(tles))
+	warnStale(logger, entity.Id, tles, trackerConfig)
 
 	// Push initial position updates
 	pushPositionUpdates(ctx, logger, worldClient, tles, entity.Id, trackerConfig)
@@ -206,6 +290,7 @@ func runTracker(ctx context.Context, logger *slog.Logger, entity *pb.Entity) err
 				} else {
 					tles = newTLEs
 					logger.Info("Refreshed TLEs", "configEntityID", entity.Id, "count", len(tles))
+					warnStale(logger, entity.Id, tles, trackerConfig)
 				}
 			}
 		}
@@ -221,23 +306,39 @@ func pushPositionUpdates(ctx context.Context, logger *slog.Logger, worldClient p
 		default:
 		}
 
-		position, err := calculatePosition(tle, time.Now())
+		now := time.Now()
+		if config.SkipStale && epochAge(tle, now) > config.maxTLEAge() {
+			continue
+		}
+
+		position, err := calculatePosition(tle, now)
 		if err != nil {
 			logger.Error("Failed to calculate position", "configEntityID", configEntityID, "satellite", tle.Name, "error", err)
 			continue
 		}
 
 		entityID, label := generateIDAndLabel(configEntityID, config, tle, len(tles))
-		entity := positionToEntity(position, entityID, label, config.Symbol, time.Duration(config.IntervalSeconds*float64(time.Second)), configEntityID)
+		expires := time.Duration(config.IntervalSeconds * float64(time.Second))
+		entity := positionToEntity(position, tle.EpochTime(), entityID, label, config.Symbol, expires, configEntityID)
 
 		if entity == nil {
 			logger.Error("Failed to convert position to entity", "configEntityID", configEntityID, "satellite", tle.Name)
 			continue
 		}
+		changes := []*pb.Entity{entity}
+
+		if config.GroundTrackMinutes > 0 {
+			shape, err := groundTrack(tle, now, config.groundTrackHorizon(), config.groundTrackStep())
+			if err != nil {
+				logger.Error("Failed to calculate ground track", "configEntityID", configEntityID, "satellite", tle.Name, "error", err)
+			} else {
+				changes = append(changes, groundTrackEntity(shape, entityID, label, expires, configEntityID))
+			}
+		}
 
 		pushCtx, pushCancel := context.WithTimeout(ctx, 2*time.Second)
 		_, err = worldClient.Push(pushCtx, &pb.EntityChangeRequest{
-			Changes: []*pb.Entity{entity},
+			Changes: changes,
 		})
 		pushCancel()
 
@@ -286,7 +387,12 @@ func generateIDAndLabel(configEntityID string, config *TrackerConfig, tle *sgp4.
 	return entityID, label
 }
 
-func positionToEntity(position *SatellitePosition, entityID, label, symbol string, expires time.Duration, controllerID string) *pb.Entity {
+// positionToEntity returns the satellite at position. The position is propagated
+// to now, Detection.LastMeasured is the TLE epoch it was propagated from, so
+// clients can tell how reliable it is. Detection.Classification tells whether
+// the satellite is sunlit or in eclipse, see SatellitePosition.illumination.
+func positionToEntity(position *SatellitePosition, epoch time.Time, entityID, label, symbol string, expires time.Duration, controllerID string) *pb.Entity {
+	illumination := position.illumination()
 	entity := &pb.Entity{
 		Id:    entityID,
 		Label: &label,
@@ -307,6 +413,10 @@ func positionToEntity(position *SatellitePosition, entityID, label, symbol strin
 			Name: "spacetrack",
 		},
 		Track: &pb.TrackComponent{},
+		Detection: &pb.DetectionComponent{
+			LastMeasured:   timestamppb.New(epoch),
+			Classification: &illumination,
+		},
 	}
 
 	return entity
@@ -320,6 +430,9 @@ func parseTrackerConfig(config *pb.ConfigurationComponent) (*TrackerConfig, erro
 		Symbol:            "SNPPS-----*****",
 		IntervalSeconds:   1.0,
 		TLERefreshSeconds: 3600,
+		MaxTLEAgeHours:    72,
+		// GroundTrackMinutes is opt-in
+		GroundTrackStepSeconds: 60,
 	}
 
 	if config.Value == nil || config.Value.Fields == nil {
@@ -340,6 +453,12 @@ func parseTrackerConfig(config *pb.ConfigurationComponent) (*TrackerConfig, erro
 	if v, ok := fields["label"]; ok {
 		trackerConfig.Label = v.GetStringValue()
 	}
+	// default_sidc is accepted like on the other builtins, symbol wins if both are set
+	if v, ok := fields["default_sidc"]; ok {
+		if symbol := v.GetStringValue(); symbol != "" {
+			trackerConfig.Symbol = symbol
+		}
+	}
 	if v, ok := fields["symbol"]; ok {
 		if symbol := v.GetStringValue(); symbol != "" {
 			trackerConfig.Symbol = symbol
@@ -355,6 +474,27 @@ func parseTrackerConfig(config *pb.ConfigurationComponent) (*TrackerConfig, erro
 			trackerConfig.TLERefreshSeconds = refresh
 		}
 	}
+	if v, ok := fields["max_tle_age_hours"]; ok {
+		if hours := v.GetNumberValue(); hours > 0 {
+			trackerConfig.MaxTLEAgeHours = hours
+		}
+	}
+	if v, ok := fields["skip_stale"]; ok {
+		trackerConfig.SkipStale = v.GetBoolValue()
+	}
+	if v, ok := fields["ground_track_minutes"]; ok {
+		if minutes := v.GetNumberValue(); minutes > 0 {
+			trackerConfig.GroundTrackMinutes = minutes
+		}
+	}
+	if v, ok := fields["ground_track_step_seconds"]; ok {
+		if step := v.GetNumberValue(); step > 0 {
+			trackerConfig.GroundTrackStepSeconds = step
+		}
+	}
+	if trackerConfig.GroundTrackMinutes > 0 && trackerConfig.groundTrackStep() > trackerConfig.groundTrackHorizon() {
+		return nil, fmt.Errorf("ground_track_step_seconds must not exceed ground_track_minutes")
+	}
 	if v, ok := fields["username"]; ok {
 		trackerConfig.Username = v.GetStringValue()
 	}
@@ -0,0 +1,71 @@
+package spacetrack
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/akhenakh/sgp4"
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/grpc"
+)
+
+// pushRecorder is a world client that keeps the labels of the entities pushed to it
+type pushRecorder struct {
+	pb.WorldServiceClient
+	labels []string
+}
+
+func (r *pushRecorder) Push(ctx context.Context, req *pb.EntityChangeRequest, _ ...grpc.CallOption) (*pb.EntityChangeResponse, error) {
+	for _, e := range req.Changes {
+		r.labels = append(r.labels, e.GetLabel())
+	}
+	return &pb.EntityChangeResponse{}, nil
+}
+
+func TestPushPositionUpdates_SkipsStaleTLEs(t *testing.T) {
+	var tles []*sgp4.TLE
+	for _, data := range []string{
+		"ISS (ZARYA)\n" +
+			"1 25544U 98067A   26289.50000000  .00016717  00000-0  30571-3 0  9993\n" +
+			"2 25544  51.6416 247.4627 0006703 130.5360 325.0288 15.49815308432158",
+		"HUBBLE\n" +
+			"1 20580U 90037B   26001.50000000  .00001084  00000-0  55124-4 0  9992\n" +
+			"2 20580  28.4699 288.8102 0002495 321.7771 171.5855 15.09299865423423",
+	} {
+		tle, err := sgp4.ParseTLE(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tles = append(tles, tle)
+	}
+	iss, hubble := tles[0], tles[1]
+
+	// the hubble epoch is months older than the one of the ISS, allow a week more than the ISS age
+	maxAge := epochAge(iss, time.Now()) + 7*24*time.Hour
+	if epochAge(hubble, time.Now()) <= maxAge {
+		t.Fatal("expected the hubble TLE to be older than the max age")
+	}
+	config := &TrackerConfig{IntervalSeconds: 1, MaxTLEAgeHours: maxAge.Hours()}
+
+	for _, tc := range []struct {
+		skipStale bool
+		want      []string
+	}{
+		{false, []string{"ISS (ZARYA)", "HUBBLE"}},
+		{true, []string{"ISS (ZARYA)"}},
+	} {
+		config.SkipStale = tc.skipStale
+		client := &pushRecorder{}
+		pushPositionUpdates(context.Background(), slog.Default(), client, tles, "sats", config)
+		if len(client.labels) != len(tc.want) {
+			t.Fatalf("skip_stale %v: pushed %v, want %v", tc.skipStale, client.labels, tc.want)
+		}
+		for i := range tc.want {
+			if client.labels[i] != tc.want[i] {
+				t.Errorf("skip_stale %v: pushed %v, want %v", tc.skipStale, client.labels, tc.want)
+			}
+		}
+	}
+}
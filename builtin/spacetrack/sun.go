@@ -0,0 +1,65 @@
+package spacetrack
+
+import (
+	"math"
+	"time"
+
+	"github.com/akhenakh/sgp4"
+)
+
+const (
+	earthRadiusKm = 6378.137
+	earthMu       = 398600.4418 // km³/s²
+	earthJ2       = 1.08263e-3
+
+	// sunSyncRate is the nodal precession of a sun-synchronous orbit, one turn per year
+	sunSyncRate = 360 / 365.2422 // degrees per day
+	// sunSyncTolerance is how far off the precession of an orbit may be to count as sun-synchronous
+	sunSyncTolerance = 0.05 // degrees per day
+)
+
+// sunDirection returns the unit vector from the earth to the sun in an
+// earth-centered inertial frame, with the low precision formula of the
+// Astronomical Almanac, good to about 0.01°
+func sunDirection(t time.Time) sgp4.Vector {
+	// days since J2000
+	n := float64(t.UnixNano())/float64(24*time.Hour) + 2440587.5 - 2451545.0
+	rad := math.Pi / 180
+
+	meanLongitude := 280.460 + 0.9856474*n
+	meanAnomaly := (357.528 + 0.9856003*n) * rad
+	longitude := (meanLongitude + 1.915*math.Sin(meanAnomaly) + 0.020*math.Sin(2*meanAnomaly)) * rad
+	obliquity := (23.439 - 0.0000004*n) * rad
+
+	return sgp4.Vector{
+		X: math.Cos(longitude),
+		Y: math.Cos(obliquity) * math.Sin(longitude),
+		Z: math.Sin(obliquity) * math.Sin(longitude),
+	}
+}
+
+// sunlit reports whether a satellite at position, in km in the inertial frame of
+// SGP4, is outside the shadow of the earth. The shadow is taken as a cylinder,
+// which is off by seconds at the edges only.
+func sunlit(position sgp4.Vector, t time.Time) bool {
+	sun := sunDirection(t)
+	along := position.X*sun.X + position.Y*sun.Y + position.Z*sun.Z
+	if along >= 0 {
+		return true
+	}
+	dx, dy, dz := position.X-along*sun.X, position.Y-along*sun.Y, position.Z-along*sun.Z
+	return math.Sqrt(dx*dx+dy*dy+dz*dz) > earthRadiusKm
+}
+
+// sunSynchronous reports whether the orbit of tle turns with the sun, so it
+// passes over places at the same local time, as most imaging satellites do
+func sunSynchronous(tle *sgp4.TLE) bool {
+	n := tle.MeanMotion * 2 * math.Pi / 86400 // rad/s
+	if n <= 0 {
+		return false
+	}
+	a := math.Cbrt(earthMu / (n * n))
+	p := a * (1 - tle.Eccentricity*tle.Eccentricity)
+	precession := -1.5 * earthJ2 * math.Pow(earthRadiusKm/p, 2) * n * math.Cos(tle.Inclination*math.Pi/180)
+	return math.Abs(precession*86400*180/math.Pi-sunSyncRate) <= sunSyncTolerance
+}
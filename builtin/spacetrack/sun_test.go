@@ -0,0 +1,48 @@
+package spacetrack
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/akhenakh/sgp4"
+)
+
+func TestSunlit_ShadowBehindEarth(t *testing.T) {
+	at := time.Date(2025, 6, 21, 12, 0, 0, 0, time.UTC)
+	sun := sunDirection(at)
+
+	// near the june solstice the sun is at its northmost declination
+	if declination := math.Asin(sun.Z) * 180 / math.Pi; math.Abs(declination-23.44) > 0.1 {
+		t.Fatalf("declination = %.2f, want about 23.44", declination)
+	}
+
+	const r = earthRadiusKm + 500
+	towards := sgp4.Vector{X: r * sun.X, Y: r * sun.Y, Z: r * sun.Z}
+	behind := sgp4.Vector{X: -towards.X, Y: -towards.Y, Z: -towards.Z}
+	if !sunlit(towards, at) {
+		t.Error("satellite between earth and sun should be sunlit")
+	}
+	if sunlit(behind, at) {
+		t.Error("satellite behind earth should be in eclipse")
+	}
+
+	// far out of the shadow cylinder on the night side
+	side := sgp4.Vector{X: -sun.Y * 42164, Y: sun.X * 42164, Z: 0}
+	side.X -= 1000 * sun.X
+	side.Y -= 1000 * sun.Y
+	if !sunlit(side, at) {
+		t.Error("satellite beside the shadow should be sunlit")
+	}
+}
+
+func TestSunSynchronous(t *testing.T) {
+	landsat := &sgp4.TLE{MeanMotion: 14.57, Eccentricity: 0.0001, Inclination: 98.2}
+	iss := &sgp4.TLE{MeanMotion: 15.5, Eccentricity: 0.0005, Inclination: 51.6}
+	if !sunSynchronous(landsat) {
+		t.Error("landsat orbit should be sun-synchronous")
+	}
+	if sunSynchronous(iss) {
+		t.Error("iss orbit should not be sun-synchronous")
+	}
+}
@@ -83,15 +83,16 @@ func handleClient(conn net.Conn, serverURL string, logger *slog.Logger, controll
 				// Parse and push position reports (type="a-f-G-U-C" and similar)
 				if strings.Contains(data, `type="a-`) && !strings.Contains(data, `type="t-`) {
 					logger.Debug("Detected position report, parsing and pushing to Hydra", "clientID", clientID)
-					entity, err := CoTToEntity(buffer[:n], controllerID)
+					entities, err := CoTToEntities(buffer[:n], controllerID)
 					if err != nil {
 						logger.Error("Error parsing CoT", "clientID", clientID, "error", err)
 					} else {
+						entity := entities[0]
 						logger.Debug("Parsed entity", "clientID", clientID, "id", entity.Id,
 							"callsign", *entity.Label, "lat", entity.Geo.Latitude, "lon", entity.Geo.Longitude)
 
-						// Push entity to Hydra
-						_, err := client.Push(ctx, &pb.EntityChangeRequest{Changes: []*pb.Entity{entity}})
+						// Push entity and device details to Hydra
+						_, err := client.Push(ctx, &pb.EntityChangeRequest{Changes: entities})
 						if err != nil {
 							logger.Error("Error pushing to Hydra", "clientID", clientID, "error", err)
 						} else {
@@ -109,6 +110,7 @@ func handleClient(conn net.Conn, serverURL string, logger *slog.Logger, controll
 	}
 
 	writer := bufio.NewWriter(conn)
+	devices := make(deviceIndex)
 	sentCount := 0
 
 	for {
@@ -121,8 +123,9 @@ func handleClient(conn net.Conn, serverURL string, logger *slog.Logger, controll
 		if event.Entity == nil {
 			continue
 		}
+		devices.observe(event)
 
-		cotXML, err := EntityToCoT(event.Entity)
+		cotXML, err := EntityToCoT(event.Entity, devices[event.Entity.Id])
 		if err != nil {
 			logger.Error("Error converting entity", "clientID", clientID, "entityID", event.Entity.Id, "error", err)
 			continue
@@ -164,12 +167,36 @@ func Run(ctx context.Context, logger *slog.Logger, serverURL string) error {
 		},
 	}, func(ctx context.Context, entity *pb.Entity) error {
 		return runInstance(ctx, logger, serverURL, entity)
-	})
+	}, controller.WithDataKeys(DeviceKey))
+}
+
+// deviceIndex holds the device entities seen in a watch by the id of their track,
+// so their details are sent along with the track
+type deviceIndex map[string]*pb.Entity
+
+func (d deviceIndex) observe(event *pb.EntityChangeEvent) {
+	e := event.Entity
+	if e.Config == nil || e.Config.Key != DeviceKey || e.Locator == nil {
+		return
+	}
+	if event.T == pb.EntityChange_EntityChangeUpdated {
+		d[e.Locator.LocatedEntityId] = e
+	} else {
+		delete(d, e.Locator.LocatedEntityId)
+	}
 }
 
 func runInstance(ctx context.Context, logger *slog.Logger, serverURL string, entity *pb.Entity) error {
 	config := entity.Config
 
+	// type_map is a CSV file of extra CoT type mappings, see cottypes.csv.
+	// They apply to all connectors.
+	if path := config.Value.GetFields()["type_map"].GetStringValue(); path != "" {
+		if err := LoadTypeMap(path); err != nil {
+			return fmt.Errorf("load type map: %w", err)
+		}
+	}
+
 	switch config.Key {
 	case "cot.server.v0":
 		return runServer(ctx, logger, serverURL, entity)
@@ -335,6 +362,7 @@ func runMulticastBroadcaster(ctx context.Context, logger *slog.Logger, serverURL
 		return err
 	}
 
+	devices := make(deviceIndex)
 	sentCount := 0
 	for {
 		select {
@@ -351,8 +379,9 @@ func runMulticastBroadcaster(ctx context.Context, logger *slog.Logger, serverURL
 		if event.Entity == nil {
 			continue
 		}
+		devices.observe(event)
 
-		cotXML, err := EntityToCoT(event.Entity)
+		cotXML, err := EntityToCoT(event.Entity, devices[event.Entity.Id])
 		if err != nil {
 			logger.Error("Error converting entity", "entityID", event.Entity.Id, "error", err)
 			continue
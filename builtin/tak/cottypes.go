@@ -0,0 +1,162 @@
+package view
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// cotTypesCSV maps CoT types to MIL-STD-2525C symbols. Columns are cot, sidc and
+// description. A "." affiliation in the CoT type and a "*" affiliation in the
+// symbol stand for any affiliation.
+//
+//go:embed cottypes.csv
+var cotTypesCSV string
+
+// typeMapping is one row of a type table
+type typeMapping struct {
+	cot  string
+	sidc string
+}
+
+// typeTable looks up mappings in both directions. Types missing from it are
+// converted structurally if possible, see cotTypeToSIDC and sidcToCoTType,
+// otherwise the closest mapped type is used.
+type typeTable struct {
+	mu sync.RWMutex
+	// byCoT is keyed by CoT type with "." affiliation
+	byCoT map[string]typeMapping
+	// bySIDC is keyed by the first 10 characters of the symbol with "*" affiliation and "P" status
+	bySIDC map[string]typeMapping
+}
+
+var cotTypes = mustParseTypeTable()
+
+func mustParseTypeTable() *typeTable {
+	t := &typeTable{
+		byCoT:  make(map[string]typeMapping),
+		bySIDC: make(map[string]typeMapping),
+	}
+	if err := t.load(strings.NewReader(cotTypesCSV)); err != nil {
+		panic(fmt.Sprintf("embedded cottypes.csv: %v", err))
+	}
+	return t
+}
+
+// LoadTypeMap adds the mappings in a CSV file with the columns of cottypes.csv
+// to the builtin ones, replacing those for the same types.
+func LoadTypeMap(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := cotTypes.load(f); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+func (t *typeTable) load(r io.Reader) error {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.Comment = '#'
+	records, err := cr.ReadAll()
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i, rec := range records {
+		if i == 0 && len(rec) > 0 && rec[0] == "cot" {
+			continue
+		}
+		if len(rec) < 2 {
+			return fmt.Errorf("line %d: expected cot,sidc[,description]", i+1)
+		}
+		// the description is only for readers of the file
+		m := typeMapping{cot: strings.TrimSpace(rec[0]), sidc: fullSIDC(strings.ToUpper(strings.TrimSpace(rec[1])))}
+		if len(strings.Split(m.cot, "-")) < 2 || len(m.sidc) < 4 {
+			return fmt.Errorf("line %d: invalid mapping %q to %q", i+1, m.cot, m.sidc)
+		}
+		t.byCoT[cotKey(m.cot)] = m
+		t.bySIDC[sidcKey(m.sidc)] = m
+	}
+	return nil
+}
+
+// cotKey replaces the affiliation of a CoT type with "."
+func cotKey(cotType string) string {
+	parts := strings.Split(cotType, "-")
+	if len(parts) > 1 {
+		parts[1] = "."
+	}
+	return strings.Join(parts, "-")
+}
+
+// sidcKey keeps the symbol up to the function ID with "*" affiliation and "P" status
+func sidcKey(sidc string) string {
+	key := []byte(fullSIDC(strings.ToUpper(sidc))[:10])
+	key[1] = '*'
+	key[3] = 'P'
+	for i := 4; i < len(key); i++ {
+		if key[i] == '*' {
+			key[i] = '-'
+		}
+	}
+	return string(key)
+}
+
+// sidc returns the symbol mapped to cotType, or with prefix to its longest mapped prefix
+func (t *typeTable) sidc(cotType string, prefix bool) (string, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	parts := strings.Split(cotType, "-")
+	shortest := len(parts)
+	if prefix {
+		shortest = 2
+	}
+	for n := len(parts); n >= shortest; n-- {
+		m, ok := t.byCoT[cotKey(strings.Join(parts[:n], "-"))]
+		if !ok {
+			continue
+		}
+		sidc := []byte(m.sidc)
+		if sidc[1] == '*' {
+			sidc[1] = sidcAffiliation(parts[1])
+		}
+		return string(sidc), true
+	}
+	return "", false
+}
+
+// cotType returns the CoT type mapped to a symbol, or with prefix that of the
+// closest mapped symbol with a shorter function ID
+func (t *typeTable) cotType(sidc string, prefix bool) (string, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	key := sidcKey(sidc)
+	shortest := len(key)
+	if prefix {
+		shortest = 4
+	}
+	for n := len(key); n >= shortest; n-- {
+		m, ok := t.bySIDC[key[:n]+strings.Repeat("-", len(key)-n)]
+		if !ok {
+			continue
+		}
+		parts := strings.Split(m.cot, "-")
+		if parts[1] == "." {
+			parts[1] = cotAffiliation(sidc[1])
+		}
+		return strings.Join(parts, "-"), true
+	}
+	return "", false
+}
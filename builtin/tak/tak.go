@@ -3,10 +3,13 @@ package view
 import (
 	"encoding/xml"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/projectqai/hydra/goclient"
 	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
 // see https://github.com/deptofdefense/AndroidTacticalAssaultKit-CIV/tree/22d11cba15dd5cfe385c0d0790670bc7e9ab7df4/takcot/mitre
@@ -34,11 +37,25 @@ type Point struct {
 }
 
 type Detail struct {
+	Takv    *Takv   `xml:"takv,omitempty"`
 	Contact Contact `xml:"contact"`
 	Group   Group   `xml:"group"`
+	Status  *Status `xml:"status,omitempty"`
 	Milsym  *Milsym `xml:"__milsym,omitempty"`
 }
 
+// Takv describes the TAK client that sent an event
+type Takv struct {
+	Device   string `xml:"device,attr,omitempty"`
+	Platform string `xml:"platform,attr,omitempty"`
+	OS       string `xml:"os,attr,omitempty"`
+	Version  string `xml:"version,attr,omitempty"`
+}
+
+type Status struct {
+	Battery string `xml:"battery,attr,omitempty"`
+}
+
 type Contact struct {
 	Callsign string `xml:"callsign,attr"`
 }
@@ -52,13 +69,71 @@ type Milsym struct {
 	ID string `xml:"id,attr"`
 }
 
+// DeviceKey is the config key of the device entities of TAK clients, see CoTToEntities
+const DeviceKey = "tak.device.v0"
+
 // CoTToEntity converts a CoT XML event to a Hydra entity
 func CoTToEntity(cotXML []byte, controllerID string) (*pb.Entity, error) {
 	var event Event
 	if err := xml.Unmarshal(cotXML, &event); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal CoT XML: %w", err)
 	}
+	return eventToEntity(event, controllerID), nil
+}
+
+// CoTToEntities converts a CoT XML event to a Hydra entity, followed by a device
+// entity if the event has <takv> or <status> details. The world proto has no
+// place for them, so the device entity is located on the track and carries
+// them as config with DeviceKey.
+func CoTToEntities(cotXML []byte, controllerID string) ([]*pb.Entity, error) {
+	var event Event
+	if err := xml.Unmarshal(cotXML, &event); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal CoT XML: %w", err)
+	}
+	entity := eventToEntity(event, controllerID)
+	if device := deviceEntity(event, entity); device != nil {
+		return []*pb.Entity{entity, device}, nil
+	}
+	return []*pb.Entity{entity}, nil
+}
+
+func deviceEntity(event Event, entity *pb.Entity) *pb.Entity {
+	fields := make(map[string]any)
+	if t := event.Detail.Takv; t != nil {
+		for k, v := range map[string]string{"device": t.Device, "platform": t.Platform, "os": t.OS, "version": t.Version} {
+			if v != "" {
+				fields[k] = v
+			}
+		}
+	}
+	if st := event.Detail.Status; st != nil && st.Battery != "" {
+		if battery, err := strconv.ParseFloat(st.Battery, 64); err == nil {
+			fields["battery"] = battery
+		}
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	value, err := structpb.NewStruct(fields)
+	if err != nil {
+		return nil
+	}
+
+	label := *entity.Label + " device"
+	return &pb.Entity{
+		Id:         entity.Id + "-device",
+		Label:      &label,
+		Controller: entity.Controller,
+		Locator:    &pb.LocatorComponent{LocatedEntityId: entity.Id},
+		Config: &pb.ConfigurationComponent{
+			Controller: "tak",
+			Key:        DeviceKey,
+			Value:      value,
+		},
+	}
+}
 
+func eventToEntity(event Event, controllerID string) *pb.Entity {
 	// Get callsign from contact detail
 	callsign := event.Detail.Contact.Callsign
 	if callsign == "" {
@@ -86,55 +161,63 @@ func CoTToEntity(cotXML []byte, controllerID string) (*pb.Entity, error) {
 		},
 	}
 
-	return entity, nil
+	return entity
 }
 
+// cotAffiliations are the affiliations shared by CoT types and MIL-STD-2525C,
+// lower case in CoT and upper case in the symbol
+const cotAffiliations = "pufanshjko"
+
+// dimensions are the battle dimensions shared by CoT atoms and symbols
+const dimensions = "PAGSUF"
+
+func sidcAffiliation(cot string) byte {
+	if len(cot) == 1 && strings.Contains(cotAffiliations, cot) {
+		return strings.ToUpper(cot)[0]
+	}
+	return 'U'
+}
+
+func cotAffiliation(sidc byte) string {
+	a := strings.ToLower(string(sidc))
+	if strings.Contains(cotAffiliations, a) {
+		return a
+	}
+	return "u"
+}
+
+// cotTypeToSIDC maps a CoT type using cotTypes. Unmapped atoms like
+// a-f-G-U-C-I are converted structurally, their parts after the dimension are
+// the function ID of the symbol. Other types use the closest mapped type.
 func cotTypeToSIDC(cotType string) string {
-	// Parse CoT type format: a-[affiliation]-[dimension]-...
-	parts := strings.Split(cotType, "-")
-	if len(parts) < 3 {
-		return "SUGP----------*"
+	if sidc, ok := cotTypes.sidc(cotType, false); ok {
+		return sidc
 	}
 
-	// Map affiliation
-	affiliation := "U"
-	if len(parts) > 1 {
-		switch parts[1] {
-		case "f":
-			affiliation = "F"
-		case "h":
-			affiliation = "H"
-		case "n":
-			affiliation = "N"
-		case "u":
-			affiliation = "U"
+	// Parse CoT type format: a-[affiliation]-[dimension]-[function]...
+	parts := strings.Split(cotType, "-")
+	if len(parts) < 3 || parts[0] != "a" || len(parts[2]) != 1 || !strings.Contains(dimensions, parts[2]) {
+		if sidc, ok := cotTypes.sidc(cotType, true); ok {
+			return sidc
 		}
+		return fullSIDC("SUGP")
 	}
 
-	// Map dimension
-	dimension := "G"
-	if len(parts) > 2 {
-		switch parts[2] {
-		case "P":
-			dimension = "P"
-		case "A":
-			dimension = "A"
-		case "G":
-			dimension = "G"
-		case "S":
-			dimension = "S"
-		case "U":
-			dimension = "U"
+	var function strings.Builder
+	for _, p := range parts[3:] {
+		if len(p) != 1 || function.Len() == 6 {
+			break
 		}
+		function.WriteString(strings.ToUpper(p))
 	}
 
-	// Build basic SIDC: S[affiliation][dimension][status]----------
 	// Status defaults to P (Present)
-	return fmt.Sprintf("S%s%sP----------*", affiliation, dimension)
+	return fullSIDC(fmt.Sprintf("S%c%sP%s", sidcAffiliation(parts[1]), parts[2], function.String()))
 }
 
-// EntityToCoT converts a Hydra entity to a CoT XML event
-func EntityToCoT(entity *pb.Entity) ([]byte, error) {
+// EntityToCoT converts a Hydra entity to a CoT XML event. If device is the
+// device entity of a TAK client, see CoTToEntities, its details are sent along.
+func EntityToCoT(entity *pb.Entity, device *pb.Entity) ([]byte, error) {
 	// Skip entities without position
 	if entity.Geo == nil {
 		return nil, nil
@@ -149,8 +232,8 @@ func EntityToCoT(entity *pb.Entity) ([]byte, error) {
 	// Get CoT type from SIDC
 	cotType := "a-u-G"
 	var milsym *Milsym
-	if entity.Symbol != nil && entity.Symbol.GetMilStd2525C() != "" {
-		sidc := entity.Symbol.GetMilStd2525C()
+	// icons have no CoT type, they show as unknown ground
+	if sidc := goclient.SIDC(entity.Symbol); sidc != "" {
 		cotType = sidcToCoTType(sidc)
 		milsym = &Milsym{ID: padSIDC(sidc)}
 	}
@@ -194,6 +277,21 @@ func EntityToCoT(entity *pb.Entity) ([]byte, error) {
 			Milsym:  milsym,
 		},
 	}
+	if device != nil && device.Config != nil {
+		fields := device.Config.Value.GetFields()
+		event.Detail.Takv = &Takv{
+			Device:   fields["device"].GetStringValue(),
+			Platform: fields["platform"].GetStringValue(),
+			OS:       fields["os"].GetStringValue(),
+			Version:  fields["version"].GetStringValue(),
+		}
+		if *event.Detail.Takv == (Takv{}) {
+			event.Detail.Takv = nil
+		}
+		if battery, ok := fields["battery"]; ok {
+			event.Detail.Status = &Status{Battery: strconv.FormatFloat(battery.GetNumberValue(), 'f', -1, 64)}
+		}
+	}
 
 	// Marshal to XML
 	xmlData, err := xml.MarshalIndent(event, "", "  ")
@@ -206,70 +304,47 @@ func EntityToCoT(entity *pb.Entity) ([]byte, error) {
 	return fullXML, nil
 }
 
+// sidcToCoTType maps a symbol using cotTypes. Unmapped warfighting symbols are
+// converted structurally, the reverse of cotTypeToSIDC, others use the closest
+// mapped symbol.
 func sidcToCoTType(sidc string) string {
+	sidc = strings.ToUpper(sidc)
 	if len(sidc) < 3 {
 		return "a-u-G"
 	}
-
-	sidc = strings.ToUpper(sidc)
-
-	// Map affiliation (position 1)
-	affiliation := "u"
-	switch sidc[1] {
-	case 'F':
-		affiliation = "f"
-	case 'H':
-		affiliation = "h"
-	case 'N':
-		affiliation = "n"
-	case 'U':
-		affiliation = "u"
+	if cotType, ok := cotTypes.cotType(sidc, false); ok {
+		return cotType
+	}
+	if sidc[0] != 'S' {
+		if cotType, ok := cotTypes.cotType(sidc, true); ok {
+			return cotType
+		}
 	}
 
-	// Map dimension (position 2)
 	dimension := "G"
-	switch sidc[2] {
-	case 'P':
-		dimension = "P"
-	case 'A':
-		dimension = "A"
-	case 'G':
-		dimension = "G"
-	case 'S':
-		dimension = "S"
-	case 'U':
-		dimension = "U"
+	if strings.ContainsRune(dimensions, rune(sidc[2])) {
+		dimension = string(sidc[2])
 	}
+	parts := []string{"a", cotAffiliation(sidc[1]), dimension}
 
-	// Check for equipment/sensor types (positions 4-6)
-	// SIDC format: S[affiliation][dimension][status][function]...
-	if len(sidc) >= 6 {
-		// Position 4 = function ID first character
-		// Position 5 = function ID second character
-		funcID := sidc[4:6]
-
-		// Equipment categories (position 4 = 'E' or 'I')
-		if sidc[4] == 'E' || sidc[4] == 'I' {
-			// Check specific equipment types
-			switch funcID {
-			case "ES": // Equipment/Sensor
-				return fmt.Sprintf("a-%s-%s-E-S", affiliation, dimension)
-			case "PE": // Equipment/Position Equipment
-				return fmt.Sprintf("a-%s-%s-E", affiliation, dimension)
-			default:
-				// Generic equipment
-				return fmt.Sprintf("a-%s-%s-E", affiliation, dimension)
+	// the function ID is at positions 4 to 9 of warfighting symbols
+	if sidc[0] == 'S' && len(sidc) > 4 {
+		for _, c := range sidc[4:min(len(sidc), 10)] {
+			if c < 'A' || c > 'Z' {
+				break
 			}
-		}
-
-		// Check for units (position 4 = 'U')
-		if sidc[4] == 'U' {
-			return fmt.Sprintf("a-%s-%s-U", affiliation, dimension)
+			parts = append(parts, string(c))
 		}
 	}
+	return strings.Join(parts, "-")
+}
 
-	// Default to basic affiliation-dimension
-	return fmt.Sprintf("a-%s-%s", affiliation, dimension)
+// fullSIDC pads the function ID of a symbol with "-" and the rest with "*"
+func fullSIDC(sidc string) string {
+	if len(sidc) < 10 {
+		sidc += strings.Repeat("-", 10-len(sidc))
+	}
+	return padSIDC(sidc)
 }
 
 func padSIDC(sidc string) string {
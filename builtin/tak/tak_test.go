@@ -0,0 +1,122 @@
+package view
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/projectqai/hydra/goclient"
+	pb "github.com/projectqai/proto/go"
+)
+
+func TestCoTToEntities_DeviceRoundTrip(t *testing.T) {
+	cot := []byte(`<event version="2.0" uid="ANDROID-1" type="a-f-G-U-C" how="m-g" time="2026-01-01T00:00:00Z" start="2026-01-01T00:00:00Z" stale="2026-01-01T00:05:00Z">
+  <point lat="50.1" lon="10.2" hae="200" ce="10" le="10"/>
+  <detail>
+    <takv device="Pixel 7" platform="ATAK-CIV" os="34" version="5.2.0"/>
+    <contact callsign="ALPHA"/>
+    <status battery="87"/>
+  </detail>
+</event>`)
+
+	entities, err := CoTToEntities(cot, "tak-server")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entities) != 2 {
+		t.Fatalf("expected track and device, got %d entities", len(entities))
+	}
+	track, device := entities[0], entities[1]
+	if device.Locator.GetLocatedEntityId() != track.Id || device.Config.GetKey() != DeviceKey {
+		t.Fatalf("device entity not linked to track: %v", device)
+	}
+	fields := device.Config.Value.GetFields()
+	if fields["platform"].GetStringValue() != "ATAK-CIV" || fields["battery"].GetNumberValue() != 87 {
+		t.Fatalf("unexpected device details: %v", fields)
+	}
+
+	out, err := EntityToCoT(track, device)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var event Event
+	if err := xml.Unmarshal(out, &event); err != nil {
+		t.Fatal(err)
+	}
+	if event.Detail.Takv == nil || *event.Detail.Takv != (Takv{Device: "Pixel 7", Platform: "ATAK-CIV", OS: "34", Version: "5.2.0"}) {
+		t.Fatalf("takv not exported: %+v", event.Detail.Takv)
+	}
+	if event.Detail.Status == nil || event.Detail.Status.Battery != "87" {
+		t.Fatalf("battery not exported: %+v", event.Detail.Status)
+	}
+
+	// events without client details have no device
+	entities, err = CoTToEntities([]byte(`<event uid="x" type="a-h-G"><point lat="1" lon="2"/><detail/></event>`), "tak-server")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entities) != 1 {
+		t.Fatalf("expected only the track, got %d entities", len(entities))
+	}
+}
+
+func TestEntityToCoT_IconIsNoSymbol(t *testing.T) {
+	entity := &pb.Entity{
+		Id:     "boat",
+		Geo:    &pb.GeoSpatialComponent{Latitude: 1, Longitude: 2},
+		Symbol: goclient.EmojiSymbol("🚤"),
+	}
+	out, err := EntityToCoT(entity, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var event Event
+	if err := xml.Unmarshal(out, &event); err != nil {
+		t.Fatal(err)
+	}
+	if event.Type != "a-u-G" || event.Detail.Milsym != nil {
+		t.Fatalf("expected an unknown ground track without milsym, got %s %+v", event.Type, event.Detail.Milsym)
+	}
+}
+
+func TestCoTTypes_RoundTrip(t *testing.T) {
+	for _, tc := range []struct{ cot, sidc string }{
+		// mapped in cottypes.csv
+		{"a-h-G-U-C-I", "SHGPUCI---*****"},
+		{"a-f-A-M-F-Q", "SFAPMFQ---*****"},
+		{"b-m-p-w", "GFGPGPPW--****X"},
+		// converted structurally
+		{"a-n-S-C-L-D-D", "SNSPCLDD--*****"},
+		{"a-u-P", "SUPP------*****"},
+		{"a-s-G-E-W-M", "SSGPEWM---*****"},
+	} {
+		if got := cotTypeToSIDC(tc.cot); got != tc.sidc {
+			t.Errorf("cotTypeToSIDC(%q) = %q, want %q", tc.cot, got, tc.sidc)
+		}
+		if got := sidcToCoTType(tc.sidc); got != tc.cot {
+			t.Errorf("sidcToCoTType(%q) = %q, want %q", tc.sidc, got, tc.cot)
+		}
+	}
+
+	// longer types fall back to the closest mapped one, unknown ones to unknown ground
+	if got := cotTypeToSIDC("b-m-p-w-GOTO"); got != "GFGPGPPW--****X" {
+		t.Errorf("unexpected symbol %q for a waypoint subtype", got)
+	}
+	if got := cotTypeToSIDC("t-x-c-t"); got != "SUGP------*****" {
+		t.Errorf("unexpected symbol %q for a ping", got)
+	}
+}
+
+func TestLoadTypeMap(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "types.csv")
+	if err := os.WriteFile(path, []byte("cot,sidc,description\nb-m-p-x-test,GFGPGPPX--****X,test point\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadTypeMap(path); err != nil {
+		t.Fatal(err)
+	}
+	if got := sidcToCoTType("GHGPGPPX--****X"); got != "b-m-p-x-test" {
+		t.Errorf("user mapping not used, got %q", got)
+	}
+}
@@ -0,0 +1,197 @@
+// Package terrain looks up the ground elevation at positions, so builtins can
+// place surface tracks on the ground and clients can show height above terrain.
+package terrain
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/paulmach/orb"
+	pb "github.com/projectqai/proto/go"
+)
+
+// Source returns the terrain elevation in meters above mean sea level for each point
+type Source interface {
+	Elevations(ctx context.Context, points []orb.Point) ([]float64, error)
+}
+
+const (
+	// maxLocations is how many points are sent in one request, the limit of the public OpenTopoData API
+	maxLocations = 100
+	// cellSize rounds positions in degrees before caching, about 100m
+	cellSize = 1e-3
+	// maxCached entries are kept before the cache is dropped
+	maxCached = 1 << 16
+)
+
+// HTTPSource queries a DEM service with the OpenTopoData API, e.g.
+// https://api.opentopodata.org/v1/srtm90m or a self hosted instance.
+// Elevations are cached by position, rounded to about 100m.
+type HTTPSource struct {
+	url    string
+	client *http.Client
+
+	mu    sync.Mutex
+	cache map[[2]int64]float64
+}
+
+// NewHTTPSource returns a source for the dataset URL of an OpenTopoData server
+func NewHTTPSource(rawURL string) (*HTTPSource, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid terrain url %q", rawURL)
+	}
+	return &HTTPSource{
+		url:    rawURL,
+		client: &http.Client{Timeout: 10 * time.Second},
+		cache:  make(map[[2]int64]float64),
+	}, nil
+}
+
+func cell(p orb.Point) [2]int64 {
+	return [2]int64{int64(math.Round(p[0] / cellSize)), int64(math.Round(p[1] / cellSize))}
+}
+
+func (s *HTTPSource) Elevations(ctx context.Context, points []orb.Point) ([]float64, error) {
+	out := make([]float64, len(points))
+
+	// look up what isn't cached yet, once per cell
+	var missing [][2]int64
+	s.mu.Lock()
+	queued := make(map[[2]int64]bool)
+	for _, p := range points {
+		c := cell(p)
+		if _, ok := s.cache[c]; !ok && !queued[c] {
+			queued[c] = true
+			missing = append(missing, c)
+		}
+	}
+	s.mu.Unlock()
+
+	for len(missing) > 0 {
+		n := min(len(missing), maxLocations)
+		elevations, err := s.fetch(ctx, missing[:n])
+		if err != nil {
+			return nil, err
+		}
+		s.mu.Lock()
+		if len(s.cache)+n > maxCached {
+			clear(s.cache)
+		}
+		for i, c := range missing[:n] {
+			s.cache[c] = elevations[i]
+		}
+		s.mu.Unlock()
+		missing = missing[n:]
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, p := range points {
+		out[i] = s.cache[cell(p)]
+	}
+	return out, nil
+}
+
+type apiResponse struct {
+	Status  string `json:"status"`
+	Error   string `json:"error"`
+	Results []struct {
+		// Elevation is null where the dataset has no data, usually the sea
+		Elevation *float64 `json:"elevation"`
+	} `json:"results"`
+}
+
+func (s *HTTPSource) fetch(ctx context.Context, cells [][2]int64) ([]float64, error) {
+	locations := make([]string, len(cells))
+	for i, c := range cells {
+		locations[i] = fmt.Sprintf("%.4f,%.4f", float64(c[1])*cellSize, float64(c[0])*cellSize)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url+"?locations="+url.QueryEscape(strings.Join(locations, "|")), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("terrain request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body apiResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("terrain response (%s): %w", resp.Status, err)
+	}
+	if resp.StatusCode != http.StatusOK || body.Status != "OK" {
+		return nil, fmt.Errorf("terrain request failed (%s): %s", resp.Status, body.Error)
+	}
+	if len(body.Results) != len(cells) {
+		return nil, fmt.Errorf("terrain response has %d results for %d locations", len(body.Results), len(cells))
+	}
+
+	elevations := make([]float64, len(cells))
+	for i, r := range body.Results {
+		if r.Elevation != nil {
+			elevations[i] = *r.Elevation
+		}
+	}
+	return elevations, nil
+}
+
+// Ground sets the altitude of entities to the terrain elevation at their position.
+// Entities without a position are left alone.
+func Ground(ctx context.Context, src Source, entities []*pb.Entity) error {
+	var located []*pb.Entity
+	var points []orb.Point
+	for _, e := range entities {
+		if e.Geo != nil {
+			located = append(located, e)
+			points = append(points, orb.Point{e.Geo.Longitude, e.Geo.Latitude})
+		}
+	}
+	if len(points) == 0 {
+		return nil
+	}
+
+	elevations, err := src.Elevations(ctx, points)
+	if err != nil {
+		return err
+	}
+	for i, e := range located {
+		e.Geo.Altitude = &elevations[i]
+	}
+	return nil
+}
+
+// AboveGround returns the height of entities above the terrain by id, for
+// entities with a position and altitude.
+func AboveGround(ctx context.Context, src Source, entities []*pb.Entity) (map[string]float64, error) {
+	var located []*pb.Entity
+	var points []orb.Point
+	for _, e := range entities {
+		if e.Geo != nil && e.Geo.Altitude != nil {
+			located = append(located, e)
+			points = append(points, orb.Point{e.Geo.Longitude, e.Geo.Latitude})
+		}
+	}
+	agl := make(map[string]float64, len(located))
+	if len(points) == 0 {
+		return agl, nil
+	}
+
+	elevations, err := src.Elevations(ctx, points)
+	if err != nil {
+		return nil, err
+	}
+	for i, e := range located {
+		agl[e.Id] = *e.Geo.Altitude - elevations[i]
+	}
+	return agl, nil
+}
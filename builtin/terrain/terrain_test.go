@@ -0,0 +1,56 @@
+package terrain
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/paulmach/orb"
+	pb "github.com/projectqai/proto/go"
+)
+
+func TestHTTPSource_CachesByCell(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		locations := strings.Split(r.URL.Query().Get("locations"), "|")
+		results := make([]string, len(locations))
+		for i := range locations {
+			results[i] = `{"elevation": 120.5}`
+		}
+		fmt.Fprintf(w, `{"status": "OK", "results": [%s]}`, strings.Join(results, ","))
+	}))
+	defer srv.Close()
+
+	src, err := NewHTTPSource(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// both points fall into the same cell
+	elevations, err := src.Elevations(context.Background(), []orb.Point{{10, 50}, {10.0001, 50.0001}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(elevations) != 2 || elevations[0] != 120.5 || elevations[1] != 120.5 {
+		t.Fatalf("unexpected elevations %v", elevations)
+	}
+
+	alt := 200.0
+	agl, err := AboveGround(context.Background(), src, []*pb.Entity{
+		{Id: "a", Geo: &pb.GeoSpatialComponent{Longitude: 10, Latitude: 50, Altitude: &alt}},
+		{Id: "b", Geo: &pb.GeoSpatialComponent{Longitude: 10, Latitude: 50}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(agl) != 1 || agl["a"] != 79.5 {
+		t.Fatalf("unexpected agl %v", agl)
+	}
+	if requests != 1 {
+		t.Fatalf("expected 1 request, got %d", requests)
+	}
+}
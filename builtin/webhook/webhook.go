@@ -0,0 +1,304 @@
+// Package webhook posts entity changes to an external HTTP endpoint.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/projectqai/hydra/builtin"
+	"github.com/projectqai/hydra/builtin/controller"
+	"github.com/projectqai/hydra/goclient"
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const controllerName = "webhook"
+
+// retryBackoff is the wait before a failed delivery is retried, it doubles with
+// every attempt up to a minute
+var retryBackoff = time.Second
+
+type HookConfig struct {
+	URL     string
+	Headers map[string]string
+	Filter  *pb.EntityFilter
+	// MinInterval batches all changes within the interval into one request,
+	// keeping only the latest change per entity. Zero posts every change on its own.
+	MinInterval time.Duration
+	MaxRetries  int
+	// DeadLetterFile receives batches that could not be delivered, one JSON object per line
+	DeadLetterFile string
+}
+
+type Event struct {
+	Change string          `json:"change"`
+	Entity json.RawMessage `json:"entity"`
+}
+
+type Batch struct {
+	Source string    `json:"source"`
+	Time   time.Time `json:"time"`
+	Events []Event   `json:"events"`
+}
+
+func Run(ctx context.Context, logger *slog.Logger, _ string) error {
+	name := controllerName
+
+	return controller.Run1to1(ctx, &pb.EntityFilter{
+		Component: []uint32{31},
+		Config: &pb.ConfigurationFilter{
+			Controller: &name,
+		},
+	}, func(ctx context.Context, entity *pb.Entity) error {
+		return runHook(ctx, logger, entity)
+	}, controller.WithValidator(validateHook))
+}
+
+func validateHook(entity *pb.Entity) error {
+	if entity.Config.Key != "webhook.v0" {
+		return fmt.Errorf("unknown config key: %s", entity.Config.Key)
+	}
+	config, err := parseHookConfig(entity.Config)
+	if err != nil {
+		return err
+	}
+	if u, err := url.Parse(config.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("invalid url %q", config.URL)
+	}
+	return nil
+}
+
+func runHook(ctx context.Context, logger *slog.Logger, entity *pb.Entity) error {
+	if entity.Config.Key != "webhook.v0" {
+		return fmt.Errorf("unknown config key: %s", entity.Config.Key)
+	}
+
+	config, err := parseHookConfig(entity.Config)
+	if err != nil {
+		return fmt.Errorf("parse config: %w", err)
+	}
+
+	grpcConn, err := builtin.BuiltinClientConn()
+	if err != nil {
+		return fmt.Errorf("gRPC connection: %w", err)
+	}
+	defer grpcConn.Close()
+
+	client := pb.NewWorldServiceClient(grpcConn)
+
+	stream, err := goclient.WatchEntitiesWithRetry(ctx, client, &pb.ListEntitiesRequest{
+		Filter: config.Filter,
+	})
+	if err != nil {
+		return err
+	}
+
+	// the reader blocks while a batch is being delivered, the engine
+	// coalesces changes for us in the meantime
+	events := make(chan *pb.EntityChangeEvent)
+	errc := make(chan error, 1)
+	go func() {
+		for {
+			event, err := stream.Recv()
+			if err != nil {
+				errc <- err
+				return
+			}
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	logger.Info("Starting webhook", "entityID", entity.Id, "url", config.URL, "minInterval", config.MinInterval)
+
+	var tick <-chan time.Time
+	if config.MinInterval > 0 {
+		ticker := time.NewTicker(config.MinInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	pending := make(map[string]Event)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-errc:
+			return err
+		case <-tick:
+			deliver(ctx, logger, entity.Id, config, pending)
+			clear(pending)
+		case event := <-events:
+			if event.Entity == nil || event.Entity.Id == entity.Id {
+				continue
+			}
+			data, err := protojson.Marshal(event.Entity)
+			if err != nil {
+				logger.Error("Failed to marshal entity", "entityID", entity.Id, "target", event.Entity.Id, "error", err)
+				continue
+			}
+			pending[event.Entity.Id] = Event{Change: changeName(event.T), Entity: data}
+
+			if tick == nil {
+				deliver(ctx, logger, entity.Id, config, pending)
+				clear(pending)
+			}
+		}
+	}
+}
+
+func changeName(t pb.EntityChange) string {
+	switch t {
+	case pb.EntityChange_EntityChangeUpdated:
+		return "updated"
+	case pb.EntityChange_EntityChangeExpired:
+		return "expired"
+	case pb.EntityChange_EntityChangeUnobserved:
+		return "unobserved"
+	}
+	return "invalid"
+}
+
+func deliver(ctx context.Context, logger *slog.Logger, hookID string, config *HookConfig, pending map[string]Event) {
+	if len(pending) == 0 {
+		return
+	}
+
+	ids := make([]string, 0, len(pending))
+	for id := range pending {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	batch := Batch{Source: hookID, Time: time.Now().UTC()}
+	for _, id := range ids {
+		batch.Events = append(batch.Events, pending[id])
+	}
+
+	body, err := json.Marshal(batch)
+	if err != nil {
+		logger.Error("Failed to marshal batch", "entityID", hookID, "error", err)
+		return
+	}
+
+	backoff := retryBackoff
+	for attempt := 0; ; attempt++ {
+		err = post(ctx, config, body)
+		if err == nil {
+			logger.Debug("Delivered batch", "entityID", hookID, "events", len(batch.Events))
+			return
+		}
+		if ctx.Err() != nil || attempt >= config.MaxRetries {
+			break
+		}
+
+		logger.Warn("Webhook delivery failed, retrying", "entityID", hookID, "attempt", attempt+1, "backoff", backoff, "error", err)
+		select {
+		case <-ctx.Done():
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, time.Minute)
+	}
+
+	logger.Error("Webhook delivery failed, dropping batch", "entityID", hookID, "events", len(batch.Events), "error", err)
+	if config.DeadLetterFile != "" {
+		if err := appendDeadLetter(config.DeadLetterFile, body); err != nil {
+			logger.Error("Failed to write dead letter", "entityID", hookID, "file", config.DeadLetterFile, "error", err)
+		}
+	}
+}
+
+func post(ctx context.Context, config *HookConfig, body []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, config.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range config.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func appendDeadLetter(path string, body []byte) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(body, '\n'))
+	return err
+}
+
+func parseHookConfig(config *pb.ConfigurationComponent) (*HookConfig, error) {
+	if config.Value == nil || config.Value.Fields == nil {
+		return nil, fmt.Errorf("empty config value")
+	}
+
+	fields := config.Value.Fields
+	hookConfig := &HookConfig{
+		Headers:     make(map[string]string),
+		MinInterval: time.Second,
+		MaxRetries:  5,
+	}
+
+	if v, ok := fields["url"]; ok {
+		hookConfig.URL = v.GetStringValue()
+	}
+	if hookConfig.URL == "" {
+		return nil, fmt.Errorf("missing url")
+	}
+
+	if v, ok := fields["headers"]; ok {
+		if s := v.GetStructValue(); s != nil {
+			for k, hv := range s.Fields {
+				hookConfig.Headers[k] = hv.GetStringValue()
+			}
+		}
+	}
+	if v, ok := fields["filter"]; ok {
+		hookConfig.Filter = controller.ParseEntityFilter(v)
+	}
+	if v, ok := fields["min_interval_seconds"]; ok {
+		hookConfig.MinInterval = time.Duration(v.GetNumberValue() * float64(time.Second))
+	}
+	if v, ok := fields["max_retries"]; ok {
+		hookConfig.MaxRetries = int(v.GetNumberValue())
+	}
+	if v, ok := fields["dead_letter_file"]; ok {
+		hookConfig.DeadLetterFile = v.GetStringValue()
+	}
+
+	return hookConfig, nil
+}
+
+func init() {
+	builtin.Register(controllerName, Run)
+}
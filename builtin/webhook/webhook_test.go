@@ -0,0 +1,149 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/projectqai/hydra/engine"
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// receiver is a webhook endpoint that answers with the given statuses in turn,
+// then 200, and records what it received
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	batches  []Batch
+	headers  []http.Header
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var batch Batch
+	json.NewDecoder(req.Body).Decode(&batch)
+	r.batches = append(r.batches, batch)
+	r.headers = append(r.headers, req.Header.Clone())
+	if len(r.statuses) > 0 {
+		w.WriteHeader(r.statuses[0])
+		r.statuses = r.statuses[1:]
+	}
+}
+
+func (r *receiver) received() ([]Batch, []http.Header) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Batch(nil), r.batches...), append([]http.Header(nil), r.headers...)
+}
+
+func hookEntity(t *testing.T, value map[string]any) *pb.Entity {
+	t.Helper()
+	v, err := structpb.NewStruct(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &pb.Entity{Id: "hook", Config: &pb.ConfigurationComponent{Controller: controllerName, Key: "webhook.v0", Value: v}}
+}
+
+func TestRunHook_BatchesWithinMinIntervalAndSendsHeaders(t *testing.T) {
+	server := engine.NewTestServer(t)
+	rcv := &receiver{}
+	endpoint := httptest.NewServer(rcv)
+	defer endpoint.Close()
+
+	var changes []*pb.Entity
+	for _, id := range []string{"a", "b", "c"} {
+		changes = append(changes, &pb.Entity{Id: id, Geo: &pb.GeoSpatialComponent{Longitude: 10, Latitude: 50}})
+	}
+	if _, err := server.Client.Push(context.Background(), &pb.EntityChangeRequest{Changes: changes}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go runHook(ctx, slog.Default(), hookEntity(t, map[string]any{
+		"url":                  endpoint.URL,
+		"min_interval_seconds": 0.5,
+		"headers":              map[string]any{"Authorization": "Bearer secret"},
+	}))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if batches, _ := rcv.received(); len(batches) > 0 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	batches, headers := rcv.received()
+	if len(batches) != 1 {
+		t.Fatalf("expected the changes within the interval in one batch, got %d", len(batches))
+	}
+	if batches[0].Source != "hook" || len(batches[0].Events) != 3 {
+		t.Fatalf("expected three events from the hook, got %+v", batches[0])
+	}
+	if got := headers[0].Get("Authorization"); got != "Bearer secret" {
+		t.Errorf("expected the configured header, got %q", got)
+	}
+}
+
+func TestDeliver_RetriesOn5xx(t *testing.T) {
+	retryBackoff = time.Millisecond
+	defer func() { retryBackoff = time.Second }()
+
+	rcv := &receiver{statuses: []int{http.StatusBadGateway, http.StatusServiceUnavailable}}
+	endpoint := httptest.NewServer(rcv)
+	defer endpoint.Close()
+
+	deadLetters := filepath.Join(t.TempDir(), "dead.jsonl")
+	config := &HookConfig{URL: endpoint.URL, MaxRetries: 2, DeadLetterFile: deadLetters}
+	deliver(context.Background(), slog.Default(), "hook", config, map[string]Event{"a": {Change: "updated", Entity: json.RawMessage(`{"id":"a"}`)}})
+
+	if batches, _ := rcv.received(); len(batches) != 3 {
+		t.Fatalf("expected two failed attempts and one delivery, got %d", len(batches))
+	}
+	if _, err := os.Stat(deadLetters); !os.IsNotExist(err) {
+		t.Error("expected no dead letter for a batch that was delivered")
+	}
+}
+
+func TestDeliver_DeadLetterAfterPersistentFailure(t *testing.T) {
+	retryBackoff = time.Millisecond
+	defer func() { retryBackoff = time.Second }()
+
+	rcv := &receiver{statuses: []int{500, 500, 500, 500}}
+	endpoint := httptest.NewServer(rcv)
+	defer endpoint.Close()
+
+	deadLetters := filepath.Join(t.TempDir(), "dead.jsonl")
+	config := &HookConfig{URL: endpoint.URL, MaxRetries: 1, DeadLetterFile: deadLetters}
+	pending := map[string]Event{"a": {Change: "expired", Entity: json.RawMessage(`{"id":"a"}`)}}
+	deliver(context.Background(), slog.Default(), "hook", config, pending)
+	deliver(context.Background(), slog.Default(), "hook", config, pending)
+
+	if batches, _ := rcv.received(); len(batches) != 4 {
+		t.Fatalf("expected each batch to be tried twice, got %d attempts", len(batches))
+	}
+	data, err := os.ReadFile(deadLetters)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected one dead letter per batch, got %d", len(lines))
+	}
+	var batch Batch
+	if err := json.Unmarshal([]byte(lines[0]), &batch); err != nil || len(batch.Events) != 1 || batch.Events[0].Change != "expired" {
+		t.Fatalf("expected the undelivered batch, got %s (%v)", lines[0], err)
+	}
+}
@@ -0,0 +1,33 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+
+	"github.com/projectqai/hydra/goclient"
+	"github.com/spf13/cobra"
+)
+
+func runBearing(cmd *cobra.Command, args []string) error {
+	var resp goclient.BearingResponse
+	query := url.Values{"from": {args[0]}, "to": {args[1]}}
+	if err := conn.GetJSON(cmd.Context(), "/bearing", query, &resp); err != nil {
+		return fmt.Errorf("failed to get bearing: %w", err)
+	}
+
+	switch outputFormat {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(resp)
+	case "table":
+		tbl := newTable("FROM", "TO", "BEARING", "RANGE")
+		tbl.AddRow(resp.From, resp.To, fmt.Sprintf("%05.1f°", resp.Bearing), formatPathLength(resp.Distance))
+		tbl.Print()
+		return nil
+	default:
+		return fmt.Errorf("unknown output format: %s (use: table, json)", outputFormat)
+	}
+}
@@ -0,0 +1,163 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+
+	"github.com/fatih/color"
+	"github.com/projectqai/hydra/builtin"
+	"github.com/projectqai/hydra/builtin/controller"
+	"github.com/projectqai/hydra/cmd"
+	"github.com/projectqai/hydra/goclient"
+	pb "github.com/projectqai/proto/go"
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func init() {
+	builtinsCmd := &cobra.Command{
+		Use:               "builtins",
+		Aliases:           []string{"builtin"},
+		Short:             "list and manage the builtins and connectors of the engine",
+		PersistentPreRunE: connect,
+	}
+	AddConnectionFlags(builtinsCmd)
+
+	lsCmd := &cobra.Command{
+		Use:   "ls",
+		Short: "list builtins and the connectors they run",
+		Args:  cobra.NoArgs,
+		RunE:  runBuiltinsLs,
+	}
+
+	restartCmd := &cobra.Command{
+		Use:   "restart <entityID>",
+		Short: "restart a connector by pushing its config entity again",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runBuiltinsRestart,
+	}
+
+	runCmd := &cobra.Command{
+		Use:   "run <builtin>...",
+		Short: "run builtins in this process against a remote engine given by --server",
+		Long:  "run builtins out of process, e.g. hydra builtin run ais in an ingest container next to a central engine. They connect to --server like any client, so policies see them as network peers, not as builtins. Exits with an error once all of them failed for good, e.g. on an invalid config.",
+		Args:  cobra.MinimumNArgs(1),
+		RunE:  runBuiltinsRun,
+	}
+
+	builtinsCmd.AddCommand(lsCmd)
+	builtinsCmd.AddCommand(restartCmd)
+	builtinsCmd.AddCommand(runCmd)
+	cmd.CMD.AddCommand(builtinsCmd)
+}
+
+func runBuiltinsLs(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	var resp goclient.BuiltinsResponse
+	if err := conn.GetJSON(ctx, "/builtins", nil, &resp); err != nil {
+		return fmt.Errorf("failed to get builtins: %w", err)
+	}
+
+	statusKey := controller.StatusKey
+	list, err := pb.NewWorldServiceClient(conn).ListEntities(ctx, &pb.ListEntitiesRequest{
+		Filter: &pb.EntityFilter{Config: &pb.ConfigurationFilter{Key: &statusKey}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list connector status: %w", err)
+	}
+	connectors := list.Entities
+	slices.SortFunc(connectors, func(a, b *pb.Entity) int {
+		return strings.Compare(a.Id, b.Id)
+	})
+
+	tbl := newTable("BUILTIN", "STATE", "RESTARTS", "CONNECTORS", "LAST ERROR")
+	for _, b := range resp.Builtins {
+		n := 0
+		for _, c := range connectors {
+			if c.Config.Controller == b.Name {
+				n++
+			}
+		}
+		tbl.AddRow(b.Name, colorState(b.State), b.Restarts, n, b.LastError)
+	}
+	tbl.Print()
+
+	if len(connectors) == 0 {
+		return nil
+	}
+	fmt.Println()
+
+	tbl = newTable("CONNECTOR", "BUILTIN", "STATE", "RESTARTS", "COUNT", "LAST ERROR")
+	for _, c := range connectors {
+		fields := c.Config.Value.GetFields()
+		tbl.AddRow(
+			fields["connector"].GetStringValue(),
+			c.Config.Controller,
+			colorState(fields["state"].GetStringValue()),
+			int(fields["restarts"].GetNumberValue()),
+			int64(fields["count"].GetNumberValue()),
+			fields["last_error"].GetStringValue(),
+		)
+	}
+	tbl.Print()
+	return nil
+}
+
+func runBuiltinsRun(cmd *cobra.Command, args []string) error {
+	if wgConfigPath != "" {
+		return fmt.Errorf("--wireguard is not supported for builtins, run them where the engine is reachable")
+	}
+
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	builtin.UseRemote(serverURL)
+	slog.Info("Running builtins out of process", "builtins", strings.Join(args, ","), "server", serverURL)
+	return builtin.RunNamed(ctx, serverURL, args...)
+}
+
+func colorState(state string) string {
+	switch state {
+	case builtin.StateRunning, controller.StateConnected:
+		return color.GreenString(state)
+	case builtin.StateRestarting, controller.StateError, controller.StateInvalid:
+		return color.RedString(state)
+	default:
+		return state
+	}
+}
+
+func runBuiltinsRestart(cmd *cobra.Command, args []string) error {
+	client := pb.NewWorldServiceClient(conn)
+	entityID := args[0]
+
+	resp, err := client.GetEntity(context.Background(), &pb.GetEntityRequest{Id: entityID})
+	if err != nil {
+		return fmt.Errorf("failed to get entity: %w", err)
+	}
+	entity := resp.Entity
+	if entity.Config == nil || entity.Config.Key == controller.StatusKey {
+		return fmt.Errorf("%s is not a connector config", entityID)
+	}
+
+	// any update of the config restarts its connector
+	if entity.Lifetime == nil {
+		entity.Lifetime = &pb.Lifetime{}
+	}
+	entity.Lifetime.From = timestamppb.Now()
+	if _, err := client.Push(context.Background(), &pb.EntityChangeRequest{
+		Changes: []*pb.Entity{entity},
+	}); err != nil {
+		return fmt.Errorf("failed to push entity: %w", err)
+	}
+
+	fmt.Printf("Connector '%s' restarted\n", entityID)
+	return nil
+}
@@ -0,0 +1,66 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/projectqai/hydra/goclient"
+	pb "github.com/projectqai/proto/go"
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+var (
+	clustersZoom     int
+	clustersMinCount int
+)
+
+func runClusters(cmd *cobra.Command, args []string) error {
+	req := goclient.ClustersRequest{
+		Zoom:     clustersZoom,
+		MinCount: clustersMinCount,
+	}
+	if filterBBox != "" {
+		bound, err := parseBBox(filterBBox)
+		if err != nil {
+			return fmt.Errorf("invalid bbox: %w", err)
+		}
+		req.Bounds = []float64{bound.Min[0], bound.Min[1], bound.Max[0], bound.Max[1]}
+	}
+	if len(filterWith) > 0 {
+		filter, err := protojson.Marshal(&pb.EntityFilter{Component: intSliceToUint32(filterWith)})
+		if err != nil {
+			return err
+		}
+		req.Filter = filter
+	}
+
+	var resp goclient.ClustersResponse
+	if err := conn.PostJSON(cmd.Context(), "/clusters", req, &resp); err != nil {
+		return fmt.Errorf("failed to query clusters: %w", err)
+	}
+
+	switch outputFormat {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(resp)
+	case "table":
+		tbl := newTable("COUNT", "Latitude", "Longitude", "ID")
+		for _, c := range resp.Clusters {
+			tbl.AddRow(c.Count, fmt.Sprintf("%.5f", c.Lat), fmt.Sprintf("%.5f", c.Lon), "-")
+		}
+		for _, raw := range resp.Entities {
+			entity := &pb.Entity{}
+			if err := protojson.Unmarshal(raw, entity); err != nil {
+				return fmt.Errorf("failed to decode entity: %w", err)
+			}
+			tbl.AddRow(1, fmt.Sprintf("%.5f", entity.Geo.GetLatitude()), fmt.Sprintf("%.5f", entity.Geo.GetLongitude()), entity.Id)
+		}
+		tbl.Print()
+		return nil
+	default:
+		return fmt.Errorf("unknown output format: %s (use: table, json)", outputFormat)
+	}
+}
@@ -0,0 +1,189 @@
+package cli
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/projectqai/hydra/goclient"
+	pb "github.com/projectqai/proto/go"
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var (
+	createID      string
+	createLat     float64
+	createLon     float64
+	createPos     string
+	createAlt     float64
+	createLabel   string
+	createSIDC    string
+	createBearing float64
+	createTTL     time.Duration
+	createSticky  bool
+	createDryRun  bool
+	createRange   float64
+	createFOV     float64
+	createRings   []float64
+)
+
+func addCreateFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&createID, "id", "", "entity ID (generated if empty)")
+	cmd.Flags().Float64Var(&createLat, "lat", 0, "latitude in decimal degrees")
+	cmd.Flags().Float64Var(&createLon, "lon", 0, "longitude in decimal degrees")
+	cmd.Flags().StringVar(&createPos, "pos", "", "position as lon,lat or MGRS instead of --lat/--lon")
+	cmd.Flags().Float64Var(&createAlt, "alt", 0, "altitude in meters above the WGS84 ellipsoid")
+	cmd.Flags().StringVar(&createLabel, "label", "", "label")
+	cmd.Flags().StringVar(&createSIDC, "sidc", "", "MIL-STD-2525C symbol code, e.g. SFGPU------")
+	cmd.Flags().Float64Var(&createBearing, "bearing", 0, "azimuth in degrees")
+	cmd.Flags().DurationVar(&createTTL, "ttl", 0, "expire the entity after this duration (e.g. 5m), never if 0")
+	cmd.Flags().BoolVar(&createSticky, "sticky", false, "never expire the entity, not even to make room or with a parent, e.g. for boundaries and named points")
+	cmd.Flags().Float64Var(&createRange, "range", 0, "sensor range in meters, adds the coverage as shape")
+	cmd.Flags().Float64Var(&createFOV, "fov", 0, "sensor field of view in degrees around --bearing, full circle if 0")
+	cmd.Flags().Float64SliceVar(&createRings, "rings", nil, "range ring radii in meters, each pushed as child entity located on this one")
+	cmd.Flags().BoolVar(&createDryRun, "dry-run", false, "print the entity as YAML instead of pushing it")
+}
+
+func buildEntity(cmd *cobra.Command) (*pb.Entity, error) {
+	flags := cmd.Flags()
+
+	id := createID
+	if id == "" {
+		b := make([]byte, 4)
+		rand.Read(b)
+		id = "manual-" + hex.EncodeToString(b)
+	}
+
+	entity := &pb.Entity{Id: id}
+
+	if createLabel != "" {
+		entity.Label = &createLabel
+	}
+
+	switch {
+	case createPos != "":
+		if flags.Changed("lat") || flags.Changed("lon") {
+			return nil, fmt.Errorf("--pos can not be combined with --lat/--lon")
+		}
+		lon, lat, err := parsePoint(createPos)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --pos: %w", err)
+		}
+		entity.Geo = &pb.GeoSpatialComponent{Longitude: lon, Latitude: lat}
+	case flags.Changed("lat") || flags.Changed("lon"):
+		if !flags.Changed("lat") || !flags.Changed("lon") {
+			return nil, fmt.Errorf("--lat and --lon must be given together")
+		}
+		entity.Geo = &pb.GeoSpatialComponent{Longitude: createLon, Latitude: createLat}
+	}
+
+	if flags.Changed("alt") {
+		if entity.Geo == nil {
+			return nil, fmt.Errorf("--alt requires a position")
+		}
+		entity.Geo.Altitude = &createAlt
+	}
+
+	if createSIDC != "" {
+		entity.Symbol = &pb.SymbolComponent{MilStd2525C: createSIDC}
+	}
+
+	if flags.Changed("bearing") {
+		entity.Bearing = &pb.BearingComponent{Azimuth: &createBearing}
+	}
+
+	if createRange > 0 {
+		if entity.Geo == nil {
+			return nil, fmt.Errorf("--range requires a position")
+		}
+		if createFOV > 0 && !flags.Changed("bearing") {
+			return nil, fmt.Errorf("--fov requires --bearing")
+		}
+		entity.Shape = goclient.SectorShape(entity.Geo.Longitude, entity.Geo.Latitude, createBearing, createFOV, createRange)
+	} else if flags.Changed("fov") {
+		return nil, fmt.Errorf("--fov requires --range")
+	}
+
+	if createSticky {
+		if createTTL > 0 {
+			return nil, fmt.Errorf("--sticky can not be combined with --ttl")
+		}
+		entity.Lifetime = goclient.StickyLifetime()
+	}
+
+	if createTTL > 0 {
+		now := time.Now()
+		entity.Lifetime = &pb.Lifetime{
+			From:  timestamppb.New(now),
+			Until: timestamppb.New(now.Add(createTTL)),
+		}
+	}
+
+	return entity, nil
+}
+
+// buildRings creates one range ring entity per --rings radius, located on the parent
+// so they expire with it when the engine cascades expiry.
+func buildRings(parent *pb.Entity) ([]*pb.Entity, error) {
+	if len(createRings) == 0 {
+		return nil, nil
+	}
+	if parent.Geo == nil {
+		return nil, fmt.Errorf("--rings requires a position")
+	}
+
+	rings := make([]*pb.Entity, 0, len(createRings))
+	for _, radius := range createRings {
+		if radius <= 0 {
+			return nil, fmt.Errorf("invalid ring radius %v", radius)
+		}
+		label := strconv.FormatFloat(radius, 'f', -1, 64) + " m"
+		rings = append(rings, &pb.Entity{
+			Id:       parent.Id + "-ring-" + strconv.FormatFloat(radius, 'f', -1, 64),
+			Label:    &label,
+			Shape:    goclient.RangeRingShape(parent.Geo.Longitude, parent.Geo.Latitude, radius),
+			Locator:  &pb.LocatorComponent{LocatedEntityId: parent.Id},
+			Lifetime: parent.Lifetime,
+		})
+	}
+	return rings, nil
+}
+
+func runCreate(cmd *cobra.Command, args []string) error {
+	entity, err := buildEntity(cmd)
+	if err != nil {
+		return err
+	}
+	rings, err := buildRings(entity)
+	if err != nil {
+		return err
+	}
+	entities := append([]*pb.Entity{entity}, rings...)
+
+	if createDryRun {
+		for i, e := range entities {
+			if i > 0 {
+				fmt.Println("---")
+			}
+			yamlBytes, err := protoToYAML(e)
+			if err != nil {
+				return fmt.Errorf("failed to marshal entity: %w", err)
+			}
+			fmt.Print(string(yamlBytes))
+		}
+		return nil
+	}
+
+	client := pb.NewWorldServiceClient(conn)
+	if _, err := client.Push(cmd.Context(), &pb.EntityChangeRequest{
+		Changes: entities,
+	}); err != nil {
+		return fmt.Errorf("failed to push entity: %w", err)
+	}
+
+	fmt.Printf("Entity '%s' created\n", entity.Id)
+	return nil
+}
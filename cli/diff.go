@@ -0,0 +1,113 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pmezard/go-difflib/difflib"
+	pb "github.com/projectqai/proto/go"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+func runDiff(cmd *cobra.Command, args []string) error {
+	client := pb.NewWorldServiceClient(conn)
+
+	entities, err := readEntities(args[0])
+	if err != nil {
+		return err
+	}
+
+	for _, local := range entities {
+		var remote *pb.Entity
+		resp, err := client.GetEntity(cmd.Context(), &pb.GetEntityRequest{Id: local.Id})
+		if err == nil {
+			remote = resp.Entity
+		} else if status.Code(err) != codes.NotFound {
+			return fmt.Errorf("failed to get entity %s: %w", local.Id, err)
+		}
+
+		// pushing an already expired entity removes it, like `ec rm`
+		if isRemoval(local) {
+			if remote == nil {
+				continue
+			}
+			local = nil
+		} else if remote != nil {
+			remote = withoutServerDefaults(remote, local)
+		}
+
+		diff, err := diffEntities(remote, local)
+		if err != nil {
+			return err
+		}
+		fmt.Print(diff)
+	}
+	return nil
+}
+
+func isRemoval(entity *pb.Entity) bool {
+	return entity.Lifetime != nil && entity.Lifetime.Until.IsValid() && !entity.Lifetime.Until.AsTime().After(time.Now())
+}
+
+// withoutServerDefaults drops fields the server fills in on push if the local
+// entity leaves them empty, so they don't show up as changes
+func withoutServerDefaults(remote, local *pb.Entity) *pb.Entity {
+	remote = proto.Clone(remote).(*pb.Entity)
+	if remote.Lifetime != nil && (local.Lifetime == nil || local.Lifetime.From == nil) {
+		remote.Lifetime.From = nil
+		if local.Lifetime == nil && remote.Lifetime.Until == nil {
+			remote.Lifetime = nil
+		}
+	}
+	return remote
+}
+
+// diffEntities renders a unified diff between the YAML of two entities, nil meaning absent
+func diffEntities(from, to *pb.Entity) (string, error) {
+	var id string
+	var a, b []byte
+	var err error
+
+	if from != nil {
+		id = from.Id
+		if a, err = protoToYAML(from); err != nil {
+			return "", err
+		}
+	}
+	if to != nil {
+		id = to.Id
+		if b, err = protoToYAML(to); err != nil {
+			return "", err
+		}
+	}
+
+	fromFile, toFile := "server/"+id, "local/"+id
+	if from == nil {
+		fromFile = "/dev/null"
+	}
+	if to == nil {
+		toFile = "/dev/null"
+	}
+
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        yamlLines(a),
+		B:        yamlLines(b),
+		FromFile: fromFile,
+		ToFile:   toFile,
+		Context:  3,
+	})
+}
+
+func yamlLines(b []byte) []string {
+	s := strings.TrimRight(string(b), "\n")
+	if s == "" {
+		return nil
+	}
+	// SplitAfter leaves an empty element after the final newline
+	lines := strings.SplitAfter(s+"\n", "\n")
+	return lines[:len(lines)-1]
+}
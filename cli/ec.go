@@ -1,22 +1,44 @@
 package cli
 
 import (
+	"bufio"
 	"bytes"
+	"cmp"
 	"context"
 	"crypto/sha256"
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
+	"net/url"
 	"os"
 	"os/exec"
+	"regexp"
+	"slices"
 	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 
+	"github.com/projectqai/hydra/builtin/terrain"
 	"github.com/projectqai/hydra/cmd"
 	"github.com/projectqai/hydra/goclient"
 	pb "github.com/projectqai/proto/go"
 
+	"github.com/fatih/color"
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/encoding/wkb"
+	"github.com/paulmach/orb/encoding/wkt"
+	"github.com/paulmach/orb/geo"
+	"github.com/paulmach/orb/geojson"
 	"github.com/rodaine/table"
 	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protodelim"
 	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/types/known/timestamppb"
 	"gopkg.in/yaml.v3"
@@ -29,7 +51,30 @@ var (
 	filterTaskableContext  string
 	filterTaskableAssignee string
 	filterBBox             string
+	filterParent           string
+	filterNear             string
+	filterRadius           float64
+	coordsFormat           string
+	deadReckoning          time.Duration
+	geoUncertainty         float64
+	showSeen               bool
+	terrainURL             string
+	observeWKT             string
+	observeFile            string
+	filterWKT              string
+	minConfidence          float64
+	minSeen                uint64
+	symbology              string
+	sortBy                 string
+	filterAltitude         string
+	debugSince             time.Duration
 	outputFormat           string
+	getOutputFormat        string
+	getWatch               bool
+	putWait                bool
+	putWaitTimeout         time.Duration
+	putClassification      string
+	filterClearance        string
 )
 
 func init() {
@@ -52,8 +97,23 @@ func init() {
 	lsCmd.Flags().StringVar(&filterConfigController, "config-controller", "", "filter by configuration controller ID")
 	lsCmd.Flags().StringVar(&filterTaskableContext, "taskable-context", "", "filter by taskable context entity ID")
 	lsCmd.Flags().StringVar(&filterTaskableAssignee, "taskable-assignee", "", "filter by taskable assignee entity ID")
-	lsCmd.Flags().StringVar(&filterBBox, "bbox", "", "filter by bounding box: lon1,lat1,lon2,lat2")
-	lsCmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "output format: table, yaml, json")
+	lsCmd.Flags().StringVar(&filterBBox, "bbox", "", "filter by bounding box: lon1,lat1,lon2,lat2 or two MGRS corners mgrs1,mgrs2")
+	lsCmd.Flags().StringVar(&filterWKT, "wkt", "", "filter by a geometry in WKT, e.g. \"POLYGON((13.3 52.5, 13.5 52.5, 13.4 52.6, 13.3 52.5))\"")
+	lsCmd.Flags().StringVar(&filterNear, "near", "", "filter by distance to a point given as lon,lat or MGRS, see --radius")
+	lsCmd.Flags().Float64Var(&filterRadius, "radius", 1000, "radius in meters for --near")
+	lsCmd.Flags().StringVar(&coordsFormat, "coords", "dd", "coordinate format: dd (decimal degrees), mgrs")
+	lsCmd.Flags().StringVar(&filterParent, "parent", "", "filter by parent entity ID (entities located on or detected by it)")
+	lsCmd.Flags().DurationVar(&deadReckoning, "dead-reckoning", 0, "extrapolate positions of moving entities last measured within this age (e.g. 30s)")
+	lsCmd.Flags().Float64Var(&geoUncertainty, "uncertainty", 0, "also match entities within this many standard deviations of their position uncertainty of --bbox/--near (e.g. 2)")
+	lsCmd.Flags().Float64Var(&minConfidence, "min-confidence", 0, "only entities pushed with at least this confidence from 0 to 1, entities pushed without one always match")
+	lsCmd.Flags().Uint64Var(&minSeen, "min-seen", 0, "only entities pushed at least this many times since they appeared, to hide tracks seen once")
+	lsCmd.Flags().StringVar(&sortBy, "sort", "id", "sort by: id, confidence (highest first), seen (most pushed first)")
+	lsCmd.Flags().StringVar(&filterAltitude, "altitude", "", "only entities with an altitude in min:max, in meters or flight levels, e.g. FL100:FL240 or :500")
+	lsCmd.Flags().StringVar(&filterClearance, "clearance", "", "only entities releasable to this clearance, e.g. \"CONFIDENTIAL//REL TO DEU\"")
+	lsCmd.Flags().BoolVar(&showSeen, "seen", false, "show the time since the engine last received an update for each entity, and how many it received")
+	lsCmd.Flags().StringVar(&terrainURL, "terrain", "", "show the height above ground next to the altitude, from this OpenTopoData dataset URL (e.g. https://api.opentopodata.org/v1/srtm90m)")
+	lsCmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "output format: table, yaml, json, pb (length-delimited protobuf for ec put)")
+	lsCmd.Flags().StringVar(&symbology, "symbology", goclient.Symbology2525C, "symbol codes to show: 2525c as stored, or 2525d where a mapping exists")
 
 	observeCmd := &cobra.Command{
 		Use:     "o",
@@ -61,6 +121,9 @@ func init() {
 		Short:   "observe entities within a geometry",
 		RunE:    runObserve,
 	}
+	observeCmd.Flags().StringVar(&filterBBox, "bbox", "", "area to observe: lon1,lat1,lon2,lat2 or two MGRS corners mgrs1,mgrs2")
+	observeCmd.Flags().StringVar(&observeWKT, "wkt", "", "geometry to observe as WKT, e.g. MULTIPOLYGON(...)")
+	observeCmd.Flags().StringVar(&observeFile, "geometry-file", "", "file with the geometry to observe as WKT or GeoJSON, - for stdin. Without an area the Berlin example is observed")
 
 	debugCmd := &cobra.Command{
 		Use:     "debug",
@@ -68,6 +131,7 @@ func init() {
 		Short:   "subscribe to all change events and print as JSON",
 		RunE:    runDebug,
 	}
+	debugCmd.Flags().DurationVar(&debugSince, "since", 0, "first print the pushes and expiries the engine received within this duration (e.g. 5m), then go live")
 
 	getCmd := &cobra.Command{
 		Use:   "get [entity-id]",
@@ -75,6 +139,9 @@ func init() {
 		Args:  cobra.ExactArgs(1),
 		RunE:  runGet,
 	}
+	getCmd.Flags().StringVarP(&getOutputFormat, "output", "o", "json", "output format: yaml, json")
+	getCmd.Flags().BoolVarP(&getWatch, "watch", "w", false, "keep running and print every change of the entity")
+	getCmd.Flags().StringVar(&symbology, "symbology", goclient.Symbology2525C, "symbol codes to show: 2525c as stored, or 2525d where a mapping exists")
 
 	putCmd := &cobra.Command{
 		Use:     "put [file or -]",
@@ -84,6 +151,25 @@ func init() {
 		Args:    cobra.ExactArgs(1),
 		RunE:    runPut,
 	}
+	putCmd.Flags().BoolVar(&putWait, "wait", false, "wait until all pushed entities are listed by the server")
+	putCmd.Flags().DurationVar(&putWaitTimeout, "timeout", 10*time.Second, "how long --wait waits before failing")
+	putCmd.Flags().StringVar(&putClassification, "classification", "", "mark the pushed entities, e.g. \"SECRET//REL TO DEU, FRA\"")
+
+	createCmd := &cobra.Command{
+		Use:   "create",
+		Short: "create an entity from flags and push it",
+		Long:  "create an entity from flags and push it. Use --dry-run to print the YAML instead, e.g. as a starting point for a file.",
+		Args:  cobra.NoArgs,
+		RunE:  runCreate,
+	}
+	addCreateFlags(createCmd)
+
+	diffCmd := &cobra.Command{
+		Use:   "diff [file or -]",
+		Short: "show what applying a JSON or YAML file would change on the server",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runDiff,
+	}
 
 	editCmd := &cobra.Command{
 		Use:   "edit [entity-id]",
@@ -107,45 +193,116 @@ func init() {
 		RunE:  runClear,
 	}
 
+	nearestCmd := &cobra.Command{
+		Use:   "nearest [lon,lat or MGRS]",
+		Short: "list the entities closest to a point or to the shape of an entity",
+		Args:  cobra.MaximumNArgs(1),
+		RunE:  runNearest,
+	}
+	nearestCmd.Flags().IntVar(&nearestK, "k", 10, "number of entities to return")
+	nearestCmd.Flags().StringVar(&nearestSIDC, "sidc", "", "only entities whose MIL-STD-2525C symbol matches this glob (e.g. \"SF*\")")
+	nearestCmd.Flags().StringVar(&nearestShapeOf, "shape-of", "", "measure distances to the shape of this entity, e.g. a line or area, instead of a point")
+	nearestCmd.Flags().IntSliceVar(&filterWith, "with", nil, "filter entities with these component field numbers")
+	nearestCmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "output format: table, yaml, json")
+
+	bearingCmd := &cobra.Command{
+		Use:   "bearing <fromID> <toID>",
+		Short: "print the great-circle bearing and range from one entity to another",
+		Args:  cobra.ExactArgs(2),
+		RunE:  runBearing,
+	}
+	bearingCmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "output format: table, json")
+
+	clustersCmd := &cobra.Command{
+		Use:   "clusters",
+		Short: "count entities in a grid of map cells, as a map shows them zoomed out",
+		Args:  cobra.NoArgs,
+		RunE:  runClusters,
+	}
+	clustersCmd.Flags().IntVar(&clustersZoom, "zoom", 4, "web map zoom level (0-20), cells are a quarter of a map tile wide")
+	clustersCmd.Flags().IntVar(&clustersMinCount, "min-count", 2, "smallest cell shown as a cluster, smaller cells list their entities")
+	clustersCmd.Flags().StringVar(&filterBBox, "bbox", "", "only entities in this bounding box: lon1,lat1,lon2,lat2 or two MGRS corners mgrs1,mgrs2")
+	clustersCmd.Flags().IntSliceVar(&filterWith, "with", nil, "filter entities with these component field numbers")
+	clustersCmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "output format: table, json")
+
+	tagCmd := &cobra.Command{
+		Use:   "tag",
+		Short: "change the label or controller of all entities matching a filter at once",
+		Long:  "change the label or controller of all entities matching a filter at once, e.g. ec tag --bbox ... --set-label-prefix RED- to mark a group during an exercise. Labels that already start with the prefix are left alone, configurations are never changed.",
+		Args:  cobra.NoArgs,
+		RunE:  runTag,
+	}
+	tagCmd.Flags().StringVar(&filterBBox, "bbox", "", "only entities in this bounding box: lon1,lat1,lon2,lat2 or two MGRS corners mgrs1,mgrs2")
+	tagCmd.Flags().IntSliceVar(&filterWith, "with", nil, "only entities with these component field numbers")
+	tagCmd.Flags().BoolVar(&tagAll, "all", false, "change all entities, without a filter")
+	tagCmd.Flags().StringVar(&tagLabelPrefix, "set-label-prefix", "", "put this in front of the labels, e.g. RED-")
+	tagCmd.Flags().StringVar(&tagController, "set-controller", "", "set the controller name")
+	tagCmd.Flags().BoolVar(&tagDryRun, "dry-run", false, "only list the entities that would change")
+
+	replayCmd := &cobra.Command{
+		Use:   "replay [file.jsonl]",
+		Short: "push recorded events with their original timing",
+		Long:  "push the events of a capture file with their original timing, scaled by --speed. Lifetimes are moved to the time of replay so the entities appear live.",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runReplay,
+	}
+	replayCmd.Flags().Float64Var(&replaySpeed, "speed", 1, "playback speed factor, e.g. 5 for 5x")
+	replayCmd.Flags().BoolVar(&replayLoop, "loop", false, "start over at the end of the capture")
+
+	recordCmd := &cobra.Command{
+		Use:   "record",
+		Short: "write watched events with their timing to a capture file for ec replay",
+		Long:  "write every watched event as one JSON line with the time since the start of the recording, until interrupted. The capture is flushed every second and can be replayed with ec replay.",
+		Args:  cobra.NoArgs,
+		RunE:  runRecord,
+	}
+	recordCmd.Flags().StringVarP(&recordOutput, "output", "o", "-", "capture file, - for stdout")
+	recordCmd.Flags().StringVar(&filterBBox, "bbox", "", "only record entities in this bounding box: lon1,lat1,lon2,lat2 or two MGRS corners mgrs1,mgrs2")
+	recordCmd.Flags().IntSliceVar(&filterWith, "with", nil, "only record entities with these component field numbers")
+
+	topCmd := &cobra.Command{
+		Use:   "top",
+		Short: "live dashboard of entity counts by controller, push rate, watchers and recent updates",
+		Args:  cobra.NoArgs,
+		RunE:  runTop,
+	}
+	topCmd.Flags().DurationVar(&topInterval, "interval", time.Second, "how often to poll the engine stats")
+	topCmd.Flags().IntVarP(&topRecent, "recent", "n", 20, "number of most recently updated entities to show")
+
 	ECCMD.AddCommand(lsCmd)
+	ECCMD.AddCommand(nearestCmd)
+	ECCMD.AddCommand(bearingCmd)
+	ECCMD.AddCommand(clustersCmd)
 	ECCMD.AddCommand(observeCmd)
 	ECCMD.AddCommand(debugCmd)
 	ECCMD.AddCommand(getCmd)
 	ECCMD.AddCommand(putCmd)
+	ECCMD.AddCommand(createCmd)
+	ECCMD.AddCommand(diffCmd)
 	ECCMD.AddCommand(editCmd)
 	ECCMD.AddCommand(rmCmd)
 	ECCMD.AddCommand(clearCmd)
+	ECCMD.AddCommand(tagCmd)
+	ECCMD.AddCommand(replayCmd)
+	ECCMD.AddCommand(recordCmd)
+	ECCMD.AddCommand(topCmd)
 
 	cmd.CMD.AddCommand(ECCMD)
 }
 
+// berlin is the area ec o observes without --bbox, --wkt or --geometry-file
+var berlin = orb.Bound{Min: orb.Point{13.08, 52.34}, Max: orb.Point{13.76, 52.68}}
+
 func runObserve(cmd *cobra.Command, args []string) error {
 	world := pb.NewWorldServiceClient(conn)
 
+	geoFilter, err := observeFilter()
+	if err != nil {
+		return err
+	}
+
 	stream, err := goclient.WatchEntitiesWithRetry(cmd.Context(), world, &pb.ListEntitiesRequest{
-		Filter: &pb.EntityFilter{
-			Geo: &pb.GeoFilter{
-				Geo: &pb.GeoFilter_Geometry{
-					Geometry: &pb.Geometry{
-						Planar: &pb.PlanarGeometry{
-							Plane: &pb.PlanarGeometry_Polygon{
-								Polygon: &pb.PlanarPolygon{
-									Outer: &pb.PlanarRing{
-										Points: []*pb.PlanarPoint{
-											{Longitude: 13.08, Latitude: 52.34},
-											{Longitude: 13.76, Latitude: 52.34},
-											{Longitude: 13.76, Latitude: 52.68},
-											{Longitude: 13.08, Latitude: 52.68},
-											{Longitude: 13.08, Latitude: 52.34},
-										},
-									},
-								},
-							},
-						},
-					},
-				},
-			},
-		},
+		Filter: &pb.EntityFilter{Geo: geoFilter},
 	})
 	if err != nil {
 		return fmt.Errorf("failed to list entities: %w", err)
@@ -159,8 +316,104 @@ func runObserve(cmd *cobra.Command, args []string) error {
 			}
 			panic(err)
 		}
-		printEntitiesTable([]*pb.Entity{m.Entity})
+		printEntitiesTable([]*pb.Entity{m.Entity}, nil, nil)
+	}
+}
+
+// observeFilter builds the area of ec o from --bbox, --wkt or --geometry-file,
+// or the Berlin example if none is given
+func observeFilter() (*pb.GeoFilter, error) {
+	if len(slices.DeleteFunc([]string{filterBBox, observeWKT, observeFile}, func(f string) bool { return f == "" })) > 1 {
+		return nil, fmt.Errorf("only one of --bbox, --wkt and --geometry-file can be given")
+	}
+
+	switch {
+	case filterBBox != "":
+		bound, err := parseBBox(filterBBox)
+		if err != nil {
+			return nil, err
+		}
+		return boundFilter(bound), nil
+	case observeWKT != "":
+		geometry, err := goclient.WKTGeometry(observeWKT)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --wkt: %w", err)
+		}
+		return &pb.GeoFilter{Geo: &pb.GeoFilter_Geometry{Geometry: geometry}}, nil
+	case observeFile != "":
+		g, err := readGeometry(observeFile)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --geometry-file: %w", err)
+		}
+		return geometryFilter(g)
+	}
+
+	fmt.Fprintln(os.Stderr, "observing the Berlin example area, use --bbox, --wkt or --geometry-file to choose one")
+	return boundFilter(berlin), nil
+}
+
+// geometryFilter creates a geo filter from the WKB of g, which unlike the
+// planar geometry can hold multi-polygons and collections
+func geometryFilter(g orb.Geometry) (*pb.GeoFilter, error) {
+	b, err := wkb.Marshal(g)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.GeoFilter{Geo: &pb.GeoFilter_Geometry{Geometry: &pb.Geometry{Wkb: b}}}, nil
+}
+
+// readGeometry reads WKT or GeoJSON from a file, or stdin if path is "-".
+// The geometries of a GeoJSON feature collection are observed together.
+func readGeometry(path string) (orb.Geometry, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || data[0] != '{' {
+		return wkt.Unmarshal(string(data))
+	}
+
+	var probe struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, err
+	}
+	switch probe.Type {
+	case "FeatureCollection":
+		fc, err := geojson.UnmarshalFeatureCollection(data)
+		if err != nil {
+			return nil, err
+		}
+		var collection orb.Collection
+		for _, f := range fc.Features {
+			if f.Geometry != nil {
+				collection = append(collection, f.Geometry)
+			}
+		}
+		if len(collection) == 1 {
+			return collection[0], nil
+		}
+		return collection, nil
+	case "Feature":
+		f, err := geojson.UnmarshalFeature(data)
+		if err != nil {
+			return nil, err
+		}
+		return f.Geometry, nil
+	}
+	g, err := geojson.UnmarshalGeometry(data)
+	if err != nil {
+		return nil, err
 	}
+	return g.Geometry(), nil
 }
 
 func intSliceToUint32(ints []int) []uint32 {
@@ -334,66 +587,238 @@ func runLS(cmd *cobra.Command, args []string) error {
 	}
 
 	// Bounding box geometry
+	if len(slices.DeleteFunc([]string{filterBBox, filterNear, filterWKT}, func(f string) bool { return f == "" })) > 1 {
+		return fmt.Errorf("only one of --bbox, --near and --wkt can be given")
+	}
+
 	if filterBBox != "" {
-		var lon1, lat1, lon2, lat2 float64
-		_, err := fmt.Sscanf(filterBBox, "%f,%f,%f,%f", &lon1, &lat1, &lon2, &lat2)
+		bound, err := parseBBox(filterBBox)
 		if err != nil {
-			return fmt.Errorf("invalid bbox format, expected 'lon1,lat1,lon2,lat2': %w", err)
-		}
-
-		// Create a planar polygon from the bounding box
-		filter.Geo = &pb.GeoFilter{
-			Geo: &pb.GeoFilter_Geometry{
-				Geometry: &pb.Geometry{
-					Planar: &pb.PlanarGeometry{
-						Plane: &pb.PlanarGeometry_Polygon{
-							Polygon: &pb.PlanarPolygon{
-								Outer: &pb.PlanarRing{
-									Points: []*pb.PlanarPoint{
-										{Longitude: lon1, Latitude: lat1},
-										{Longitude: lon2, Latitude: lat1},
-										{Longitude: lon2, Latitude: lat2},
-										{Longitude: lon1, Latitude: lat2},
-										{Longitude: lon1, Latitude: lat1},
-									},
-								},
-							},
-						},
-					},
-				},
-			},
+			return err
+		}
+		filter.Geo = boundFilter(bound)
+	}
+
+	if filterWKT != "" {
+		geometry, err := goclient.WKTGeometry(filterWKT)
+		if err != nil {
+			return fmt.Errorf("invalid --wkt: %w", err)
+		}
+		filter.Geo = &pb.GeoFilter{Geo: &pb.GeoFilter_Geometry{Geometry: geometry}}
+	}
+
+	var near orb.Point
+	if filterNear != "" {
+		lon, lat, err := parsePoint(filterNear)
+		if err != nil {
+			return fmt.Errorf("invalid --near: %w", err)
 		}
+		near = orb.Point{lon, lat}
+		// the server filters by bounding box, the exact radius is applied below
+		filter.Geo = boundFilter(geo.NewBoundAroundPoint(near, filterRadius))
 	}
 
 	req := &pb.ListEntitiesRequest{Filter: filter}
 
-	resp, err := client.ListEntities(context.Background(), req)
+	ctx := context.Background()
+	if filterParent != "" {
+		ctx = goclient.WithParent(ctx, filterParent)
+	}
+	if deadReckoning > 0 {
+		ctx = goclient.WithDeadReckoning(ctx, deadReckoning)
+	}
+	if geoUncertainty > 0 {
+		ctx = goclient.WithGeoUncertainty(ctx, geoUncertainty)
+	}
+	if filterClearance != "" {
+		ctx = goclient.WithClearance(ctx, filterClearance)
+	}
+	if minConfidence > 0 {
+		ctx = goclient.WithMinConfidence(ctx, minConfidence)
+	}
+	if minSeen > 0 {
+		ctx = goclient.WithMinSeen(ctx, minSeen)
+	}
+	ctx = withSymbology(ctx)
+	if filterAltitude != "" {
+		min, max, err := parseAltitudeBand(filterAltitude)
+		if err != nil {
+			return fmt.Errorf("invalid --altitude: %w", err)
+		}
+		ctx = goclient.WithAltitudeBand(ctx, min, max)
+	}
+
+	resp, err := client.ListEntities(ctx, req)
 	if err != nil {
 		return fmt.Errorf("failed to list entities: %w", err)
 	}
 
+	if filterNear != "" {
+		resp.Entities = slices.DeleteFunc(resp.Entities, func(e *pb.Entity) bool {
+			if e.Geo == nil {
+				return true
+			}
+			return geo.Distance(near, orb.Point{e.Geo.Longitude, e.Geo.Latitude}) > filterRadius+geoUncertainty*goclient.UncertaintyRadius(e)
+		})
+	}
+
+	var meta map[string]goclient.EntityMeta
+	if showSeen || sortBy == "confidence" || sortBy == "seen" {
+		var metaResp goclient.EntityMetaResponse
+		if err := conn.GetJSON(ctx, "/entities/meta", nil, &metaResp); err != nil {
+			return fmt.Errorf("failed to get entity meta: %w", err)
+		}
+		meta = metaResp.Entities
+	}
+	switch sortBy {
+	case "id":
+	case "confidence":
+		confidence := func(e *pb.Entity) float64 {
+			if c := meta[e.Id].Confidence; c != nil {
+				return *c
+			}
+			return 1
+		}
+		slices.SortStableFunc(resp.Entities, func(a, b *pb.Entity) int { return cmp.Compare(confidence(b), confidence(a)) })
+	case "seen":
+		slices.SortStableFunc(resp.Entities, func(a, b *pb.Entity) int { return cmp.Compare(meta[b.Id].SeenCount, meta[a.Id].SeenCount) })
+	default:
+		return fmt.Errorf("unknown sort: %s (use: id, confidence, seen)", sortBy)
+	}
+	if !showSeen {
+		meta = nil
+	}
+
 	// Output based on format
 	switch outputFormat {
 	case "yaml":
 		return printEntitiesYAML(resp.Entities)
 	case "json":
 		return printEntitiesJSON(resp.Entities)
+	case "pb":
+		return writeEntitiesPB(os.Stdout, resp.Entities)
 	case "table":
-		printEntitiesTable(resp.Entities)
+		var agl map[string]float64
+		if terrainURL != "" {
+			src, err := terrain.NewHTTPSource(terrainURL)
+			if err != nil {
+				return err
+			}
+			if agl, err = terrain.AboveGround(ctx, src, resp.Entities); err != nil {
+				return fmt.Errorf("failed to look up terrain: %w", err)
+			}
+		}
+		printEntitiesTable(resp.Entities, meta, agl)
 		return nil
 	default:
-		return fmt.Errorf("unknown output format: %s (use: table, yaml, json)", outputFormat)
+		return fmt.Errorf("unknown output format: %s (use: table, yaml, json, pb)", outputFormat)
+	}
+}
+
+// parseAltitudeBand reads "min:max" where each side is meters, a flight level
+// like FL240, or empty for an open bound
+func parseAltitudeBand(s string) (min, max float64, err error) {
+	lo, hi, ok := strings.Cut(s, ":")
+	if !ok {
+		return 0, 0, fmt.Errorf("expected min:max")
+	}
+	min, max = math.Inf(-1), math.Inf(1)
+	if lo != "" {
+		if min, err = parseAltitude(lo); err != nil {
+			return 0, 0, err
+		}
+	}
+	if hi != "" {
+		if max, err = parseAltitude(hi); err != nil {
+			return 0, 0, err
+		}
+	}
+	if min > max {
+		return 0, 0, fmt.Errorf("min is above max")
+	}
+	return min, max, nil
+}
+
+// parseAltitude reads meters or a flight level (hundreds of feet) like FL100
+func parseAltitude(s string) (float64, error) {
+	if fl, ok := strings.CutPrefix(strings.ToUpper(s), "FL"); ok {
+		level, err := strconv.Atoi(fl)
+		if err != nil {
+			return 0, fmt.Errorf("invalid flight level %q", s)
+		}
+		return float64(level) * 100 * 0.3048, nil
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+// parseBBox reads "lon1,lat1,lon2,lat2" or two MGRS corners "mgrs1,mgrs2"
+func parseBBox(s string) (orb.Bound, error) {
+	var lon1, lat1, lon2, lat2 float64
+	if _, err := fmt.Sscanf(s, "%f,%f,%f,%f", &lon1, &lat1, &lon2, &lat2); err == nil {
+		return orb.MultiPoint{{lon1, lat1}, {lon2, lat2}}.Bound(), nil
+	}
+
+	corners := strings.Split(s, ",")
+	if len(corners) != 2 {
+		return orb.Bound{}, fmt.Errorf("invalid bbox format, expected 'lon1,lat1,lon2,lat2' or 'mgrs1,mgrs2'")
 	}
+	var bound orb.MultiPoint
+	for _, c := range corners {
+		lat, lon, err := fromMGRS(c)
+		if err != nil {
+			return orb.Bound{}, fmt.Errorf("invalid bbox corner: %w", err)
+		}
+		bound = append(bound, orb.Point{lon, lat})
+	}
+	return bound.Bound(), nil
 }
 
-func printEntitiesTable(entities []*pb.Entity) {
+// boundFilter creates a geo filter from a planar polygon of the bounding box
+func boundFilter(b orb.Bound) *pb.GeoFilter {
+	return &pb.GeoFilter{
+		Geo: &pb.GeoFilter_Geometry{
+			Geometry: &pb.Geometry{
+				Planar: &pb.PlanarGeometry{
+					Plane: &pb.PlanarGeometry_Polygon{
+						Polygon: &pb.PlanarPolygon{
+							Outer: &pb.PlanarRing{
+								Points: []*pb.PlanarPoint{
+									{Longitude: b.Min[0], Latitude: b.Min[1]},
+									{Longitude: b.Max[0], Latitude: b.Min[1]},
+									{Longitude: b.Max[0], Latitude: b.Max[1]},
+									{Longitude: b.Min[0], Latitude: b.Max[1]},
+									{Longitude: b.Min[0], Latitude: b.Min[1]},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// printEntitiesTable prints one row per entity, with a "seen" column if meta is not nil
+// and altitude columns above sea level and ground if agl is not nil
+func printEntitiesTable(entities []*pb.Entity, meta map[string]goclient.EntityMeta, agl map[string]float64) {
 	if len(entities) == 0 {
 		fmt.Println("No entities found")
 		return
 	}
 
-	tbl := table.New("ID", "symbol", "Latitude", "Longitude")
+	columns := []interface{}{"ID", "symbol", "controller", "Latitude", "Longitude", "expires"}
+	if coordsFormat == "mgrs" {
+		columns = []interface{}{"ID", "symbol", "controller", "MGRS", "expires"}
+	}
+	if agl != nil {
+		columns = append(columns, "MSL", "AGL")
+	}
+	if meta != nil {
+		columns = append(columns, "seen")
+	}
+	tbl := newTable(columns...)
 
+	now := time.Now()
 	for _, entity := range entities {
 		if entity == nil {
 			continue
@@ -403,18 +828,104 @@ func printEntitiesTable(entities []*pb.Entity) {
 		if entity.Geo != nil {
 			lat = fmt.Sprintf("%.6f", entity.Geo.Latitude)
 			lon = fmt.Sprintf("%.6f", entity.Geo.Longitude)
+		} else if length, ok := goclient.PathLength(entity); ok {
+			lat, lon = "path", formatPathLength(length)
 		}
 		symbol := ""
 		if entity.Symbol != nil {
 			symbol = entity.Symbol.MilStd2525C
 		}
+		controller := ""
+		if entity.Controller != nil {
+			controller = entity.Controller.Name
+		}
 
-		tbl.AddRow(entity.Id, symbol, lat, lon)
+		row := []interface{}{entity.Id, symbol, controller, lat, lon, formatExpiry(entity, now)}
+		if coordsFormat == "mgrs" {
+			ref := "N/A"
+			if entity.Geo != nil {
+				if r, err := toMGRS(entity.Geo.Latitude, entity.Geo.Longitude, 5); err == nil {
+					ref = r
+				}
+			} else if length, ok := goclient.PathLength(entity); ok {
+				ref = "path " + formatPathLength(length)
+			}
+			row = []interface{}{entity.Id, symbol, controller, ref, formatExpiry(entity, now)}
+		}
+		if agl != nil {
+			msl, above := "N/A", "N/A"
+			if entity.Geo != nil && entity.Geo.Altitude != nil {
+				msl = fmt.Sprintf("%.0fm", *entity.Geo.Altitude)
+			}
+			if h, ok := agl[entity.Id]; ok {
+				above = fmt.Sprintf("%.0fm", h)
+			}
+			row = append(row, msl, above)
+		}
+		if meta != nil {
+			row = append(row, formatSeen(meta[entity.Id]))
+		}
+
+		tbl.AddRow(row...)
 	}
 
 	tbl.Print()
 }
 
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// newTable returns a table with a bold header that aligns colored cells
+func newTable(columns ...interface{}) table.Table {
+	return table.New(columns...).
+		WithHeaderFormatter(color.New(color.Bold).SprintfFunc()).
+		WithWidthFunc(func(s string) int {
+			return utf8.RuneCountInString(ansiEscape.ReplaceAllString(s, ""))
+		})
+}
+
+// formatPathLength renders the great-circle length of a path in km or m
+func formatPathLength(meters float64) string {
+	if meters >= 10_000 {
+		return fmt.Sprintf("%.0f km", meters/1000)
+	}
+	return fmt.Sprintf("%.0f m", meters)
+}
+
+// formatExpiry renders the time until the entity expires, red when it is about to
+func formatExpiry(entity *pb.Entity, now time.Time) string {
+	if entity.Lifetime == nil || !entity.Lifetime.Until.IsValid() {
+		return "-"
+	}
+
+	left := entity.Lifetime.Until.AsTime().Sub(now)
+	switch {
+	case left <= 0:
+		return color.RedString("expired")
+	case left < 10*time.Second:
+		return color.RedString("%s", left.Round(100*time.Millisecond))
+	case left < time.Minute:
+		return color.YellowString("%s", left.Round(time.Second))
+	default:
+		return left.Round(time.Second).String()
+	}
+}
+
+// formatSeen renders the time since the last update, yellow once it is older than a minute
+func formatSeen(meta goclient.EntityMeta) string {
+	if meta.LastSeen.IsZero() {
+		return "-"
+	}
+	age := time.Duration(meta.Age * float64(time.Second)).Round(time.Second)
+	seen := fmt.Sprintf("%s ago", age)
+	if age >= time.Minute {
+		seen = color.YellowString("%s", seen)
+	}
+	if meta.SeenCount > 0 {
+		seen += fmt.Sprintf(" (%d×)", meta.SeenCount)
+	}
+	return seen
+}
+
 func printEntitiesYAML(entities []*pb.Entity) error {
 	for i, entity := range entities {
 		yamlBytes, err := protoToYAML(entity)
@@ -429,6 +940,43 @@ func printEntitiesYAML(entities []*pb.Entity) error {
 	return nil
 }
 
+// writeEntitiesPB writes entities as a stream of varint length-prefixed protobuf messages
+func writeEntitiesPB(w io.Writer, entities []*pb.Entity) error {
+	bw := bufio.NewWriter(w)
+	for _, entity := range entities {
+		if _, err := protodelim.MarshalTo(bw, entity); err != nil {
+			return fmt.Errorf("failed to marshal entity %s: %w", entity.Id, err)
+		}
+	}
+	return bw.Flush()
+}
+
+// readEntitiesPB reads a stream written by writeEntitiesPB. It fails on anything
+// else, including messages with fields unknown to Entity, so text input is not
+// mistaken for protobuf.
+func readEntitiesPB(input []byte) ([]*pb.Entity, error) {
+	var entities []*pb.Entity
+	r := bufio.NewReader(bytes.NewReader(input))
+	for {
+		entity := &pb.Entity{}
+		err := protodelim.UnmarshalFrom(r, entity)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(entity.ProtoReflect().GetUnknown()) > 0 {
+			return nil, fmt.Errorf("unknown fields in entity")
+		}
+		entities = append(entities, entity)
+	}
+	if len(entities) == 0 {
+		return nil, fmt.Errorf("no entities")
+	}
+	return entities, nil
+}
+
 func printEntitiesJSON(entities []*pb.Entity) error {
 	marshaler := protojson.MarshalOptions{
 		UseProtoNames:   true,
@@ -457,8 +1005,13 @@ func printEntitiesJSON(entities []*pb.Entity) error {
 func runDebug(cmd *cobra.Command, args []string) error {
 	world := pb.NewWorldServiceClient(conn)
 
-	// Subscribe to all change events (no geometry filter)
-	stream, err := goclient.WatchEntitiesWithRetry(cmd.Context(), world, &pb.ListEntitiesRequest{})
+	// Subscribe to all change events (no geometry filter). After the history only
+	// changes are of interest, not the current state of everything.
+	ctx := cmd.Context()
+	if debugSince > 0 {
+		ctx = goclient.WithLiveOnly(ctx)
+	}
+	stream, err := goclient.WatchEntitiesWithRetry(ctx, world, &pb.ListEntitiesRequest{})
 	if err != nil {
 		return fmt.Errorf("failed to watch entities: %w", err)
 	}
@@ -470,6 +1023,13 @@ func runDebug(cmd *cobra.Command, args []string) error {
 		Indent:          "  ",
 	}
 
+	// the watch is already open, so nothing between the history and live events is lost
+	if debugSince > 0 {
+		if err := printHistory(cmd.Context(), marshaler, debugSince); err != nil {
+			return err
+		}
+	}
+
 	for {
 		event, err := stream.Recv()
 		if err != nil {
@@ -489,16 +1049,115 @@ func runDebug(cmd *cobra.Command, args []string) error {
 	}
 }
 
+// printHistory prints the pushes and expiries of the last since as events, framed by markers on stderr
+func printHistory(ctx context.Context, marshaler protojson.MarshalOptions, since time.Duration) error {
+	var history goclient.HistoryResponse
+	query := url.Values{"since": {since.String()}}
+	if err := conn.GetJSON(ctx, "/events", query, &history); err != nil {
+		return fmt.Errorf("failed to get history: %w", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "--- history: %d events since %s ---\n", len(history.Events), time.Now().Add(-since).Format(time.TimeOnly))
+	for _, ev := range history.Events {
+		entity := &pb.Entity{}
+		if err := protojson.Unmarshal(ev.Entity, entity); err != nil {
+			return fmt.Errorf("failed to decode history event: %w", err)
+		}
+		change := pb.EntityChange_EntityChangeUpdated
+		if ev.Expired {
+			change = pb.EntityChange_EntityChangeExpired
+		}
+		jsonBytes, err := marshaler.Marshal(&pb.EntityChangeEvent{Entity: entity, T: change})
+		if err != nil {
+			return fmt.Errorf("failed to marshal event: %w", err)
+		}
+		fmt.Println(string(jsonBytes))
+	}
+	fmt.Fprintln(os.Stderr, "--- live ---")
+	return nil
+}
+
 func runGet(cmd *cobra.Command, args []string) error {
 	client := pb.NewWorldServiceClient(conn)
 	entityID := args[0]
 
-	resp, err := client.GetEntity(context.Background(), &pb.GetEntityRequest{
+	if getOutputFormat != "json" && getOutputFormat != "yaml" {
+		return fmt.Errorf("unknown output format: %s (use: yaml, json)", getOutputFormat)
+	}
+
+	if getWatch {
+		return watchEntity(cmd, client, entityID)
+	}
+
+	var header metadata.MD
+	resp, err := client.GetEntity(withSymbology(context.Background()), &pb.GetEntityRequest{
 		Id: entityID,
-	})
+	}, grpc.Header(&header))
 	if err != nil {
 		return fmt.Errorf("failed to get entity: %w", err)
 	}
+	if aliases := header.Get(goclient.HeaderAlias); len(aliases) > 0 {
+		fmt.Fprintf(os.Stderr, "aliases: %s\n", strings.Join(aliases, ", "))
+	}
+
+	return printEntity(resp.Entity)
+}
+
+// withSymbology asks the engine for the symbol codes of --symbology, the engine
+// rejects unknown ones
+func withSymbology(ctx context.Context) context.Context {
+	if symbology == "" || strings.EqualFold(symbology, goclient.Symbology2525C) {
+		return ctx
+	}
+	return goclient.WithSymbology(ctx, symbology)
+}
+
+// watchEntity prints every new state of a single entity until interrupted
+func watchEntity(cmd *cobra.Command, client pb.WorldServiceClient, entityID string) error {
+	stream, err := goclient.WatchEntitiesWithRetry(withSymbology(cmd.Context()), client, &pb.ListEntitiesRequest{
+		Filter: &pb.EntityFilter{Id: &entityID},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to watch entity: %w", err)
+	}
+
+	first := true
+	for {
+		event, err := stream.Recv()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("stream error: %w", err)
+		}
+		if event.Entity == nil || event.Entity.Id != entityID {
+			continue
+		}
+
+		if event.T == pb.EntityChange_EntityChangeExpired {
+			fmt.Fprintf(os.Stderr, "entity %s expired\n", entityID)
+			continue
+		}
+
+		if getOutputFormat == "yaml" && !first {
+			fmt.Println("---")
+		}
+		first = false
+		if err := printEntity(event.Entity); err != nil {
+			return err
+		}
+	}
+}
+
+func printEntity(entity *pb.Entity) error {
+	if getOutputFormat == "yaml" {
+		yamlBytes, err := protoToYAML(entity)
+		if err != nil {
+			return fmt.Errorf("failed to marshal entity: %w", err)
+		}
+		fmt.Print(string(yamlBytes))
+		return nil
+	}
 
 	marshaler := protojson.MarshalOptions{
 		UseProtoNames:   true,
@@ -506,7 +1165,7 @@ func runGet(cmd *cobra.Command, args []string) error {
 		Indent:          "  ",
 	}
 
-	jsonBytes, err := marshaler.Marshal(resp.Entity)
+	jsonBytes, err := marshaler.Marshal(entity)
 	if err != nil {
 		return fmt.Errorf("failed to marshal entity: %w", err)
 	}
@@ -517,8 +1176,44 @@ func runGet(cmd *cobra.Command, args []string) error {
 
 func runPut(cmd *cobra.Command, args []string) error {
 	client := pb.NewWorldServiceClient(conn)
-	path := args[0]
 
+	entities, err := readEntities(args[0])
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	if putClassification != "" {
+		ctx = goclient.WithClassification(ctx, putClassification)
+	}
+
+	// Push entities
+	resp, err := client.Push(ctx, &pb.EntityChangeRequest{
+		Changes: entities,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to push entities: %w", err)
+	}
+
+	if resp.Accepted {
+		if len(entities) == 1 {
+			fmt.Printf("Entity '%s' pushed successfully\n", entities[0].Id)
+		} else {
+			fmt.Printf("%d entities pushed successfully\n", len(entities))
+		}
+	} else {
+		fmt.Println("Entity push was not accepted")
+	}
+
+	if putWait {
+		return waitForEntities(cmd.Context(), client, entities, putWaitTimeout)
+	}
+
+	return nil
+}
+
+// readEntities reads one or more entities as JSON or YAML from a file, or stdin if path is "-"
+func readEntities(path string) ([]*pb.Entity, error) {
 	// Read from file or stdin
 	var inputBytes []byte
 	var err error
@@ -526,17 +1221,15 @@ func runPut(cmd *cobra.Command, args []string) error {
 	if path == "-" {
 		inputBytes, err = io.ReadAll(os.Stdin)
 		if err != nil {
-			return fmt.Errorf("failed to read from stdin: %w", err)
+			return nil, fmt.Errorf("failed to read from stdin: %w", err)
 		}
 	} else {
 		inputBytes, err = os.ReadFile(path)
 		if err != nil {
-			return fmt.Errorf("failed to read file: %w", err)
+			return nil, fmt.Errorf("failed to read file: %w", err)
 		}
 	}
 
-	var entities []*pb.Entity
-
 	// Try JSON first (single entity)
 	entity := &pb.Entity{}
 	unmarshaler := protojson.UnmarshalOptions{
@@ -544,45 +1237,70 @@ func runPut(cmd *cobra.Command, args []string) error {
 	}
 
 	err = unmarshaler.Unmarshal(inputBytes, entity)
-	if err != nil {
-		// JSON failed, try YAML (single or multiple documents)
-		multiEntities, multiErr := yamlToProtoMulti(inputBytes)
-		if multiErr != nil {
-			// Multi-document YAML failed, try single document
-			if yamlErr := yamlToProto(inputBytes, entity); yamlErr != nil {
-				// All formats failed, return errors
-				return fmt.Errorf("failed to unmarshal as JSON: %w\nfailed to unmarshal as YAML: %v", err, yamlErr)
-			}
-			// Single YAML succeeded
-			entities = []*pb.Entity{entity}
-		} else {
-			// Multi-document YAML succeeded
-			entities = multiEntities
-		}
-	} else {
-		// JSON succeeded
-		entities = []*pb.Entity{entity}
+	if err == nil {
+		return []*pb.Entity{entity}, nil
 	}
 
-	// Push entities
-	resp, err := client.Push(context.Background(), &pb.EntityChangeRequest{
-		Changes: entities,
-	})
-	if err != nil {
-		return fmt.Errorf("failed to push entities: %w", err)
+	// Length-delimited protobuf, as written by ec ls -o pb
+	if entities, pbErr := readEntitiesPB(inputBytes); pbErr == nil {
+		return entities, nil
 	}
 
-	if resp.Accepted {
-		if len(entities) == 1 {
-			fmt.Printf("Entity '%s' pushed successfully\n", entities[0].Id)
-		} else {
-			fmt.Printf("%d entities pushed successfully\n", len(entities))
-		}
-	} else {
-		fmt.Println("Entity push was not accepted")
+	// JSON failed, try YAML (single or multiple documents)
+	multiEntities, multiErr := yamlToProtoMulti(inputBytes)
+	if multiErr == nil {
+		return multiEntities, nil
 	}
 
-	return nil
+	// Multi-document YAML failed, try single document
+	if yamlErr := yamlToProto(inputBytes, entity); yamlErr != nil {
+		// All formats failed, return errors
+		return nil, fmt.Errorf("failed to unmarshal as JSON: %w\nfailed to unmarshal as YAML: %v", err, yamlErr)
+	}
+	return []*pb.Entity{entity}, nil
+}
+
+// waitForEntities polls ListEntities until all entities are listed, which means
+// they passed the bus, filters and read policy.
+func waitForEntities(ctx context.Context, client pb.WorldServiceClient, entities []*pb.Entity, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	pending := make(map[string]bool, len(entities))
+	for _, e := range entities {
+		pending[e.Id] = true
+	}
+
+	ticker := time.NewTicker(200 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		filter := &pb.EntityFilter{}
+		for id := range pending {
+			filter.Or = append(filter.Or, &pb.EntityFilter{Id: &id})
+		}
+
+		resp, err := client.ListEntities(ctx, &pb.ListEntitiesRequest{Filter: filter})
+		if err == nil {
+			for _, e := range resp.Entities {
+				delete(pending, e.Id)
+			}
+			if len(pending) == 0 {
+				return nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			ids := make([]string, 0, len(pending))
+			for id := range pending {
+				ids = append(ids, id)
+			}
+			sort.Strings(ids)
+			return fmt.Errorf("timed out after %s waiting for %s", timeout, strings.Join(ids, ", "))
+		case <-ticker.C:
+		}
+	}
 }
 
 func runEdit(cmd *cobra.Command, args []string) error {
@@ -590,9 +1308,10 @@ func runEdit(cmd *cobra.Command, args []string) error {
 	entityID := args[0]
 
 	// Get the entity
+	var header metadata.MD
 	resp, err := client.GetEntity(context.Background(), &pb.GetEntityRequest{
 		Id: entityID,
-	})
+	}, grpc.Header(&header))
 	if err != nil {
 		return fmt.Errorf("failed to get entity: %w", err)
 	}
@@ -662,10 +1381,24 @@ func runEdit(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to unmarshal edited entity YAML: %w", err)
 	}
 
-	// Push updated entity
-	pushResp, err := client.Push(context.Background(), &pb.EntityChangeRequest{
+	// Push updated entity, unless someone else changed it while editing
+	pushCtx := context.Background()
+	if version := header.Get(goclient.HeaderVersion); len(version) > 0 && editedEntity.Id == resp.Entity.Id {
+		if v, err := strconv.ParseUint(version[0], 10, 64); err == nil {
+			pushCtx = goclient.WithIfVersion(pushCtx, editedEntity.Id, v)
+		}
+	}
+	pushResp, err := client.Push(pushCtx, &pb.EntityChangeRequest{
 		Changes: []*pb.Entity{editedEntity},
 	})
+	if status.Code(err) == codes.Aborted {
+		fmt.Fprintf(os.Stderr, "Entity '%s' was changed by someone else while you were editing it\n", editedEntity.Id)
+		fmt.Fprintf(os.Stderr, "Your edit is saved at: %s\n", tmpPath)
+		if !confirm("Edit the current version instead?") {
+			return fmt.Errorf("entity not updated: %w", err)
+		}
+		return runEdit(cmd, args)
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		fmt.Fprintf(os.Stderr, "Edited file saved at: %s\n", tmpPath)
@@ -684,6 +1417,15 @@ func runEdit(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// confirm asks a yes/no question on the terminal, defaulting to no
+func confirm(question string) bool {
+	fmt.Fprintf(os.Stderr, "%s [y/N] ", question)
+	var answer string
+	fmt.Scanln(&answer)
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
 func runRM(cmd *cobra.Command, args []string) error {
 	client := pb.NewWorldServiceClient(conn)
 	entityID := args[0]
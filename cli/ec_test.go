@@ -0,0 +1,38 @@
+package cli
+
+import (
+	"bytes"
+	"testing"
+
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestEntitiesPBRoundTrip(t *testing.T) {
+	label := "alpha"
+	entities := []*pb.Entity{
+		{Id: "a", Label: &label, Geo: &pb.GeoSpatialComponent{Longitude: 10.123456789, Latitude: 50}},
+		{Id: "b"},
+	}
+
+	var buf bytes.Buffer
+	if err := writeEntitiesPB(&buf, entities); err != nil {
+		t.Fatal(err)
+	}
+	got, err := readEntitiesPB(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(entities) {
+		t.Fatalf("expected %d entities, got %d", len(entities), len(got))
+	}
+	for i := range entities {
+		if !proto.Equal(got[i], entities[i]) {
+			t.Errorf("entity %d: expected %v, got %v", i, entities[i], got[i])
+		}
+	}
+
+	if _, err := readEntitiesPB([]byte("id: a\ngeo:\n  latitude: 50\n  longitude: 10\n")); err == nil {
+		t.Error("expected YAML not to be read as protobuf")
+	}
+}
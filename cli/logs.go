@@ -0,0 +1,105 @@
+package cli
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/projectqai/hydra/cmd"
+	"github.com/projectqai/hydra/logging"
+	"github.com/spf13/cobra"
+)
+
+var (
+	logsFollow bool
+	logsLines  int
+)
+
+func init() {
+	logsCmd := &cobra.Command{
+		Use:               "logs [module...]",
+		Short:             "show the recent log lines of the engine, e.g. of the ais builtin",
+		Long:              "show the log lines the engine keeps in memory, optionally only those of some builtins. If the engine serves its admin endpoints on --admin-listen, pass that address as --server.",
+		PersistentPreRunE: connect,
+		RunE:              runLogs,
+	}
+	AddConnectionFlags(logsCmd)
+	logsCmd.Flags().BoolVarP(&logsFollow, "follow", "f", false, "keep printing new lines until interrupted")
+	logsCmd.Flags().IntVarP(&logsLines, "lines", "n", 100, "number of recent lines to show, -1 for all")
+
+	cmd.CMD.AddCommand(logsCmd)
+}
+
+func runLogs(cmd *cobra.Command, args []string) error {
+	query := url.Values{"module": args}
+	if logsLines >= 0 {
+		query.Set("n", strconv.Itoa(logsLines))
+	}
+	if logsFollow {
+		query.Set("follow", "true")
+	}
+
+	req, err := http.NewRequestWithContext(cmd.Context(), http.MethodGet, conn.URL("/logs", query), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := conn.HTTPClient().Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to get logs: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to get logs: %s", resp.Status)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var line logging.Line
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			return fmt.Errorf("invalid log line: %w", err)
+		}
+		fmt.Println(formatLogLine(line))
+	}
+	if cmd.Context().Err() != nil {
+		return nil
+	}
+	return scanner.Err()
+}
+
+// formatLogLine prints a line like the engine does, with attributes sorted by key
+func formatLogLine(line logging.Line) string {
+	var b strings.Builder
+	b.WriteString(line.Time.Local().Format(time.Kitchen))
+	b.WriteString(" ")
+	switch line.Level {
+	case "ERROR":
+		b.WriteString(color.RedString("ERR"))
+	case "WARN":
+		b.WriteString(color.YellowString("WRN"))
+	case "INFO":
+		b.WriteString(color.GreenString("INF"))
+	default:
+		b.WriteString(line.Level)
+	}
+	if line.Module != "" {
+		b.WriteString(" [" + line.Module + "]")
+	}
+	b.WriteString(" " + line.Message)
+	keys := make([]string, 0, len(line.Attrs))
+	for k := range line.Attrs {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&b, " %s=%s", color.New(color.Faint).Sprint(k), line.Attrs[k])
+	}
+	return b.String()
+}
@@ -3,6 +3,7 @@ package cli
 import (
 	"fmt"
 
+	"github.com/projectqai/hydra/builtin"
 	"github.com/projectqai/hydra/goclient"
 	"github.com/spf13/cobra"
 )
@@ -14,7 +15,7 @@ var (
 )
 
 func AddConnectionFlags(cmd *cobra.Command) {
-	cmd.PersistentFlags().StringVar(&serverURL, "server", "localhost:50051", "gRPC server address")
+	cmd.PersistentFlags().StringVar(&serverURL, "server", builtin.DefaultServerURL(), "gRPC server address, defaults to $HYDRA_SERVER if set")
 	cmd.PersistentFlags().StringVar(&wgConfigPath, "wireguard", "", "path to WireGuard config to each the server")
 }
 
@@ -0,0 +1,154 @@
+package cli
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
+	utm "github.com/im7mortal/UTM"
+)
+
+// MGRS references on top of UTM. The utm package does the projection and picks
+// the zone and latitude band, including the Norway and Svalbard exceptions,
+// this file only adds the 100km square letters. Polar regions (UPS) are not
+// supported.
+
+const (
+	// mgrsBands are the latitude bands from 80°S, 8° each
+	mgrsBands = "CDEFGHJKLMNPQRSTUVWX"
+	// mgrsLetters are the 100km square letters, A-Z without I and O. Columns use
+	// 8 of them per zone, rows the first 20.
+	mgrsLetters = "ABCDEFGHJKLMNPQRSTUVWXYZ"
+)
+
+// mgrsSquareOrigin returns the offsets of the first column and row letter of a zone,
+// they repeat every 3 zones for columns and every 2 zones for rows
+func mgrsSquareOrigin(zone int) (column, row int) {
+	column = (zone - 1) % 3 * 8
+	if zone%2 == 0 {
+		row = 5
+	}
+	return column, row
+}
+
+// toMGRS renders a position as MGRS with the given number of digits per axis (1-5)
+func toMGRS(lat, lon float64, digits int) (string, error) {
+	if lat < -80 || lat > 84 {
+		return "", fmt.Errorf("latitude %.4f outside MGRS/UTM coverage, polar regions are not supported", lat)
+	}
+	easting, northing, zone, band, err := utm.FromLatLon(lat, lon, false)
+	if err != nil {
+		return "", err
+	}
+
+	column, row := mgrsSquareOrigin(zone)
+	col := mgrsLetters[column+int(easting/100000)-1]
+	r := mgrsLetters[(row+int(northing/100000))%20]
+
+	div := math.Pow10(5 - digits)
+	e := int(math.Floor(math.Mod(easting, 100000) / div))
+	n := int(math.Floor(math.Mod(northing, 100000) / div))
+
+	return fmt.Sprintf("%02d%s%c%c%0*d%0*d", zone, band, col, r, digits, e, digits, n), nil
+}
+
+// fromMGRS parses an MGRS reference (spaces allowed) and returns the center of the referenced square
+func fromMGRS(s string) (lat, lon float64, err error) {
+	s = strings.ToUpper(strings.ReplaceAll(s, " ", ""))
+
+	i := 0
+	for i < len(s) && i < 2 && unicode.IsDigit(rune(s[i])) {
+		i++
+	}
+	if i == 0 || len(s) < i+3 {
+		return 0, 0, fmt.Errorf("invalid MGRS reference %q", s)
+	}
+	zone, _ := strconv.Atoi(s[:i])
+	if zone < 1 || zone > 60 {
+		return 0, 0, fmt.Errorf("invalid MGRS zone %d", zone)
+	}
+
+	band := strings.IndexByte(mgrsBands, s[i])
+	if band < 0 {
+		return 0, 0, fmt.Errorf("invalid MGRS latitude band %q", s[i])
+	}
+
+	digits := s[i+3:]
+	if len(digits)%2 != 0 || len(digits) > 10 {
+		return 0, 0, fmt.Errorf("invalid MGRS digits %q", digits)
+	}
+	precision := len(digits) / 2
+
+	column, row := mgrsSquareOrigin(zone)
+	col := strings.IndexByte(mgrsLetters, s[i+1]) - column
+	if col < 0 || col > 7 {
+		return 0, 0, fmt.Errorf("invalid MGRS column letter %q for zone %d", s[i+1], zone)
+	}
+	r := strings.IndexByte(mgrsLetters[:20], s[i+2])
+	if r < 0 {
+		return 0, 0, fmt.Errorf("invalid MGRS row letter %q", s[i+2])
+	}
+	easting := float64(col+1) * 100000
+	northing := float64((r-row+20)%20) * 100000
+
+	// row letters repeat every 2000km, place the square in its latitude band
+	minNorthing, err := bandMinNorthing(band)
+	if err != nil {
+		return 0, 0, err
+	}
+	for northing < minNorthing {
+		northing += 2000000
+	}
+
+	// center of the referenced square
+	size := math.Pow10(5 - precision)
+	if precision > 0 {
+		e, err := strconv.Atoi(digits[:precision])
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid MGRS easting %q", digits[:precision])
+		}
+		n, err := strconv.Atoi(digits[precision:])
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid MGRS northing %q", digits[precision:])
+		}
+		easting += float64(e) * size
+		northing += float64(n) * size
+	}
+	easting += size / 2
+	northing += size / 2
+
+	return utm.ToLatLon(easting, northing, zone, "", band >= strings.IndexByte(mgrsBands, 'N'))
+}
+
+// bandMinNorthing is the northing of the southern edge of a latitude band,
+// rounded down to the 100km square it starts in. It only depends on the
+// distance to the central meridian, so it is measured in zone 1.
+func bandMinNorthing(band int) (float64, error) {
+	lat := float64(-80 + 8*band)
+
+	// parallels bend towards the pole, so the band edge is furthest south at the
+	// central meridian in the north and at the zone border in the south
+	lon := -177.0
+	if lat < 0 {
+		lon = -180
+	}
+	_, northing, _, _, err := utm.FromLatLon(lat, lon, false)
+	if err != nil {
+		return 0, err
+	}
+	return math.Floor(northing/100000) * 100000, nil
+}
+
+// parsePoint reads a position given as "lon,lat" or as an MGRS reference
+func parsePoint(s string) (lon, lat float64, err error) {
+	if _, err := fmt.Sscanf(s, "%f,%f", &lon, &lat); err == nil {
+		return lon, lat, nil
+	}
+	lat, lon, err = fromMGRS(s)
+	if err != nil {
+		return 0, 0, fmt.Errorf("expected lon,lat or MGRS: %w", err)
+	}
+	return lon, lat, nil
+}
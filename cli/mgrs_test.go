@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"math"
+	"testing"
+)
+
+func TestToMGRS(t *testing.T) {
+	tests := []struct {
+		lat, lon float64
+		want     string
+	}{
+		// MGRS truncates to the containing square, rounding tools print 33UXP0500444998
+		{48.24949, 16.41450, "33UXP0500444997"},
+		{53.55, 9.93, "32UNE6161533858"},
+		{-33.8568, 151.2153, "56HLH3490052288"},
+		// Norway and Svalbard zone exceptions
+		{60.39, 5.32, "32VKN9723000510"},
+		{78.22, 15.65, "33XWG1481383004"},
+		{71.99, 20.5, "34WDE8274587888"},
+		// band edges
+		{-79.5, -70.1, "19CDM7762274009"},
+		{83.9, 30, "35XNP3557517856"},
+		{-45, -65, "20GLR4236915103"},
+	}
+	for _, tt := range tests {
+		got, err := toMGRS(tt.lat, tt.lon, 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("toMGRS(%v, %v) = %s, want %s", tt.lat, tt.lon, got, tt.want)
+		}
+	}
+}
+
+func TestToMGRS_PolarRegions(t *testing.T) {
+	for _, lat := range []float64{84.5, -80.5} {
+		if ref, err := toMGRS(lat, 10, 5); err == nil {
+			t.Errorf("toMGRS(%v, 10) = %s, want an error", lat, ref)
+		}
+	}
+}
+
+func TestMGRSRoundTrip(t *testing.T) {
+	points := [][2]float64{
+		{0.1, 0.1}, {53.55, 9.93}, {-33.86, 151.21}, {60.39, 5.32}, {78.22, 15.65}, {-79.5, -70.1}, {40.71, -74.0},
+		{83.9, 30}, {71.99, 20.5}, {-45, -65}, {0.0001, -0.0001}, {-0.0001, 179.99},
+	}
+	for _, p := range points {
+		ref, err := toMGRS(p[0], p[1], 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lat, lon, err := fromMGRS(ref)
+		if err != nil {
+			t.Fatalf("fromMGRS(%s): %v", ref, err)
+		}
+		// 1m squares, decoded to their center
+		if math.Abs(lat-p[0]) > 0.00002 || math.Abs(lon-p[1]) > 0.00002 {
+			t.Errorf("%v -> %s -> %v,%v", p, ref, lat, lon)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/projectqai/hydra/goclient"
+	pb "github.com/projectqai/proto/go"
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+var (
+	nearestK       int
+	nearestSIDC    string
+	nearestShapeOf string
+)
+
+func runNearest(cmd *cobra.Command, args []string) error {
+	req := goclient.NearestRequest{
+		K:    nearestK,
+		Sidc: nearestSIDC,
+	}
+
+	switch {
+	case nearestShapeOf != "" && len(args) == 0:
+		client := pb.NewWorldServiceClient(conn)
+		resp, err := client.GetEntity(cmd.Context(), &pb.GetEntityRequest{Id: nearestShapeOf})
+		if err != nil {
+			return fmt.Errorf("failed to get entity: %w", err)
+		}
+		if resp.Entity.Shape == nil || resp.Entity.Shape.Geometry == nil {
+			return fmt.Errorf("entity %s has no shape", nearestShapeOf)
+		}
+		geometry, err := protojson.Marshal(resp.Entity.Shape.Geometry)
+		if err != nil {
+			return err
+		}
+		req.Geometry = geometry
+	case nearestShapeOf == "" && len(args) == 1:
+		lon, lat, err := parsePoint(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid point: %w", err)
+		}
+		req.Lon, req.Lat = lon, lat
+	default:
+		return fmt.Errorf("give either a point or --shape-of")
+	}
+	if len(filterWith) > 0 {
+		filter, err := protojson.Marshal(&pb.EntityFilter{Component: intSliceToUint32(filterWith)})
+		if err != nil {
+			return err
+		}
+		req.Filter = filter
+	}
+
+	var resp goclient.NearestResponse
+	if err := conn.PostJSON(cmd.Context(), "/nearest", req, &resp); err != nil {
+		return fmt.Errorf("failed to query nearest entities: %w", err)
+	}
+
+	entities := make([]*pb.Entity, 0, len(resp.Results))
+	distances := make([]float64, 0, len(resp.Results))
+	for _, r := range resp.Results {
+		entity := &pb.Entity{}
+		if err := protojson.Unmarshal(r.Entity, entity); err != nil {
+			return fmt.Errorf("failed to decode entity: %w", err)
+		}
+		if entity.Id == nearestShapeOf {
+			continue
+		}
+		entities = append(entities, entity)
+		distances = append(distances, r.Distance)
+	}
+
+	switch outputFormat {
+	case "yaml":
+		return printEntitiesYAML(entities)
+	case "json":
+		return printEntitiesJSON(entities)
+	case "table":
+		if len(entities) == 0 {
+			fmt.Println("No entities found")
+			return nil
+		}
+		tbl := newTable("ID", "symbol", "Distance (m)")
+		for i, entity := range entities {
+			symbol := ""
+			if entity.Symbol != nil {
+				symbol = entity.Symbol.MilStd2525C
+			}
+			tbl.AddRow(entity.Id, symbol, fmt.Sprintf("%.0f", distances[i]))
+		}
+		tbl.Print()
+		return nil
+	default:
+		return fmt.Errorf("unknown output format: %s (use: table, yaml, json)", outputFormat)
+	}
+}
@@ -0,0 +1,123 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+
+	"github.com/projectqai/hydra/goclient"
+	pb "github.com/projectqai/proto/go"
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+var recordOutput string
+
+// recordFlushInterval bounds how much of a capture is lost if the process is killed
+const recordFlushInterval = time.Second
+
+// recordBufferSize flushes early when a burst of events fills the buffer
+const recordBufferSize = 64 * 1024
+
+func runRecord(cmd *cobra.Command, args []string) error {
+	filter := &pb.EntityFilter{}
+	if len(filterWith) > 0 {
+		filter.Component = intSliceToUint32(filterWith)
+	}
+	if filterBBox != "" {
+		bound, err := parseBBox(filterBBox)
+		if err != nil {
+			return err
+		}
+		filter.Geo = boundFilter(bound)
+	}
+
+	var out io.Writer = os.Stdout
+	if recordOutput != "" && recordOutput != "-" {
+		f, err := os.Create(recordOutput)
+		if err != nil {
+			return fmt.Errorf("failed to create capture file: %w", err)
+		}
+		defer f.Close()
+		out = f
+	}
+
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+	defer stop()
+
+	world := pb.NewWorldServiceClient(conn)
+	stream, err := goclient.WatchEntitiesWithRetry(ctx, world, &pb.ListEntitiesRequest{Filter: filter})
+	if err != nil {
+		return fmt.Errorf("failed to watch entities: %w", err)
+	}
+
+	// complete lines are collected and written to out in a single call, so a
+	// capture cut off between flushes still ends on a whole line
+	var (
+		mu      sync.Mutex
+		pending bytes.Buffer
+	)
+	flush := func() error {
+		mu.Lock()
+		defer mu.Unlock()
+		_, err := out.Write(pending.Bytes())
+		pending.Reset()
+		return err
+	}
+	defer flush()
+
+	go func() {
+		ticker := time.NewTicker(recordFlushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				flush()
+			}
+		}
+	}()
+
+	start := time.Now()
+	count := 0
+	for {
+		event, err := stream.Recv()
+		if err != nil {
+			if err == io.EOF || ctx.Err() != nil {
+				fmt.Fprintf(os.Stderr, "recorded %d events\n", count)
+				return nil
+			}
+			return fmt.Errorf("stream error: %w", err)
+		}
+		if event.Entity == nil || event.T == pb.EntityChange_EntityChangeInvalid {
+			continue
+		}
+
+		eventJSON, err := protojson.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("failed to marshal event: %w", err)
+		}
+		line, err := json.Marshal(recordedEvent{T: time.Since(start).Seconds(), Event: eventJSON})
+		if err != nil {
+			return err
+		}
+
+		mu.Lock()
+		pending.Write(line)
+		pending.WriteByte('\n')
+		size := pending.Len()
+		mu.Unlock()
+		if size >= recordBufferSize {
+			if err := flush(); err != nil {
+				return fmt.Errorf("failed to write capture: %w", err)
+			}
+		}
+		count++
+	}
+}
@@ -0,0 +1,165 @@
+package cli
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	pb "github.com/projectqai/proto/go"
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var (
+	replaySpeed float64
+	replayLoop  bool
+)
+
+// recordedEvent is one line of a capture file
+type recordedEvent struct {
+	// T is the time since the start of the capture in seconds
+	T float64 `json:"t"`
+	// Event is an EntityChangeEvent in protojson encoding
+	Event json.RawMessage `json:"event"`
+}
+
+type replayEvent struct {
+	offset time.Duration
+	event  *pb.EntityChangeEvent
+}
+
+// readCapture reads a capture file. A malformed last line is skipped with a
+// warning, it is what a recording killed mid-write leaves behind.
+func readCapture(path string) ([]replayEvent, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var events []replayEvent
+	// malformed is the error of the previous line, only fatal if more lines follow
+	var malformed error
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		if malformed != nil {
+			return nil, malformed
+		}
+		event, err := parseRecordedEvent(scanner.Bytes())
+		if err != nil {
+			malformed = fmt.Errorf("line %d: %w", line, err)
+			continue
+		}
+		events = append(events, event)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if malformed != nil {
+		fmt.Fprintf(os.Stderr, "skipping truncated last line of %s: %v\n", path, malformed)
+	}
+	return events, nil
+}
+
+func parseRecordedEvent(line []byte) (replayEvent, error) {
+	var rec recordedEvent
+	if err := json.Unmarshal(line, &rec); err != nil {
+		return replayEvent{}, err
+	}
+	event := &pb.EntityChangeEvent{}
+	if err := protojson.Unmarshal(rec.Event, event); err != nil {
+		return replayEvent{}, err
+	}
+	return replayEvent{
+		offset: time.Duration(rec.T * float64(time.Second)),
+		event:  event,
+	}, nil
+}
+
+// rebase moves the lifetime of a recorded entity to now, keeping its duration
+func rebase(entity *pb.Entity, now time.Time) {
+	if entity.Lifetime == nil {
+		return
+	}
+	if entity.Lifetime.From.IsValid() && entity.Lifetime.Until.IsValid() {
+		ttl := entity.Lifetime.Until.AsTime().Sub(entity.Lifetime.From.AsTime())
+		entity.Lifetime.Until = timestamppb.New(now.Add(ttl))
+	}
+	entity.Lifetime.From = timestamppb.New(now)
+	if entity.Detection != nil && entity.Detection.LastMeasured.IsValid() {
+		entity.Detection.LastMeasured = timestamppb.New(now)
+	}
+}
+
+func runReplay(cmd *cobra.Command, args []string) error {
+	if replaySpeed <= 0 {
+		return fmt.Errorf("--speed must be positive")
+	}
+
+	events, err := readCapture(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to read capture: %w", err)
+	}
+	if len(events) == 0 {
+		return fmt.Errorf("no events in %s", args[0])
+	}
+
+	ctx := cmd.Context()
+	client := pb.NewWorldServiceClient(conn)
+
+	for {
+		// last pushed state per id, expiring pushes it again instead of the recorded stub
+		pushed := make(map[string]*pb.Entity)
+
+		start := time.Now()
+		for _, ev := range events {
+			due := start.Add(time.Duration(float64(ev.offset) / replaySpeed))
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(time.Until(due)):
+			}
+
+			entity := ev.event.Entity
+			if entity == nil {
+				continue
+			}
+			now := time.Now()
+			switch ev.event.T {
+			case pb.EntityChange_EntityChangeUpdated:
+				entity = proto.Clone(entity).(*pb.Entity)
+				rebase(entity, now)
+				pushed[entity.Id] = entity
+			case pb.EntityChange_EntityChangeExpired:
+				if last, ok := pushed[entity.Id]; ok {
+					entity = last
+				}
+				entity = proto.Clone(entity).(*pb.Entity)
+				if entity.Lifetime == nil {
+					entity.Lifetime = &pb.Lifetime{}
+				}
+				entity.Lifetime.Until = timestamppb.New(now)
+				delete(pushed, entity.Id)
+			default:
+				continue
+			}
+
+			if _, err := client.Push(ctx, &pb.EntityChangeRequest{Changes: []*pb.Entity{entity}}); err != nil {
+				return fmt.Errorf("failed to push entity %s: %w", entity.Id, err)
+			}
+		}
+
+		fmt.Fprintf(os.Stderr, "replayed %d events\n", len(events))
+		if !replayLoop {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadCapture_TruncatedLastLine(t *testing.T) {
+	complete := `{"t":0,"event":{"entity":{"id":"a"},"t":"EntityChangeUpdated"}}` + "\n" +
+		`{"t":1,"event":{"entity":{"id":"b"},"t":"EntityChangeUpdated"}}` + "\n"
+	truncated := `{"t":2,"event":{"entity":{"id"`
+
+	path := filepath.Join(t.TempDir(), "capture.jsonl")
+	if err := os.WriteFile(path, []byte(complete+truncated), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	events, err := readCapture(path)
+	if err != nil {
+		t.Fatalf("expected the truncated last line to be skipped, got %v", err)
+	}
+	if len(events) != 2 || events[1].event.Entity.Id != "b" {
+		t.Fatalf("expected the 2 complete events, got %d", len(events))
+	}
+
+	// a malformed line in the middle is corruption, not a cut off recording
+	if err := os.WriteFile(path, []byte(truncated+"\n"+complete), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readCapture(path); err == nil {
+		t.Fatal("expected an error for a malformed line before the end")
+	}
+}
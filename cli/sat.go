@@ -0,0 +1,132 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/projectqai/hydra/builtin/spacetrack"
+	"github.com/projectqai/hydra/cmd"
+	pb "github.com/projectqai/proto/go"
+	"github.com/spf13/cobra"
+)
+
+var (
+	satID           string
+	satLat          float64
+	satLon          float64
+	satAlt          float64
+	satMinElevation float64
+	satHours        float64
+	satOutput       string
+)
+
+func init() {
+	satCmd := &cobra.Command{
+		Use:               "sat",
+		Short:             "satellites tracked by the spacetrack builtin",
+		PersistentPreRunE: connect,
+	}
+	AddConnectionFlags(satCmd)
+
+	passesCmd := &cobra.Command{
+		Use:   "passes",
+		Short: "predict when a tracked satellite passes over a location",
+		Long:  "predict the passes of a satellite over a location from the TLE its spacetrack tracker loads. AOS and LOS are when it rises above and sets below the horizon, passes that stay below --min-elevation are left out.",
+		Args:  cobra.NoArgs,
+		RunE:  runSatPasses,
+	}
+	passesCmd.Flags().StringVar(&satID, "id", "", "entity id of the satellite, e.g. spacetrack-iss")
+	passesCmd.Flags().Float64Var(&satLat, "lat", 0, "latitude of the observer")
+	passesCmd.Flags().Float64Var(&satLon, "lon", 0, "longitude of the observer")
+	passesCmd.Flags().Float64Var(&satAlt, "alt", 0, "altitude of the observer in meters")
+	passesCmd.Flags().Float64Var(&satMinElevation, "min-elevation", 10, "minimum elevation of a pass in degrees")
+	passesCmd.Flags().Float64Var(&satHours, "hours", 24, "how many hours ahead to predict")
+	passesCmd.Flags().StringVarP(&satOutput, "output", "o", "table", "output format: table, json")
+	_ = passesCmd.MarkFlagRequired("id")
+	_ = passesCmd.MarkFlagRequired("lat")
+	_ = passesCmd.MarkFlagRequired("lon")
+
+	satCmd.AddCommand(passesCmd)
+	cmd.CMD.AddCommand(satCmd)
+}
+
+// satPass is a predicted pass as printed by sat passes -o json
+type satPass struct {
+	AOS              time.Time `json:"aos"`
+	LOS              time.Time `json:"los"`
+	AOSAzimuth       float64   `json:"aosAzimuth"`
+	LOSAzimuth       float64   `json:"losAzimuth"`
+	MaxElevation     float64   `json:"maxElevation"`
+	MaxElevationTime time.Time `json:"maxElevationTime"`
+	Duration         string    `json:"duration"`
+}
+
+func runSatPasses(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	if satHours <= 0 {
+		return fmt.Errorf("--hours must be positive")
+	}
+
+	client := pb.NewWorldServiceClient(conn)
+	sat, err := client.GetEntity(ctx, &pb.GetEntityRequest{Id: satID})
+	if err != nil {
+		return fmt.Errorf("failed to get satellite: %w", err)
+	}
+	if sat.Entity.GetController().GetName() != "spacetrack" {
+		return fmt.Errorf("%s is not tracked by spacetrack", satID)
+	}
+	config, err := client.GetEntity(ctx, &pb.GetEntityRequest{Id: sat.Entity.Controller.Id})
+	if err != nil {
+		return fmt.Errorf("failed to get tracker config: %w", err)
+	}
+	tle, err := spacetrack.TrackedTLE(ctx, config.Entity, satID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	until := now.Add(time.Duration(satHours * float64(time.Hour)))
+	passes, err := spacetrack.Passes(tle, satLat, satLon, satAlt, now, until, satMinElevation)
+	if err != nil {
+		return fmt.Errorf("failed to predict passes: %w", err)
+	}
+
+	switch satOutput {
+	case "json":
+		out := make([]satPass, len(passes))
+		for i, p := range passes {
+			out[i] = satPass{
+				AOS:              p.AOS,
+				LOS:              p.LOS,
+				AOSAzimuth:       p.AOSAzimuth,
+				LOSAzimuth:       p.LOSAzimuth,
+				MaxElevation:     p.MaxElevation,
+				MaxElevationTime: p.MaxElevationTime,
+				Duration:         p.Duration.Round(time.Second).String(),
+			}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(out)
+	case "table":
+		if len(passes) == 0 {
+			fmt.Fprintf(os.Stderr, "no passes above %g° in the next %gh\n", satMinElevation, satHours)
+			return nil
+		}
+		tbl := newTable("AOS", "AZ", "MAX EL", "AT", "AZ", "LOS", "AZ", "DURATION")
+		for _, p := range passes {
+			tbl.AddRow(
+				p.AOS.Local().Format("Jan 02 15:04:05"), fmt.Sprintf("%05.1f°", p.AOSAzimuth),
+				fmt.Sprintf("%.1f°", p.MaxElevation), p.MaxElevationTime.Local().Format("15:04:05"), fmt.Sprintf("%05.1f°", p.MaxElevationAz),
+				p.LOS.Local().Format("15:04:05"), fmt.Sprintf("%05.1f°", p.LOSAzimuth),
+				p.Duration.Round(time.Second),
+			)
+		}
+		tbl.Print()
+		return nil
+	default:
+		return fmt.Errorf("unknown output format: %s (use: table, json)", satOutput)
+	}
+}
@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/projectqai/hydra/goclient"
+	pb "github.com/projectqai/proto/go"
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+var (
+	tagLabelPrefix string
+	tagController  string
+	tagAll         bool
+	tagDryRun      bool
+)
+
+func runTag(cmd *cobra.Command, args []string) error {
+	filter := &pb.EntityFilter{}
+	if filterBBox != "" {
+		bound, err := parseBBox(filterBBox)
+		if err != nil {
+			return err
+		}
+		filter.Geo = boundFilter(bound)
+	}
+	if len(filterWith) > 0 {
+		filter.Component = intSliceToUint32(filterWith)
+	}
+	if filter.Geo == nil && filter.Component == nil && !tagAll {
+		return fmt.Errorf("give --bbox or --with, or --all to change every entity")
+	}
+	if tagLabelPrefix == "" && tagController == "" {
+		return fmt.Errorf("nothing to change, give --set-label-prefix or --set-controller")
+	}
+
+	req := goclient.TagRequest{LabelPrefix: tagLabelPrefix, Controller: tagController, DryRun: tagDryRun}
+	if !tagAll {
+		f, err := protojson.Marshal(filter)
+		if err != nil {
+			return err
+		}
+		req.Filter = f
+	}
+
+	// the engine changes all entities in one go, instead of a round trip per entity
+	var resp goclient.TagResponse
+	if err := conn.PostJSON(cmd.Context(), "/entities/tag", req, &resp); err != nil {
+		return fmt.Errorf("failed to tag entities: %w", err)
+	}
+
+	for _, id := range resp.Tagged {
+		fmt.Println(id)
+	}
+	verb := "tagged"
+	if tagDryRun {
+		verb = "would tag"
+	}
+	fmt.Printf("%s %d entities\n", verb, len(resp.Tagged))
+	return nil
+}
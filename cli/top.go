@@ -0,0 +1,234 @@
+package cli
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/projectqai/hydra/goclient"
+	pb "github.com/projectqai/proto/go"
+	"github.com/spf13/cobra"
+)
+
+var (
+	topInterval time.Duration
+	topRecent   int
+)
+
+type (
+	topEventMsg *pb.EntityChangeEvent
+	topStatsMsg struct {
+		stats goclient.Stats
+		at    time.Time
+		err   error
+	}
+	topErrMsg struct{ err error }
+)
+
+type topModel struct {
+	ctx    context.Context
+	stream pb.WorldService_WatchEntitiesClient
+	width  int
+	height int
+
+	entities map[string]*pb.Entity
+	// updated is the Lifetime.From of the last update per entity, so the
+	// initial snapshot of the watch doesn't show up as recent activity
+	updated map[string]time.Time
+
+	stats     goclient.Stats
+	statsAt   time.Time
+	statsErr  error
+	pushRate  float64
+	streamErr error
+}
+
+func (m topModel) Init() tea.Cmd {
+	return tea.Batch(m.recv(), m.poll(0))
+}
+
+func (m topModel) recv() tea.Cmd {
+	return func() tea.Msg {
+		event, err := m.stream.Recv()
+		if err != nil {
+			return topErrMsg{err}
+		}
+		return topEventMsg(event)
+	}
+}
+
+func (m topModel) poll(after time.Duration) tea.Cmd {
+	return tea.Tick(after, func(time.Time) tea.Msg {
+		var stats goclient.Stats
+		err := conn.GetJSON(m.ctx, "/stats", nil, &stats)
+		return topStatsMsg{stats: stats, at: time.Now(), err: err}
+	})
+}
+
+func (m topModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "ctrl+c", "q", "esc":
+			return m, tea.Quit
+		}
+
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+
+	case topEventMsg:
+		if msg.Entity != nil {
+			switch msg.T {
+			case pb.EntityChange_EntityChangeUpdated:
+				m.entities[msg.Entity.Id] = msg.Entity
+				updated := time.Now()
+				if msg.Entity.Lifetime != nil && msg.Entity.Lifetime.From.IsValid() {
+					updated = msg.Entity.Lifetime.From.AsTime()
+				}
+				m.updated[msg.Entity.Id] = updated
+			case pb.EntityChange_EntityChangeExpired, pb.EntityChange_EntityChangeUnobserved:
+				delete(m.entities, msg.Entity.Id)
+				delete(m.updated, msg.Entity.Id)
+			}
+		}
+		return m, m.recv()
+
+	case topErrMsg:
+		m.streamErr = msg.err
+		return m, nil
+
+	case topStatsMsg:
+		m.statsErr = msg.err
+		if msg.err == nil {
+			if !m.statsAt.IsZero() && msg.stats.Pushed >= m.stats.Pushed {
+				m.pushRate = float64(msg.stats.Pushed-m.stats.Pushed) / msg.at.Sub(m.statsAt).Seconds()
+			}
+			m.stats = msg.stats
+			m.statsAt = msg.at
+		}
+		return m, m.poll(topInterval)
+	}
+
+	return m, nil
+}
+
+func (m topModel) View() string {
+	headerStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("cyan"))
+	statusStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("205"))
+	errStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+	helpStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("241"))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "hydra at %s\n", serverURL)
+	b.WriteString(statusStyle.Render(fmt.Sprintf("entities %d | watchers %d | pushes %.1f/s",
+		m.stats.Entities, m.stats.Watchers, m.pushRate)))
+	b.WriteString("\n")
+	if m.statsErr != nil {
+		b.WriteString(errStyle.Render(fmt.Sprintf("stats: %v", m.statsErr)))
+		b.WriteString("\n")
+	}
+	if m.streamErr != nil {
+		b.WriteString(errStyle.Render(fmt.Sprintf("watch: %v", m.streamErr)))
+		b.WriteString("\n")
+	}
+
+	// counts by controller
+	counts := make(map[string]int)
+	for _, e := range m.entities {
+		counts[controllerOf(e)]++
+	}
+	controllers := make([]string, 0, len(counts))
+	for c := range counts {
+		controllers = append(controllers, c)
+	}
+	slices.SortFunc(controllers, func(a, b string) int {
+		return cmp.Or(cmp.Compare(counts[b], counts[a]), strings.Compare(a, b))
+	})
+
+	b.WriteString("\n")
+	b.WriteString(headerStyle.Render(fmt.Sprintf("%-30s %8s", "CONTROLLER", "ENTITIES")))
+	b.WriteString("\n")
+	for _, c := range controllers {
+		fmt.Fprintf(&b, "%-30s %8d\n", truncate(c, 30), counts[c])
+	}
+
+	// most recently updated
+	ids := make([]string, 0, len(m.updated))
+	for id := range m.updated {
+		ids = append(ids, id)
+	}
+	slices.SortFunc(ids, func(a, b string) int {
+		return cmp.Or(m.updated[b].Compare(m.updated[a]), strings.Compare(a, b))
+	})
+	limit := topRecent
+	if m.height > 0 {
+		// header, status, blank lines, both table headers, help
+		limit = min(limit, m.height-len(controllers)-8)
+	}
+	ids = ids[:max(0, min(limit, len(ids)))]
+
+	b.WriteString("\n")
+	b.WriteString(headerStyle.Render(fmt.Sprintf("%-30s %-24s %-20s %8s", "ID", "LABEL", "CONTROLLER", "AGE")))
+	b.WriteString("\n")
+	now := time.Now()
+	for _, id := range ids {
+		e := m.entities[id]
+		label := ""
+		if e.Label != nil {
+			label = *e.Label
+		}
+		age := now.Sub(m.updated[id]).Round(time.Second)
+		fmt.Fprintf(&b, "%-30s %-24s %-20s %8s\n", truncate(id, 30), truncate(label, 24), truncate(controllerOf(e), 20), age)
+	}
+
+	b.WriteString(helpStyle.Render("\nq: quit"))
+	return b.String()
+}
+
+func controllerOf(e *pb.Entity) string {
+	if e.Controller != nil && e.Controller.Name != "" {
+		return e.Controller.Name
+	}
+	if e.Controller != nil && e.Controller.Id != "" {
+		return e.Controller.Id
+	}
+	return "-"
+}
+
+func truncate(s string, n int) string {
+	if len([]rune(s)) <= n {
+		return s
+	}
+	return string([]rune(s)[:n-1]) + "…"
+}
+
+func runTop(cmd *cobra.Command, args []string) error {
+	if topInterval <= 0 {
+		return fmt.Errorf("--interval must be positive")
+	}
+
+	ctx, cancel := context.WithCancel(cmd.Context())
+	defer cancel()
+
+	stream, err := goclient.WatchEntitiesWithRetry(ctx, pb.NewWorldServiceClient(conn), &pb.ListEntitiesRequest{})
+	if err != nil {
+		return fmt.Errorf("failed to watch entities: %w", err)
+	}
+
+	model := topModel{
+		ctx:      ctx,
+		stream:   stream,
+		entities: make(map[string]*pb.Entity),
+		updated:  make(map[string]time.Time),
+	}
+	if _, err := tea.NewProgram(model, tea.WithAltScreen()).Run(); err != nil {
+		return fmt.Errorf("error running top: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/projectqai/hydra/cmd"
+	"github.com/projectqai/hydra/goclient"
+	"github.com/projectqai/hydra/version"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	versionCmd := &cobra.Command{
+		Use:   "version",
+		Short: "Print version information, and that of a server if --server is given",
+		Args:  cobra.NoArgs,
+		RunE:  runVersion,
+	}
+	AddConnectionFlags(versionCmd)
+	cmd.CMD.AddCommand(versionCmd)
+}
+
+func runVersion(c *cobra.Command, args []string) error {
+	if !c.Flags().Changed("server") {
+		fmt.Println(version.Version)
+		return nil
+	}
+
+	if err := connect(c, args); err != nil {
+		return err
+	}
+	defer disconnect()
+
+	var stats goclient.Stats
+	if err := conn.GetJSON(c.Context(), "/stats", nil, &stats); err != nil {
+		return fmt.Errorf("failed to get server version: %w", err)
+	}
+	fmt.Printf("client: %s\nserver: %s\n", version.Version, stats.Version)
+	return nil
+}
@@ -0,0 +1,41 @@
+package engine
+
+import (
+	"net/http"
+	"net/http/pprof"
+)
+
+// handleAdmin registers the endpoints for operators on mux: metrics, readiness,
+// logs and, if enabled, profiles. They are served on the main port unless
+// EngineConfig.AdminAddr moves them to their own.
+func (s *WorldServer) handleAdmin(mux *http.ServeMux, metrics http.Handler, cfg EngineConfig) {
+	mux.Handle("/metrics", metrics)
+	mux.HandleFunc("/readyz", s.handleReady)
+	mux.HandleFunc("/logs", s.handleLogs)
+
+	if cfg.Pprof {
+		mux.HandleFunc("/debug/pprof/", pprof.Index)
+		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	}
+}
+
+// handleHealth answers as long as the server runs
+func handleHealth(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write([]byte("OK"))
+}
+
+// handleReady answers 503 once the engine is shutting down, so load balancers
+// stop sending new clients before the listener closes
+func (s *WorldServer) handleReady(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain")
+	if s.externalWatchers.draining() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("shutting down"))
+		return
+	}
+	w.Write([]byte("OK"))
+}
@@ -0,0 +1,27 @@
+package engine
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestReady_UnavailableWhileShuttingDown(t *testing.T) {
+	w := testWorld(nil)
+	ready := func() int {
+		rec := httptest.NewRecorder()
+		w.handleReady(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		return rec.Code
+	}
+
+	if code := ready(); code != http.StatusOK {
+		t.Fatalf("expected a running engine to be ready, got %d", code)
+	}
+	if err := w.DrainWatchers(context.Background(), false); err != nil {
+		t.Fatal(err)
+	}
+	if code := ready(); code != http.StatusServiceUnavailable {
+		t.Fatalf("expected a draining engine not to be ready, got %d", code)
+	}
+}
@@ -0,0 +1,82 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geo"
+	"github.com/projectqai/hydra/goclient"
+	"github.com/projectqai/hydra/policy"
+)
+
+// errNotFound is returned by lookups of entities that don't exist or the reader may not see
+var errNotFound = errors.New("not found")
+
+// position returns the position of a readable entity by id or alias. Caller must hold s.l.
+func (s *WorldServer) position(ctx context.Context, ability *policy.Ability, id string) (orb.Point, error) {
+	entity, ok := s.head.Lookup(id)
+	if !ok {
+		if target, alias := s.aliases[id]; alias {
+			entity, ok = s.head.Lookup(target)
+		}
+	}
+	if !ok || !ability.CanRead(ctx, entity, s.head.Marking(entity.Id)) {
+		return orb.Point{}, fmt.Errorf("entity %s %w", id, errNotFound)
+	}
+	if entity.Geo == nil {
+		return orb.Point{}, fmt.Errorf("entity %s has no position", id)
+	}
+	return orb.Point{entity.Geo.Longitude, entity.Geo.Latitude}, nil
+}
+
+// bearing computes bearing and range between two entities
+func (s *WorldServer) bearing(ctx context.Context, ability *policy.Ability, fromID, toID string) (goclient.BearingResponse, error) {
+	s.l.RLock()
+	defer s.l.RUnlock()
+
+	from, err := s.position(ctx, ability, fromID)
+	if err != nil {
+		return goclient.BearingResponse{}, err
+	}
+	to, err := s.position(ctx, ability, toID)
+	if err != nil {
+		return goclient.BearingResponse{}, err
+	}
+	return goclient.BearingResponse{
+		From:     fromID,
+		To:       toID,
+		Bearing:  math.Mod(geo.Bearing(from, to)+360, 360),
+		Distance: geo.DistanceHaversine(from, to),
+	}, nil
+}
+
+func (s *WorldServer) handleBearing(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	fromID, toID := r.URL.Query().Get("from"), r.URL.Query().Get("to")
+	if fromID == "" || toID == "" {
+		http.Error(w, "from and to are required", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := s.bearing(r.Context(), policy.ForHTTP(s.policy, r), fromID, toID)
+	if errors.Is(err, errNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,41 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/projectqai/hydra/policy"
+	pb "github.com/projectqai/proto/go"
+)
+
+func TestBearing_BetweenEntities(t *testing.T) {
+	w := testWorld(map[string]*pb.Entity{
+		"a":     {Id: "a", Geo: &pb.GeoSpatialComponent{Longitude: 10, Latitude: 50}},
+		"north": {Id: "north", Geo: &pb.GeoSpatialComponent{Longitude: 10, Latitude: 51}},
+		"west":  {Id: "west", Geo: &pb.GeoSpatialComponent{Longitude: 9, Latitude: 50}},
+		"area":  {Id: "area"},
+	})
+	ability := policy.For(context.Background(), nil, "")
+
+	resp, err := w.bearing(context.Background(), ability, "a", "north")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(resp.Bearing) > 0.01 || math.Abs(resp.Distance-111_319) > 100 {
+		t.Fatalf("expected due north at about 111 km, got %+v", resp)
+	}
+
+	// bearings are in [0, 360)
+	if resp, _ := w.bearing(context.Background(), ability, "a", "west"); resp.Bearing < 269 || resp.Bearing > 271 {
+		t.Fatalf("expected due west, got %v", resp.Bearing)
+	}
+
+	if _, err := w.bearing(context.Background(), ability, "a", "area"); err == nil || errors.Is(err, errNotFound) {
+		t.Fatalf("expected missing position error, got %v", err)
+	}
+	if _, err := w.bearing(context.Background(), ability, "a", "gone"); !errors.Is(err, errNotFound) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+}
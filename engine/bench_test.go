@@ -0,0 +1,163 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"connectrpc.com/connect"
+	pb "github.com/projectqai/proto/go"
+)
+
+// benchWorld returns a world with n entities pushed through Push
+func benchWorld(b *testing.B, n int) *WorldServer {
+	b.Helper()
+	w := testWorld(nil)
+	changes := make([]*pb.Entity, n)
+	for i := range changes {
+		changes[i] = benchEntity(i)
+	}
+	if _, err := w.Push(context.Background(), connect.NewRequest(&pb.EntityChangeRequest{Changes: changes})); err != nil {
+		b.Fatal(err)
+	}
+	return w
+}
+
+func benchEntity(i int) *pb.Entity {
+	return &pb.Entity{
+		Id:  fmt.Sprintf("e%d", i),
+		Geo: &pb.GeoSpatialComponent{Longitude: float64(i%360) - 180, Latitude: float64(i%180) - 90},
+	}
+}
+
+// BenchmarkPush_ConcurrentReaders pushes single updates while watchers read the head,
+// like the sender loops of watch clients do for every change they send
+func BenchmarkPush_ConcurrentReaders(b *testing.B) {
+	const entities, readers = 10_000, 8
+	w := benchWorld(b, entities)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var reads atomic.Int64
+	var wg sync.WaitGroup
+	for r := range readers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := r; ctx.Err() == nil; i++ {
+				id := fmt.Sprintf("e%d", i%entities)
+				w.head.Entry(id)
+				reads.Add(1)
+			}
+		}()
+	}
+
+	b.ResetTimer()
+	for i := range b.N {
+		req := connect.NewRequest(&pb.EntityChangeRequest{Changes: []*pb.Entity{benchEntity(i % entities)}})
+		if _, err := w.Push(context.Background(), req); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.StopTimer()
+	cancel()
+	wg.Wait()
+	b.ReportMetric(float64(reads.Load())/float64(b.N), "reads/push")
+}
+
+// BenchmarkPush pushes batches of updates to existing entities
+func BenchmarkPush(b *testing.B) {
+	for _, entities := range []int{1_000, 10_000, 100_000} {
+		b.Run(fmt.Sprintf("entities=%d", entities), func(b *testing.B) {
+			const batch = 100
+			w := benchWorld(b, entities)
+
+			b.ResetTimer()
+			for i := range b.N {
+				changes := make([]*pb.Entity, batch)
+				for j := range changes {
+					changes[j] = benchEntity((i*batch + j) % entities)
+				}
+				if _, err := w.Push(context.Background(), connect.NewRequest(&pb.EntityChangeRequest{Changes: changes})); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(b.N*batch)/b.Elapsed().Seconds(), "entities/s")
+		})
+	}
+}
+
+// BenchmarkListEntities lists the whole world, and filters it for configurations
+// of which there are none, which leaves the cost of the scan
+func BenchmarkListEntities(b *testing.B) {
+	for _, entities := range []int{1_000, 10_000, 100_000} {
+		w := benchWorld(b, entities)
+		b.Run(fmt.Sprintf("entities=%d", entities), func(b *testing.B) {
+			for range b.N {
+				if _, err := w.ListEntities(context.Background(), connect.NewRequest(&pb.ListEntitiesRequest{})); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("entities=%d/filtered", entities), func(b *testing.B) {
+			req := &pb.ListEntitiesRequest{Filter: &pb.EntityFilter{Component: []uint32{31}}}
+			for range b.N {
+				if _, err := w.ListEntities(context.Background(), connect.NewRequest(req)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkFanOut pushes updates to M watchers and waits until all of them sent
+// everything, the sender loops don't rate limit
+func BenchmarkFanOut(b *testing.B) {
+	const entities, batch = 10_000, 100
+	for _, watchers := range []int{1, 10, 100} {
+		b.Run(fmt.Sprintf("watchers=%d", watchers), func(b *testing.B) {
+			w := benchWorld(b, entities)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			var sent atomic.Int64
+			consumers := make([]*Consumer, watchers)
+			for i := range consumers {
+				c := NewConsumer(w, nil, nil, nil)
+				w.bus.Register(c)
+				go c.SenderLoop(ctx, func(*pb.EntityChangeEvent) error {
+					sent.Add(1)
+					return nil
+				})
+				consumers[i] = c
+			}
+
+			b.ResetTimer()
+			for i := range b.N {
+				changes := make([]*pb.Entity, batch)
+				for j := range changes {
+					changes[j] = benchEntity((i*batch + j) % entities)
+				}
+				if _, err := w.Push(context.Background(), connect.NewRequest(&pb.EntityChangeRequest{Changes: changes})); err != nil {
+					b.Fatal(err)
+				}
+			}
+			for _, c := range consumers {
+				for {
+					c.mu.Lock()
+					n := c.pending()
+					c.mu.Unlock()
+					if n == 0 {
+						break
+					}
+					runtime.Gosched()
+				}
+			}
+			b.StopTimer()
+			// changes to the same entity coalesce, so this is less than pushed times watchers
+			b.ReportMetric(float64(sent.Load())/b.Elapsed().Seconds(), "events/s")
+		})
+	}
+}
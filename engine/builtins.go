@@ -0,0 +1,20 @@
+package engine
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/projectqai/hydra/builtin"
+	"github.com/projectqai/hydra/goclient"
+)
+
+// handleBuiltins returns the state of the builtins registered in this process
+func handleBuiltins(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(goclient.BuiltinsResponse{Builtins: builtin.Statuses()})
+}
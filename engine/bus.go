@@ -29,11 +29,15 @@ func (b *Bus) Unregister(c *Consumer) {
 	delete(b.consumers, c)
 }
 
+// Len returns the number of registered consumers
+func (b *Bus) Len() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.consumers)
+}
+
 func (b *Bus) Dirty(entityID string, entity *pb.Entity, change pb.EntityChange) {
-	priority := pb.Priority_PriorityRoutine
-	if entity != nil && entity.Priority != nil {
-		priority = *entity.Priority
-	}
+	priority := priorityOf(entity)
 
 	b.mu.RLock()
 	defer b.mu.RUnlock()
@@ -42,3 +46,23 @@ func (b *Bus) Dirty(entityID string, entity *pb.Entity, change pb.EntityChange)
 		c.markDirty(entityID, priority, change)
 	}
 }
+
+// Created is Dirty for an entity that wasn't in the head before, watchers see
+// it as an update but may ask for appearances at a higher priority
+func (b *Bus) Created(entityID string, entity *pb.Entity) {
+	priority := priorityOf(entity)
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for c := range b.consumers {
+		c.markCreated(entityID, priority)
+	}
+}
+
+func priorityOf(entity *pb.Entity) pb.Priority {
+	if entity != nil && entity.Priority != nil {
+		return *entity.Priority
+	}
+	return pb.Priority_PriorityRoutine
+}
@@ -5,26 +5,65 @@ package engine
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"sync"
 	"testing"
 	"time"
 
+	"connectrpc.com/connect"
+	"github.com/paulmach/orb"
+	"github.com/projectqai/hydra/goclient"
 	pb "github.com/projectqai/proto/go"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 func ptr[T any](v T) *T { return &v }
 
+// testClock only moves when advanced
+type testClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *testClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
 // testWorld creates a WorldServer with the given entities for testing
 func testWorld(entities map[string]*pb.Entity) *WorldServer {
 	w := &WorldServer{
+		clock: wallClock{},
 		bus:   NewBus(),
-		head:  make(map[string]*pb.Entity),
+		head:  newHead(),
 		store: NewStore(),
+
+		lastSeen: make(map[string]time.Time),
+		versions: make(map[string]uint64),
+		epoch:    newEpoch(),
+
+		throttled: make(map[string]throttledPush),
+		contents:  make(map[string]content),
+
+		fieldTimes: make(map[string]map[protoreflect.FieldNumber]time.Time),
+
+		tombstones:         make(map[string]tombstone),
+		tombstoneRetention: DefaultTombstoneRetention,
+
+		externalWatchers: newWatcherGroup(),
+		builtinWatchers:  newWatcherGroup(),
 	}
 	for id, e := range entities {
-		w.head[id] = e
+		w.head.Set(id, e)
 	}
 	return w
 }
@@ -312,6 +351,65 @@ func TestSenderLoop_BurstBypassesRateLimit(t *testing.T) {
 	}
 }
 
+func TestSenderLoop_PriorityRates(t *testing.T) {
+	entities := map[string]*pb.Entity{}
+	for i := range 5 {
+		id := fmt.Sprintf("routine-%d", i)
+		entities[id] = &pb.Entity{Id: id, Priority: ptr(pb.Priority_PriorityRoutine)}
+	}
+	entities["urgent"] = &pb.Entity{Id: "urgent", Priority: ptr(pb.Priority_PriorityImmediate)}
+	world := testWorld(entities)
+
+	h := http.Header{}
+	h.Add(goclient.HeaderWatchPriorityRate, "routine=1")
+	h.Add(goclient.HeaderWatchPriorityRate, "immediate=100")
+	opts, err := parseRequestOptions(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewConsumer(world, nil, nil, nil)
+	c.setPriorityRates(opts.priorityRates)
+	c.setBurst(1)
+	for i := range 5 {
+		c.markDirty(fmt.Sprintf("routine-%d", i), pb.Priority_PriorityRoutine, pb.EntityChange_EntityChangeUpdated)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+
+	var mu sync.Mutex
+	var sent []string
+	go c.SenderLoop(ctx, func(ev *pb.EntityChangeEvent) error {
+		mu.Lock()
+		defer mu.Unlock()
+		sent = append(sent, ev.Entity.Id)
+		return nil
+	})
+
+	// routine is stuck on its budget, immediate comes in and is not held up by it
+	time.Sleep(50 * time.Millisecond)
+	c.markDirty("urgent", pb.Priority_PriorityImmediate, pb.EntityChange_EntityChangeUpdated)
+	time.Sleep(50 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(sent) != 1 || sent[0] != "urgent" {
+		t.Fatalf("expected only the immediate change within the routine budget, got %v", sent)
+	}
+	// one of them is popped and waiting for the budget
+	c.mu.Lock()
+	pending := c.pending()
+	c.mu.Unlock()
+	if pending != 4 {
+		t.Fatalf("expected routine changes to stay pending, %d are", pending)
+	}
+
+	h.Set(goclient.HeaderWatchPriorityRate, "flash=1")
+	if _, err := parseRequestOptions(h); err == nil {
+		t.Error("expected flash to be refused")
+	}
+}
+
 func TestSenderLoop_Filter(t *testing.T) {
 	filter := &pb.EntityFilter{Id: proto.String("e1")}
 
@@ -473,7 +571,7 @@ func TestBus_ProducerFasterThanConsumer(t *testing.T) {
 		id := fmt.Sprintf("e%d", i)
 		entity := &pb.Entity{Id: id, Priority: ptr(pb.Priority_PriorityRoutine)}
 		world.l.Lock()
-		world.head[id] = entity
+		world.head.Set(id, entity)
 		world.l.Unlock()
 		bus.Dirty(id, entity, pb.EntityChange_EntityChangeUpdated)
 	}
@@ -507,7 +605,7 @@ func TestConsumer_BurstPriorityUnderLoad(t *testing.T) {
 	for i := 0; i < 100; i++ {
 		id := fmt.Sprintf("low%d", i)
 		world.l.Lock()
-		world.head[id] = &pb.Entity{Id: id, Priority: ptr(pb.Priority_PriorityRoutine)}
+		world.head.Set(id, &pb.Entity{Id: id, Priority: ptr(pb.Priority_PriorityRoutine)})
 		world.l.Unlock()
 		c.markDirty(id, pb.Priority_PriorityRoutine, pb.EntityChange_EntityChangeUpdated)
 	}
@@ -625,7 +723,7 @@ func TestSenderLoop_ContextCancelledDuringRateLimit(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	var sent []*pb.EntityChangeEvent
-	done := make(chan error)
+	done := make(chan error, 1)
 	go func() {
 		done <- c.SenderLoop(ctx, func(ev *pb.EntityChangeEvent) error {
 			sent = append(sent, ev)
@@ -737,7 +835,7 @@ func TestSenderLoop_EntityReMarkedDuringLoop(t *testing.T) {
 			if version == 0 {
 				version++
 				world.l.Lock()
-				world.head["e1"] = &pb.Entity{Id: "e1", Label: ptr("v1")}
+				world.head.Set("e1", &pb.Entity{Id: "e1", Label: ptr("v1")})
 				world.l.Unlock()
 				c.markDirty("e1", pb.Priority_PriorityRoutine, pb.EntityChange_EntityChangeUpdated)
 			}
@@ -774,7 +872,7 @@ func TestBus_UnregisterDuringSenderLoop(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
 	defer cancel()
 
-	done := make(chan error)
+	done := make(chan error, 1)
 	go func() {
 		done <- c.SenderLoop(ctx, func(ev *pb.EntityChangeEvent) error { return nil })
 	}()
@@ -816,6 +914,67 @@ func TestSenderLoop_SendError(t *testing.T) {
 	}
 }
 
+func TestSenderLoop_SendPanic(t *testing.T) {
+	world := testWorld(map[string]*pb.Entity{"e1": {Id: "e1"}})
+	c := NewConsumer(world, nil, nil, nil)
+	world.bus.Register(c)
+	c.markDirty("e1", pb.Priority_PriorityRoutine, pb.EntityChange_EntityChangeUpdated)
+
+	err := c.SenderLoop(context.Background(), func(ev *pb.EntityChangeEvent) error {
+		panic("stream closed")
+	})
+
+	if err == nil {
+		t.Fatal("expected panic to be returned as error")
+	}
+	world.bus.mu.RLock()
+	_, registered := world.bus.consumers[c]
+	world.bus.mu.RUnlock()
+	if registered {
+		t.Error("expected panicking consumer to be unregistered")
+	}
+
+	// the bus still works for everyone else
+	world.bus.Dirty("e1", world.head.Get("e1"), pb.EntityChange_EntityChangeUpdated)
+}
+
+func TestSenderLoop_SlowConsumerDisconnected(t *testing.T) {
+	world := testWorld(map[string]*pb.Entity{"e1": {Id: "e1"}})
+	world.slowConsumerTimeout = time.Second
+	c := NewConsumer(world, nil, nil, nil)
+	world.bus.Register(c)
+	c.markDirty("e1", pb.Priority_PriorityRoutine, pb.EntityChange_EntityChangeUpdated)
+	c.backlogSince = time.Now().Add(-time.Minute)
+
+	err := c.SenderLoop(context.Background(), func(ev *pb.EntityChangeEvent) error {
+		t.Error("slow consumer should not be sent to")
+		return nil
+	})
+	if connect.CodeOf(err) != connect.CodeResourceExhausted {
+		t.Fatalf("expected resource exhausted, got %v", err)
+	}
+	if _, ok := world.bus.consumers[c]; ok {
+		t.Error("expected slow consumer to be unregistered")
+	}
+
+	// a consumer that drained its backlog is not behind
+	c = NewConsumer(world, nil, nil, nil)
+	c.markDirty("e1", pb.Priority_PriorityRoutine, pb.EntityChange_EntityChangeUpdated)
+	c.popNext()
+	c.popNext()
+	if c.behind(time.Now()) != 0 {
+		t.Error("expected empty consumer not to be behind")
+	}
+
+	c = NewConsumer(world, nil, nil, nil)
+	c.maxPending = 1
+	c.markDirty("e1", pb.Priority_PriorityRoutine, pb.EntityChange_EntityChangeUpdated)
+	c.markDirty("e2", pb.Priority_PriorityRoutine, pb.EntityChange_EntityChangeUpdated)
+	if err := c.SenderLoop(context.Background(), func(*pb.EntityChangeEvent) error { return nil }); connect.CodeOf(err) != connect.CodeResourceExhausted {
+		t.Fatalf("expected overflowing consumer to be disconnected, got %v", err)
+	}
+}
+
 func TestIsExpired(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -852,9 +1011,191 @@ func TestIsExpired(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := isExpired(tt.entity); got != tt.expected {
+			if got := isExpired(tt.entity, time.Now()); got != tt.expected {
 				t.Errorf("isExpired() = %v, want %v", got, tt.expected)
 			}
 		})
 	}
 }
+
+func TestConsumer_CachesGeoFilter(t *testing.T) {
+	triangle := &pb.GeoFilter{Geo: &pb.GeoFilter_Geometry{Geometry: &pb.Geometry{Wkb: []byte("POLYGON((10 50, 10.1 50, 10 50.1, 10 50))")}}}
+	c := NewConsumer(testWorld(nil), nil, nil, &pb.EntityFilter{Not: &pb.EntityFilter{Geo: triangle}})
+	if fg := c.geoms[triangle]; fg == nil || fg.bound != (orb.Bound{Min: orb.Point{10, 50}, Max: orb.Point{10.1, 50.1}}) {
+		t.Fatalf("expected the triangle to be decoded once for the watch, got %+v", fg)
+	}
+
+	if c.matches(&pb.Entity{Id: "in", Geo: &pb.GeoSpatialComponent{Longitude: 10.02, Latitude: 50.02}}) {
+		t.Error("expected the entity inside not to match the negated filter")
+	}
+	if !c.matches(&pb.Entity{Id: "out", Geo: &pb.GeoSpatialComponent{Longitude: 10.5, Latitude: 50.5}}) {
+		t.Error("expected the entity outside to match the negated filter")
+	}
+}
+
+func TestSenderLoop_RateLimitBurstAfterIdle(t *testing.T) {
+	limiter := &pb.WatchLimiter{
+		MaxMessagesPerSecond: ptr(uint64(10)),
+	}
+	entities := map[string]*pb.Entity{}
+	for i := range 8 {
+		id := fmt.Sprintf("e%d", i)
+		entities[id] = &pb.Entity{Id: id}
+	}
+	world := testWorld(entities)
+	c := NewConsumer(world, nil, limiter, nil)
+
+	var mu sync.Mutex
+	var sent int
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.SenderLoop(ctx, func(ev *pb.EntityChangeEvent) error {
+		mu.Lock()
+		sent++
+		mu.Unlock()
+		return nil
+	})
+
+	// idle long enough to save up the default burst of one second worth
+	time.Sleep(time.Second)
+	for id := range entities {
+		c.markDirty(id, pb.Priority_PriorityRoutine, pb.EntityChange_EntityChangeUpdated)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if sent != len(entities) {
+		t.Errorf("expected the backlog of %d to be sent at once after idling, got %d", len(entities), sent)
+	}
+}
+
+func TestWatchBoost_AppearancesAndExpiries(t *testing.T) {
+	w := testWorld(nil)
+	h := http.Header{}
+	h.Set(goclient.HeaderWatchBoost, "created=immediate,expired=immediate")
+	opts, err := parseRequestOptions(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// only immediate changes are wanted, routine movement is dropped
+	c := NewConsumer(w, nil, &pb.WatchLimiter{MinPriority: ptr(pb.Priority_PriorityImmediate)}, nil)
+	c.boostCreated, c.boostExpired = opts.boostCreated, opts.boostExpired
+	w.bus.Register(c)
+
+	push := func(id string) {
+		t.Helper()
+		if _, err := w.Push(context.Background(), connect.NewRequest(&pb.EntityChangeRequest{Changes: []*pb.Entity{{Id: id}}})); err != nil {
+			t.Fatal(err)
+		}
+	}
+	push("a")
+	// an update before the appearance is sent doesn't take the boost away
+	push("a")
+	id, change, priority, ok := c.popNext()
+	if !ok || id != "a" || change != pb.EntityChange_EntityChangeUpdated || priority != pb.Priority_PriorityImmediate {
+		t.Fatalf("expected boosted appearance, got %s %v %v", id, change, priority)
+	}
+
+	push("a")
+	if _, _, _, ok := c.popNext(); ok {
+		t.Fatal("expected routine update to be dropped")
+	}
+
+	w.l.Lock()
+	w.bury("a", w.head.Get("a"), goclient.ExpiryLifetime)
+	w.l.Unlock()
+	id, change, priority, ok = c.popNext()
+	if !ok || id != "a" || change != pb.EntityChange_EntityChangeExpired || priority != pb.Priority_PriorityImmediate {
+		t.Fatalf("expected boosted expiry, got %s %v %v", id, change, priority)
+	}
+
+	if _, err := parseRequestOptions(http.Header{"Hydra-Watch-Boost": {"moved=flash"}}); err == nil {
+		t.Error("expected unknown change type to be refused")
+	}
+}
+
+func TestWatchTransitions_EnterAndLeave(t *testing.T) {
+	server := NewTestServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	push := func(e *pb.Entity) {
+		t.Helper()
+		if _, err := server.Client.Push(ctx, &pb.EntityChangeRequest{Changes: []*pb.Entity{e}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	push(&pb.Entity{Id: "tasked", Taskable: &pb.TaskableComponent{}})
+	push(&pb.Entity{Id: "idle"})
+
+	filter := &pb.EntityFilter{Component: []uint32{23}}
+	stream, err := server.Client.WatchEntities(goclient.WithWatchTransitions(ctx), &pb.ListEntitiesRequest{Filter: filter})
+	if err != nil {
+		t.Fatal(err)
+	}
+	next := func() string {
+		t.Helper()
+		for {
+			ev, err := stream.Recv()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if id, ok := goclient.MatchEnter(ev); ok {
+				return "enter " + id
+			}
+			if ev.Entity != nil {
+				return fmt.Sprintf("%s %s", ev.T, ev.Entity.Id)
+			}
+		}
+	}
+
+	// matching when the watch starts doesn't count as entering
+	if got := next(); got != "EntityChangeUpdated tasked" {
+		t.Fatalf("expected the snapshot, got %q", got)
+	}
+
+	push(&pb.Entity{Id: "idle", Taskable: &pb.TaskableComponent{}})
+	for _, want := range []string{"enter idle", "EntityChangeUpdated idle"} {
+		if got := next(); got != want {
+			t.Fatalf("expected %q, got %q", want, got)
+		}
+	}
+
+	push(&pb.Entity{Id: "idle"})
+	if got := next(); got != "EntityChangeUnobserved idle" {
+		t.Fatalf("expected idle to leave, got %q", got)
+	}
+}
+
+func TestWatch_LiveOnlySkipsSnapshot(t *testing.T) {
+	server := NewTestServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	push := func(e *pb.Entity) {
+		t.Helper()
+		if _, err := server.Client.Push(ctx, &pb.EntityChangeRequest{Changes: []*pb.Entity{e}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	push(&pb.Entity{Id: "existing", Geo: &pb.GeoSpatialComponent{Latitude: 50, Longitude: 10}})
+
+	stream, err := server.Client.WatchEntities(goclient.WithLiveOnly(ctx), &pb.ListEntitiesRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the watch is registered once the ready event arrives
+	if ev, err := stream.Recv(); err != nil || ev.T != pb.EntityChange_EntityChangeInvalid {
+		t.Fatalf("expected the ready event, got %v %v", ev, err)
+	}
+
+	push(&pb.Entity{Id: "live", Geo: &pb.GeoSpatialComponent{Latitude: 50, Longitude: 10}})
+	ev, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev.Entity.GetId() != "live" {
+		t.Fatalf("expected only changes after the watch started, got %s %s", ev.T, ev.Entity.GetId())
+	}
+}
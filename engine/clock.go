@@ -0,0 +1,17 @@
+package engine
+
+import (
+	"time"
+)
+
+// Clock is where the engine reads the current time. Tests replace the wall clock
+// to move time precisely, e.g. past an expiry, instead of sleeping.
+// Throttled updates are still flushed by wall clock timers, and watch pacing,
+// like rate limits and slow consumer detection, is real time.
+type Clock interface {
+	Now() time.Time
+}
+
+type wallClock struct{}
+
+func (wallClock) Now() time.Time { return time.Now() }
@@ -0,0 +1,151 @@
+package engine
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"slices"
+
+	"github.com/paulmach/orb"
+	"github.com/projectqai/hydra/goclient"
+	"github.com/projectqai/hydra/policy"
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const (
+	maxClusterZoom = 20
+	// clusterCellShift makes cells a quarter of a map tile wide, 64px on a 256px tile map
+	clusterCellShift       = 2
+	defaultClusterMinCount = 2
+)
+
+type clusterCell struct {
+	entities []*pb.Entity
+	sum      orb.Point
+	bound    orb.Bound
+}
+
+// mercatorTile returns the x and y of the web mercator map tile containing p
+func mercatorTile(p orb.Point, zoom int) [2]int {
+	n := math.Exp2(float64(zoom))
+	lat := max(min(p[1], 85.0511), -85.0511) * math.Pi / 180
+	x := (p[0] + 180) / 360 * n
+	y := (1 - math.Log(math.Tan(lat)+1/math.Cos(lat))/math.Pi) / 2 * n
+	clamp := func(v float64) int {
+		return int(max(min(math.Floor(v), n-1), 0))
+	}
+	return [2]int{clamp(x), clamp(y)}
+}
+
+// clusters buckets the readable entities within bound that match filter into
+// cells at zoom. It returns the clusters largest first and the entities of
+// cells with fewer than minCount entities.
+func (s *WorldServer) clusters(ctx context.Context, ability *policy.Ability, zoom int, bound *orb.Bound, minCount int, filter *pb.EntityFilter) ([]goclient.Cluster, []*pb.Entity) {
+	s.l.RLock()
+	defer s.l.RUnlock()
+
+	cells := make(map[[2]int]*clusterCell)
+	for _, e := range s.head.All() {
+		p, ok := entityPoint(e)
+		if !ok || (bound != nil && !bound.Contains(p)) {
+			continue
+		}
+		if !s.matchesEntityFilter(e, filter) || !ability.CanRead(ctx, e, s.head.Marking(e.Id)) {
+			continue
+		}
+
+		tile := mercatorTile(p, zoom+clusterCellShift)
+		cell, ok := cells[tile]
+		if !ok {
+			cell = &clusterCell{bound: p.Bound()}
+			cells[tile] = cell
+		}
+		cell.entities = append(cell.entities, e)
+		cell.sum = orb.Point{cell.sum[0] + p[0], cell.sum[1] + p[1]}
+		cell.bound = cell.bound.Extend(p)
+	}
+
+	var clusters []goclient.Cluster
+	var entities []*pb.Entity
+	for _, cell := range cells {
+		n := len(cell.entities)
+		if n < minCount {
+			entities = append(entities, cell.entities...)
+			continue
+		}
+		clusters = append(clusters, goclient.Cluster{
+			Count:  n,
+			Lon:    cell.sum[0] / float64(n),
+			Lat:    cell.sum[1] / float64(n),
+			Bounds: [4]float64{cell.bound.Min[0], cell.bound.Min[1], cell.bound.Max[0], cell.bound.Max[1]},
+		})
+	}
+
+	slices.SortFunc(clusters, func(a, b goclient.Cluster) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Lon, b.Lon), cmp.Compare(a.Lat, b.Lat))
+	})
+	slices.SortFunc(entities, func(a, b *pb.Entity) int {
+		return cmp.Compare(a.Id, b.Id)
+	})
+	return clusters, entities
+}
+
+func (s *WorldServer) handleClusters(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req goclient.ClustersRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Zoom < 0 || req.Zoom > maxClusterZoom {
+		http.Error(w, "zoom must be between 0 and 20", http.StatusBadRequest)
+		return
+	}
+	if req.MinCount <= 0 {
+		req.MinCount = defaultClusterMinCount
+	}
+
+	var bound *orb.Bound
+	if len(req.Bounds) > 0 {
+		if len(req.Bounds) != 4 {
+			http.Error(w, "bounds must be [minLon, minLat, maxLon, maxLat]", http.StatusBadRequest)
+			return
+		}
+		bound = &orb.Bound{Min: orb.Point{req.Bounds[0], req.Bounds[1]}, Max: orb.Point{req.Bounds[2], req.Bounds[3]}}
+	}
+
+	var filter *pb.EntityFilter
+	if len(req.Filter) > 0 {
+		filter = &pb.EntityFilter{}
+		if err := protojson.Unmarshal(req.Filter, filter); err != nil {
+			http.Error(w, "invalid filter: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	ability := policy.ForHTTP(s.policy, r)
+	clusters, entities := s.clusters(r.Context(), ability, req.Zoom, bound, req.MinCount, filter)
+
+	resp := goclient.ClustersResponse{Clusters: clusters, Entities: make([]json.RawMessage, 0, len(entities))}
+	if resp.Clusters == nil {
+		resp.Clusters = []goclient.Cluster{}
+	}
+	for _, e := range entities {
+		entity, err := protojson.Marshal(e)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		resp.Entities = append(resp.Entities, entity)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,32 @@
+package engine
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/projectqai/hydra/policy"
+	pb "github.com/projectqai/proto/go"
+)
+
+func TestClusters_ZoomSplitsCells(t *testing.T) {
+	w := testWorld(map[string]*pb.Entity{
+		"a": {Id: "a", Geo: &pb.GeoSpatialComponent{Longitude: 10, Latitude: 50}},
+		"b": {Id: "b", Geo: &pb.GeoSpatialComponent{Longitude: 10.1, Latitude: 50}},
+		"c": {Id: "c", Geo: &pb.GeoSpatialComponent{Longitude: 11, Latitude: 50}},
+	})
+	ability := policy.For(context.Background(), nil, "")
+
+	clusters, entities := w.clusters(context.Background(), ability, 8, nil, 2, nil)
+	if len(clusters) != 1 || clusters[0].Count != 2 || math.Abs(clusters[0].Lon-10.05) > 1e-9 {
+		t.Errorf("expected a and b in one cluster centered between them, got %+v", clusters)
+	}
+	if len(entities) != 1 || entities[0].Id != "c" {
+		t.Errorf("expected c on its own, got %v", entities)
+	}
+
+	clusters, entities = w.clusters(context.Background(), ability, 12, nil, 2, nil)
+	if len(clusters) != 0 || len(entities) != 3 {
+		t.Errorf("expected no clusters when zoomed in, got %d clusters and %d entities", len(clusters), len(entities))
+	}
+}
@@ -0,0 +1,57 @@
+package engine
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+// confidences holds the confidence entities were last pushed with, see
+// goclient.HeaderConfidence. It has its own lock because list and watch filter
+// by it without holding the world lock.
+type confidences struct {
+	mu sync.RWMutex
+	m  map[string]float64
+}
+
+func (c *confidences) set(id string, confidence float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.m == nil {
+		c.m = make(map[string]float64)
+	}
+	c.m[id] = confidence
+}
+
+// get returns the confidence of an entity, ok is false if it was never pushed with one
+func (c *confidences) get(id string) (confidence float64, ok bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	confidence, ok = c.m[id]
+	return confidence, ok
+}
+
+func (c *confidences) delete(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.m, id)
+}
+
+// confident reports whether the entity has at least min confidence, entities
+// pushed without one are fully trusted
+func (s *WorldServer) confident(id string, min float64) bool {
+	if min <= 0 {
+		return true
+	}
+	confidence, ok := s.confidence.get(id)
+	return !ok || confidence >= min
+}
+
+// parseConfidence reads a confidence from 0 to 1
+func parseConfidence(v string) (float64, error) {
+	confidence, err := strconv.ParseFloat(v, 64)
+	if err != nil || !(confidence >= 0 && confidence <= 1) {
+		return 0, fmt.Errorf("confidence must be between 0 and 1")
+	}
+	return confidence, nil
+}
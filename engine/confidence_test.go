@@ -0,0 +1,43 @@
+package engine
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/projectqai/hydra/goclient"
+	pb "github.com/projectqai/proto/go"
+)
+
+func TestConfidence_MinConfidenceFilter(t *testing.T) {
+	server := NewTestServer(t)
+	ctx := context.Background()
+
+	push := func(ctx context.Context, id string) {
+		t.Helper()
+		if _, err := server.Client.Push(ctx, &pb.EntityChangeRequest{Changes: []*pb.Entity{{Id: id}}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	push(goclient.WithConfidence(ctx, 0.9), "strong")
+	push(goclient.WithConfidence(ctx, 0.3), "weak")
+	push(ctx, "unrated")
+	// a push without confidence keeps the one the entity has
+	push(ctx, "weak")
+
+	resp, err := server.Client.ListEntities(goclient.WithMinConfidence(ctx, 0.7), &pb.ListEntitiesRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, e := range resp.Entities {
+		ids = append(ids, e.Id)
+	}
+	if !slices.Equal(ids, []string{"strong", "unrated"}) {
+		t.Errorf("expected strong and unrated, got %v", ids)
+	}
+
+	if _, err := server.Client.Push(goclient.WithConfidence(ctx, 1.5), &pb.EntityChangeRequest{Changes: []*pb.Entity{{Id: "x"}}}); err == nil {
+		t.Error("expected a confidence above 1 to be rejected")
+	}
+}
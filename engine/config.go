@@ -0,0 +1,117 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"gopkg.in/yaml.v3"
+)
+
+// configController is the controller of entities declared in the config file.
+// Entities with a controller are not written to the world file, so the config
+// file stays the only place they are declared.
+var configController = &pb.ControllerRef{Id: "config", Name: "config"}
+
+// ConfigFile declares builtins for a fixed deployment, as an alternative to pushing
+// their config entities. JSON is accepted as well.
+//
+//	builtins:
+//	  - id: ais-norway
+//	    controller: ais
+//	    key: ais.stream.v0
+//	    value:
+//	      host: 153.44.253.27
+//	      port: 5631
+type ConfigFile struct {
+	Builtins []BuiltinConfig `yaml:"builtins"`
+}
+
+// BuiltinConfig is the config of one connector
+type BuiltinConfig struct {
+	// ID of the config entity, "<controller>-config" if empty
+	ID         string         `yaml:"id"`
+	Label      string         `yaml:"label"`
+	Controller string         `yaml:"controller"`
+	Key        string         `yaml:"key"`
+	Value      map[string]any `yaml:"value"`
+}
+
+// ParseConfigFile reads a config file and returns the config entities it declares
+func ParseConfigFile(b []byte) ([]*pb.Entity, error) {
+	var cfg ConfigFile
+	decoder := yaml.NewDecoder(bytes.NewReader(b))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("failed to decode config: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	entities := make([]*pb.Entity, 0, len(cfg.Builtins))
+	for i, b := range cfg.Builtins {
+		if b.Controller == "" || b.Key == "" {
+			return nil, fmt.Errorf("builtin %d: controller and key are required", i)
+		}
+		id := b.ID
+		if id == "" {
+			id = b.Controller + "-config"
+		}
+		if seen[id] {
+			return nil, fmt.Errorf("builtin %d: duplicate id %q, set a distinct id for every builtin of the same controller", i, id)
+		}
+		seen[id] = true
+
+		value, err := structpb.NewStruct(b.Value)
+		if err != nil {
+			return nil, fmt.Errorf("builtin %q: invalid value: %w", id, err)
+		}
+
+		entity := &pb.Entity{
+			Id:         id,
+			Controller: configController,
+			Config: &pb.ConfigurationComponent{
+				Controller: b.Controller,
+				Key:        b.Key,
+				Value:      value,
+			},
+		}
+		if b.Label != "" {
+			entity.Label = &b.Label
+		}
+		entities = append(entities, entity)
+	}
+	return entities, nil
+}
+
+// LoadConfigFile declares the builtins of a config file, see ConfigFile
+func (s *WorldServer) LoadConfigFile(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+	entities, err := ParseConfigFile(b)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	now := s.clock.Now()
+	for _, e := range entities {
+		e.Lifetime = &pb.Lifetime{From: timestamppb.New(now)}
+		s.store.Push(context.Background(), Event{Entity: e, Received: now})
+		s.head.Set(e.Id, e)
+		delete(s.tombstones, e.Id)
+		s.touch(e.Id)
+		s.bus.Dirty(e.Id, e, pb.EntityChange_EntityChangeUpdated)
+	}
+
+	slog.Info("declared builtins from config", "path", path, "builtins", len(entities))
+	return nil
+}
@@ -0,0 +1,33 @@
+package engine
+
+import "testing"
+
+func TestParseConfigFile(t *testing.T) {
+	entities, err := ParseConfigFile([]byte(`
+builtins:
+  - controller: adsblol
+    key: adsblol.location.v0
+    value: {latitude: 53.55, longitude: 9.93, radius_nm: 500}
+  - id: ais-norway
+    controller: ais
+    key: ais.stream.v0
+    value:
+      sources: [{host: 153.44.253.27, port: 5631}]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entities) != 2 || entities[0].Id != "adsblol-config" || entities[1].Id != "ais-norway" {
+		t.Fatalf("unexpected entities %v", entities)
+	}
+	if port := entities[1].Config.Value.Fields["sources"].GetListValue().Values[0].GetStructValue().Fields["port"].GetNumberValue(); port != 5631 {
+		t.Errorf("expected nested port 5631, got %v", port)
+	}
+
+	if _, err := ParseConfigFile([]byte("builtins:\n  - controller: ais\n    kye: ais.stream.v0\n")); err == nil {
+		t.Error("expected an unknown field to be rejected")
+	}
+	if _, err := ParseConfigFile([]byte("builtins:\n  - {controller: ais, key: a}\n  - {controller: ais, key: b}\n")); err == nil {
+		t.Error("expected duplicate default ids to be rejected")
+	}
+}
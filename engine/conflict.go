@@ -0,0 +1,154 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// ConflictPolicy decides between pushes of the same entity by different controllers
+type ConflictPolicy string
+
+const (
+	// ConflictLastWriter applies every push, the default
+	ConflictLastWriter ConflictPolicy = "last-writer"
+	// ConflictPriority ignores pushes with a lower Priority than the entity has,
+	// unset counts as routine. Among equal priorities the last writer wins.
+	ConflictPriority ConflictPolicy = "priority"
+	// ConflictFieldNewest merges pushes component by component, each component
+	// is kept from the push measured last, see lastMeasured. Components left out
+	// of a push are kept, so another controller can't remove them.
+	ConflictFieldNewest ConflictPolicy = "field-newest"
+)
+
+// ParseConflictPolicy reads the name of a ConflictPolicy
+func ParseConflictPolicy(name string) (ConflictPolicy, error) {
+	switch p := ConflictPolicy(name); p {
+	case ConflictLastWriter, ConflictPriority, ConflictFieldNewest:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown conflict policy %q, expected last-writer, priority or field-newest", name)
+}
+
+// ConflictConfig resolves pushes of an entity by another controller than the
+// one that wrote it last, e.g. a federated copy of an entity that a local feed
+// also produces, which would otherwise flap between both. Pushes by the same
+// controller and removals always apply.
+type ConflictConfig struct {
+	// Policy applies to all entities, default ConflictLastWriter
+	Policy ConflictPolicy
+	// IDs overrides Policy for entities whose id starts with a key, the longest key wins
+	IDs map[string]ConflictPolicy
+}
+
+func (c *ConflictConfig) policy(id string) ConflictPolicy {
+	if c == nil {
+		return ConflictLastWriter
+	}
+	policy, longest := c.Policy, -1
+	for prefix, p := range c.IDs {
+		if strings.HasPrefix(id, prefix) && len(prefix) > longest {
+			policy, longest = p, len(prefix)
+		}
+	}
+	if policy == "" {
+		return ConflictLastWriter
+	}
+	return policy
+}
+
+// resolveConflict applies the conflict policy to a pushed entity and reports
+// whether to apply it. Under ConflictFieldNewest it merges the current entity
+// into e. It records when the components of e were set. Caller must hold s.l.
+func (s *WorldServer) resolveConflict(e *pb.Entity) bool {
+	current, ok := s.head.Lookup(e.Id)
+	removal := e.Lifetime.Until.IsValid() && !e.Lifetime.Until.AsTime().After(s.clock.Now())
+	conflict := ok && !removal && !sameController(e, current)
+	at, _ := lastMeasured(e)
+
+	switch policy := s.conflict.policy(e.Id); {
+	case !conflict || policy == ConflictLastWriter:
+	case policy == ConflictPriority:
+		if priorityOf(e) < priorityOf(current) {
+			return false
+		}
+	case policy == ConflictFieldNewest:
+		times := s.fieldTimes[e.Id]
+		if times == nil {
+			// not pushed since the engine started, its components are as old as it is
+			measured, _ := lastMeasured(current)
+			times = fieldTimesOf(current, measured)
+		}
+		mergeNewest(e, current, at, times)
+		s.fieldTimes[e.Id] = times
+		return true
+	}
+	s.fieldTimes[e.Id] = fieldTimesOf(e, at)
+	return true
+}
+
+// sameController reports whether both entities were written by the same controller
+func sameController(a, b *pb.Entity) bool {
+	return a.GetController().GetId() == b.GetController().GetId() &&
+		a.GetController().GetName() == b.GetController().GetName()
+}
+
+// fieldTimesOf returns at for every component set in e
+func fieldTimesOf(e *pb.Entity, at time.Time) map[protoreflect.FieldNumber]time.Time {
+	times := make(map[protoreflect.FieldNumber]time.Time)
+	e.ProtoReflect().Range(func(fd protoreflect.FieldDescriptor, _ protoreflect.Value) bool {
+		if fd.Name() != "id" {
+			times[fd.Number()] = at
+		}
+		return true
+	})
+	return times
+}
+
+// mergeNewest fills e, measured at, with the components of current that are
+// newer according to times or missing in e, and updates times
+func mergeNewest(e, current *pb.Entity, at time.Time, times map[protoreflect.FieldNumber]time.Time) {
+	m, cm := e.ProtoReflect(), current.ProtoReflect()
+	fields := m.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if fd.Name() == "id" {
+			continue
+		}
+		if m.Has(fd) && !at.Before(times[fd.Number()]) {
+			times[fd.Number()] = at
+			continue
+		}
+		if cm.Has(fd) {
+			v := cm.Get(fd)
+			if fd.Message() != nil {
+				// the current entity is shared with readers
+				v = protoreflect.ValueOfMessage(proto.Clone(v.Message().Interface()).ProtoReflect())
+			}
+			m.Set(fd, v)
+		} else {
+			m.Clear(fd)
+		}
+	}
+}
+
+// componentTimes returns when each component of an entity was last set, by the
+// JSON name of the component. Caller must hold s.l.
+func (s *WorldServer) componentTimes(id string) map[string]time.Time {
+	times := s.fieldTimes[id]
+	if len(times) == 0 {
+		return nil
+	}
+	fields := (&pb.Entity{}).ProtoReflect().Descriptor().Fields()
+	named := make(map[string]time.Time, len(times))
+	for number, at := range times {
+		if fd := fields.ByNumber(number); fd != nil {
+			named[fd.JSONName()] = at
+		}
+	}
+	return named
+}
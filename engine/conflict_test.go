@@ -0,0 +1,41 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestConflict_PriorityAndFieldNewest(t *testing.T) {
+	w := testWorld(nil)
+	w.conflict = &ConflictConfig{Policy: ConflictPriority, IDs: map[string]ConflictPolicy{"fused-": ConflictFieldNewest}}
+	t0 := time.Now()
+	push := func(id, controller string, at time.Time, e *pb.Entity) {
+		t.Helper()
+		e.Id = id
+		e.Controller = &pb.ControllerRef{Id: controller, Name: controller}
+		e.Lifetime = &pb.Lifetime{From: timestamppb.New(at)}
+		if _, err := w.Push(context.Background(), connect.NewRequest(&pb.EntityChangeRequest{Changes: []*pb.Entity{e}})); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	immediate, routine := pb.Priority_PriorityImmediate, pb.Priority_PriorityRoutine
+	push("track-1", "radar", t0, &pb.Entity{Label: ptr("radar"), Priority: &immediate})
+	push("track-1", "federation", t0.Add(time.Second), &pb.Entity{Label: ptr("federation"), Priority: &routine})
+	if got := w.head.Get("track-1").GetLabel(); got != "radar" {
+		t.Errorf("expected the higher priority to win, got %q", got)
+	}
+
+	// the label is newer from federation, the position from the radar
+	push("fused-1", "radar", t0.Add(2*time.Second), &pb.Entity{Geo: &pb.GeoSpatialComponent{Longitude: 10, Latitude: 50}})
+	push("fused-1", "federation", t0.Add(time.Second), &pb.Entity{Label: ptr("fed"), Geo: &pb.GeoSpatialComponent{Longitude: 11, Latitude: 51}})
+	got := w.head.Get("fused-1")
+	if got.GetLabel() != "fed" || got.GetGeo().GetLongitude() != 10 {
+		t.Errorf("expected the label of federation at the position of the radar, got %v", got)
+	}
+}
@@ -2,11 +2,19 @@ package engine
 
 import (
 	"context"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"connectrpc.com/connect"
+	"github.com/projectqai/hydra/goclient"
+	"github.com/projectqai/hydra/metrics"
 	"github.com/projectqai/hydra/policy"
 	pb "github.com/projectqai/proto/go"
+	"golang.org/x/time/rate"
 )
 
 type Consumer struct {
@@ -14,12 +22,51 @@ type Consumer struct {
 	ability *policy.Ability
 	limiter *pb.WatchLimiter
 	filter  *pb.EntityFilter
+	options requestOptions
+
+	// geoms are the geometries of the geo filters in filter, decoded once for the watch
+	geoms geoFilterCache
+
+	// peer is the remote address, for logging
+	peer string
 
 	mu    sync.Mutex
 	dirty [4]map[string]pb.EntityChange // [priority]map[entityID]EntityChange
 
+	// backlogSince is when the dirty set last became non-empty, zero while it is empty
+	backlogSince time.Time
+	// maxPending limits the dirty set, zero is unlimited. overflow is set once it is exceeded.
+	maxPending int
+	overflow   bool
+
 	signal      chan struct{}
-	rateLimiter *time.Ticker
+	rateLimiter *rate.Limiter
+	// priorityLimiters are the budgets of priorities with their own rate, which
+	// don't count against rateLimiter, see goclient.HeaderWatchPriorityRate
+	priorityLimiters [4]*rate.Limiter
+
+	// boostCreated and boostExpired are the least priority appearances and
+	// expiries are sent with, see goclient.HeaderWatchBoost. boosted holds the
+	// pending changes that were raised, so later updates don't lower them again.
+	boostCreated pb.Priority
+	boostExpired pb.Priority
+	boosted      map[string]struct{}
+
+	// closing is closed when the watch is drained on shutdown, see DrainWatchers
+	closing <-chan struct{}
+
+	// lastCheckpoint is the last resume token sent, for watchers that asked for them
+	lastCheckpoint string
+
+	// matched holds the entities last sent as matching, for watchers that asked
+	// for transitions. Only the sender loop uses it once it runs.
+	matched map[string]struct{}
+
+	// counters for metrics, see metrics.ConsumerStats
+	sent        atomic.Int64
+	coalesced   atomic.Int64
+	rateLimited atomic.Int64
+	rateWait    atomic.Int64 // nanoseconds
 }
 
 func NewConsumer(world *WorldServer, ability *policy.Ability, limiter *pb.WatchLimiter, filter *pb.EntityFilter) *Consumer {
@@ -29,6 +76,11 @@ func NewConsumer(world *WorldServer, ability *policy.Ability, limiter *pb.WatchL
 		limiter: limiter,
 		filter:  filter,
 		signal:  make(chan struct{}, 1),
+		boosted: make(map[string]struct{}),
+		matched: make(map[string]struct{}),
+	}
+	if filter != nil {
+		c.geoms = compileGeoFilters(filter)
 	}
 
 	for i := range c.dirty {
@@ -36,13 +88,67 @@ func NewConsumer(world *WorldServer, ability *policy.Ability, limiter *pb.WatchL
 	}
 
 	if limiter != nil && limiter.MaxMessagesPerSecond != nil && *limiter.MaxMessagesPerSecond > 0 {
-		interval := time.Second / time.Duration(*limiter.MaxMessagesPerSecond)
-		c.rateLimiter = time.NewTicker(interval)
+		perSecond := *limiter.MaxMessagesPerSecond
+		// by default up to one second worth of messages can be saved up while idle
+		c.rateLimiter = rate.NewLimiter(rate.Limit(perSecond), int(perSecond))
+		c.setBurst(int(perSecond))
 	}
 
 	return c
 }
 
+// setBurst sets how many messages the consumer may send at once after being idle.
+// The bucket starts empty, so a new watch is paced from the start.
+func (c *Consumer) setBurst(burst int) {
+	if burst < 1 {
+		return
+	}
+	now := time.Now()
+	for _, l := range append([]*rate.Limiter{c.rateLimiter}, c.priorityLimiters[:]...) {
+		if l != nil {
+			l.SetBurstAt(now, burst)
+			l.ReserveN(now, burst)
+		}
+	}
+}
+
+// setPriorityRates gives priorities their own budget in messages per second,
+// by default with a burst of one second worth like the shared limit.
+// Unspecified priority counts as Routine, Flash is never limited.
+func (c *Consumer) setPriorityRates(rates map[pb.Priority]float64) {
+	now := time.Now()
+	for p, perSecond := range rates {
+		if p >= pb.Priority_PriorityFlash || perSecond <= 0 {
+			continue
+		}
+		burst := max(1, int(perSecond))
+		l := rate.NewLimiter(rate.Limit(perSecond), burst)
+		l.ReserveN(now, burst)
+		c.priorityLimiters[p] = l
+		if p == pb.Priority_PriorityRoutine && c.priorityLimiters[pb.Priority_PriorityUnspecified] == nil {
+			c.priorityLimiters[pb.Priority_PriorityUnspecified] = l
+		}
+	}
+}
+
+// limiterFor returns the budget a change of priority counts against, nil if unlimited
+func (c *Consumer) limiterFor(priority pb.Priority) *rate.Limiter {
+	if l := c.priorityLimiters[priority]; l != nil {
+		return l
+	}
+	return c.rateLimiter
+}
+
+// hasPriorityRates reports whether any priority has its own budget
+func (c *Consumer) hasPriorityRates() bool {
+	for _, l := range c.priorityLimiters {
+		if l != nil {
+			return true
+		}
+	}
+	return false
+}
+
 func (c *Consumer) minPriority() pb.Priority {
 	if c.limiter != nil && c.limiter.MinPriority != nil {
 		return *c.limiter.MinPriority
@@ -51,17 +157,54 @@ func (c *Consumer) minPriority() pb.Priority {
 }
 
 func (c *Consumer) markDirty(entityID string, priority pb.Priority, change pb.EntityChange) {
+	boost := change == pb.EntityChange_EntityChangeExpired && c.boostExpired > priority
+	if boost {
+		priority = c.boostExpired
+	}
+	c.mark(entityID, priority, change, boost)
+}
+
+// markCreated marks an entity that just appeared in the head
+func (c *Consumer) markCreated(entityID string, priority pb.Priority) {
+	boost := c.boostCreated > priority
+	if boost {
+		priority = c.boostCreated
+	}
+	c.mark(entityID, priority, pb.EntityChange_EntityChangeUpdated, boost)
+}
+
+func (c *Consumer) mark(entityID string, priority pb.Priority, change pb.EntityChange, boost bool) {
 	if priority < c.minPriority() {
 		return
 	}
 
 	c.mu.Lock()
 
+	if c.backlogSince.IsZero() {
+		c.backlogSince = time.Now()
+	}
+
 	// just in case priority has changed, reseat it
 	for p := range c.dirty {
-		delete(c.dirty[p], entityID)
+		if _, ok := c.dirty[p][entityID]; ok {
+			c.coalesced.Add(1)
+			delete(c.dirty[p], entityID)
+			// an appearance or expiry not sent yet keeps its boost
+			if _, ok := c.boosted[entityID]; ok && pb.Priority(p) > priority {
+				priority, boost = pb.Priority(p), true
+			}
+		}
 	}
 	c.dirty[priority][entityID] = change
+	if boost {
+		c.boosted[entityID] = struct{}{}
+	} else {
+		delete(c.boosted, entityID)
+	}
+
+	if c.maxPending > 0 && c.pending() > c.maxPending {
+		c.overflow = true
+	}
 
 	c.mu.Unlock()
 
@@ -84,72 +227,299 @@ func (c *Consumer) popNext() (entityID string, change pb.EntityChange, priority
 		}
 		for id, ch := range c.dirty[p] {
 			delete(c.dirty[p], id)
+			delete(c.boosted, id)
 			return id, ch, p, true
 		}
 	}
+	c.backlogSince = time.Time{}
 	return "", 0, 0, false
 }
 
-func (c *Consumer) SenderLoop(ctx context.Context, send func(*pb.EntityChangeEvent) error) error {
+// pending is the number of unsent changes. Caller must hold c.mu.
+func (c *Consumer) pending() int {
+	n := 0
+	for p := range c.dirty {
+		n += len(c.dirty[p])
+	}
+	return n
+}
+
+// behind is how long the consumer has had unsent changes without catching up
+func (c *Consumer) behind(now time.Time) time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.backlogSince.IsZero() {
+		return 0
+	}
+	return now.Sub(c.backlogSince)
+}
+
+// SenderLoop sends dirty entities until ctx is done or send fails.
+// A panic in send or filtering only ends this consumer, it is unregistered and
+// returned as error.
+func (c *Consumer) SenderLoop(ctx context.Context, send func(*pb.EntityChangeEvent) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Error("watch consumer panicked, disconnecting", "peer", c.peer, "panic", r, "stack", string(debug.Stack()))
+			c.world.bus.Unregister(c)
+			err = connect.NewError(connect.CodeInternal, fmt.Errorf("internal error: %v", r))
+		}
+	}()
+
 	for {
 		if ctx.Err() != nil {
 			return ctx.Err()
 		}
 
+		if err := c.checkSlow(); err != nil {
+			return err
+		}
+
 		entityID, change, priority, ok := c.popNext()
 		if !ok {
+			if c.options.resume != "" {
+				if token, ok := c.checkpoint(); ok {
+					if err := send(checkpointEvent(token)); err != nil {
+						return err
+					}
+					continue
+				}
+			}
 			select {
 			case <-ctx.Done():
 				return ctx.Err()
 			case <-c.signal:
 				continue
+			case <-c.closing:
+				// everything pending is sent, tell the client before ending the stream
+				if err := send(shutdownEvent()); err != nil {
+					return err
+				}
+				return errShuttingDown
 			}
 		}
 
-		entity := c.world.GetHead(entityID)
+		entity, marking, _ := c.world.head.Entry(entityID)
+		if entity == nil {
+			// gone from the head, send the last state with the expiry
+			if t, ok := c.world.tombstoneOf(entityID); ok {
+				entity, marking = t.entity, t.marking
+				change = pb.EntityChange_EntityChangeExpired
+			}
+		}
 
-		// Check read policy
-		if entity != nil && c.ability != nil && !c.ability.CanRead(ctx, entity) {
-			continue
+		// Check read policy and the requested clearance
+		if entity != nil {
+			if !c.options.cleared(marking) || (c.ability != nil && !c.ability.CanRead(ctx, entity, marking)) {
+				// no longer readable, don't send more than the id
+				if err := c.sendLeave(send, entityID, &pb.Entity{Id: entityID}); err != nil {
+					return err
+				}
+				continue
+			}
 		}
 
 		if priority == pb.Priority_PriorityFlash {
+			if entity != nil {
+				entity = c.options.present(entity, c.world.now())
+			}
 			if entity != nil || change == pb.EntityChange_EntityChangeExpired {
+				if err := c.sendExpiryReason(send, entityID, entity, change); err != nil {
+					return err
+				}
 				if err := send(&pb.EntityChangeEvent{Entity: entity, T: change}); err != nil {
 					return err
 				}
+				c.sent.Add(1)
+			}
+			if change == pb.EntityChange_EntityChangeExpired {
+				delete(c.matched, entityID)
 			}
 			continue
 		}
 
-		if entity == nil || isExpired(entity) {
+		if entity == nil || isExpired(entity, c.world.clock.Now()) {
 			change = pb.EntityChange_EntityChangeExpired
 		}
 
-		if entity != nil && c.filter != nil && !c.world.matchesEntityFilter(entity, c.filter) {
+		if entity != nil && !c.matches(entity) {
+			if err := c.sendLeave(send, entityID, c.options.present(entity, c.world.now())); err != nil {
+				return err
+			}
 			continue
 		}
 
-		if c.rateLimiter != nil {
-			select {
-			case <-ctx.Done():
-				return ctx.Err()
-			case <-c.rateLimiter.C:
+		if limiter := c.limiterFor(priority); limiter != nil && !limiter.Allow() {
+			c.rateLimited.Add(1)
+			start := time.Now()
+			if c.hasPriorityRates() {
+				// don't hold up more urgent changes, which have their own budget
+				sent, err := c.waitOrRequeue(ctx, limiter, entityID, change, priority)
+				c.rateWait.Add(int64(time.Since(start)))
+				if err != nil {
+					return err
+				}
+				if !sent {
+					continue
+				}
+			} else {
+				if err := limiter.Wait(ctx); err != nil {
+					if ctx.Err() != nil {
+						return ctx.Err()
+					}
+					return err
+				}
+				c.rateWait.Add(int64(time.Since(start)))
 			}
 		}
 
+		if entity != nil {
+			entity = c.options.present(entity, c.world.now())
+		}
+
+		if err := c.sendExpiryReason(send, entityID, entity, change); err != nil {
+			return err
+		}
+		if err := c.sendEnter(send, entityID, change); err != nil {
+			return err
+		}
 		if err := send(&pb.EntityChangeEvent{Entity: entity, T: change}); err != nil {
 			return err
 		}
+		c.sent.Add(1)
+	}
+}
+
+// matches reports whether entity passes the filter and options of the watch
+func (c *Consumer) matches(entity *pb.Entity) bool {
+	if c.filter != nil && !c.world.matchesEntityFilterCached(entity, c.filter, c.options.uncertaintySigma, c.geoms) {
+		return false
+	}
+	if !c.world.confident(entity.Id, c.options.minConfidence) || !c.world.seenEnough(entity.Id, c.options.minSeen) {
+		return false
+	}
+	return c.options.matches(entity)
+}
+
+// visible reports whether the watcher would be sent entity as it is now
+func (c *Consumer) visible(ctx context.Context, entity *pb.Entity, marking policy.Marking) bool {
+	if !c.options.cleared(marking) || (c.ability != nil && !c.ability.CanRead(ctx, entity, marking)) {
+		return false
+	}
+	return !isExpired(entity, c.world.clock.Now()) && c.matches(entity)
+}
+
+// sendEnter tells watchers that asked for transitions that an entity started
+// matching, before it is sent, and forgets expired ones
+func (c *Consumer) sendEnter(send func(*pb.EntityChangeEvent) error, id string, change pb.EntityChange) error {
+	if change != pb.EntityChange_EntityChangeUpdated {
+		delete(c.matched, id)
+		return nil
+	}
+	if _, ok := c.matched[id]; ok || !c.options.transitions {
+		return nil
+	}
+	c.matched[id] = struct{}{}
+	return send(&pb.EntityChangeEvent{
+		T:      pb.EntityChange_EntityChangeInvalid,
+		Entity: &pb.Entity{Id: goclient.MatchEnterPrefix + id},
+	})
+}
+
+// sendLeave tells watchers that asked for transitions that an entity they were
+// sent stopped matching
+func (c *Consumer) sendLeave(send func(*pb.EntityChangeEvent) error, id string, entity *pb.Entity) error {
+	if _, ok := c.matched[id]; !ok {
+		return nil
+	}
+	delete(c.matched, id)
+	if entity == nil {
+		entity = &pb.Entity{Id: id}
+	}
+	if err := send(&pb.EntityChangeEvent{Entity: entity, T: pb.EntityChange_EntityChangeUnobserved}); err != nil {
+		return err
+	}
+	c.sent.Add(1)
+	return nil
+}
+
+// sendExpiryReason tells the watcher why an entity expired if it asked for it
+func (c *Consumer) sendExpiryReason(send func(*pb.EntityChangeEvent) error, id string, entity *pb.Entity, change pb.EntityChange) error {
+	if !c.options.expiryReason || change != pb.EntityChange_EntityChangeExpired {
+		return nil
+	}
+	reason := c.world.expiryReason(id, entity)
+	return send(&pb.EntityChangeEvent{
+		T:      pb.EntityChange_EntityChangeInvalid,
+		Entity: &pb.Entity{Id: goclient.ExpiryReasonPrefix + reason + ":" + id},
+	})
+}
+
+// waitOrRequeue waits for a token of limiter, but gives up when another change
+// comes in, which may be of a priority with budget left. The change is then put
+// back for later and false returned.
+func (c *Consumer) waitOrRequeue(ctx context.Context, limiter *rate.Limiter, entityID string, change pb.EntityChange, priority pb.Priority) (bool, error) {
+	r := limiter.Reserve()
+	timer := time.NewTimer(r.Delay())
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true, nil
+	case <-ctx.Done():
+		r.Cancel()
+		return false, ctx.Err()
+	case <-c.signal:
+		r.Cancel()
+		c.requeue(entityID, change, priority)
+		return false, nil
+	}
+}
+
+// requeue puts back a popped change, unless a newer one of the entity came in meanwhile
+func (c *Consumer) requeue(entityID string, change pb.EntityChange, priority pb.Priority) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for p := range c.dirty {
+		if _, ok := c.dirty[p][entityID]; ok {
+			return
+		}
+	}
+	if c.backlogSince.IsZero() {
+		c.backlogSince = time.Now()
+	}
+	c.dirty[priority][entityID] = change
+}
+
+// checkSlow unregisters the consumer and returns an error if it fell too far behind,
+// either in time or in number of pending changes
+func (c *Consumer) checkSlow() error {
+	c.mu.Lock()
+	overflow := c.overflow
+	c.mu.Unlock()
+
+	var err error
+	if overflow {
+		err = fmt.Errorf("consumer has more than %d pending changes", c.maxPending)
+	} else if timeout := c.world.slowConsumerTimeout; timeout > 0 && c.rateLimiter == nil && !c.hasPriorityRates() && c.behind(time.Now()) > timeout {
+		err = fmt.Errorf("consumer did not keep up for %s", timeout)
 	}
+	if err == nil {
+		return nil
+	}
+
+	slog.Warn("disconnecting slow watch consumer", "peer", c.peer, "reason", err)
+	metrics.IncDroppedConsumers()
+	c.world.bus.Unregister(c)
+	return connect.NewError(connect.CodeResourceExhausted, err)
 }
 
-func isExpired(entity *pb.Entity) bool {
+func isExpired(entity *pb.Entity, now time.Time) bool {
 	if entity.Lifetime == nil || entity.Lifetime.Until == nil {
 		return false
 	}
 	if !entity.Lifetime.Until.IsValid() {
 		return false
 	}
-	return time.Now().After(entity.Lifetime.Until.AsTime())
+	return now.After(entity.Lifetime.Until.AsTime())
 }
@@ -0,0 +1,51 @@
+package engine
+
+import (
+	"net/http"
+
+	"github.com/rs/cors"
+)
+
+// CORSConfig sets which web pages may call the engine from a browser. The view
+// is served by the engine itself and doesn't need it, only pages of other
+// origins do, e.g. a dashboard in development.
+type CORSConfig struct {
+	// Origins may contain one * each, e.g. "https://*.example.org". Empty
+	// allows pages on this machine only, "*" allows any page.
+	Origins []string
+	// Methods defaults to the methods the engine serves
+	Methods []string
+	// Headers defaults to any header
+	Headers []string
+}
+
+// DefaultCORSOrigins are the origins allowed if none are configured
+var DefaultCORSOrigins = []string{
+	"http://localhost", "http://localhost:*",
+	"https://localhost", "https://localhost:*",
+	"http://127.0.0.1", "http://127.0.0.1:*",
+	"https://127.0.0.1", "https://127.0.0.1:*",
+	"http://[::1]", "http://[::1]:*",
+}
+
+// Handler wraps h to answer CORS requests, a nil config uses the defaults
+func (c *CORSConfig) Handler(h http.Handler) http.Handler {
+	var cfg CORSConfig
+	if c != nil {
+		cfg = *c
+	}
+	if len(cfg.Origins) == 0 {
+		cfg.Origins = DefaultCORSOrigins
+	}
+	if len(cfg.Methods) == 0 {
+		cfg.Methods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
+	}
+	if len(cfg.Headers) == 0 {
+		cfg.Headers = []string{"*"}
+	}
+	return cors.New(cors.Options{
+		AllowedOrigins: cfg.Origins,
+		AllowedMethods: cfg.Methods,
+		AllowedHeaders: cfg.Headers,
+	}).Handler(h)
+}
@@ -0,0 +1,29 @@
+package engine
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCORS_DefaultsToThisMachine(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	allowed := func(cfg *CORSConfig, origin string) bool {
+		req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+		req.Header.Set("Origin", origin)
+		rec := httptest.NewRecorder()
+		cfg.Handler(ok).ServeHTTP(rec, req)
+		return rec.Header().Get("Access-Control-Allow-Origin") != ""
+	}
+
+	var defaults *CORSConfig
+	if !allowed(defaults, "http://localhost:5173") || !allowed(defaults, "http://127.0.0.1:8080") {
+		t.Fatal("expected pages on this machine to be allowed by default")
+	}
+	if allowed(defaults, "https://example.org") || allowed(defaults, "http://localhost.example.org") {
+		t.Fatal("expected other pages to be rejected by default")
+	}
+	if !allowed(&CORSConfig{Origins: []string{"*"}}, "https://example.org") {
+		t.Fatal("expected * to allow any page")
+	}
+}
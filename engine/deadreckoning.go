@@ -0,0 +1,58 @@
+package engine
+
+import (
+	"time"
+
+	"github.com/paulmach/orb"
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/proto"
+)
+
+// lastMeasured is when the entity's position was last known to be accurate
+func lastMeasured(entity *pb.Entity) (time.Time, bool) {
+	if entity.Detection != nil && entity.Detection.LastMeasured.IsValid() {
+		return entity.Detection.LastMeasured.AsTime(), true
+	}
+	if entity.Lifetime != nil && entity.Lifetime.From.IsValid() {
+		return entity.Lifetime.From.AsTime(), true
+	}
+	return time.Time{}, false
+}
+
+// deadReckon returns a copy of entity with its position extrapolated to now
+// using Kinematics.VelocityEnu. Entities without velocity, or last measured
+// longer than maxAge ago, are returned unchanged. The stored entity is never modified.
+func deadReckon(entity *pb.Entity, now time.Time, maxAge time.Duration) *pb.Entity {
+	if entity.Geo == nil || entity.Kinematics == nil || entity.Kinematics.VelocityEnu == nil {
+		return entity
+	}
+
+	measured, ok := lastMeasured(entity)
+	if !ok {
+		return entity
+	}
+	elapsed := now.Sub(measured)
+	if elapsed <= 0 || elapsed > maxAge {
+		return entity
+	}
+
+	v := entity.Kinematics.VelocityEnu
+	dt := elapsed.Seconds()
+	var east, north float64
+	if v.East != nil {
+		east = *v.East * dt
+	}
+	if v.North != nil {
+		north = *v.North * dt
+	}
+
+	out := proto.Clone(entity).(*pb.Entity)
+	p := offsetENU(orb.Point{entity.Geo.Longitude, entity.Geo.Latitude}, east, north)
+	out.Geo.Longitude = p[0]
+	out.Geo.Latitude = p[1]
+	if v.Up != nil && out.Geo.Altitude != nil {
+		alt := *out.Geo.Altitude + *v.Up*dt
+		out.Geo.Altitude = &alt
+	}
+	return out
+}
@@ -0,0 +1,40 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestDeadReckon(t *testing.T) {
+	now := time.Now()
+	entity := &pb.Entity{
+		Id:       "e1",
+		Lifetime: &pb.Lifetime{From: timestamppb.New(now.Add(-10 * time.Second))},
+		Geo:      &pb.GeoSpatialComponent{Longitude: 10, Latitude: 50, Altitude: ptr(100.0)},
+		Kinematics: &pb.KinematicsComponent{
+			VelocityEnu: &pb.KinematicsEnu{North: ptr(10.0), Up: ptr(1.0)},
+		},
+	}
+
+	out := deadReckon(entity, now, time.Minute)
+	if out == entity {
+		t.Fatal("expected a copy")
+	}
+	if entity.Geo.Latitude != 50 {
+		t.Error("stored entity was modified")
+	}
+	// 100m north is roughly 0.0009 degrees
+	if d := out.Geo.Latitude - 50; d < 0.00089 || d > 0.00091 {
+		t.Errorf("expected ~0.0009 degrees north, got %v", d)
+	}
+	if *out.Geo.Altitude != 110 {
+		t.Errorf("expected altitude 110, got %v", *out.Geo.Altitude)
+	}
+
+	if out := deadReckon(entity, now, 5*time.Second); out != entity {
+		t.Error("expected no extrapolation beyond max age")
+	}
+}
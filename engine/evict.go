@@ -0,0 +1,61 @@
+package engine
+
+import (
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/projectqai/hydra/goclient"
+	pb "github.com/projectqai/proto/go"
+)
+
+// evictHeadroom is the share of MaxEntities freed at once when the cap is hit,
+// so a world at the cap doesn't scan all entities on every push
+const evictHeadroom = 0.01
+
+// evict expires entities while the head holds more than maxEntities. Routine
+// entities go before Immediate ones, the least recently updated first. Flash
+// entities and configurations are never evicted, the cap may be exceeded by them.
+// Caller must hold s.l.
+func (s *WorldServer) evict() {
+	if s.maxEntities <= 0 || s.head.Len() <= s.maxEntities {
+		return
+	}
+
+	type candidate struct {
+		id       string
+		priority pb.Priority
+		seen     time.Time
+	}
+	var candidates []candidate
+	for id, e := range s.head.All() {
+		if e.Config != nil || e.GetPriority() >= pb.Priority_PriorityFlash || goclient.IsSticky(e) {
+			continue
+		}
+		priority := e.GetPriority()
+		if priority == pb.Priority_PriorityUnspecified {
+			priority = pb.Priority_PriorityRoutine
+		}
+		candidates = append(candidates, candidate{id: id, priority: priority, seen: s.lastSeen[id]})
+	}
+	slices.SortFunc(candidates, func(a, b candidate) int {
+		if a.priority != b.priority {
+			return int(a.priority) - int(b.priority)
+		}
+		return a.seen.Compare(b.seen)
+	})
+
+	n := s.head.Len() - s.maxEntities + int(float64(s.maxEntities)*evictHeadroom)
+	n = min(n, len(candidates))
+	evicted := make([]string, 0, n)
+	for _, c := range candidates[:n] {
+		s.bury(c.id, s.head.Get(c.id), goclient.ExpiryEvicted)
+		evicted = append(evicted, c.id)
+	}
+	if s.cascadeExpiry {
+		s.cascadeExpire(evicted)
+	}
+	s.evicted.Add(uint64(len(evicted)))
+
+	slog.Warn("world is full, evicted least recently updated entities", "evicted", len(evicted), "max", s.maxEntities)
+}
@@ -0,0 +1,51 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	pb "github.com/projectqai/proto/go"
+)
+
+func TestMaxEntities_EvictsLeastRecentlyUpdated(t *testing.T) {
+	w := testWorld(nil)
+	w.maxEntities = 3
+	clock := &testClock{now: time.Now()}
+	w.clock = clock
+	c := NewConsumer(w, nil, nil, nil)
+	w.bus.Register(c)
+
+	push := func(entities ...*pb.Entity) {
+		t.Helper()
+		if _, err := w.Push(context.Background(), connect.NewRequest(&pb.EntityChangeRequest{Changes: entities})); err != nil {
+			t.Fatal(err)
+		}
+		clock.Advance(time.Second)
+	}
+	push(&pb.Entity{Id: "config", Config: &pb.ConfigurationComponent{Key: "x"}})
+	push(&pb.Entity{Id: "urgent", Priority: ptr(pb.Priority_PriorityImmediate)})
+	push(&pb.Entity{Id: "old"})
+	push(&pb.Entity{Id: "new"})
+
+	if w.head.Len() != 3 || w.head.Get("old") != nil {
+		t.Fatalf("expected the oldest routine entity to be evicted, head has %v", w.head)
+	}
+	if _, ok := w.tombstoneOf("old"); !ok {
+		t.Fatal("expected evicted entity to be buried")
+	}
+	if change, ok := c.dirty[pb.Priority_PriorityRoutine]["old"]; !ok || change != pb.EntityChange_EntityChangeExpired {
+		t.Fatalf("expected watchers to see the eviction, got %v", change)
+	}
+
+	// without routine entities left, immediate ones go, configurations stay
+	push(&pb.Entity{Id: "newer", Priority: ptr(pb.Priority_PriorityImmediate)})
+	if w.head.Get("new") != nil || w.head.Get("config") == nil || w.head.Get("urgent") == nil {
+		t.Fatalf("unexpected head after second eviction: %v", w.head)
+	}
+	push(&pb.Entity{Id: "newest", Priority: ptr(pb.Priority_PriorityImmediate)})
+	if w.head.Get("urgent") != nil || w.head.Get("config") == nil || w.evicted.Load() != 3 {
+		t.Fatalf("expected least recently updated immediate entity to go, head %v", w.head)
+	}
+}
@@ -1,9 +1,15 @@
 package engine
 
 import (
+	"fmt"
+	"math"
+
+	"github.com/projectqai/hydra/goclient"
 	pb "github.com/projectqai/proto/go"
 
 	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geo"
+	"github.com/paulmach/orb/planar"
 )
 
 func entityHasComponent(entity *pb.Entity, field uint32) bool {
@@ -34,6 +40,12 @@ func entityHasComponent(entity *pb.Entity, field uint32) bool {
 		return entity.Locator != nil
 	case 23:
 		return entity.Taskable != nil
+	case 24:
+		return entity.Kinematics != nil
+	case 25:
+		return entity.Shape != nil
+	case 26:
+		return entity.Classification != nil
 	case 31:
 		return entity.Config != nil
 	}
@@ -79,77 +91,302 @@ func taskableContainsAssignee(taskable *pb.TaskableComponent, assignee *pb.Taska
 	return false
 }
 
-func planarToOrb(planar *pb.PlanarGeometry) orb.Geometry {
-	if planar == nil {
+// validateGeoFilter rejects filter geometries that can't be decoded or are
+// degenerate, e.g. a polygon of a single point or a self-intersecting ring.
+// They would otherwise match everything or nothing without telling the client.
+func validateGeoFilter(filter *pb.EntityFilter) error {
+	if filter == nil {
 		return nil
 	}
+	if g, ok := filter.Geo.GetGeo().(*pb.GeoFilter_Geometry); ok {
+		if err := validateFilterGeometry(g.Geometry); err != nil {
+			return fmt.Errorf("invalid filter geometry: %w", err)
+		}
+	}
+	for _, or := range filter.Or {
+		if err := validateGeoFilter(or); err != nil {
+			return err
+		}
+	}
+	return validateGeoFilter(filter.Not)
+}
 
-	switch p := planar.Plane.(type) {
-	case *pb.PlanarGeometry_Point:
-		if p.Point != nil {
-			return orb.Point{p.Point.Longitude, p.Point.Latitude}
+func validateFilterGeometry(g *pb.Geometry) error {
+	var geom orb.Geometry
+	if g.GetPlanar() != nil {
+		geom = goclient.PlanarToOrb(g.Planar)
+	} else if len(g.GetWkb()) > 0 {
+		var err error
+		if geom, err = goclient.DecodeGeometry(g.Wkb); err != nil {
+			return err
 		}
-	case *pb.PlanarGeometry_Line:
-		if p.Line != nil && len(p.Line.Points) > 0 {
-			line := make(orb.LineString, len(p.Line.Points))
-			for i, pt := range p.Line.Points {
-				line[i] = orb.Point{pt.Longitude, pt.Latitude}
+	}
+	if geom == nil {
+		return fmt.Errorf("geometry is empty")
+	}
+	return validateGeometry(geom)
+}
+
+// validateGeometry checks coordinates, that lines have two points and that
+// polygon rings enclose an area without crossing themselves
+func validateGeometry(g orb.Geometry) error {
+	switch g := g.(type) {
+	case orb.Point:
+		return validatePoint(g)
+	case orb.MultiPoint:
+		if len(g) == 0 {
+			return fmt.Errorf("multi-point is empty")
+		}
+		for _, p := range g {
+			if err := validatePoint(p); err != nil {
+				return err
 			}
-			return line
 		}
-	case *pb.PlanarGeometry_Polygon:
-		if p.Polygon != nil && p.Polygon.Outer != nil && len(p.Polygon.Outer.Points) > 0 {
-			outer := make(orb.Ring, len(p.Polygon.Outer.Points))
-			for i, pt := range p.Polygon.Outer.Points {
-				outer[i] = orb.Point{pt.Longitude, pt.Latitude}
+	case orb.LineString:
+		if n := len(distinctPoints(g)); n < 2 {
+			return fmt.Errorf("line has %d distinct points, needs at least 2", n)
+		}
+		for _, p := range g {
+			if err := validatePoint(p); err != nil {
+				return err
 			}
-			poly := orb.Polygon{outer}
-
-			// Add holes if present
-			for _, hole := range p.Polygon.Holes {
-				if len(hole.Points) > 0 {
-					holeRing := make(orb.Ring, len(hole.Points))
-					for i, pt := range hole.Points {
-						holeRing[i] = orb.Point{pt.Longitude, pt.Latitude}
-					}
-					poly = append(poly, holeRing)
+		}
+	case orb.MultiLineString:
+		if len(g) == 0 {
+			return fmt.Errorf("multi-line is empty")
+		}
+		for _, l := range g {
+			if err := validateGeometry(l); err != nil {
+				return err
+			}
+		}
+	case orb.Polygon:
+		if len(g) == 0 {
+			return fmt.Errorf("polygon is empty")
+		}
+		for i, r := range g {
+			if err := validateRing(r); err != nil {
+				if i > 0 {
+					return fmt.Errorf("polygon hole %d: %w", i, err)
 				}
+				return fmt.Errorf("polygon: %w", err)
+			}
+		}
+	case orb.MultiPolygon:
+		if len(g) == 0 {
+			return fmt.Errorf("multi-polygon is empty")
+		}
+		for _, p := range g {
+			if err := validateGeometry(p); err != nil {
+				return err
+			}
+		}
+	case orb.Collection:
+		if len(g) == 0 {
+			return fmt.Errorf("collection is empty")
+		}
+		for _, part := range g {
+			if err := validateGeometry(part); err != nil {
+				return err
 			}
-			return poly
 		}
 	}
+	return nil
+}
+
+func validatePoint(p orb.Point) error {
+	if math.IsNaN(p[0]) || math.IsInf(p[0], 0) || math.IsNaN(p[1]) || math.IsInf(p[1], 0) {
+		return fmt.Errorf("point %v is not a number", p)
+	}
+	if p[1] < -90 || p[1] > 90 {
+		return fmt.Errorf("latitude %v is out of range", p[1])
+	}
+	return nil
+}
 
+func validateRing(r orb.Ring) error {
+	for _, p := range r {
+		if err := validatePoint(p); err != nil {
+			return err
+		}
+	}
+	points := distinctPoints(r)
+	if len(points) > 1 && points[0] == points[len(points)-1] {
+		points = points[:len(points)-1]
+	}
+	if len(points) < 3 {
+		return fmt.Errorf("ring has %d distinct points, needs at least 3", len(points))
+	}
+	if ringSelfIntersects(points) {
+		return fmt.Errorf("ring intersects itself")
+	}
+	if planar.Area(orb.Ring(points)) == 0 {
+		return fmt.Errorf("ring encloses no area")
+	}
 	return nil
 }
 
-func entityIntersectsGeoFilter(entity *pb.Entity, geoFilter *pb.GeoFilter) bool {
+// distinctPoints drops points that repeat the one before them
+func distinctPoints(points []orb.Point) []orb.Point {
+	out := make([]orb.Point, 0, len(points))
+	for i, p := range points {
+		if i == 0 || p != points[i-1] {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// ringSelfIntersects reports whether two edges of the ring that don't follow
+// each other touch or cross, e.g. in a bow-tie. The ring must not repeat its
+// first point at the end. It compares all pairs of edges, which is fast enough
+// for the hand drawn areas filters are made of.
+func ringSelfIntersects(r []orb.Point) bool {
+	n := len(r)
+	for i := 0; i < n; i++ {
+		for j := i + 2; j < n; j++ {
+			if i == 0 && j == n-1 {
+				continue // the closing edge follows the first
+			}
+			if segmentsIntersect(r[i], r[(i+1)%n], r[j], r[(j+1)%n]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// boundIntersects tests b against the bounds of every part of g, so the gaps
+// between disjoint polygons of a multi-polygon don't match
+func boundIntersects(b orb.Bound, g orb.Geometry) bool {
+	switch g := g.(type) {
+	case orb.MultiPolygon:
+		for _, p := range g {
+			if b.Intersects(p.Bound()) {
+				return true
+			}
+		}
+		return false
+	case orb.MultiLineString:
+		for _, l := range g {
+			if b.Intersects(l.Bound()) {
+				return true
+			}
+		}
+		return false
+	case orb.MultiPoint:
+		for _, p := range g {
+			if b.Intersects(p.Bound()) {
+				return true
+			}
+		}
+		return false
+	case orb.Collection:
+		for _, part := range g {
+			if boundIntersects(b, part) {
+				return true
+			}
+		}
+		return false
+	}
+	return b.Intersects(g.Bound())
+}
+
+// filterGeometry is the decoded geometry of a geo filter and its bound
+type filterGeometry struct {
+	geom  orb.Geometry
+	bound orb.Bound
+}
+
+func newFilterGeometry(g *pb.Geometry) *filterGeometry {
+	geom := goclient.GeometryToOrb(g)
+	if geom == nil {
+		return nil
+	}
+	return &filterGeometry{geom: geom, bound: geom.Bound()}
+}
+
+// geoFilterCache holds the decoded geometries of the geo filters of a filter,
+// so a watch decodes them once instead of for every change. Geo filters are
+// looked up by pointer, those not in it are decoded on every test.
+type geoFilterCache map[*pb.GeoFilter]*filterGeometry
+
+func compileGeoFilters(filter *pb.EntityFilter) geoFilterCache {
+	c := geoFilterCache{}
+	c.add(filter)
+	return c
+}
+
+func (c geoFilterCache) add(filter *pb.EntityFilter) {
+	if filter == nil {
+		return
+	}
+	if g, ok := filter.Geo.GetGeo().(*pb.GeoFilter_Geometry); ok {
+		c[filter.Geo] = newFilterGeometry(g.Geometry)
+	}
+	for _, or := range filter.Or {
+		c.add(or)
+	}
+	c.add(filter.Not)
+}
+
+// geometry returns the decoded geometry of the geo filter, nil if it has none
+func (c geoFilterCache) geometry(geoFilter *pb.GeoFilter, g *pb.Geometry) *filterGeometry {
+	if fg, ok := c[geoFilter]; ok {
+		return fg
+	}
+	return newFilterGeometry(g)
+}
+
+// entityIntersectsGeoFilter tests the entity position and shape against the filter.
+// With uncertaintySigma > 0 the entity is padded by that many standard deviations
+// of its position uncertainty.
+func entityIntersectsGeoFilter(entity *pb.Entity, geoFilter *pb.GeoFilter, uncertaintySigma float64) bool {
+	return geoFilterCache(nil).entityIntersects(entity, geoFilter, uncertaintySigma)
+}
+
+// entityIntersects is entityIntersectsGeoFilter with the filter geometry taken from c
+func (c geoFilterCache) entityIntersects(entity *pb.Entity, geoFilter *pb.GeoFilter, uncertaintySigma float64) bool {
 	if geoFilter == nil {
 		return true // no geo filter = match all
 	}
 
-	if entity.Geo == nil {
+	entityBound, ok := entityBound(entity)
+	if !ok {
 		return false
 	}
-
-	entityPoint := orb.Point{entity.Geo.Longitude, entity.Geo.Latitude}
+	if uncertaintySigma > 0 {
+		if r := goclient.UncertaintyRadius(entity); r > 0 {
+			entityBound = geo.BoundPad(entityBound, uncertaintySigma*r)
+		}
+	}
 
 	// Handle geometry-based filtering
 	if geoFilter.Geo != nil {
 		switch g := geoFilter.Geo.(type) {
 		case *pb.GeoFilter_Geometry:
-			if g.Geometry == nil || g.Geometry.Planar == nil {
+			fg := c.geometry(geoFilter, g.Geometry)
+			if fg == nil {
 				return true
 			}
+			filterGeom := fg.geom
 
-			filterGeom := planarToOrb(g.Geometry.Planar)
-			if filterGeom == nil {
-				return true
+			// Paths match where the route itself crosses the filter, not anywhere in its bounds.
+			// Padding by uncertainty only applies to bounds.
+			if path, ok := goclient.EntityPath(entity); ok && uncertaintySigma == 0 {
+				if p, ok := entityPoint(entity); ok && boundIntersects(p.Bound(), filterGeom) {
+					return true
+				}
+				return pathIntersects(path, filterGeom)
 			}
 
-			// Check if entity point intersects with filter geometry bounds
-			entityBound := entityPoint.Bound()
-			filterBound := filterGeom.Bound()
-			return entityBound.Intersects(filterBound)
+			// Nothing outside the bound of the whole filter can match any of its parts.
+			// Paths are not tested this way, their great circles leave the bound of their points.
+			if !entityBound.Intersects(fg.bound) {
+				return false
+			}
+			// Check if entity position or shape intersects with filter geometry bounds
+			return boundIntersects(entityBound, filterGeom)
 
 		case *pb.GeoFilter_GeoEntityId:
 			// TODO: implement entity-based geo filtering
@@ -162,6 +399,18 @@ func entityIntersectsGeoFilter(entity *pb.Entity, geoFilter *pb.GeoFilter) bool
 }
 
 func (s *WorldServer) matchesEntityFilter(entity *pb.Entity, filter *pb.EntityFilter) bool {
+	return s.matchesEntityFilterWithUncertainty(entity, filter, 0)
+}
+
+// matchesEntityFilterWithUncertainty is matchesEntityFilter with geo filters
+// padded by the entity's position uncertainty, see entityIntersectsGeoFilter.
+func (s *WorldServer) matchesEntityFilterWithUncertainty(entity *pb.Entity, filter *pb.EntityFilter, uncertaintySigma float64) bool {
+	return s.matchesEntityFilterCached(entity, filter, uncertaintySigma, nil)
+}
+
+// matchesEntityFilterCached is matchesEntityFilterWithUncertainty with the
+// geometries of geo filters taken from geoms, see compileGeoFilters
+func (s *WorldServer) matchesEntityFilterCached(entity *pb.Entity, filter *pb.EntityFilter, uncertaintySigma float64, geoms geoFilterCache) bool {
 	if filter == nil {
 		return true
 	}
@@ -169,7 +418,7 @@ func (s *WorldServer) matchesEntityFilter(entity *pb.Entity, filter *pb.EntityFi
 	// Handle OR filters
 	if len(filter.Or) > 0 {
 		for _, orFilter := range filter.Or {
-			if s.matchesEntityFilter(entity, orFilter) {
+			if s.matchesEntityFilterCached(entity, orFilter, uncertaintySigma, geoms) {
 				return true
 			}
 		}
@@ -178,7 +427,7 @@ func (s *WorldServer) matchesEntityFilter(entity *pb.Entity, filter *pb.EntityFi
 
 	// Handle NOT filter
 	if filter.Not != nil {
-		return !s.matchesEntityFilter(entity, filter.Not)
+		return !s.matchesEntityFilterCached(entity, filter.Not, uncertaintySigma, geoms)
 	}
 
 	// ID filter (exact match)
@@ -199,7 +448,7 @@ func (s *WorldServer) matchesEntityFilter(entity *pb.Entity, filter *pb.EntityFi
 	}
 
 	// Geo filter
-	if !entityIntersectsGeoFilter(entity, filter.Geo) {
+	if !geoms.entityIntersects(entity, filter.Geo, uncertaintySigma) {
 		return false
 	}
 
@@ -233,6 +482,7 @@ func (s *WorldServer) matchesEntityFilter(entity *pb.Entity, filter *pb.EntityFi
 	return true
 }
 
-func (s *WorldServer) matchesListEntitiesRequest(entity *pb.Entity, req *pb.ListEntitiesRequest) bool {
-	return s.matchesEntityFilter(entity, req.Filter)
+func (s *WorldServer) matchesListEntitiesRequest(entity *pb.Entity, req *pb.ListEntitiesRequest, opts *requestOptions) bool {
+	return s.matchesEntityFilterWithUncertainty(entity, req.Filter, opts.uncertaintySigma) && s.confident(entity.Id, opts.minConfidence) &&
+		s.seenEnough(entity.Id, opts.minSeen)
 }
@@ -0,0 +1,184 @@
+package engine
+
+import (
+	"context"
+	"testing"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/encoding/wkb"
+	"github.com/projectqai/hydra/goclient"
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestGeoFilter_MatchesShape(t *testing.T) {
+	// sensor north-east of the filter box, looking south-west into it
+	sensor := &pb.Entity{
+		Id:    "radar",
+		Geo:   &pb.GeoSpatialComponent{Longitude: 10.1, Latitude: 50.1},
+		Shape: goclient.SectorShape(10.1, 50.1, 225, 40, 30000),
+	}
+	bound := orb.Bound{Min: orb.Point{9.9, 49.9}, Max: orb.Point{10, 50}}
+	filter := &pb.GeoFilter{Geo: &pb.GeoFilter_Geometry{Geometry: &pb.Geometry{Planar: goclient.ToPlanar(bound.ToPolygon())}}}
+
+	if !entityIntersectsGeoFilter(sensor, filter, 0) {
+		t.Error("expected coverage sector to match the filter")
+	}
+
+	sensor.Shape = nil
+	if entityIntersectsGeoFilter(sensor, filter, 0) {
+		t.Error("expected position alone not to match the filter")
+	}
+
+	ring := &pb.Entity{Id: "ring", Shape: goclient.RangeRingShape(10.1, 50.1, 20000)}
+	if !entityIntersectsGeoFilter(ring, filter, 0) {
+		t.Error("expected entity with only a shape to match the filter")
+	}
+}
+
+func TestGeoFilter_Uncertainty(t *testing.T) {
+	// contact ~1.1km outside the filter box with 1km sigma
+	contact := &pb.Entity{
+		Id:  "contact",
+		Geo: &pb.GeoSpatialComponent{Longitude: 10.005, Latitude: 50.01},
+		LocationUncertainty: &pb.LocationUncertaintyComponent{
+			PositionEnuCov: &pb.CovarianceMatrix{Mxx: ptr(1e6), Myy: ptr(1e6)},
+		},
+	}
+	bound := orb.Bound{Min: orb.Point{10, 49.99}, Max: orb.Point{10.01, 50}}
+	filter := &pb.GeoFilter{Geo: &pb.GeoFilter_Geometry{Geometry: &pb.Geometry{Planar: goclient.ToPlanar(bound.ToPolygon())}}}
+
+	if entityIntersectsGeoFilter(contact, filter, 0) {
+		t.Error("expected point outside the filter not to match without uncertainty")
+	}
+	if entityIntersectsGeoFilter(contact, filter, 1) {
+		t.Error("expected 1 sigma not to reach the filter")
+	}
+	if !entityIntersectsGeoFilter(contact, filter, 2) {
+		t.Error("expected 2 sigma to reach the filter")
+	}
+}
+
+func TestGeoFilter_MultiPolygonWKB(t *testing.T) {
+	areas := orb.MultiPolygon{
+		orb.Bound{Min: orb.Point{10, 50}, Max: orb.Point{10.1, 50.1}}.ToPolygon(),
+		orb.Bound{Min: orb.Point{11, 50}, Max: orb.Point{11.1, 50.1}}.ToPolygon(),
+	}
+	b, err := wkb.Marshal(areas)
+	if err != nil {
+		t.Fatal(err)
+	}
+	filter := &pb.GeoFilter{Geo: &pb.GeoFilter_Geometry{Geometry: &pb.Geometry{Wkb: b}}}
+
+	for _, tc := range []struct {
+		lon  float64
+		want bool
+	}{
+		{10.05, true},
+		{11.05, true},
+		{10.5, false}, // between the areas, inside their common bound
+	} {
+		entity := &pb.Entity{Id: "e", Geo: &pb.GeoSpatialComponent{Longitude: tc.lon, Latitude: 50.05}}
+		if got := entityIntersectsGeoFilter(entity, filter, 0); got != tc.want {
+			t.Errorf("lon %v: expected %v, got %v", tc.lon, tc.want, got)
+		}
+	}
+}
+
+func TestGeoFilter_PathIntersection(t *testing.T) {
+	route := func(points ...orb.Point) *pb.Entity {
+		return &pb.Entity{Id: "route", Shape: goclient.Shape(orb.LineString(points))}
+	}
+	box := func(minLon, minLat, maxLon, maxLat float64) *pb.GeoFilter {
+		bound := orb.Bound{Min: orb.Point{minLon, minLat}, Max: orb.Point{maxLon, maxLat}}
+		return &pb.GeoFilter{Geo: &pb.GeoFilter_Geometry{Geometry: &pb.Geometry{Planar: goclient.ToPlanar(bound.ToPolygon())}}}
+	}
+
+	diagonal := route(orb.Point{0, 0}, orb.Point{1, 1})
+	if !entityIntersectsGeoFilter(diagonal, box(0.4, 0.4, 0.6, 0.6), 0) {
+		t.Error("expected a box on the route to match")
+	}
+	if entityIntersectsGeoFilter(diagonal, box(0.8, 0, 1, 0.2), 0) {
+		t.Error("expected a box within the route bounds but off the route not to match")
+	}
+
+	// the great circle from Canada to Europe passes far north of both ends
+	atlantic := route(orb.Point{-60, 50}, orb.Point{10, 50})
+	if !entityIntersectsGeoFilter(atlantic, box(-30, 54, -20, 57), 0) {
+		t.Error("expected the great circle route to cross the box north of its end points")
+	}
+	if entityIntersectsGeoFilter(atlantic, box(-30, 49, -20, 51), 0) {
+		t.Error("expected the straight lon/lat line between the end points not to match")
+	}
+}
+
+func TestGeoFilter_WKT(t *testing.T) {
+	triangle := []byte("POLYGON((10 50, 10.1 50, 10 50.1, 10 50))")
+	filter := &pb.EntityFilter{Geo: &pb.GeoFilter{Geo: &pb.GeoFilter_Geometry{Geometry: &pb.Geometry{Wkb: triangle}}}}
+	if err := validateGeoFilter(filter); err != nil {
+		t.Fatal(err)
+	}
+
+	if g, err := goclient.DecodeGeometry(triangle); err != nil || g.GeoJSONType() != "Polygon" {
+		t.Fatalf("expected a polygon, got %v, %v", g, err)
+	}
+	if _, err := goclient.DecodeGeometry(nil); err == nil {
+		t.Fatal("expected an error for an empty geometry")
+	}
+	// clients encode WKT as WKB before sending it
+	encoded, err := goclient.WKTGeometry(string(triangle))
+	if err != nil || encoded.Wkb[0] > 1 {
+		t.Fatalf("expected WKB, got %v, %v", encoded, err)
+	}
+	if g, err := goclient.DecodeGeometry(encoded.Wkb); err != nil || g.GeoJSONType() != "Polygon" {
+		t.Fatalf("expected the WKB to decode to a polygon, got %v, %v", g, err)
+	}
+	inside := &pb.Entity{Id: "in", Geo: &pb.GeoSpatialComponent{Longitude: 10.02, Latitude: 50.02}}
+	outside := &pb.Entity{Id: "out", Geo: &pb.GeoSpatialComponent{Longitude: 10.5, Latitude: 50.5}}
+	if !entityIntersectsGeoFilter(inside, filter.Geo, 0) {
+		t.Error("expected the entity inside to match")
+	}
+	if entityIntersectsGeoFilter(outside, filter.Geo, 0) {
+		t.Error("expected the entity outside not to match")
+	}
+
+	broken := &pb.EntityFilter{Not: &pb.EntityFilter{Geo: &pb.GeoFilter{Geo: &pb.GeoFilter_Geometry{Geometry: &pb.Geometry{Wkb: []byte("POLYGON((10 50")}}}}}
+	if err := validateGeoFilter(broken); err == nil {
+		t.Error("expected invalid WKT to be rejected")
+	}
+}
+
+func TestGeoFilter_Degenerate(t *testing.T) {
+	ts := NewTestServer(t)
+	list := func(wkt string) error {
+		_, err := ts.Client.ListEntities(context.Background(), &pb.ListEntitiesRequest{
+			Filter: &pb.EntityFilter{Geo: &pb.GeoFilter{Geo: &pb.GeoFilter_Geometry{Geometry: &pb.Geometry{Wkb: []byte(wkt)}}}},
+		})
+		return err
+	}
+
+	for _, wkt := range []string{
+		"POLYGON((10 50))",
+		"POLYGON((10 50, 11 50, 12 50, 10 50))",
+		"POLYGON((10 50, 11 51, 11 50, 10 51, 10 50))",
+		"POLYGON((10 50, 11 50, 10 91, 10 50))",
+		"LINESTRING(10 50, 10 50)",
+	} {
+		if err := list(wkt); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("%s: expected invalid argument, got %v", wkt, err)
+		}
+	}
+	if err := list("POLYGON((10 50, 11 50, 10 51, 10 50))"); err != nil {
+		t.Errorf("expected a triangle to be accepted, got %v", err)
+	}
+
+	point := &pb.EntityFilter{Or: []*pb.EntityFilter{{Geo: &pb.GeoFilter{Geo: &pb.GeoFilter_Geometry{Geometry: &pb.Geometry{
+		Planar: &pb.PlanarGeometry{Plane: &pb.PlanarGeometry_Polygon{Polygon: &pb.PlanarPolygon{Outer: &pb.PlanarRing{
+			Points: []*pb.PlanarPoint{{Longitude: 10, Latitude: 50}},
+		}}}},
+	}}}}}}
+	if err := validateGeoFilter(point); err == nil {
+		t.Error("expected a planar polygon of one point to be rejected")
+	}
+}
@@ -0,0 +1,174 @@
+package engine
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/projectqai/hydra/policy"
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Defaults of /firehose, chosen for a wall display that shows where things are
+const (
+	// firehoseRate is messages per second. Changes of the same entity coalesce
+	// while waiting, so a busy world is shown at its latest state, not lagging.
+	firehoseRate = 10
+	// firehoseFields are the components sent, the id is always sent
+	firehoseFields = "label,geo,symbol"
+)
+
+// handleFirehose streams all changes of the world, like WatchEntities with a
+// rate limit and only the components a map needs, to dashboards that can't
+// assemble these options themselves. Query parameters override the defaults:
+//
+//	rate      messages per second, default 10
+//	fields    comma separated components in their JSON names, default label,geo,symbol, "all" for everything
+//	snapshot  "true" to start with the current state, default live changes only
+//	format    "sse" for server-sent events, default, or "jsonl" for one event per line
+//
+// Events are EntityChangeEvent in protojson encoding.
+func (s *WorldServer) handleFirehose(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	perSecond := uint64(firehoseRate)
+	if v := q.Get("rate"); v != "" {
+		n, err := strconv.ParseUint(v, 10, 32)
+		if err != nil || n == 0 {
+			http.Error(w, "rate must be a positive number of messages per second", http.StatusBadRequest)
+			return
+		}
+		perSecond = n
+	}
+	fields := firehoseFields
+	if q.Has("fields") {
+		fields = q.Get("fields")
+	}
+	project, err := entityProjection(fields)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	snapshot := false
+	if v := q.Get("snapshot"); v != "" {
+		if snapshot, err = strconv.ParseBool(v); err != nil {
+			http.Error(w, "snapshot must be true or false", http.StatusBadRequest)
+			return
+		}
+	}
+	format := q.Get("format")
+	switch format {
+	case "":
+		format = "sse"
+	case "sse", "jsonl":
+	default:
+		http.Error(w, "format must be sse or jsonl", http.StatusBadRequest)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	watchers := s.watchers(r.RemoteAddr)
+	if !watchers.join() {
+		http.Error(w, "server shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	defer watchers.leave()
+
+	consumer := NewConsumer(s, policy.ForHTTP(s.policy, r), &pb.WatchLimiter{MaxMessagesPerSecond: &perSecond}, nil)
+	consumer.peer = r.RemoteAddr
+	consumer.closing = watchers.closing
+	consumer.maxPending = s.watchBufferSize
+	s.bus.Register(consumer)
+	defer s.bus.Unregister(consumer)
+
+	if snapshot {
+		s.l.RLock()
+		for id, e := range s.head.All() {
+			priority := pb.Priority_PriorityRoutine
+			if e.Priority != nil {
+				priority = *e.Priority
+			}
+			consumer.markDirty(id, priority, pb.EntityChange_EntityChangeUpdated)
+		}
+		s.l.RUnlock()
+	}
+
+	if format == "sse" {
+		w.Header().Set("Content-Type", "text/event-stream")
+	} else {
+		w.Header().Set("Content-Type", "application/jsonl")
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	consumer.SenderLoop(r.Context(), func(ev *pb.EntityChangeEvent) error {
+		if ev.Entity != nil {
+			ev = &pb.EntityChangeEvent{T: ev.T, Entity: project(ev.Entity)}
+		}
+		data, err := protojson.Marshal(ev)
+		if err != nil {
+			return err
+		}
+		if format == "sse" {
+			_, err = fmt.Fprintf(w, "data: %s\n\n", data)
+		} else {
+			_, err = fmt.Fprintf(w, "%s\n", data)
+		}
+		if err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	})
+}
+
+// entityProjection returns a func that keeps the id and the given top-level
+// fields of entities, by their JSON or proto names. "all" keeps everything.
+func entityProjection(fields string) (func(*pb.Entity) *pb.Entity, error) {
+	if fields == "all" {
+		return func(e *pb.Entity) *pb.Entity { return e }, nil
+	}
+
+	desc := (&pb.Entity{}).ProtoReflect().Descriptor().Fields()
+	keep := map[protoreflect.FieldNumber]bool{desc.ByName("id").Number(): true}
+	for _, name := range strings.Split(fields, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		fd := desc.ByJSONName(name)
+		if fd == nil {
+			fd = desc.ByName(protoreflect.Name(name))
+		}
+		if fd == nil {
+			return nil, fmt.Errorf("unknown entity field %q", name)
+		}
+		keep[fd.Number()] = true
+	}
+
+	return func(e *pb.Entity) *pb.Entity {
+		projected := proto.Clone(e).(*pb.Entity)
+		m := projected.ProtoReflect()
+		m.Range(func(fd protoreflect.FieldDescriptor, _ protoreflect.Value) bool {
+			if !keep[fd.Number()] {
+				m.Clear(fd)
+			}
+			return true
+		})
+		return projected
+	}, nil
+}
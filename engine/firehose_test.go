@@ -0,0 +1,26 @@
+package engine
+
+import (
+	"testing"
+
+	pb "github.com/projectqai/proto/go"
+)
+
+func TestEntityProjection_KeepsIDAndFields(t *testing.T) {
+	project, err := entityProjection(firehoseFields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := &pb.Entity{Id: "a", Label: ptr("A"), Geo: &pb.GeoSpatialComponent{Latitude: 1}, Taskable: &pb.TaskableComponent{}}
+	got := project(e)
+	if got.Id != "a" || got.GetLabel() != "A" || got.Geo == nil || got.Taskable != nil {
+		t.Fatalf("unexpected projection %v", got)
+	}
+	if e.Taskable == nil {
+		t.Fatal("projection changed the original entity")
+	}
+
+	if _, err := entityProjection("label,nope"); err == nil {
+		t.Fatal("expected unknown fields to be rejected")
+	}
+}
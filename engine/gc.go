@@ -1,25 +1,72 @@
 package engine
 
 import (
+	"hash/fnv"
 	"time"
 
-	proto "github.com/projectqai/proto/go"
+	"github.com/projectqai/hydra/goclient"
+	pb "github.com/projectqai/proto/go"
 )
 
-func (s *WorldServer) gc() {
-	now := time.Now()
+// now is the current time of the world, which stands still while the timeline is frozen
+func (s *WorldServer) now() time.Time {
 	if s.frozen.Load() {
-		now = s.frozenAt
+		return s.frozenAt
+	}
+	return s.clock.Now()
+}
+
+// expiresAt is when gc expires an entity, its Lifetime.Until plus the expiry
+// jitter. Entities deleted by a push with an Until that already passed are
+// not delayed. Caller must hold s.l.
+func (s *WorldServer) expiresAt(id string, e *pb.Entity) time.Time {
+	until := e.Lifetime.Until.AsTime()
+	if s.expiryJitter <= 0 || s.lifetimeExpiry(id, e) == goclient.ExpiryDeleted {
+		return until
 	}
+	// the same delay every time gc looks, so each entity expires once
+	h := fnv.New64a()
+	h.Write([]byte(id))
+	return until.Add(time.Duration(h.Sum64() % uint64(s.expiryJitter)))
+}
+
+func (s *WorldServer) gc() {
+	now := s.now()
 
 	s.l.Lock()
-	for k, v := range s.head {
-		if v.Lifetime != nil {
-			if v.Lifetime.Until.IsValid() && now.After(v.Lifetime.Until.AsTime()) {
-				delete(s.head, k)
-				s.bus.Dirty(k, v, proto.EntityChange_EntityChangeExpired)
+	var expired []string
+	for k, v := range s.head.All() {
+		if v.Lifetime != nil && !goclient.IsSticky(v) {
+			if v.Lifetime.Until.IsValid() && now.After(s.expiresAt(k, v)) {
+				s.bury(k, v, s.lifetimeExpiry(k, v))
+				expired = append(expired, k)
+			}
+		}
+	}
+	if s.cascadeExpiry {
+		s.cascadeExpire(expired)
+	}
+	// entities can also come in without a push, e.g. from the world file
+	s.evict()
+	for alias, target := range s.aliases {
+		if !s.head.Has(target) {
+			delete(s.aliases, alias)
+		}
+	}
+	for id := range s.lastSeen {
+		if !s.head.Has(id) {
+			delete(s.lastSeen, id)
+			delete(s.versions, id)
+			delete(s.contents, id)
+			s.head.DeleteMarking(id)
+			// watchers still filter the expiry by them, they go with the tombstone
+			if _, buried := s.tombstones[id]; !buried {
+				s.confidence.delete(id)
+				s.seen.delete(id)
 			}
+			delete(s.fieldTimes, id)
 		}
 	}
+	s.pruneTombstones(s.clock.Now())
 	s.l.Unlock()
 }
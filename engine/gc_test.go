@@ -0,0 +1,133 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/projectqai/hydra/goclient"
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestGC_CascadeExpire(t *testing.T) {
+	past := &pb.Lifetime{Until: timestamppb.New(time.Now().Add(-time.Hour))}
+	world := testWorld(map[string]*pb.Entity{
+		"ship":   {Id: "ship", Lifetime: past},
+		"heli":   {Id: "heli", Locator: &pb.LocatorComponent{LocatedEntityId: "ship"}},
+		"track":  {Id: "track", Detection: &pb.DetectionComponent{DetectorEntityId: ptr("heli")}},
+		"other":  {Id: "other"},
+		"looped": {Id: "looped", Locator: &pb.LocatorComponent{LocatedEntityId: "looped"}},
+	})
+	world.cascadeExpiry = true
+
+	world.gc()
+
+	for _, id := range []string{"ship", "heli", "track"} {
+		if world.head.Has(id) {
+			t.Errorf("expected %s to be expired", id)
+		}
+	}
+	for _, id := range []string{"other", "looped"} {
+		if !world.head.Has(id) {
+			t.Errorf("expected %s to survive", id)
+		}
+	}
+}
+
+func TestGC_ExpiresOnClock(t *testing.T) {
+	w := testWorld(nil)
+	clock := &testClock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	w.clock = clock
+
+	e := &pb.Entity{Id: "a", Lifetime: &pb.Lifetime{Until: timestamppb.New(clock.Now().Add(10 * time.Second))}}
+	if _, err := w.Push(context.Background(), connect.NewRequest(&pb.EntityChangeRequest{Changes: []*pb.Entity{e}})); err != nil {
+		t.Fatal(err)
+	}
+	if !e.Lifetime.From.AsTime().Equal(clock.Now()) {
+		t.Fatalf("expected lifetime to start at the clock, got %v", e.Lifetime.From.AsTime())
+	}
+
+	clock.Advance(9 * time.Second)
+	w.gc()
+	if w.GetHead("a") == nil {
+		t.Fatal("expected entity to live until its expiry")
+	}
+
+	clock.Advance(2 * time.Second)
+	w.gc()
+	if w.GetHead("a") != nil {
+		t.Fatal("expected entity to be expired")
+	}
+	if ts, ok := w.tombstoneOf("a"); !ok || !ts.deleted.Equal(clock.Now()) {
+		t.Fatalf("expected tombstone at the clock time, got %v", ts.deleted)
+	}
+
+	// tombstones are pruned by the same clock
+	clock.Advance(w.tombstoneRetention + time.Second)
+	w.gc()
+	if _, ok := w.tombstoneOf("a"); ok {
+		t.Fatal("expected tombstone to be pruned")
+	}
+}
+
+func TestGC_KeepsStickyEntities(t *testing.T) {
+	past := &pb.Lifetime{Until: timestamppb.New(time.Now().Add(-time.Hour))}
+	world := testWorld(map[string]*pb.Entity{
+		"ship":     {Id: "ship", Lifetime: past},
+		"boundary": {Id: "boundary", Lifetime: goclient.StickyLifetime(), Locator: &pb.LocatorComponent{LocatedEntityId: "ship"}},
+		"track":    {Id: "track"},
+	})
+	world.cascadeExpiry = true
+	world.maxEntities = 1
+
+	world.gc()
+
+	if !world.head.Has("boundary") {
+		t.Fatal("expected the sticky entity to survive its parent and eviction")
+	}
+	if world.head.Has("ship") || world.head.Has("track") {
+		t.Fatalf("expected other entities to expire, head has %v", world.head)
+	}
+}
+
+func TestGC_ExpiryJitterSpreadsBatches(t *testing.T) {
+	w := testWorld(nil)
+	clock := &testClock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	w.clock = clock
+	w.expiryJitter = 10 * time.Second
+
+	until := timestamppb.New(clock.Now().Add(time.Second))
+	var batch []*pb.Entity
+	for i := range 100 {
+		batch = append(batch, &pb.Entity{Id: fmt.Sprintf("e%d", i), Lifetime: &pb.Lifetime{Until: until}})
+	}
+	if _, err := w.Push(context.Background(), connect.NewRequest(&pb.EntityChangeRequest{Changes: batch})); err != nil {
+		t.Fatal(err)
+	}
+	deleted := &pb.Entity{Id: "deleted", Lifetime: &pb.Lifetime{Until: timestamppb.New(clock.Now())}}
+	if _, err := w.Push(context.Background(), connect.NewRequest(&pb.EntityChangeRequest{Changes: []*pb.Entity{deleted}})); err != nil {
+		t.Fatal(err)
+	}
+
+	clock.Advance(time.Second + time.Millisecond)
+	w.gc()
+	if w.GetHead("deleted") != nil {
+		t.Fatal("expected deletions not to be delayed")
+	}
+
+	var ticks int
+	for w.head.Len() > 0 {
+		clock.Advance(time.Second)
+		w.gc()
+		ticks++
+		if ticks > 11 {
+			t.Fatalf("expected all entities to expire within the jitter, %d left", w.head.Len())
+		}
+	}
+	if ticks < 5 {
+		t.Fatalf("expected the batch to expire over the jitter, took %d ticks", ticks)
+	}
+}
@@ -0,0 +1,43 @@
+package engine
+
+import (
+	"math"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geo"
+	pb "github.com/projectqai/proto/go"
+)
+
+func entityPoint(entity *pb.Entity) (orb.Point, bool) {
+	if entity.Geo == nil {
+		return orb.Point{}, false
+	}
+	return orb.Point{entity.Geo.Longitude, entity.Geo.Latitude}, true
+}
+
+// offsetENU moves p by east and north meters
+func offsetENU(p orb.Point, east, north float64) orb.Point {
+	distance := math.Hypot(east, north)
+	if distance == 0 {
+		return p
+	}
+	bearing := math.Atan2(east, north) * 180 / math.Pi
+	return geo.PointAtBearingAndDistance(p, bearing, distance)
+}
+
+// enuBetween returns the east and north offset in meters from a to b
+func enuBetween(a, b orb.Point) (east, north float64) {
+	distance := geo.Distance(a, b)
+	if distance == 0 {
+		return 0, 0
+	}
+	bearing := geo.Bearing(a, b) * math.Pi / 180
+	return distance * math.Sin(bearing), distance * math.Cos(bearing)
+}
+
+func closedPath(r orb.Ring) orb.LineString {
+	if len(r) > 1 && r[0] != r[len(r)-1] {
+		return append(orb.LineString(r[:len(r):len(r)]), r[0])
+	}
+	return orb.LineString(r)
+}
@@ -0,0 +1,198 @@
+package engine
+
+import (
+	"hash/maphash"
+	"iter"
+	"sync"
+
+	"github.com/projectqai/hydra/policy"
+	pb "github.com/projectqai/proto/go"
+)
+
+// headShards is the number of independently locked parts of the head
+const headShards = 32
+
+// head holds the live entities and their classification, sharded by id so
+// readers of single entities, like the sender loop of every watch, don't wait
+// for a push of unrelated entities.
+//
+// Writes happen with s.l held, so code holding s.l sees a consistent head.
+// Reads without s.l, like GetHead and ListEntities, see each shard consistent
+// on its own.
+type head struct {
+	seed   maphash.Seed
+	shards [headShards]headShard
+}
+
+type headShard struct {
+	mu       sync.RWMutex
+	entities map[string]*pb.Entity
+	markings map[string]policy.Marking
+}
+
+func newHead() *head {
+	h := &head{seed: maphash.MakeSeed()}
+	for i := range h.shards {
+		h.shards[i].entities = make(map[string]*pb.Entity)
+		h.shards[i].markings = make(map[string]policy.Marking)
+	}
+	return h
+}
+
+func (h *head) shard(id string) *headShard {
+	return &h.shards[maphash.String(h.seed, id)%headShards]
+}
+
+func (h *head) Lookup(id string) (*pb.Entity, bool) {
+	sh := h.shard(id)
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+	e, ok := sh.entities[id]
+	return e, ok
+}
+
+// Get is Lookup without the ok, nil if there is no entity with id
+func (h *head) Get(id string) *pb.Entity {
+	e, _ := h.Lookup(id)
+	return e
+}
+
+func (h *head) Has(id string) bool {
+	_, ok := h.Lookup(id)
+	return ok
+}
+
+func (h *head) Set(id string, e *pb.Entity) {
+	sh := h.shard(id)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	sh.entities[id] = e
+}
+
+// Put sets the entity and, unless nil, its marking at once, so readers never see
+// an entity with the classification of what it replaced
+func (h *head) Put(id string, e *pb.Entity, marking *policy.Marking) {
+	sh := h.shard(id)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	sh.entities[id] = e
+	if marking != nil {
+		sh.markings[id] = *marking
+	}
+}
+
+// Entry returns an entity together with its marking
+func (h *head) Entry(id string) (*pb.Entity, policy.Marking, bool) {
+	sh := h.shard(id)
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+	e, ok := sh.entities[id]
+	return e, sh.markings[id], ok
+}
+
+// Delete removes the entity, its marking is kept until DeleteMarking
+func (h *head) Delete(id string) {
+	sh := h.shard(id)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	delete(sh.entities, id)
+}
+
+// LookupMarking returns the classification of an entity, see goclient.HeaderClassification
+func (h *head) LookupMarking(id string) (policy.Marking, bool) {
+	sh := h.shard(id)
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+	m, ok := sh.markings[id]
+	return m, ok
+}
+
+// Marking is LookupMarking without the ok, unclassified if there is none
+func (h *head) Marking(id string) policy.Marking {
+	m, _ := h.LookupMarking(id)
+	return m
+}
+
+func (h *head) SetMarking(id string, m policy.Marking) {
+	sh := h.shard(id)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	sh.markings[id] = m
+}
+
+func (h *head) DeleteMarking(id string) {
+	sh := h.shard(id)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	delete(sh.markings, id)
+}
+
+func (h *head) Len() int {
+	n := 0
+	for i := range h.shards {
+		sh := &h.shards[i]
+		sh.mu.RLock()
+		n += len(sh.entities)
+		sh.mu.RUnlock()
+	}
+	return n
+}
+
+// All iterates over all entities. Each shard is copied before its entities are
+// yielded, so the loop may change the head.
+func (h *head) All() iter.Seq2[string, *pb.Entity] {
+	return func(yield func(string, *pb.Entity) bool) {
+		var ids []string
+		var entities []*pb.Entity
+		for i := range h.shards {
+			sh := &h.shards[i]
+			ids, entities = ids[:0], entities[:0]
+			sh.mu.RLock()
+			for id, e := range sh.entities {
+				ids = append(ids, id)
+				entities = append(entities, e)
+			}
+			sh.mu.RUnlock()
+
+			for j, id := range ids {
+				if !yield(id, entities[j]) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Entries iterates over all entities with their marking, like All
+func (h *head) Entries() iter.Seq2[*pb.Entity, policy.Marking] {
+	return func(yield func(*pb.Entity, policy.Marking) bool) {
+		var entities []*pb.Entity
+		var markings []policy.Marking
+		for i := range h.shards {
+			sh := &h.shards[i]
+			entities, markings = entities[:0], markings[:0]
+			sh.mu.RLock()
+			for id, e := range sh.entities {
+				entities = append(entities, e)
+				markings = append(markings, sh.markings[id])
+			}
+			sh.mu.RUnlock()
+
+			for j, e := range entities {
+				if !yield(e, markings[j]) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Clear removes all entities, markings are kept
+func (h *head) Clear() {
+	for i := range h.shards {
+		sh := &h.shards[i]
+		sh.mu.Lock()
+		clear(sh.entities)
+		sh.mu.Unlock()
+	}
+}
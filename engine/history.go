@@ -0,0 +1,42 @@
+package engine
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/projectqai/hydra/goclient"
+	"github.com/projectqai/hydra/policy"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// handleEvents returns the pushes received within the duration given as since query
+// parameter, e.g. /events?since=5m, oldest first, including expiries.
+func (s *WorldServer) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	since, err := time.ParseDuration(r.URL.Query().Get("since"))
+	if err != nil || since < 0 {
+		http.Error(w, "since must be a positive duration", http.StatusBadRequest)
+		return
+	}
+
+	ability := policy.ForHTTP(s.policy, r)
+	resp := goclient.HistoryResponse{Events: []goclient.HistoryEvent{}}
+	for _, ev := range s.store.EventsSince(s.clock.Now().Add(-since)) {
+		if !ability.CanRead(r.Context(), ev.Entity, ev.Marking) {
+			continue
+		}
+		data, err := protojson.Marshal(ev.Entity)
+		if err != nil {
+			continue
+		}
+		resp.Events = append(resp.Events, goclient.HistoryEvent{Received: ev.Received, Entity: data, Expired: ev.Expired})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,29 @@
+package engine
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/projectqai/hydra/goclient"
+	"github.com/projectqai/hydra/version"
+)
+
+// Info describes this build, federation peers check it before exchanging entities
+func Info() goclient.ServerInfo {
+	return goclient.ServerInfo{
+		Version:  version.Version,
+		Proto:    version.Proto(),
+		Protocol: goclient.ProtocolVersion,
+		Features: goclient.Features,
+	}
+}
+
+func handleInfo(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Info())
+}
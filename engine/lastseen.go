@@ -0,0 +1,58 @@
+package engine
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/projectqai/hydra/goclient"
+	"github.com/projectqai/hydra/policy"
+)
+
+// touch records that the entity was pushed just now and bumps its version. Caller must hold s.l.
+func (s *WorldServer) touch(id string) {
+	s.lastSeen[id] = s.clock.Now()
+	s.versions[id]++
+}
+
+// handleEntityMeta returns the meta of the entities given as id query parameters, or all if none are given
+func (s *WorldServer) handleEntityMeta(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ability := policy.ForHTTP(s.policy, r)
+	ids := r.URL.Query()["id"]
+	now := s.clock.Now()
+
+	s.l.RLock()
+	if len(ids) == 0 {
+		ids = make([]string, 0, s.head.Len())
+		for id := range s.head.All() {
+			ids = append(ids, id)
+		}
+	}
+	resp := goclient.EntityMetaResponse{Entities: make(map[string]goclient.EntityMeta, len(ids))}
+	for _, id := range ids {
+		entity, ok := s.head.Lookup(id)
+		if !ok || !ability.CanRead(r.Context(), entity, s.head.Marking(id)) {
+			continue
+		}
+		seen, ok := s.lastSeen[id]
+		if !ok {
+			continue
+		}
+		meta := goclient.EntityMeta{LastSeen: seen, Age: now.Sub(seen).Seconds(), Version: s.versions[id], SeenCount: s.seen.get(id), Components: s.componentTimes(id)}
+		if marking, ok := s.head.LookupMarking(id); ok {
+			meta.Classification = marking.String()
+		}
+		if confidence, ok := s.confidence.get(id); ok {
+			meta.Confidence = &confidence
+		}
+		resp.Entities[id] = meta
+	}
+	s.l.RUnlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,47 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/projectqai/hydra/goclient"
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestComponentTimes_KeptPerComponent(t *testing.T) {
+	w := testWorld(nil)
+	w.conflict = &ConflictConfig{Policy: ConflictFieldNewest}
+	t0 := time.Now().Truncate(time.Second)
+	push := func(controller string, at time.Time, e *pb.Entity) {
+		t.Helper()
+		e.Id = "track-1"
+		e.Controller = &pb.ControllerRef{Id: controller}
+		e.Lifetime = &pb.Lifetime{From: timestamppb.New(at)}
+		if _, err := w.Push(context.Background(), connect.NewRequest(&pb.EntityChangeRequest{Changes: []*pb.Entity{e}})); err != nil {
+			t.Fatal(err)
+		}
+	}
+	push("tak", t0, &pb.Entity{Label: ptr("Alpha"), Geo: &pb.GeoSpatialComponent{Longitude: 10, Latitude: 50}})
+	push("radar", t0.Add(time.Minute), &pb.Entity{Geo: &pb.GeoSpatialComponent{Longitude: 10.1, Latitude: 50}})
+
+	req := httptest.NewRequest(http.MethodGet, "/entities/meta?id=track-1", nil)
+	rec := httptest.NewRecorder()
+	w.handleEntityMeta(rec, req)
+	var resp goclient.EntityMetaResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	times := resp.Entities["track-1"].Components
+	if !times["label"].Equal(t0) || !times["geo"].Equal(t0.Add(time.Minute)) {
+		t.Errorf("expected the label from the first push and the position from the second, got %v", times)
+	}
+	if _, ok := times["id"]; ok {
+		t.Error("expected no time for the id")
+	}
+}
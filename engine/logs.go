@@ -0,0 +1,100 @@
+package engine
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/projectqai/hydra/logging"
+	"github.com/projectqai/hydra/policy"
+)
+
+// handleLogs returns the recent log lines of the engine as one JSON object per
+// line, see logging.Line. Query parameters:
+//
+//	module  only lines of these builtins, e.g. ais, may be given multiple times
+//	n       only the last n lines
+//	follow  "true" to keep streaming new lines until the client disconnects
+//
+// Log lines can contain secrets, so the policy has to allow reading them.
+func (s *WorldServer) handleLogs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := policy.ForHTTP(s.policy, r).AuthorizeLogs(r.Context()); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	q := r.URL.Query()
+	modules := q["module"]
+	n := -1
+	if v := q.Get("n"); v != "" {
+		var err error
+		if n, err = strconv.Atoi(v); err != nil || n < 0 {
+			http.Error(w, "n must be a number of lines", http.StatusBadRequest)
+			return
+		}
+	}
+	follow := false
+	if v := q.Get("follow"); v != "" {
+		var err error
+		if follow, err = strconv.ParseBool(v); err != nil {
+			http.Error(w, "follow must be true or false", http.StatusBadRequest)
+			return
+		}
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if follow && !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	var lines []logging.Line
+	var next <-chan logging.Line
+	if follow {
+		var stop func()
+		lines, next, stop = logging.Recent.Follow()
+		defer stop()
+	} else {
+		lines = logging.Recent.Lines()
+	}
+	matches := func(line logging.Line) bool {
+		return len(modules) == 0 || slices.Contains(modules, line.Module)
+	}
+	lines = slices.DeleteFunc(lines, func(line logging.Line) bool { return !matches(line) })
+	if n >= 0 && len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+
+	w.Header().Set("Content-Type", "application/jsonl")
+	w.Header().Set("Cache-Control", "no-cache")
+	enc := json.NewEncoder(w)
+	for _, line := range lines {
+		if err := enc.Encode(line); err != nil {
+			return
+		}
+	}
+	if !follow {
+		return
+	}
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case line := <-next:
+			if !matches(line) {
+				continue
+			}
+			if err := enc.Encode(line); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
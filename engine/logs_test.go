@@ -0,0 +1,66 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/projectqai/hydra/logging"
+	"github.com/projectqai/hydra/policy"
+)
+
+func TestLogs_FilterByModule(t *testing.T) {
+	slog.Default().With("module", "logs-test").Info("polled feed", "vessels", 3)
+	slog.Default().With("module", "other").Info("unrelated")
+
+	rec := httptest.NewRecorder()
+	testWorld(nil).handleLogs(rec, httptest.NewRequest(http.MethodGet, "/logs?module=logs-test", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	var line logging.Line
+	if err := json.Unmarshal(rec.Body.Bytes(), &line); err != nil {
+		t.Fatalf("expected one line, got %q: %v", rec.Body.String(), err)
+	}
+	if line.Module != "logs-test" || line.Message != "polled feed" || line.Attrs["vessels"] != "3" {
+		t.Errorf("unexpected line %+v", line)
+	}
+}
+
+func TestLogs_DeniedByPolicy(t *testing.T) {
+	slog.Default().With("module", "logs-test").Info("posting", "url", "https://hooks.example.org/secret")
+
+	w := testWorld(nil)
+	var subject policy.Subject
+	w.policy = policy.NewEngineFunc(func(ctx context.Context, input policy.Input) bool {
+		if input.Action != policy.ActionLogs {
+			return true
+		}
+		subject = input.Subject
+		return input.Subject.Token == "operator"
+	})
+
+	rec := httptest.NewRecorder()
+	w.handleLogs(rec, httptest.NewRequest(http.MethodGet, "/logs?module=logs-test", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 without a token, got %d", rec.Code)
+	}
+	if strings.Contains(rec.Body.String(), "secret") {
+		t.Fatalf("expected no log lines in a denied response, got %q", rec.Body.String())
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/logs?module=logs-test", nil)
+	req.Header.Set("Authorization", "Bearer operator")
+	rec = httptest.NewRecorder()
+	w.handleLogs(rec, req)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "secret") {
+		t.Fatalf("expected the operator to read the logs, got %d %q", rec.Code, rec.Body.String())
+	}
+	if subject.Token != "operator" || subject.SourceIP != "192.0.2.1" {
+		t.Errorf("expected the policy to see the token and source ip, got %+v", subject)
+	}
+}
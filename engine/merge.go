@@ -0,0 +1,123 @@
+package engine
+
+import (
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geo"
+	"github.com/projectqai/hydra/goclient"
+	pb "github.com/projectqai/proto/go"
+)
+
+// MergeConfig enables merging of entities that different feeds report under
+// different ids. A new id pushed by one of Controllers is folded into an existing
+// entity of another controller if it is within Distance meters, was measured within
+// MaxAge of it and has a compatible symbol. The new id is kept as an alias.
+type MergeConfig struct {
+	Distance    float64
+	MaxAge      time.Duration
+	Controllers []string
+}
+
+func (c *MergeConfig) enabled() bool {
+	return c != nil && c.Distance > 0 && len(c.Controllers) > 0
+}
+
+// resolveAlias rewrites the id of a pushed entity if it was merged into another
+// entity before, or merges it now if it qualifies. Caller must hold s.l.
+func (s *WorldServer) resolveAlias(e *pb.Entity) {
+	if !s.merge.enabled() {
+		return
+	}
+
+	if target, ok := s.aliases[e.Id]; ok {
+		if s.head.Has(target) {
+			e.Id = target
+			return
+		}
+		delete(s.aliases, e.Id)
+	}
+
+	if s.head.Has(e.Id) {
+		return
+	}
+	if e.Geo == nil || e.Controller == nil || !slices.Contains(s.merge.Controllers, e.Controller.Name) {
+		return
+	}
+
+	target := s.mergeCandidate(e)
+	if target == "" {
+		return
+	}
+
+	slog.Info("merging entity into nearby entity", "id", e.Id, "into", target)
+	s.aliases[e.Id] = target
+	e.Id = target
+}
+
+func (s *WorldServer) mergeCandidate(e *pb.Entity) string {
+	p := orb.Point{e.Geo.Longitude, e.Geo.Latitude}
+	at, _ := lastMeasured(e)
+
+	best := ""
+	bestDistance := s.merge.Distance
+	for id, other := range s.head.All() {
+		if other.Geo == nil {
+			continue
+		}
+		// only merge across feeds, a single feed knows its objects apart
+		if other.Controller != nil && other.Controller.Id == e.Controller.Id {
+			continue
+		}
+		if s.merge.MaxAge > 0 {
+			otherAt, ok := lastMeasured(other)
+			if !ok || absDuration(at.Sub(otherAt)) > s.merge.MaxAge {
+				continue
+			}
+		}
+		if !compatibleSymbols(e.Symbol, other.Symbol) {
+			continue
+		}
+		d := geo.Distance(p, orb.Point{other.Geo.Longitude, other.Geo.Latitude})
+		if d <= bestDistance {
+			best = id
+			bestDistance = d
+		}
+	}
+	return best
+}
+
+// aliasesOf returns the ids merged into id. Caller must hold s.l.
+func (s *WorldServer) aliasesOf(id string) []string {
+	var aliases []string
+	for alias, target := range s.aliases {
+		if target == id {
+			aliases = append(aliases, alias)
+		}
+	}
+	slices.Sort(aliases)
+	return aliases
+}
+
+// compatibleSymbols compares battle dimension and affiliation of two 2525C codes.
+// Unknown or pending affiliation matches any affiliation, and so do icons.
+func compatibleSymbols(a, b *pb.SymbolComponent) bool {
+	sa, sb := goclient.SIDC(a), goclient.SIDC(b)
+	if len(sa) < 3 || len(sb) < 3 {
+		return true
+	}
+	if sa[2] != sb[2] {
+		return false
+	}
+	unknown := func(c byte) bool { return c == 'U' || c == 'P' || c == '-' || c == '*' }
+	return sa[1] == sb[1] || unknown(sa[1]) || unknown(sb[1])
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
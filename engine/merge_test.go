@@ -0,0 +1,58 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestResolveAlias_MergesAcrossFeeds(t *testing.T) {
+	now := timestamppb.Now()
+	world := testWorld(map[string]*pb.Entity{
+		"ais-1": {
+			Id:         "ais-1",
+			Controller: &pb.ControllerRef{Id: "ais-feed", Name: "ais"},
+			Lifetime:   &pb.Lifetime{From: now},
+			Geo:        &pb.GeoSpatialComponent{Longitude: 10, Latitude: 50},
+			Symbol:     &pb.SymbolComponent{MilStd2525C: "SNSP------"},
+		},
+	})
+	world.aliases = make(map[string]string)
+	world.merge = &MergeConfig{Distance: 100, MaxAge: 10 * time.Second, Controllers: []string{"radar"}}
+
+	near := &pb.Entity{
+		Id:         "radar-7",
+		Controller: &pb.ControllerRef{Id: "radar-feed", Name: "radar"},
+		Lifetime:   &pb.Lifetime{From: now},
+		Geo:        &pb.GeoSpatialComponent{Longitude: 10.0005, Latitude: 50},
+		Symbol:     &pb.SymbolComponent{MilStd2525C: "SUSP------"},
+	}
+	world.resolveAlias(near)
+	if near.Id != "ais-1" {
+		t.Fatalf("expected merge into ais-1, got %s", near.Id)
+	}
+
+	// later updates under the alias go to the same entity
+	again := &pb.Entity{Id: "radar-7", Controller: near.Controller}
+	world.resolveAlias(again)
+	if again.Id != "ais-1" {
+		t.Errorf("expected alias to resolve to ais-1, got %s", again.Id)
+	}
+
+	hostile := proto.Clone(near).(*pb.Entity)
+	hostile.Id = "radar-8"
+	hostile.Symbol.MilStd2525C = "SHAP------"
+	world.resolveAlias(hostile)
+	if hostile.Id != "radar-8" {
+		t.Errorf("incompatible symbol must not merge, got %s", hostile.Id)
+	}
+
+	ais := &pb.Entity{Id: "ais-2", Controller: &pb.ControllerRef{Id: "ais-feed", Name: "ais"}, Geo: near.Geo}
+	world.resolveAlias(ais)
+	if ais.Id != "ais-2" {
+		t.Errorf("controller not in scope must not merge, got %s", ais.Id)
+	}
+}
@@ -9,10 +9,28 @@ import (
 func (s *WorldServer) EntityCount() int {
 	s.l.RLock()
 	defer s.l.RUnlock()
-	return len(s.head)
+	return s.head.Len()
+}
+
+// consumerStats lists the counters of all connected watch clients
+func (s *WorldServer) consumerStats() []metrics.ConsumerStats {
+	s.bus.mu.RLock()
+	defer s.bus.mu.RUnlock()
+	stats := make([]metrics.ConsumerStats, 0, len(s.bus.consumers))
+	for c := range s.bus.consumers {
+		stats = append(stats, metrics.ConsumerStats{
+			Peer:          c.peer,
+			Sent:          c.sent.Load(),
+			Coalesced:     c.coalesced.Load(),
+			RateLimited:   c.rateLimited.Load(),
+			RateLimitWait: time.Duration(c.rateWait.Load()),
+		})
+	}
+	return stats
 }
 
 func StartMetricsUpdater(server *WorldServer) {
+	metrics.SetConsumerStatsSource(server.consumerStats)
 	go func() {
 		ticker := time.NewTicker(time.Second)
 		defer ticker.Stop()
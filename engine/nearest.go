@@ -0,0 +1,128 @@
+package engine
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"net/http"
+	"path"
+	"slices"
+
+	"github.com/paulmach/orb"
+	"github.com/projectqai/hydra/goclient"
+	"github.com/projectqai/hydra/policy"
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const (
+	defaultNearestK = 10
+	// maxNearestK caps k, larger requests get the maxNearestK closest entities
+	maxNearestK = 1000
+)
+
+type nearestCandidate struct {
+	entity   *pb.Entity
+	distance float64
+}
+
+// nearest returns up to k entities closest to target that match filter and sidc.
+// There is no spatial index, so every call scans all entities in the head and
+// is O(n). Only the k closest are kept while scanning.
+func (s *WorldServer) nearest(ctx context.Context, ability *policy.Ability, target orb.Geometry, k int, sidc string, filter *pb.EntityFilter) []nearestCandidate {
+	k = min(k, maxNearestK)
+
+	s.l.RLock()
+	defer s.l.RUnlock()
+
+	byDistance := func(a, b nearestCandidate) int { return cmp.Compare(a.distance, b.distance) }
+	candidates := make([]nearestCandidate, 0, k+1)
+	for _, e := range s.head.All() {
+		p, ok := entityPoint(e)
+		if !ok {
+			continue
+		}
+		if sidc != "" {
+			if e.Symbol == nil {
+				continue
+			}
+			if ok, _ := path.Match(sidc, e.Symbol.MilStd2525C); !ok {
+				continue
+			}
+		}
+		if !s.matchesEntityFilter(e, filter) {
+			continue
+		}
+		if !ability.CanRead(ctx, e, s.head.Marking(e.Id)) {
+			continue
+		}
+		c := nearestCandidate{entity: e, distance: goclient.DistanceToGeometry(p, target)}
+		if len(candidates) == k && c.distance >= candidates[k-1].distance {
+			continue
+		}
+		i, _ := slices.BinarySearchFunc(candidates, c, byDistance)
+		candidates = slices.Insert(candidates, i, c)
+		if len(candidates) > k {
+			candidates = candidates[:k]
+		}
+	}
+	return candidates
+}
+
+func (s *WorldServer) handleNearest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req goclient.NearestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.K <= 0 {
+		req.K = defaultNearestK
+	}
+	if _, err := path.Match(req.Sidc, ""); err != nil {
+		http.Error(w, "invalid sidc pattern: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var filter *pb.EntityFilter
+	if len(req.Filter) > 0 {
+		filter = &pb.EntityFilter{}
+		if err := protojson.Unmarshal(req.Filter, filter); err != nil {
+			http.Error(w, "invalid filter: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	var target orb.Geometry = orb.Point{req.Lon, req.Lat}
+	if len(req.Geometry) > 0 {
+		g := &pb.Geometry{}
+		if err := protojson.Unmarshal(req.Geometry, g); err != nil {
+			http.Error(w, "invalid geometry: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if target = goclient.GeometryToOrb(g); target == nil {
+			http.Error(w, "geometry is empty or invalid", http.StatusBadRequest)
+			return
+		}
+	}
+
+	ability := policy.ForHTTP(s.policy, r)
+	candidates := s.nearest(r.Context(), ability, target, req.K, req.Sidc, filter)
+
+	resp := goclient.NearestResponse{Results: make([]goclient.NearestResult, 0, len(candidates))}
+	for _, c := range candidates {
+		entity, err := protojson.Marshal(c.entity)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		resp.Results = append(resp.Results, goclient.NearestResult{Distance: c.distance, Entity: entity})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
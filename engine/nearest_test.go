@@ -0,0 +1,38 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/paulmach/orb"
+	"github.com/projectqai/hydra/policy"
+	pb "github.com/projectqai/proto/go"
+)
+
+func TestNearest_KeepsKClosestInOrder(t *testing.T) {
+	entities := map[string]*pb.Entity{
+		"no-position": {Id: "no-position"},
+	}
+	// further east the higher the number, inserted in map order
+	for i := 5; i >= 1; i-- {
+		id := fmt.Sprintf("e%d", i)
+		entities[id] = &pb.Entity{Id: id, Geo: &pb.GeoSpatialComponent{Latitude: 50, Longitude: 10 + float64(i)*0.01}}
+	}
+	w := testWorld(entities)
+	ability := policy.For(context.Background(), nil, "")
+
+	got := w.nearest(context.Background(), ability, orb.Point{10, 50}, 3, "", nil)
+	if len(got) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(got))
+	}
+	for i, c := range got {
+		if want := fmt.Sprintf("e%d", i+1); c.entity.Id != want {
+			t.Errorf("result %d is %s, want %s", i, c.entity.Id, want)
+		}
+	}
+
+	if got := w.nearest(context.Background(), ability, orb.Point{10, 50}, maxNearestK+1, "", nil); len(got) != 5 {
+		t.Errorf("expected all 5 located entities for a k above the cap, got %d", len(got))
+	}
+}
@@ -1,6 +1,9 @@
 package engine
 
-import "net"
+import (
+	"net"
+	"strconv"
+)
 
 func getAllLocalIPs() []string {
 	var ips []string
@@ -47,3 +50,21 @@ func getAllLocalIPs() []string {
 
 	return ips
 }
+
+// bannerURLs returns the addresses to reach a server listening on addr with,
+// local is empty if it isn't reachable from this machine as localhost
+func bannerURLs(addr *net.TCPAddr) (local string, network []string) {
+	port := strconv.Itoa(addr.Port)
+	switch {
+	case addr.IP.IsUnspecified():
+		local = net.JoinHostPort("localhost", port)
+		for _, ip := range getAllLocalIPs() {
+			network = append(network, net.JoinHostPort(ip, port))
+		}
+	case addr.IP.IsLoopback():
+		local = net.JoinHostPort("localhost", port)
+	default:
+		network = []string{net.JoinHostPort(addr.IP.String(), port)}
+	}
+	return local, network
+}
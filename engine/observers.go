@@ -3,6 +3,7 @@ package engine
 import (
 	"context"
 
+	"github.com/projectqai/hydra/goclient"
 	"github.com/projectqai/hydra/policy"
 	pb "github.com/projectqai/proto/go"
 
@@ -10,8 +11,31 @@ import (
 )
 
 func (s *WorldServer) WatchEntities(ctx context.Context, req *connect.Request[pb.ListEntitiesRequest], stream *connect.ServerStream[pb.EntityChangeEvent]) error {
-	ability := policy.For(s.policy, req.Peer().Addr)
+	ability := policy.For(ctx, s.policy, req.Peer().Addr)
+	opts, err := parseRequestOptions(req.Header())
+	if err != nil {
+		return connect.NewError(connect.CodeInvalidArgument, err)
+	}
+	if err := validateGeoFilter(req.Msg.Filter); err != nil {
+		return connect.NewError(connect.CodeInvalidArgument, err)
+	}
+
+	watchers := s.watchers(req.Peer().Addr)
+	if !watchers.join() {
+		return errShuttingDown
+	}
+	defer watchers.leave()
+
 	consumer := NewConsumer(s, ability, req.Msg.WatchLimiter, req.Msg.Filter)
+	consumer.options = opts
+	consumer.peer = req.Peer().Addr
+	consumer.closing = watchers.closing
+	consumer.maxPending = s.watchBufferSize
+	consumer.setPriorityRates(opts.priorityRates)
+	consumer.boostCreated, consumer.boostExpired = opts.boostCreated, opts.boostExpired
+	if opts.watchBurst > 0 {
+		consumer.setBurst(opts.watchBurst)
+	}
 	s.bus.Register(consumer)
 	defer s.bus.Unregister(consumer)
 
@@ -22,14 +46,26 @@ func (s *WorldServer) WatchEntities(ctx context.Context, req *connect.Request[pb
 		return err
 	}
 
-	// Mark all current entities as dirty, since we don't know what the consumer missed
+	// Resuming watchers only get what they missed. Otherwise mark all current
+	// entities as dirty, since we don't know what the consumer missed.
 	s.l.RLock()
-	for id, e := range s.head {
-		priority := pb.Priority_PriorityRoutine
-		if e.Priority != nil {
-			priority = *e.Priority
+	resumed := opts.resume != "" && opts.resume != goclient.ResumeStart && s.resumeFrom(consumer, opts.resume)
+	if !resumed && !opts.liveOnly {
+		for id, e := range s.head.All() {
+			priority := pb.Priority_PriorityRoutine
+			if e.Priority != nil {
+				priority = *e.Priority
+			}
+			consumer.markDirty(id, priority, pb.EntityChange_EntityChangeUpdated)
+		}
+	}
+	if opts.transitions {
+		// entities matching already don't start matching later
+		for e, marking := range s.head.Entries() {
+			if consumer.visible(ctx, e, marking) {
+				consumer.matched[e.Id] = struct{}{}
+			}
 		}
-		consumer.markDirty(id, priority, pb.EntityChange_EntityChangeUpdated)
 	}
 	s.l.RUnlock()
 
@@ -0,0 +1,299 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/projectqai/hydra/goclient"
+	"github.com/projectqai/hydra/policy"
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/proto"
+)
+
+// requestOptions are per-request settings that are not part of the world proto.
+// They are passed as request headers, see goclient.Header*.
+type requestOptions struct {
+	parent string
+
+	// deadReckoning is the max extrapolation age, zero disables it
+	deadReckoning time.Duration
+
+	// uncertaintySigma pads entities by their position uncertainty in geo filters, zero disables it
+	uncertaintySigma float64
+
+	// altitudeBand limits entities to altitudes within [min, max] meters if set
+	altitudeBand *[2]float64
+
+	// clearance drops entities with a marking it doesn't permit if set
+	clearance *policy.Marking
+
+	// minConfidence drops entities pushed with a lower confidence, zero disables it
+	minConfidence float64
+
+	// minSeen drops entities pushed fewer times since they were created, zero disables it
+	minSeen uint64
+
+	// symbology2525D presents symbols as MIL-STD-2525D, see goclient.HeaderSymbology
+	symbology2525D bool
+
+	// liveOnly skips the initial snapshot of watches
+	liveOnly bool
+
+	// resume asks watches for resume tokens, it is either goclient.ResumeStart or a token to resume from
+	resume string
+
+	// watchBurst overrides the burst of the watch rate limit if positive
+	watchBurst int
+
+	// priorityRates are per-priority watch budgets in messages per second
+	priorityRates map[pb.Priority]float64
+
+	// expiryReason sends why entities expired before their expiry, see goclient.ExpiryReasonPrefix
+	expiryReason bool
+
+	// transitions tells watchers when entities start and stop matching, see goclient.MatchEnterPrefix
+	transitions bool
+
+	// boostCreated and boostExpired raise the priority of appearances and expiries, unspecified leaves them
+	boostCreated pb.Priority
+	boostExpired pb.Priority
+}
+
+func parseRequestOptions(h http.Header) (requestOptions, error) {
+	opts := requestOptions{
+		parent: h.Get(goclient.HeaderParent),
+		resume: h.Get(goclient.HeaderResume),
+	}
+
+	if v := h.Get(goclient.HeaderDeadReckoning); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return opts, fmt.Errorf("invalid %s: %q", goclient.HeaderDeadReckoning, v)
+		}
+		opts.deadReckoning = d
+	}
+
+	if v := h.Get(goclient.HeaderGeoUncertainty); v != "" {
+		sigma, err := strconv.ParseFloat(v, 64)
+		if err != nil || sigma < 0 || math.IsInf(sigma, 0) {
+			return opts, fmt.Errorf("invalid %s: %q", goclient.HeaderGeoUncertainty, v)
+		}
+		opts.uncertaintySigma = sigma
+	}
+
+	if v := h.Get(goclient.HeaderAltitudeBand); v != "" {
+		band, err := parseAltitudeBand(v)
+		if err != nil {
+			return opts, fmt.Errorf("invalid %s: %q", goclient.HeaderAltitudeBand, v)
+		}
+		opts.altitudeBand = band
+	}
+
+	if v := h.Get(goclient.HeaderMinConfidence); v != "" {
+		min, err := parseConfidence(v)
+		if err != nil {
+			return opts, fmt.Errorf("invalid %s: %q", goclient.HeaderMinConfidence, v)
+		}
+		opts.minConfidence = min
+	}
+
+	if v := h.Get(goclient.HeaderMinSeen); v != "" {
+		min, err := parseSeenCount(v)
+		if err != nil {
+			return opts, fmt.Errorf("invalid %s: %q", goclient.HeaderMinSeen, v)
+		}
+		opts.minSeen = min
+	}
+
+	switch v := strings.ToLower(h.Get(goclient.HeaderSymbology)); v {
+	case "", goclient.Symbology2525C:
+	case goclient.Symbology2525D:
+		opts.symbology2525D = true
+	default:
+		return opts, fmt.Errorf("invalid %s: %q, expected %s or %s", goclient.HeaderSymbology, v, goclient.Symbology2525C, goclient.Symbology2525D)
+	}
+
+	if v := h.Get(goclient.HeaderLiveOnly); v != "" {
+		live, err := strconv.ParseBool(v)
+		if err != nil {
+			return opts, fmt.Errorf("invalid %s: %q", goclient.HeaderLiveOnly, v)
+		}
+		opts.liveOnly = live
+	}
+
+	if v := h.Get(goclient.HeaderExpiryReason); v != "" {
+		on, err := strconv.ParseBool(v)
+		if err != nil {
+			return opts, fmt.Errorf("invalid %s: %q", goclient.HeaderExpiryReason, v)
+		}
+		opts.expiryReason = on
+	}
+
+	if v := h.Get(goclient.HeaderWatchTransitions); v != "" {
+		on, err := strconv.ParseBool(v)
+		if err != nil {
+			return opts, fmt.Errorf("invalid %s: %q", goclient.HeaderWatchTransitions, v)
+		}
+		opts.transitions = on
+	}
+
+	if v := h.Get(goclient.HeaderWatchBurst); v != "" {
+		burst, err := strconv.Atoi(v)
+		if err != nil || burst < 1 {
+			return opts, fmt.Errorf("invalid %s: %q", goclient.HeaderWatchBurst, v)
+		}
+		opts.watchBurst = burst
+	}
+
+	if values := h.Values(goclient.HeaderWatchPriorityRate); len(values) > 0 {
+		rates, err := parsePriorityRates(strings.Join(values, ","))
+		if err != nil {
+			return opts, fmt.Errorf("invalid %s: %w", goclient.HeaderWatchPriorityRate, err)
+		}
+		opts.priorityRates = rates
+	}
+
+	if v := h.Get(goclient.HeaderWatchBoost); v != "" {
+		if err := opts.parseBoost(v); err != nil {
+			return opts, fmt.Errorf("invalid %s: %w", goclient.HeaderWatchBoost, err)
+		}
+	}
+
+	if v := h.Get(goclient.HeaderClearance); v != "" {
+		clearance, err := policy.ParseMarking(v)
+		if err != nil {
+			return opts, fmt.Errorf("invalid %s: %w", goclient.HeaderClearance, err)
+		}
+		opts.clearance = &clearance
+	}
+
+	return opts, nil
+}
+
+// parsePriorityRates reads "routine=5,immediate=50"
+func parsePriorityRates(v string) (map[pb.Priority]float64, error) {
+	rates := make(map[pb.Priority]float64)
+	for _, part := range strings.Split(v, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return nil, fmt.Errorf("expected priority=rate, got %q", part)
+		}
+		priority, ok := parsePriority(name)
+		if !ok || priority == pb.Priority_PriorityFlash {
+			return nil, fmt.Errorf("priority %q can't be limited, expected routine or immediate", name)
+		}
+		perSecond, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || perSecond <= 0 || math.IsInf(perSecond, 0) {
+			return nil, fmt.Errorf("invalid rate %q", value)
+		}
+		rates[priority] = perSecond
+	}
+	return rates, nil
+}
+
+// parseBoost reads "created=immediate,expired=flash"
+func (opts *requestOptions) parseBoost(v string) error {
+	for _, part := range strings.Split(v, ",") {
+		change, name, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return fmt.Errorf("expected change=priority, got %q", part)
+		}
+		priority, ok := parsePriority(name)
+		if !ok {
+			return fmt.Errorf("unknown priority %q", name)
+		}
+		switch strings.ToLower(strings.TrimSpace(change)) {
+		case "created":
+			opts.boostCreated = priority
+		case "expired":
+			opts.boostExpired = priority
+		default:
+			return fmt.Errorf("unknown change %q, expected created or expired", change)
+		}
+	}
+	return nil
+}
+
+// parsePriority reads a priority name like "immediate"
+func parsePriority(name string) (pb.Priority, bool) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "routine":
+		return pb.Priority_PriorityRoutine, true
+	case "immediate":
+		return pb.Priority_PriorityImmediate, true
+	case "flash":
+		return pb.Priority_PriorityFlash, true
+	}
+	return pb.Priority_PriorityUnspecified, false
+}
+
+// parseAltitudeBand reads "min,max", either side may be empty for an open bound
+func parseAltitudeBand(v string) (*[2]float64, error) {
+	lo, hi, ok := strings.Cut(v, ",")
+	if !ok {
+		return nil, fmt.Errorf("expected min,max")
+	}
+	band := [2]float64{math.Inf(-1), math.Inf(1)}
+	for i, s := range []string{lo, hi} {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil || math.IsNaN(f) {
+			return nil, fmt.Errorf("invalid altitude %q", s)
+		}
+		band[i] = f
+	}
+	if band[0] > band[1] {
+		return nil, fmt.Errorf("min is above max")
+	}
+	return &band, nil
+}
+
+func (o *requestOptions) matches(entity *pb.Entity) bool {
+	if o.parent != "" && !hasParent(entity, o.parent) {
+		return false
+	}
+	if o.altitudeBand != nil {
+		if entity.Geo == nil || entity.Geo.Altitude == nil {
+			return false
+		}
+		if alt := *entity.Geo.Altitude; alt < o.altitudeBand[0] || alt > o.altitudeBand[1] {
+			return false
+		}
+	}
+	return true
+}
+
+// cleared reports whether an entity with marking may be sent to the requester
+func (o *requestOptions) cleared(marking policy.Marking) bool {
+	return o.clearance == nil || marking.Permits(*o.clearance)
+}
+
+// present applies view transformations to an entity about to be sent.
+// The returned entity must not be stored.
+func (o *requestOptions) present(entity *pb.Entity, now time.Time) *pb.Entity {
+	if o.deadReckoning > 0 {
+		entity = deadReckon(entity, now, o.deadReckoning)
+	}
+	if o.symbology2525D {
+		entity = with2525D(entity)
+	}
+	return entity
+}
+
+// with2525D returns entity with its symbol converted to MIL-STD-2525D if it
+// has an equivalent, the stored entity keeps the 2525C code
+func with2525D(entity *pb.Entity) *pb.Entity {
+	sidc, ok := goclient.SIDC2525D(entity.GetSymbol().GetMilStd2525C())
+	if !ok {
+		return entity
+	}
+	entity = proto.Clone(entity).(*pb.Entity)
+	entity.Symbol.MilStd2525C = sidc
+	return entity
+}
@@ -0,0 +1,128 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/projectqai/hydra/goclient"
+	pb "github.com/projectqai/proto/go"
+)
+
+func TestRequestOptions_AltitudeBand(t *testing.T) {
+	h := http.Header{}
+	h.Set(goclient.HeaderAltitudeBand, "1000,")
+	opts, err := parseRequestOptions(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	at := func(alt *float64) *pb.Entity {
+		return &pb.Entity{Id: "a", Geo: &pb.GeoSpatialComponent{Longitude: 10, Latitude: 50, Altitude: alt}}
+	}
+	if !opts.matches(at(ptr(3000.0))) {
+		t.Error("expected entity above the floor to match")
+	}
+	if opts.matches(at(ptr(500.0))) {
+		t.Error("expected entity below the floor not to match")
+	}
+	if opts.matches(at(nil)) {
+		t.Error("expected entity without altitude not to match")
+	}
+
+	h.Set(goclient.HeaderAltitudeBand, "2000,1000")
+	if _, err := parseRequestOptions(h); err == nil {
+		t.Error("expected inverted band to be rejected")
+	}
+}
+
+func TestListEntities_Clearance(t *testing.T) {
+	w := testWorld(map[string]*pb.Entity{})
+
+	push := func(id, classification string) {
+		req := connect.NewRequest(&pb.EntityChangeRequest{Changes: []*pb.Entity{{Id: id}}})
+		if classification != "" {
+			req.Header().Set(goclient.HeaderClassification, classification)
+		}
+		if _, err := w.Push(context.Background(), req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	push("open", "")
+	push("conf", "CONFIDENTIAL")
+	push("secret-rel", "SECRET//REL TO DEU")
+	// pushes without a classification keep the previous one
+	push("conf", "")
+
+	list := func(clearance string) []string {
+		req := connect.NewRequest(&pb.ListEntitiesRequest{})
+		req.Header().Set(goclient.HeaderClearance, clearance)
+		resp, err := w.ListEntities(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []string
+		for _, e := range resp.Msg.Entities {
+			ids = append(ids, e.Id)
+		}
+		return ids
+	}
+
+	if got := list("C"); fmt.Sprint(got) != "[conf open]" {
+		t.Errorf("CONFIDENTIAL clearance: got %v", got)
+	}
+	if got := list("SECRET//REL TO FRA"); fmt.Sprint(got) != "[conf open]" {
+		t.Errorf("SECRET clearance of another nation: got %v", got)
+	}
+	if got := list("TS//REL TO DEU"); fmt.Sprint(got) != "[conf open secret-rel]" {
+		t.Errorf("TOP SECRET clearance: got %v", got)
+	}
+}
+
+func TestSymbology_2525DProjection(t *testing.T) {
+	for sidc, want := range map[string]string{
+		"SHGPUCI---*****":                   "10061000001211000000",
+		"SFGPUCI---AF***":                   "10031002161211000000",
+		"SFAPMFQ---*****":                   "10030100001103000000",
+		"SFAPMFB---*****":                   "10030100001101000000",
+		"SNSPCLDD--*****":                   "10043000001202030000",
+		"GFGPGLB---****X":                   "",
+		"icon:https://example.org/boat.png": "",
+	} {
+		got, ok := goclient.SIDC2525D(sidc)
+		if got != want || ok != (want != "") {
+			t.Errorf("SIDC2525D(%q) = %q, %v, want %q", sidc, got, ok, want)
+		}
+	}
+
+	server := NewTestServer(t)
+	ctx := context.Background()
+	_, err := server.Client.Push(ctx, &pb.EntityChangeRequest{Changes: []*pb.Entity{
+		{Id: "infantry", Symbol: &pb.SymbolComponent{MilStd2525C: "SHGPUCI---*****"}},
+		{Id: "boat", Symbol: &pb.SymbolComponent{MilStd2525C: "emoji:🚤"}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := server.Client.ListEntities(goclient.WithSymbology(ctx, goclient.Symbology2525D), &pb.ListEntitiesRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	symbols := map[string]string{}
+	for _, e := range resp.Entities {
+		symbols[e.Id] = e.GetSymbol().GetMilStd2525C()
+	}
+	if symbols["infantry"] != "10061000001211000000" || symbols["boat"] != "emoji:🚤" {
+		t.Errorf("expected infantry in 2525D and the emoji unchanged, got %v", symbols)
+	}
+	if stored := server.World.GetHead("infantry").GetSymbol().GetMilStd2525C(); stored != "SHGPUCI---*****" {
+		t.Errorf("expected 2525C to stay stored, got %q", stored)
+	}
+
+	if _, err := server.Client.GetEntity(goclient.WithSymbology(ctx, "app6"), &pb.GetEntityRequest{Id: "infantry"}); err == nil {
+		t.Error("expected an unknown symbology to be rejected")
+	}
+}
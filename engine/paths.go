@@ -0,0 +1,143 @@
+package engine
+
+import (
+	"math"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geo"
+	"github.com/paulmach/orb/planar"
+)
+
+// maxPathSegment is the longest segment of a path tested as a straight line in
+// lon/lat, longer ones are split along the great circle first
+const maxPathSegment = 50_000
+
+// greatCirclePath adds points to segments longer than maxSegment meters, so the
+// path follows the great circle between its points, as aircraft and ships do
+func greatCirclePath(line orb.LineString, maxSegment float64) orb.LineString {
+	if len(line) < 2 {
+		return line
+	}
+	result := orb.LineString{line[0]}
+	for i := 1; i < len(line); i++ {
+		a, b := line[i-1], line[i]
+		n := math.Ceil(geo.Distance(a, b) / maxSegment)
+		for j := 1.0; j < n; j++ {
+			result = append(result, greatCirclePoint(a, b, j/n))
+		}
+		result = append(result, b)
+	}
+	return result
+}
+
+// greatCirclePoint is the point at fraction f of the great circle from a to b
+func greatCirclePoint(a, b orb.Point, f float64) orb.Point {
+	lon1, lat1 := deg2rad(a[0]), deg2rad(a[1])
+	lon2, lat2 := deg2rad(b[0]), deg2rad(b[1])
+	d := geo.Distance(a, b) / orb.EarthRadius
+	if d == 0 {
+		return a
+	}
+	ka := math.Sin((1-f)*d) / math.Sin(d)
+	kb := math.Sin(f*d) / math.Sin(d)
+	x := ka*math.Cos(lat1)*math.Cos(lon1) + kb*math.Cos(lat2)*math.Cos(lon2)
+	y := ka*math.Cos(lat1)*math.Sin(lon1) + kb*math.Cos(lat2)*math.Sin(lon2)
+	z := ka*math.Sin(lat1) + kb*math.Sin(lat2)
+	return orb.Point{rad2deg(math.Atan2(y, x)), rad2deg(math.Atan2(z, math.Hypot(x, y)))}
+}
+
+func deg2rad(d float64) float64 { return d * math.Pi / 180 }
+func rad2deg(r float64) float64 { return r * 180 / math.Pi }
+
+// pathIntersects tests whether a path crosses or lies within g
+func pathIntersects(path orb.MultiLineString, g orb.Geometry) bool {
+	for _, line := range path {
+		if lineIntersects(greatCirclePath(line, maxPathSegment), g) {
+			return true
+		}
+	}
+	return false
+}
+
+func lineIntersects(line orb.LineString, g orb.Geometry) bool {
+	switch g := g.(type) {
+	case orb.Bound:
+		return lineIntersects(line, g.ToPolygon())
+	case orb.Ring:
+		return lineIntersects(line, orb.Polygon{g})
+	case orb.Polygon:
+		if len(g) == 0 || len(line) == 0 {
+			return false
+		}
+		// either the line starts inside, or it crosses the outline to get in
+		if planar.PolygonContains(g, line[0]) {
+			return true
+		}
+		for _, ring := range g {
+			if linesCross(line, closedPath(ring)) {
+				return true
+			}
+		}
+		return false
+	case orb.MultiPolygon:
+		for _, p := range g {
+			if lineIntersects(line, p) {
+				return true
+			}
+		}
+		return false
+	case orb.LineString:
+		return linesCross(line, g)
+	case orb.MultiLineString:
+		for _, l := range g {
+			if linesCross(line, l) {
+				return true
+			}
+		}
+		return false
+	case orb.Collection:
+		for _, part := range g {
+			if lineIntersects(line, part) {
+				return true
+			}
+		}
+		return false
+	}
+	// points can't be hit exactly, fall back to bounds
+	return boundIntersects(line.Bound(), g)
+}
+
+// linesCross tests whether any segment of a touches any segment of b
+func linesCross(a, b orb.LineString) bool {
+	for i := 1; i < len(a); i++ {
+		for j := 1; j < len(b); j++ {
+			if segmentsIntersect(a[i-1], a[i], b[j-1], b[j]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func segmentsIntersect(p1, p2, q1, q2 orb.Point) bool {
+	d1 := orientation(q1, q2, p1)
+	d2 := orientation(q1, q2, p2)
+	d3 := orientation(p1, p2, q1)
+	d4 := orientation(p1, p2, q2)
+	if ((d1 > 0 && d2 < 0) || (d1 < 0 && d2 > 0)) && ((d3 > 0 && d4 < 0) || (d3 < 0 && d4 > 0)) {
+		return true
+	}
+	return (d1 == 0 && onSegment(q1, q2, p1)) || (d2 == 0 && onSegment(q1, q2, p2)) ||
+		(d3 == 0 && onSegment(p1, p2, q1)) || (d4 == 0 && onSegment(p1, p2, q2))
+}
+
+// orientation is positive if c is left of a->b, negative if right and zero if collinear
+func orientation(a, b, c orb.Point) float64 {
+	return (b[0]-a[0])*(c[1]-a[1]) - (b[1]-a[1])*(c[0]-a[0])
+}
+
+// onSegment tests whether collinear point p lies within segment a-b
+func onSegment(a, b, p orb.Point) bool {
+	return p[0] >= math.Min(a[0], b[0]) && p[0] <= math.Max(a[0], b[0]) &&
+		p[1] >= math.Min(a[1], b[1]) && p[1] <= math.Max(a[1], b[1])
+}
@@ -38,7 +38,7 @@ func (s *WorldServer) LoadFromFile(path string) error {
 	defer s.l.Unlock()
 
 	for _, e := range entities {
-		s.head[e.Id] = e
+		s.head.Set(e.Id, e)
 		s.bus.Dirty(e.Id, e, pb.EntityChange_EntityChangeUpdated)
 	}
 
@@ -106,8 +106,8 @@ func (s *WorldServer) FlushToFile() error {
 	}
 
 	s.l.RLock()
-	entities := make([]*pb.Entity, 0, len(s.head))
-	for _, e := range s.head {
+	entities := make([]*pb.Entity, 0, s.head.Len())
+	for _, e := range s.head.All() {
 		if shouldPersist(e) {
 			entities = append(entities, e)
 		}
@@ -0,0 +1,107 @@
+package engine
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/projectqai/hydra/policy"
+	pb "github.com/projectqai/proto/go"
+)
+
+func TestPush_WritePolicySeesSubjectAndExisting(t *testing.T) {
+	var mu sync.Mutex
+	var writes []policy.Input
+
+	w := testWorld(nil)
+	// keep connectors from overwriting the entities of another
+	w.policy = policy.NewEngineFunc(func(ctx context.Context, input policy.Input) bool {
+		if input.Action != policy.ActionWrite {
+			return true
+		}
+		mu.Lock()
+		writes = append(writes, input)
+		mu.Unlock()
+		return input.Existing == nil || input.Existing.GetController().GetId() == input.Entity.GetController().GetId()
+	})
+
+	push := func(controller string) error {
+		req := connect.NewRequest(&pb.EntityChangeRequest{Changes: []*pb.Entity{{
+			Id:         "track-1",
+			Controller: &pb.ControllerRef{Id: controller},
+			Geo:        &pb.GeoSpatialComponent{Latitude: 50, Longitude: 10},
+		}}})
+		// as put there by policy.TokenInterceptor
+		_, err := w.Push(policy.WithToken(context.Background(), controller+"-key"), req)
+		return err
+	}
+
+	if err := push("tak"); err != nil {
+		t.Fatalf("expected the first push to be allowed: %v", err)
+	}
+	if err := push("ais"); connect.CodeOf(err) != connect.CodePermissionDenied {
+		t.Fatalf("expected overwriting the entity of another controller to be denied, got %v", err)
+	}
+	if err := push("tak"); err != nil {
+		t.Fatalf("expected the owning controller to update its entity: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(writes) != 3 {
+		t.Fatalf("expected 3 write checks, got %d", len(writes))
+	}
+	if writes[0].Existing != nil {
+		t.Errorf("expected no existing entity for a new one, got %v", writes[0].Existing)
+	}
+	if got := writes[1].Existing.GetController().GetId(); got != "tak" {
+		t.Errorf("expected the stored tak entity as existing, got controller %q", got)
+	}
+	for _, input := range writes {
+		if want := input.Entity.GetController().GetId() + "-key"; input.Subject.Token != want || input.Subject.Builtin {
+			t.Errorf("expected subject with token %q from the network, got %+v", want, input.Subject)
+		}
+	}
+	if got := w.head.Get("track-1").GetController().GetId(); got != "tak" {
+		t.Errorf("expected the denied push not to be applied, stored controller is %q", got)
+	}
+}
+
+func TestAsBuiltin_DistinctSubject(t *testing.T) {
+	var subject policy.Subject
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		subject = policy.ForHTTP(nil, r).Subject()
+	})
+
+	external := httptest.NewRequest(http.MethodGet, "/", nil)
+	external.RemoteAddr = "192.0.2.1:4711"
+	h.ServeHTTP(httptest.NewRecorder(), external)
+	if subject.Builtin || subject.SourceIP != "192.0.2.1" {
+		t.Errorf("expected external subject from 192.0.2.1, got %+v", subject)
+	}
+
+	// the in-process transport reports its own address, which must not matter
+	internal := httptest.NewRequest(http.MethodGet, "/", nil)
+	internal.RemoteAddr = "bufconn"
+	asBuiltin(h).ServeHTTP(httptest.NewRecorder(), internal)
+	if !subject.Builtin {
+		t.Errorf("expected builtin subject, got %+v", subject)
+	}
+}
+
+func TestSubject_BearerToken(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "192.0.2.1:4711"
+	r.Header.Set("Authorization", "Bearer s3cret")
+	if subject := policy.ForHTTP(nil, r).Subject(); subject.Token != "s3cret" || subject.SourceIP != "192.0.2.1" {
+		t.Errorf("expected token and source ip, got %+v", subject)
+	}
+
+	r.Header.Set("Authorization", "Basic dXNlcjpwYXNz")
+	if subject := policy.ForHTTP(nil, r).Subject(); subject.Token != "" {
+		t.Errorf("expected no token for basic auth, got %+v", subject)
+	}
+}
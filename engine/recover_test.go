@@ -0,0 +1,36 @@
+package engine
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"connectrpc.com/connect"
+	pb "github.com/projectqai/proto/go"
+	"github.com/projectqai/proto/go/_goconnect"
+)
+
+// panickingWorld fails every ListEntities with a panic
+type panickingWorld struct {
+	_goconnect.UnimplementedWorldServiceHandler
+}
+
+func (panickingWorld) ListEntities(context.Context, *connect.Request[pb.ListEntitiesRequest]) (*connect.Response[pb.ListEntitiesResponse], error) {
+	panic("malformed geometry")
+}
+
+func TestHandlerPanic_ReturnsInternalError(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle(_goconnect.NewWorldServiceHandler(panickingWorld{}, handlerOptions()...))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := _goconnect.NewWorldServiceClient(server.Client(), server.URL)
+	for range 2 {
+		_, err := client.ListEntities(context.Background(), connect.NewRequest(&pb.ListEntitiesRequest{}))
+		if connect.CodeOf(err) != connect.CodeInternal {
+			t.Fatalf("expected an internal error, got %v", err)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package engine
+
+import (
+	"github.com/projectqai/hydra/goclient"
+	pb "github.com/projectqai/proto/go"
+)
+
+// Relation types. Relations are derived from the references an entity
+// already carries, so they survive persistence and federation unchanged.
+const (
+	// the entity is positioned on its parent (locator), e.g. an aircraft on a carrier
+	relationLocated = "located"
+	// the entity is a detection made by its parent sensor
+	relationDetected = "detected"
+)
+
+type relation struct {
+	parent string
+	kind   string
+}
+
+func entityRelations(entity *pb.Entity) []relation {
+	var relations []relation
+	if entity.Locator != nil && entity.Locator.LocatedEntityId != "" && entity.Locator.LocatedEntityId != entity.Id {
+		relations = append(relations, relation{parent: entity.Locator.LocatedEntityId, kind: relationLocated})
+	}
+	if entity.Detection != nil && entity.Detection.DetectorEntityId != nil && *entity.Detection.DetectorEntityId != entity.Id {
+		relations = append(relations, relation{parent: *entity.Detection.DetectorEntityId, kind: relationDetected})
+	}
+	return relations
+}
+
+func hasParent(entity *pb.Entity, parentID string) bool {
+	for _, r := range entityRelations(entity) {
+		if r.parent == parentID {
+			return true
+		}
+	}
+	return false
+}
+
+// childIndex maps parent id to the ids of its children. Caller must hold s.l.
+func (s *WorldServer) childIndex() map[string][]string {
+	index := make(map[string][]string)
+	for id, e := range s.head.All() {
+		for _, r := range entityRelations(e) {
+			index[r.parent] = append(index[r.parent], id)
+		}
+	}
+	return index
+}
+
+// cascadeExpire removes all descendants of the given expired parents from head.
+// Caller must hold s.l.
+func (s *WorldServer) cascadeExpire(parents []string) {
+	if len(parents) == 0 {
+		return
+	}
+
+	children := s.childIndex()
+	for len(parents) > 0 {
+		parent := parents[len(parents)-1]
+		parents = parents[:len(parents)-1]
+
+		for _, id := range children[parent] {
+			child, ok := s.head.Lookup(id)
+			if !ok || goclient.IsSticky(child) {
+				continue
+			}
+			s.bury(id, child, goclient.ExpiryCascade)
+			parents = append(parents, id)
+		}
+	}
+}
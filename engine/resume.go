@@ -0,0 +1,90 @@
+package engine
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/projectqai/hydra/goclient"
+	pb "github.com/projectqai/proto/go"
+)
+
+// Resume tokens are "<epoch>.<seq>", where seq numbers the store events the
+// watcher has received. The epoch changes when the engine restarts or the
+// timeline moves, which invalidates all earlier tokens.
+
+func newEpoch() string {
+	return strconv.FormatInt(time.Now().UnixNano(), 36)
+}
+
+// resumeToken returns the token for the current store sequence, the caller holds s.l
+func (s *WorldServer) resumeToken() string {
+	return s.epoch + "." + strconv.FormatUint(s.store.Seq(), 10)
+}
+
+// resumeFrom marks the entities changed since token as dirty and reports whether
+// that covers everything the watcher missed. It returns false for tokens of
+// another epoch, or if the watcher missed changes older than the tombstone
+// retention, the watcher then needs a full snapshot. The caller holds s.l.
+func (s *WorldServer) resumeFrom(c *Consumer, token string) bool {
+	epoch, v, ok := strings.Cut(token, ".")
+	if !ok || epoch != s.epoch {
+		return false
+	}
+	seq, err := strconv.ParseUint(v, 10, 64)
+	if err != nil || seq > s.store.Seq() {
+		return false
+	}
+
+	missed := s.store.EventsFrom(seq)
+	if len(missed) > 0 && s.clock.Now().Sub(missed[0].Received) > s.tombstoneRetention {
+		return false
+	}
+
+	seen := make(map[string]bool)
+	for _, ev := range missed {
+		id := ev.Entity.Id
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		priority := pb.Priority_PriorityRoutine
+		change := pb.EntityChange_EntityChangeExpired
+		if e, ok := s.head.Lookup(id); ok {
+			change = pb.EntityChange_EntityChangeUpdated
+			if e.Priority != nil {
+				priority = *e.Priority
+			}
+		}
+		c.markDirty(id, priority, change)
+	}
+	return true
+}
+
+// checkpoint returns a token for a watcher that has caught up with all changes,
+// and false if it has pending changes or nothing was pushed since the last one
+func (c *Consumer) checkpoint() (string, bool) {
+	// hold the world lock so no push can mark the consumer dirty in between
+	c.world.l.RLock()
+	defer c.world.l.RUnlock()
+
+	c.mu.Lock()
+	pending := c.pending()
+	c.mu.Unlock()
+
+	token := c.world.resumeToken()
+	if pending > 0 || token == c.lastCheckpoint {
+		return "", false
+	}
+	c.lastCheckpoint = token
+	return token, true
+}
+
+// checkpointEvent carries a resume token to watchers that asked for them, see goclient.HeaderResume
+func checkpointEvent(token string) *pb.EntityChangeEvent {
+	return &pb.EntityChangeEvent{
+		T:      pb.EntityChange_EntityChangeInvalid,
+		Entity: &pb.Entity{Id: goclient.ResumeTokenPrefix + token},
+	}
+}
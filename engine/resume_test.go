@@ -0,0 +1,84 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestResumeFrom_OnlyMissedChanges(t *testing.T) {
+	w := testWorld(map[string]*pb.Entity{})
+	// every push changes the label, unchanged entities would only refresh their lifetime
+	pushes := 0
+	push := func(ids ...string) {
+		var changes []*pb.Entity
+		for _, id := range ids {
+			pushes++
+			changes = append(changes, &pb.Entity{Id: id, Label: ptr(fmt.Sprint(pushes))})
+		}
+		if _, err := w.Push(context.Background(), connect.NewRequest(&pb.EntityChangeRequest{Changes: changes})); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	push("a", "b")
+	token := w.resumeToken()
+	push("b", "c", "b")
+
+	c := NewConsumer(w, nil, nil, nil)
+	if !w.resumeFrom(c, token) {
+		t.Fatal("expected to resume from a current token")
+	}
+	if c.pending() != 2 {
+		t.Errorf("expected b and c to be pending, got %d", c.pending())
+	}
+	if _, ok := c.dirty[pb.Priority_PriorityRoutine]["a"]; ok {
+		t.Error("expected a, unchanged since the token, not to be sent again")
+	}
+
+	w.epoch = newEpoch() + "x"
+	if w.resumeFrom(NewConsumer(w, nil, nil, nil), token) {
+		t.Error("expected a token of another epoch to require a snapshot")
+	}
+}
+
+func TestResumeFrom_MissedExpiry(t *testing.T) {
+	w := testWorld(map[string]*pb.Entity{})
+	a := &pb.Entity{Id: "a", Lifetime: &pb.Lifetime{Until: timestamppb.New(time.Now().Add(-time.Second))}}
+	if _, err := w.Push(context.Background(), connect.NewRequest(&pb.EntityChangeRequest{Changes: []*pb.Entity{a}})); err != nil {
+		t.Fatal(err)
+	}
+	token := w.resumeToken()
+	w.gc()
+
+	c := NewConsumer(w, nil, nil, nil)
+	if !w.resumeFrom(c, token) {
+		t.Fatal("expected to resume within the tombstone retention")
+	}
+	events := make(chan *pb.EntityChangeEvent, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.SenderLoop(ctx, func(ev *pb.EntityChangeEvent) error {
+		events <- ev
+		return nil
+	})
+
+	select {
+	case ev := <-events:
+		if ev.T != pb.EntityChange_EntityChangeExpired || ev.Entity == nil || ev.Entity.Id != "a" {
+			t.Errorf("expected the expiry of a, got %v", ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the missed expiry to be sent")
+	}
+
+	w.tombstoneRetention = 0
+	if w.resumeFrom(NewConsumer(w, nil, nil, nil), token) {
+		t.Error("expected a token older than the tombstone retention to require a snapshot")
+	}
+}
@@ -0,0 +1,57 @@
+package engine
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+// seenCounts counts the pushes of each entity since it was created, see
+// goclient.HeaderMinSeen. Unlike versions it also counts pushes that changed
+// nothing or were throttled, each is an observation. It has its own lock
+// because list and watch filter by it without holding the world lock.
+type seenCounts struct {
+	mu sync.RWMutex
+	m  map[string]uint64
+}
+
+// observe counts a push of the entity, restarting from one if it was created by it
+func (c *seenCounts) observe(id string, created bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.m == nil {
+		c.m = make(map[string]uint64)
+	}
+	if created {
+		c.m[id] = 0
+	}
+	c.m[id]++
+}
+
+// get returns how often the entity was pushed, 0 if it came in without a push,
+// e.g. from the world file
+func (c *seenCounts) get(id string) uint64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.m[id]
+}
+
+func (c *seenCounts) delete(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.m, id)
+}
+
+// seenEnough reports whether the entity was pushed at least min times
+func (s *WorldServer) seenEnough(id string, min uint64) bool {
+	return min == 0 || s.seen.get(id) >= min
+}
+
+// parseSeenCount reads a number of pushes
+func parseSeenCount(v string) (uint64, error) {
+	n, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("seen count must be a number of pushes")
+	}
+	return n, nil
+}
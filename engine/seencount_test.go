@@ -0,0 +1,111 @@
+package engine
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/projectqai/hydra/goclient"
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestSeenCount_MinSeenFilterAndReset(t *testing.T) {
+	w := testWorld(nil)
+	push := func(id string, until time.Time) {
+		t.Helper()
+		e := &pb.Entity{Id: id, Lifetime: &pb.Lifetime{Until: timestamppb.New(until)}}
+		if _, err := w.Push(context.Background(), connect.NewRequest(&pb.EntityChangeRequest{Changes: []*pb.Entity{e}})); err != nil {
+			t.Fatal(err)
+		}
+	}
+	list := func(min uint64) []string {
+		t.Helper()
+		req := connect.NewRequest(&pb.ListEntitiesRequest{})
+		req.Header().Set(goclient.HeaderMinSeen, strconv.FormatUint(min, 10))
+		resp, err := w.ListEntities(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []string
+		for _, e := range resp.Msg.Entities {
+			ids = append(ids, e.Id)
+		}
+		return ids
+	}
+
+	later := time.Now().Add(time.Hour)
+	for range 3 {
+		push("real", later)
+	}
+	push("noise", later)
+	if ids := list(3); !slices.Equal(ids, []string{"real"}) {
+		t.Errorf("expected only real with 3 pushes, got %v", ids)
+	}
+
+	// expired and pushed again, it starts over
+	push("real", time.Now().Add(-time.Second))
+	w.gc()
+	push("real", later)
+	if got := w.seen.get("real"); got != 1 {
+		t.Errorf("expected the count to restart after expiry, got %d", got)
+	}
+}
+
+func TestSeenCount_MinSeenWatchGetsExpiry(t *testing.T) {
+	w := testWorld(nil)
+	clock := &testClock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	w.clock = clock
+	h := http.Header{}
+	h.Set(goclient.HeaderMinSeen, "2")
+	opts, err := parseRequestOptions(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewConsumer(w, nil, nil, nil)
+	c.options = opts
+	w.bus.Register(c)
+
+	until := &pb.Lifetime{Until: timestamppb.New(clock.Now().Add(time.Second))}
+	for _, id := range []string{"real", "real", "noise"} {
+		e := &pb.Entity{Id: id, Lifetime: until}
+		if _, err := w.Push(context.Background(), connect.NewRequest(&pb.EntityChangeRequest{Changes: []*pb.Entity{e}})); err != nil {
+			t.Fatal(err)
+		}
+	}
+	clock.Advance(2 * time.Second)
+	w.gc()
+
+	var mu sync.Mutex
+	got := make(map[string]pb.EntityChange)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	go c.SenderLoop(ctx, func(ev *pb.EntityChangeEvent) error {
+		mu.Lock()
+		got[ev.Entity.GetId()] = ev.T
+		mu.Unlock()
+		return nil
+	})
+	time.Sleep(50 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if got["real"] != pb.EntityChange_EntityChangeExpired {
+		t.Errorf("expected the expiry of real, got %v", got)
+	}
+	if _, ok := got["noise"]; ok {
+		t.Errorf("expected nothing of noise, pushed only once, got %v", got)
+	}
+
+	// the count goes with the tombstone
+	clock.Advance(DefaultTombstoneRetention + time.Second)
+	w.gc()
+	if n := w.seen.get("real"); n != 0 {
+		t.Errorf("expected the count to be dropped with the tombstone, got %d", n)
+	}
+}
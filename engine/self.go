@@ -0,0 +1,91 @@
+package engine
+
+import (
+	"context"
+	"time"
+
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// selfController is the controller of the self entity the engine publishes
+var selfController = &pb.ControllerRef{Id: "self", Name: "engine"}
+
+const (
+	// selfInterval is how often the self entity is refreshed
+	selfInterval = 30 * time.Second
+	// selfTTL expires the self entity if the engine stops refreshing it
+	selfTTL = 3 * selfInterval
+)
+
+// SelfConfig publishes an own-ship entity at the position of the engine host,
+// independent of which feeds are running
+type SelfConfig struct {
+	// ID defaults to "self"
+	ID string
+	// Label defaults to "Self"
+	Label string
+	// SIDC defaults to a friendly ground unit
+	SIDC      string
+	Latitude  float64
+	Longitude float64
+	Altitude  *float64
+}
+
+func (c *SelfConfig) entity(now time.Time) *pb.Entity {
+	id := c.ID
+	if id == "" {
+		id = "self"
+	}
+	label := c.Label
+	if label == "" {
+		label = "Self"
+	}
+	sidc := c.SIDC
+	if sidc == "" {
+		sidc = "SFGPU-----*****"
+	}
+	return &pb.Entity{
+		Id:         id,
+		Label:      &label,
+		Controller: selfController,
+		Lifetime: &pb.Lifetime{
+			From:  timestamppb.New(now),
+			Until: timestamppb.New(now.Add(selfTTL)),
+		},
+		Geo: &pb.GeoSpatialComponent{
+			Latitude:  c.Latitude,
+			Longitude: c.Longitude,
+			Altitude:  c.Altitude,
+		},
+		Symbol: &pb.SymbolComponent{MilStd2525C: sidc},
+	}
+}
+
+// publishSelf keeps the self entity alive until ctx is done. A live entity of the
+// same id pushed by someone else, e.g. a GPS builtin, is left alone, the
+// configured position only fills in while it is missing.
+func (s *WorldServer) publishSelf(ctx context.Context, cfg *SelfConfig) {
+	refresh := func() {
+		now := s.clock.Now()
+		e := cfg.entity(now)
+
+		s.l.Lock()
+		defer s.l.Unlock()
+		if current, ok := s.head.Lookup(e.Id); ok && current.Controller.GetId() != selfController.Id && !isExpired(current, now) {
+			return
+		}
+		s.apply(ctx, e, nil)
+	}
+
+	ticker := time.NewTicker(selfInterval)
+	defer ticker.Stop()
+	for {
+		refresh()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
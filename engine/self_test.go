@@ -0,0 +1,32 @@
+package engine
+
+import (
+	"context"
+	"testing"
+
+	pb "github.com/projectqai/proto/go"
+)
+
+func TestPublishSelf_YieldsToLiveFeed(t *testing.T) {
+	w := testWorld(nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// a cancelled publisher still refreshes once before returning
+	w.publishSelf(ctx, &SelfConfig{Latitude: 50, Longitude: 10})
+	self := w.head.Get("self")
+	if self == nil || self.Geo.Latitude != 50 || self.Controller.GetId() != selfController.Id {
+		t.Fatalf("expected configured self entity, got %v", self)
+	}
+
+	// a GPS feed keeps its own position
+	w.head.Set("self", &pb.Entity{
+		Id:         "self",
+		Controller: &pb.ControllerRef{Id: "gps", Name: "gps"},
+		Geo:        &pb.GeoSpatialComponent{Latitude: 51, Longitude: 11},
+	})
+	w.publishSelf(ctx, &SelfConfig{Latitude: 50, Longitude: 10})
+	if w.head.Get("self").Geo.Latitude != 51 {
+		t.Fatal("configured position replaced the live feed")
+	}
+}
@@ -0,0 +1,34 @@
+package engine
+
+import (
+	"github.com/paulmach/orb"
+	"github.com/projectqai/hydra/goclient"
+	pb "github.com/projectqai/proto/go"
+)
+
+// shapeBound is the bounding box of the entity's shape
+func shapeBound(entity *pb.Entity) (orb.Bound, bool) {
+	if entity.Shape == nil || entity.Shape.Geometry == nil {
+		return orb.Bound{}, false
+	}
+	g := goclient.GeometryToOrb(entity.Shape.Geometry)
+	if g == nil {
+		return orb.Bound{}, false
+	}
+	return g.Bound(), true
+}
+
+// entityBound covers both the position and the shape of an entity
+func entityBound(entity *pb.Entity) (orb.Bound, bool) {
+	p, hasPoint := entityPoint(entity)
+	b, hasShape := shapeBound(entity)
+	switch {
+	case hasPoint && hasShape:
+		return b.Extend(p), true
+	case hasShape:
+		return b, true
+	case hasPoint:
+		return p.Bound(), true
+	}
+	return orb.Bound{}, false
+}
@@ -0,0 +1,139 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/projectqai/hydra/goclient"
+	"github.com/projectqai/hydra/policy"
+	pb "github.com/projectqai/proto/go"
+)
+
+// errShuttingDown ends watches that were drained, clients should back off before reconnecting
+var errShuttingDown = connect.NewError(connect.CodeUnavailable, errors.New("server shutting down"))
+
+// watcherGroup tracks the watches of one kind of client, so they can be drained
+// on shutdown before their listener is closed
+type watcherGroup struct {
+	mu      sync.Mutex
+	closing chan struct{}
+	closed  bool
+	active  sync.WaitGroup
+}
+
+func newWatcherGroup() *watcherGroup {
+	return &watcherGroup{closing: make(chan struct{})}
+}
+
+// join registers a watch, it returns false once the group is drained
+func (g *watcherGroup) join() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.closed {
+		return false
+	}
+	g.active.Add(1)
+	return true
+}
+
+func (g *watcherGroup) leave() {
+	g.active.Done()
+}
+
+// draining reports whether the group refuses new watches
+func (g *watcherGroup) draining() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.closed
+}
+
+// drain tells all watches to finish and waits for them until ctx is done
+func (g *watcherGroup) drain(ctx context.Context) error {
+	g.mu.Lock()
+	if !g.closed {
+		g.closed = true
+		close(g.closing)
+	}
+	g.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		g.active.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// watchers returns the group of a watch from peer, builtins are drained separately
+func (s *WorldServer) watchers(peer string) *watcherGroup {
+	if peer == policy.BuiltinAddr {
+		return s.builtinWatchers
+	}
+	return s.externalWatchers
+}
+
+// DrainWatchers ends all WatchEntities streams of external clients, or of builtins,
+// and refuses new ones. Each watch first sends what it has pending and a
+// shutdown event, see goclient.ShutdownEventID, then ends with CodeUnavailable.
+// It returns once all watches ended, or with the error of ctx.
+func (s *WorldServer) DrainWatchers(ctx context.Context, builtins bool) error {
+	if builtins {
+		return s.builtinWatchers.drain(ctx)
+	}
+	return s.externalWatchers.drain(ctx)
+}
+
+// shutdownEvent is the last event of a drained watch
+func shutdownEvent() *pb.EntityChangeEvent {
+	return &pb.EntityChangeEvent{
+		T:      pb.EntityChange_EntityChangeInvalid,
+		Entity: &pb.Entity{Id: goclient.ShutdownEventID},
+	}
+}
+
+// shutdownTimeout bounds each step of the shutdown
+const shutdownTimeout = 5 * time.Second
+
+// shutdown stops external clients first, so builtins like federation can still
+// flush what they have before the in-process listener closes too. The admin
+// server, if any, is closed last, so readiness probes see the shutdown.
+func (s *WorldServer) shutdown(external, builtins, admin *http.Server, cfg EngineConfig) {
+	step := func(name string, fn func(ctx context.Context) error) {
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := fn(ctx); err != nil {
+			slog.Warn("shutdown step did not finish", "step", name, "error", err)
+		}
+	}
+
+	slog.Info("shutting down, draining watchers")
+	step("drain external watchers", func(ctx context.Context) error { return s.DrainWatchers(ctx, false) })
+	step("close external listener", external.Shutdown)
+	step("drain builtin watchers", func(ctx context.Context) error { return s.DrainWatchers(ctx, true) })
+	if cfg.StopBuiltins != nil {
+		cfg.StopBuiltins()
+	}
+	step("close builtin listener", builtins.Shutdown)
+	if admin != nil {
+		step("close admin listener", admin.Shutdown)
+	}
+
+	if s.worldFile != "" {
+		if err := s.FlushToFile(); err != nil {
+			slog.Warn("failed to flush world state", "error", err)
+		}
+	}
+	if cfg.Stopped != nil {
+		close(cfg.Stopped)
+	}
+}
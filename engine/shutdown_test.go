@@ -0,0 +1,54 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/projectqai/hydra/goclient"
+	"github.com/projectqai/hydra/policy"
+	pb "github.com/projectqai/proto/go"
+)
+
+func TestDrainWatchers_FlushesThenSaysGoodbye(t *testing.T) {
+	world := testWorld(map[string]*pb.Entity{"e1": {Id: "e1"}})
+	watchers := world.watchers("192.0.2.1:1234")
+	if !watchers.join() {
+		t.Fatal("expected watch to be accepted")
+	}
+	c := NewConsumer(world, nil, nil, nil)
+	c.closing = watchers.closing
+	world.bus.Register(c)
+	c.markDirty("e1", pb.Priority_PriorityRoutine, pb.EntityChange_EntityChangeUpdated)
+
+	var events []*pb.EntityChangeEvent
+	done := make(chan error, 1)
+	go func() {
+		defer watchers.leave()
+		done <- c.SenderLoop(context.Background(), func(ev *pb.EntityChangeEvent) error {
+			events = append(events, ev)
+			return nil
+		})
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := world.DrainWatchers(ctx, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; connect.CodeOf(err) != connect.CodeUnavailable {
+		t.Fatalf("expected unavailable, got %v", err)
+	}
+	if len(events) != 2 || events[0].Entity.GetId() != "e1" || events[1].Entity.GetId() != goclient.ShutdownEventID {
+		t.Fatalf("expected pending change then shutdown event, got %v", events)
+	}
+
+	// new watches are refused, builtins are drained separately
+	if watchers.join() {
+		t.Fatal("expected new watch to be refused")
+	}
+	if !world.watchers(policy.BuiltinAddr).join() {
+		t.Fatal("expected builtin watch to be accepted")
+	}
+}
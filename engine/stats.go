@@ -0,0 +1,31 @@
+package engine
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/projectqai/hydra/goclient"
+	"github.com/projectqai/hydra/version"
+)
+
+func (s *WorldServer) handleStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.l.RLock()
+	stats := goclient.Stats{
+		Version:   version.Version,
+		Entities:  s.head.Len(),
+		Watchers:  s.bus.Len(),
+		Pushed:    s.pushed.Load(),
+		Throttled: s.throttledCount.Load(),
+		Unchanged: s.unchanged.Load(),
+		Evicted:   s.evicted.Load(),
+	}
+	s.l.RUnlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
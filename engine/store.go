@@ -2,14 +2,23 @@ package engine
 
 import (
 	"context"
+	"slices"
+	"sort"
 	"sync"
 	"time"
 
+	"github.com/projectqai/hydra/policy"
 	pb "github.com/projectqai/proto/go"
 )
 
 type Event struct {
 	Entity *pb.Entity
+	// Received is when the engine received the push, events are appended in this order
+	Received time.Time
+	// Marking is the classification of the entity at the time of the push
+	Marking policy.Marking
+	// Expired is set for the removal of Entity from the head, Entity is its last state
+	Expired bool
 }
 
 // remember to design this to sync over nats AND into kv
@@ -65,14 +74,34 @@ func (s *Store) GetTimeline() (time.Time, time.Time) {
 	return s.min, s.max
 }
 
+// EventsSince returns the events received at or after t, oldest first
+func (s *Store) EventsSince(t time.Time) []Event {
+	s.l.RLock()
+	defer s.l.RUnlock()
+
+	i := sort.Search(len(s.events), func(i int) bool {
+		return !s.events[i].Received.Before(t)
+	})
+	return slices.Clone(s.events[i:])
+}
+
 func (s *Store) GetEventsInTimeRange(targetTime time.Time) []*pb.Entity {
 	s.l.RLock()
 	defer s.l.RUnlock()
 
 	entityMap := make(map[string]*pb.Entity)
+	// expired is when entities were removed at or before targetTime, last removal wins
+	expired := make(map[string]time.Time)
+	received := make(map[string]time.Time)
 
 	for _, event := range s.events {
 		entity := event.Entity
+		if event.Expired {
+			if !event.Received.After(targetTime) {
+				expired[entity.Id] = event.Received
+			}
+			continue
+		}
 		if entity.Lifetime == nil {
 			continue
 		}
@@ -92,13 +121,32 @@ func (s *Store) GetEventsInTimeRange(targetTime time.Time) []*pb.Entity {
 
 		if existing, exists := entityMap[entity.Id]; !exists || fromTime.After(existing.Lifetime.From.AsTime()) {
 			entityMap[entity.Id] = entity
+			received[entity.Id] = event.Received
 		}
 	}
 
 	var result []*pb.Entity
-	for _, entity := range entityMap {
+	for id, entity := range entityMap {
+		// removed after it was last pushed, e.g. a child expired with its parent
+		if t, ok := expired[id]; ok && t.After(received[id]) {
+			continue
+		}
 		result = append(result, entity)
 	}
 
 	return result
 }
+
+// Seq is the number of events pushed so far, events are numbered from zero in push order
+func (s *Store) Seq() uint64 {
+	s.l.RLock()
+	defer s.l.RUnlock()
+	return uint64(len(s.events))
+}
+
+// EventsFrom returns the events numbered seq or later, oldest first
+func (s *Store) EventsFrom(seq uint64) []Event {
+	s.l.RLock()
+	defer s.l.RUnlock()
+	return slices.Clone(s.events[min(seq, uint64(len(s.events))):])
+}
@@ -0,0 +1,112 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/projectqai/hydra/goclient"
+	"github.com/projectqai/hydra/policy"
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// tag applies req to all matching entities the ability can read, as one push.
+// If any of the writes is denied, none is applied.
+func (s *WorldServer) tag(ctx context.Context, ability *policy.Ability, filter *pb.EntityFilter, req goclient.TagRequest) ([]string, error) {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	var changes []*pb.Entity
+	for e, marking := range s.head.Entries() {
+		if e.Config != nil || !s.matchesEntityFilter(e, filter) || !ability.CanRead(ctx, e, marking) {
+			continue
+		}
+		if changed := tagEntity(e, req); changed != nil {
+			changes = append(changes, changed)
+		}
+	}
+	slices.SortFunc(changes, func(a, b *pb.Entity) int { return strings.Compare(a.Id, b.Id) })
+
+	for _, e := range changes {
+		if err := ability.AuthorizeWrite(ctx, e, s.head.Get(e.Id)); err != nil {
+			return nil, err
+		}
+	}
+
+	ids := make([]string, len(changes))
+	for i, e := range changes {
+		ids[i] = e.Id
+		if !req.DryRun {
+			// the next push of the feed is a change again, see refreshUnchanged
+			delete(s.contents, e.Id)
+			s.apply(ctx, e, nil)
+		}
+	}
+	if !req.DryRun {
+		s.pushed.Add(uint64(len(changes)))
+	}
+	return ids, nil
+}
+
+// tagEntity returns a changed copy of e, nil if req doesn't change it
+func tagEntity(e *pb.Entity, req goclient.TagRequest) *pb.Entity {
+	changed := proto.Clone(e).(*pb.Entity)
+	if req.LabelPrefix != "" && !strings.HasPrefix(e.GetLabel(), req.LabelPrefix) {
+		label := e.GetLabel()
+		if label == "" {
+			label = e.Id
+		}
+		label = req.LabelPrefix + label
+		changed.Label = &label
+	}
+	if req.Controller != "" {
+		if changed.Controller == nil {
+			changed.Controller = &pb.ControllerRef{}
+		}
+		changed.Controller.Name = req.Controller
+	}
+	if proto.Equal(changed, e) {
+		return nil
+	}
+	return changed
+}
+
+func (s *WorldServer) handleTag(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req goclient.TagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.LabelPrefix == "" && req.Controller == "" {
+		http.Error(w, "nothing to change, set label_prefix or controller", http.StatusBadRequest)
+		return
+	}
+
+	var filter *pb.EntityFilter
+	if len(req.Filter) > 0 {
+		filter = &pb.EntityFilter{}
+		if err := protojson.Unmarshal(req.Filter, filter); err != nil {
+			http.Error(w, "invalid filter: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	ids, err := s.tag(r.Context(), policy.ForHTTP(s.policy, r), filter, req)
+	if err != nil {
+		// only the policy can refuse
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(goclient.TagResponse{Tagged: ids})
+}
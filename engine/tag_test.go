@@ -0,0 +1,38 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/projectqai/hydra/goclient"
+	"github.com/projectqai/hydra/policy"
+	pb "github.com/projectqai/proto/go"
+)
+
+func TestTag_PrefixesMatchingLabelsOnce(t *testing.T) {
+	w := testWorld(map[string]*pb.Entity{
+		"a":      {Id: "a", Label: ptr("Alpha"), Taskable: &pb.TaskableComponent{}},
+		"b":      {Id: "b", Taskable: &pb.TaskableComponent{}},
+		"c":      {Id: "c", Label: ptr("Charlie")},
+		"config": {Id: "config", Taskable: &pb.TaskableComponent{}, Config: &pb.ConfigurationComponent{Key: "x"}},
+	})
+	ability := policy.For(context.Background(), nil, "")
+	filter := &pb.EntityFilter{Component: []uint32{23}}
+	req := goclient.TagRequest{LabelPrefix: "RED-"}
+
+	ids, err := w.tag(context.Background(), ability, filter, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(ids) != "[a b]" {
+		t.Fatalf("expected a and b to be tagged, got %v", ids)
+	}
+	if w.head.Get("a").GetLabel() != "RED-Alpha" || w.head.Get("b").GetLabel() != "RED-b" || w.head.Get("c").GetLabel() != "Charlie" {
+		t.Fatalf("unexpected labels %v", w.head)
+	}
+
+	if ids, _ := w.tag(context.Background(), ability, filter, req); len(ids) != 0 {
+		t.Fatalf("expected tagging again to change nothing, got %v", ids)
+	}
+}
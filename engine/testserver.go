@@ -0,0 +1,58 @@
+package engine
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/projectqai/hydra/builtin"
+	pb "github.com/projectqai/proto/go"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// TestServer is a world served over an in-memory listener instead of a port,
+// for integration tests of builtins and clients. Builtins reach it through
+// builtin.BuiltinClientConn while it runs, so tests using it can't run in parallel.
+type TestServer struct {
+	World *WorldServer
+	// Conn is connected as a builtin, like the connections of builtins
+	Conn   *grpc.ClientConn
+	Client pb.WorldServiceClient
+}
+
+// NewTestServer starts a TestServer that is stopped when the test ends
+func NewTestServer(t testing.TB) *TestServer {
+	t.Helper()
+
+	world := NewWorldServer()
+	mux := http.NewServeMux()
+	world.handle(mux)
+	server := &http.Server{
+		Handler: h2c.NewHandler(asBuiltin(mux), &http2.Server{}),
+	}
+
+	listener := bufconn.Listen(1 << 20)
+	previous := builtin.SwapBuiltinListener(listener)
+	go server.Serve(listener)
+
+	conn, err := builtin.BuiltinClientConn()
+	if err != nil {
+		server.Close()
+		builtin.SwapBuiltinListener(previous)
+		t.Fatalf("connect to test server: %v", err)
+	}
+
+	t.Cleanup(func() {
+		conn.Close()
+		server.Close()
+		builtin.SwapBuiltinListener(previous)
+	})
+
+	return &TestServer{
+		World:  world,
+		Conn:   conn,
+		Client: pb.NewWorldServiceClient(conn),
+	}
+}
@@ -0,0 +1,89 @@
+package engine
+
+import (
+	"context"
+	"time"
+
+	"github.com/projectqai/hydra/policy"
+	pb "github.com/projectqai/proto/go"
+)
+
+// ThrottleConfig limits how often the engine accepts updates of the same entity,
+// to protect watchers from a single chatty source. Updates that come in faster
+// are merged, the latest one is applied once the interval has passed since the
+// last accepted one. Removals are never throttled.
+type ThrottleConfig struct {
+	// Interval is the min time between updates of an entity, zero disables it
+	Interval time.Duration
+	// Controllers overrides Interval for entities of these controllers by name
+	Controllers map[string]time.Duration
+}
+
+func (c *ThrottleConfig) interval(e *pb.Entity) time.Duration {
+	if c == nil {
+		return 0
+	}
+	if e.Controller != nil {
+		if d, ok := c.Controllers[e.Controller.Name]; ok {
+			return d
+		}
+	}
+	return c.Interval
+}
+
+type throttledPush struct {
+	entity  *pb.Entity
+	marking *policy.Marking
+	due     time.Time
+}
+
+// throttle defers e if its entity was updated less than the interval ago and
+// reports whether it did. Caller must hold s.l.
+func (s *WorldServer) throttle(e *pb.Entity, marking *policy.Marking) bool {
+	interval := s.throttleConfig.interval(e)
+	if interval <= 0 || s.frozen.Load() {
+		return false
+	}
+
+	now := s.clock.Now()
+	last, seen := s.lastSeen[e.Id]
+	removal := e.Lifetime.Until.IsValid() && !e.Lifetime.Until.AsTime().After(now)
+	if !seen || removal || now.Sub(last) >= interval {
+		// supersedes a deferred update
+		delete(s.throttled, e.Id)
+		return false
+	}
+
+	pending, ok := s.throttled[e.Id]
+	if !ok {
+		pending.due = last.Add(interval)
+		id := e.Id
+		time.AfterFunc(pending.due.Sub(now), func() { s.flushThrottled(id) })
+	}
+	pending.entity = e
+	if marking != nil {
+		pending.marking = marking
+	}
+	s.throttled[e.Id] = pending
+	s.throttledCount.Add(1)
+	return true
+}
+
+// flushThrottled applies the deferred update of an entity once it is due
+func (s *WorldServer) flushThrottled(id string) {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	// superseded, possibly deferred again after that with its own timer
+	p, ok := s.throttled[id]
+	if !ok {
+		return
+	}
+	// the timer runs on the wall clock, which may be ahead of s.clock
+	if now := s.clock.Now(); now.Before(p.due) {
+		time.AfterFunc(p.due.Sub(now), func() { s.flushThrottled(id) })
+		return
+	}
+	delete(s.throttled, id)
+	s.apply(context.Background(), p.entity, p.marking)
+}
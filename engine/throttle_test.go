@@ -0,0 +1,77 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	pb "github.com/projectqai/proto/go"
+)
+
+func TestPush_ThrottleMergesUpdates(t *testing.T) {
+	w := testWorld(map[string]*pb.Entity{})
+	w.throttleConfig = &ThrottleConfig{Interval: 50 * time.Millisecond}
+	push := func(label string) {
+		e := &pb.Entity{Id: "a", Label: &label}
+		if _, err := w.Push(context.Background(), connect.NewRequest(&pb.EntityChangeRequest{Changes: []*pb.Entity{e}})); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the deferred push is applied from a timer, read under the world lock
+	version := func() uint64 {
+		w.l.RLock()
+		defer w.l.RUnlock()
+		return w.versions["a"]
+	}
+
+	push("1")
+	push("2")
+	push("3")
+	if label := w.GetHead("a").GetLabel(); label != "1" {
+		t.Fatalf("expected updates within the interval to be deferred, got label %q", label)
+	}
+	if v := version(); v != 1 {
+		t.Errorf("expected one accepted update, got version %d", v)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if label := w.GetHead("a").GetLabel(); label != "3" {
+		t.Errorf("expected the latest deferred update to be applied after the interval, got label %q", label)
+	}
+	if v := version(); v != 2 {
+		t.Errorf("expected the deferred updates to be merged into one, got version %d", v)
+	}
+}
+
+func TestPush_ThrottleFlushesOnceClockIsDue(t *testing.T) {
+	clock := &testClock{now: time.Now()}
+	w := testWorld(map[string]*pb.Entity{})
+	w.clock = clock
+	w.throttleConfig = &ThrottleConfig{Interval: 50 * time.Millisecond}
+	push := func(label string) {
+		e := &pb.Entity{Id: "a", Label: &label}
+		if _, err := w.Push(context.Background(), connect.NewRequest(&pb.EntityChangeRequest{Changes: []*pb.Entity{e}})); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	push("1")
+	push("2")
+
+	// the flush timer fires on the wall clock before the engine clock is due
+	time.Sleep(100 * time.Millisecond)
+	if label := w.GetHead("a").GetLabel(); label != "1" {
+		t.Fatalf("expected the update to stay deferred until the engine clock is due, got label %q", label)
+	}
+
+	clock.Advance(50 * time.Millisecond)
+	deadline := time.Now().Add(time.Second)
+	for w.GetHead("a").GetLabel() != "2" {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the deferred update to be applied once the engine clock is due")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
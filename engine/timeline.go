@@ -13,7 +13,7 @@ import (
 )
 
 func (s *WorldServer) GetTimeline(ctx context.Context, req *connect.Request[pb.GetTimelineRequest], stream *connect.ServerStream[pb.GetTimelineResponse]) error {
-	if err := policy.For(s.policy, req.Peer().Addr).AuthorizeTimeline(ctx); err != nil {
+	if err := policy.For(ctx, s.policy, req.Peer().Addr).AuthorizeTimeline(ctx); err != nil {
 		return err
 	}
 
@@ -58,7 +58,7 @@ func (s *WorldServer) GetTimeline(ctx context.Context, req *connect.Request[pb.G
 }
 
 func (s *WorldServer) MoveTimeline(ctx context.Context, req *connect.Request[pb.MoveTimelineRequest]) (*connect.Response[pb.MoveTimelineResponse], error) {
-	if err := policy.For(s.policy, req.Peer().Addr).AuthorizeTimeline(ctx); err != nil {
+	if err := policy.For(ctx, s.policy, req.Peer().Addr).AuthorizeTimeline(ctx); err != nil {
 		return nil, err
 	}
 
@@ -74,9 +74,12 @@ func (s *WorldServer) MoveTimeline(ctx context.Context, req *connect.Request[pb.
 	entities := s.store.GetEventsInTimeRange(req.Msg.At.AsTime())
 
 	s.l.Lock()
-	s.head = make(map[string]*pb.Entity)
+	// the head no longer follows the store sequence, watchers can't resume across the move
+	s.epoch = newEpoch()
+	s.head.Clear()
+	clear(s.contents)
 	for _, ev := range entities {
-		s.head[ev.Id] = ev
+		s.head.Set(ev.Id, ev)
 	}
 	s.l.Unlock()
 
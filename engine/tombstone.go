@@ -0,0 +1,73 @@
+package engine
+
+import (
+	"context"
+	"time"
+
+	"github.com/projectqai/hydra/goclient"
+	"github.com/projectqai/hydra/policy"
+	pb "github.com/projectqai/proto/go"
+)
+
+// DefaultTombstoneRetention is how long expired entities are remembered by default
+const DefaultTombstoneRetention = time.Hour
+
+// tombstone is kept for an entity that left the head, so watchers get its
+// last state with the expiry, also when they resume after missing it
+type tombstone struct {
+	entity  *pb.Entity
+	marking policy.Marking
+	deleted time.Time
+	// reason is why the entity expired, one of goclient.ExpiryLifetime and the like
+	reason string
+}
+
+// bury removes an expired entity from the head, keeps a tombstone and records
+// the expiry in the store. Caller must hold s.l.
+func (s *WorldServer) bury(id string, entity *pb.Entity, reason string) {
+	now := s.clock.Now()
+	marking := s.head.Marking(id)
+
+	s.head.Delete(id)
+	s.tombstones[id] = tombstone{entity: entity, marking: marking, deleted: now, reason: reason}
+	s.store.Push(context.Background(), Event{Entity: entity, Received: now, Marking: marking, Expired: true})
+	s.bus.Dirty(id, entity, pb.EntityChange_EntityChangeExpired)
+}
+
+func (s *WorldServer) tombstoneOf(id string) (tombstone, bool) {
+	s.l.RLock()
+	defer s.l.RUnlock()
+	t, ok := s.tombstones[id]
+	return t, ok
+}
+
+// pruneTombstones drops tombstones older than the retention, with the
+// confidence and seen count kept for them. Caller must hold s.l.
+func (s *WorldServer) pruneTombstones(now time.Time) {
+	for id, t := range s.tombstones {
+		if now.Sub(t.deleted) > s.tombstoneRetention {
+			delete(s.tombstones, id)
+			s.confidence.delete(id)
+			s.seen.delete(id)
+		}
+	}
+}
+
+// lifetimeExpiry tells an entity that ran out of lifetime from one that was
+// deleted, which is pushed with a lifetime that ended before the push. Caller must hold s.l.
+func (s *WorldServer) lifetimeExpiry(id string, entity *pb.Entity) string {
+	if seen, ok := s.lastSeen[id]; ok && !entity.GetLifetime().GetUntil().AsTime().After(seen) {
+		return goclient.ExpiryDeleted
+	}
+	return goclient.ExpiryLifetime
+}
+
+// expiryReason is why an entity that is no longer live expired, see goclient.ExpiryReasonPrefix
+func (s *WorldServer) expiryReason(id string, entity *pb.Entity) string {
+	s.l.RLock()
+	defer s.l.RUnlock()
+	if t, ok := s.tombstones[id]; ok && t.reason != "" {
+		return t.reason
+	}
+	return s.lifetimeExpiry(id, entity)
+}
@@ -0,0 +1,53 @@
+package engine
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/projectqai/hydra/goclient"
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestExpiryReason_TellsDeletedFromLifetime(t *testing.T) {
+	w := testWorld(nil)
+	clock := &testClock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	w.clock = clock
+	c := NewConsumer(w, nil, nil, nil)
+	c.options.expiryReason = true
+	w.bus.Register(c)
+
+	now := clock.Now()
+	changes := []*pb.Entity{
+		{Id: "ran-out", Lifetime: &pb.Lifetime{Until: timestamppb.New(now.Add(time.Second))}},
+		{Id: "deleted", Lifetime: &pb.Lifetime{Until: timestamppb.New(now)}},
+	}
+	if _, err := w.Push(context.Background(), connect.NewRequest(&pb.EntityChangeRequest{Changes: changes})); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(2 * time.Second)
+	w.gc()
+
+	reasons := make(map[string]string)
+	var mu sync.Mutex
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	go c.SenderLoop(ctx, func(ev *pb.EntityChangeEvent) error {
+		if id, reason, ok := goclient.ExpiryReason(ev); ok {
+			mu.Lock()
+			reasons[id] = reason
+			mu.Unlock()
+		}
+		return nil
+	})
+	time.Sleep(50 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if reasons["ran-out"] != goclient.ExpiryLifetime || reasons["deleted"] != goclient.ExpiryDeleted {
+		t.Fatalf("unexpected expiry reasons %v", reasons)
+	}
+}
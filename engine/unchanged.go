@@ -0,0 +1,79 @@
+package engine
+
+import (
+	"hash/maphash"
+	"time"
+
+	"github.com/projectqai/hydra/policy"
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/proto"
+)
+
+// content identifies a pushed entity without its lifetime, and holds the
+// lifetime watchers last got for it
+type content struct {
+	sum         uint64
+	from, until time.Time
+}
+
+// contentSeed keys the content sums, they are only compared within one process
+var contentSeed = maphash.MakeSeed()
+
+// contentOf sums e as pushed, without its lifetime, which feeds renew on every push
+func contentOf(e *pb.Entity) uint64 {
+	lifetime := e.Lifetime
+	e.Lifetime = nil
+	b, err := proto.MarshalOptions{Deterministic: true}.Marshal(e)
+	e.Lifetime = lifetime
+	if err != nil {
+		return 0
+	}
+	return maphash.Bytes(contentSeed, b)
+}
+
+// refreshUnchanged takes over the lifetime of e if it is the same as the last
+// push of its entity otherwise, and reports whether it did. Watchers aren't
+// told, unless more than half of the lifetime they know has passed, so views
+// that expire entities by Lifetime.Until on their own keep showing it. Every
+// other push is recorded for the next comparison. Caller must hold s.l.
+func (s *WorldServer) refreshUnchanged(e *pb.Entity, marking *policy.Marking) bool {
+	if s.frozen.Load() {
+		return false
+	}
+	now := s.clock.Now()
+	if e.Lifetime.Until.IsValid() && !e.Lifetime.Until.AsTime().After(now) {
+		// removals always go through
+		delete(s.contents, e.Id)
+		return false
+	}
+
+	sum := contentOf(e)
+	last, ok := s.contents[e.Id]
+	current, marked, live := s.head.Entry(e.Id)
+	remarked := marking != nil && marking.String() != marked.String()
+	if !ok || !live || last.sum != sum || remarked || halfLived(last, now) {
+		next := content{sum: sum, from: e.Lifetime.From.AsTime()}
+		if e.Lifetime.Until.IsValid() {
+			next.until = e.Lifetime.Until.AsTime()
+		}
+		s.contents[e.Id] = next
+		return false
+	}
+
+	// entities in the head are shared with readers, replace rather than change it
+	refreshed := proto.Clone(current).(*pb.Entity)
+	refreshed.Lifetime = e.Lifetime
+	s.head.Set(e.Id, refreshed)
+	s.touch(e.Id)
+	s.unchanged.Add(1)
+	return true
+}
+
+// halfLived reports whether more than half of the lifetime watchers know of has
+// passed, entities without an end never need a refresh
+func halfLived(c content, now time.Time) bool {
+	if c.until.IsZero() {
+		return false
+	}
+	return now.After(c.from.Add(c.until.Sub(c.from) / 2))
+}
@@ -0,0 +1,68 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/projectqai/hydra/goclient"
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestPush_UnchangedOnlyRefreshesLifetime(t *testing.T) {
+	w := testWorld(nil)
+	clock := &testClock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	w.clock = clock
+	c := NewConsumer(w, nil, nil, nil)
+	w.bus.Register(c)
+
+	push := func(heartbeat bool) {
+		e := &pb.Entity{
+			Id:       "parked",
+			Geo:      &pb.GeoSpatialComponent{Latitude: 1, Longitude: 2},
+			Lifetime: &pb.Lifetime{From: timestamppb.New(clock.Now()), Until: timestamppb.New(clock.Now().Add(time.Minute))},
+		}
+		req := connect.NewRequest(&pb.EntityChangeRequest{Changes: []*pb.Entity{e}})
+		if heartbeat {
+			req.Header().Set(goclient.HeaderHeartbeat, "true")
+		}
+		if _, err := w.Push(context.Background(), req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	drain := func() int {
+		n := 0
+		for {
+			if _, _, _, ok := c.popNext(); !ok {
+				return n
+			}
+			n++
+		}
+	}
+
+	push(false)
+	drain()
+
+	clock.Advance(10 * time.Second)
+	push(false)
+	if n := drain(); n != 0 {
+		t.Fatalf("expected no event for an unchanged push, got %d", n)
+	}
+	if until := w.GetHead("parked").Lifetime.Until.AsTime(); !until.Equal(clock.Now().Add(time.Minute)) {
+		t.Fatalf("expected the lifetime to be refreshed, got until %v", until)
+	}
+
+	// watchers hear of it again before the lifetime they know runs out
+	clock.Advance(25 * time.Second)
+	push(false)
+	if n := drain(); n != 1 {
+		t.Fatalf("expected an update after half the known lifetime, got %d", n)
+	}
+
+	push(true)
+	if n := drain(); n != 1 {
+		t.Fatalf("expected an update for a heartbeat push, got %d", n)
+	}
+}
@@ -0,0 +1,49 @@
+package engine
+
+import (
+	"time"
+
+	"github.com/paulmach/orb"
+	pb "github.com/projectqai/proto/go"
+)
+
+// maxVelocityEstimateGap is the longest time between two updates that is still
+// used for a velocity estimate. Older positions say little about current motion.
+const maxVelocityEstimateGap = 60 * time.Second
+
+// estimateVelocity fills in Kinematics.VelocityEnu of an updated entity from the
+// difference to its previous position, if the source didn't report kinematics.
+func estimateVelocity(prev, next *pb.Entity) {
+	if prev == nil || prev.Geo == nil || next.Geo == nil || next.Kinematics != nil {
+		return
+	}
+
+	prevAt, ok := lastMeasured(prev)
+	if !ok {
+		return
+	}
+	nextAt, ok := lastMeasured(next)
+	if !ok {
+		return
+	}
+	gap := nextAt.Sub(prevAt)
+	if gap <= 0 || gap > maxVelocityEstimateGap {
+		return
+	}
+	dt := gap.Seconds()
+
+	east, north := enuBetween(
+		orb.Point{prev.Geo.Longitude, prev.Geo.Latitude},
+		orb.Point{next.Geo.Longitude, next.Geo.Latitude},
+	)
+	east /= dt
+	north /= dt
+
+	velocity := &pb.KinematicsEnu{East: &east, North: &north}
+	if prev.Geo.Altitude != nil && next.Geo.Altitude != nil {
+		up := (*next.Geo.Altitude - *prev.Geo.Altitude) / dt
+		velocity.Up = &up
+	}
+
+	next.Kinematics = &pb.KinematicsComponent{VelocityEnu: velocity}
+}
@@ -0,0 +1,39 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	pb "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestEstimateVelocity(t *testing.T) {
+	now := time.Now()
+	prev := &pb.Entity{
+		Id:       "e1",
+		Lifetime: &pb.Lifetime{From: timestamppb.New(now.Add(-10 * time.Second))},
+		Geo:      &pb.GeoSpatialComponent{Longitude: 10, Latitude: 50},
+	}
+	next := &pb.Entity{
+		Id:       "e1",
+		Lifetime: &pb.Lifetime{From: timestamppb.New(now)},
+		Geo:      &pb.GeoSpatialComponent{Longitude: 10, Latitude: 50.0009},
+	}
+
+	estimateVelocity(prev, next)
+	if next.Kinematics == nil || next.Kinematics.VelocityEnu == nil {
+		t.Fatal("expected estimated velocity")
+	}
+	v := next.Kinematics.VelocityEnu
+	if *v.North < 9.9 || *v.North > 10.1 || *v.East > 0.01 || *v.East < -0.01 {
+		t.Errorf("expected ~10 m/s north, got east=%v north=%v", *v.East, *v.North)
+	}
+
+	reported := &pb.KinematicsComponent{}
+	next.Kinematics = reported
+	estimateVelocity(prev, next)
+	if next.Kinematics != reported {
+		t.Error("reported kinematics must not be overwritten")
+	}
+}
@@ -0,0 +1,30 @@
+package engine
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"connectrpc.com/connect"
+	"github.com/projectqai/hydra/goclient"
+)
+
+// checkVersions verifies the goclient.HeaderIfVersion preconditions of a push.
+// Caller must hold s.l.
+func (s *WorldServer) checkVersions(h http.Header) error {
+	for _, v := range h.Values(goclient.HeaderIfVersion) {
+		id, version, ok := strings.Cut(v, "=")
+		want, err := strconv.ParseUint(version, 10, 64)
+		if !ok || id == "" || err != nil {
+			return connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("invalid %s: %q", goclient.HeaderIfVersion, v))
+		}
+		if target, ok := s.aliases[id]; ok {
+			id = target
+		}
+		if current := s.versions[id]; current != want {
+			return connect.NewError(connect.CodeAborted, fmt.Errorf("entity %s is at version %d, not %d", id, current, want))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package engine
+
+import (
+	"context"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/projectqai/hydra/goclient"
+	pb "github.com/projectqai/proto/go"
+)
+
+func TestPush_IfVersion(t *testing.T) {
+	w := testWorld(map[string]*pb.Entity{})
+	push := func(version string) error {
+		req := connect.NewRequest(&pb.EntityChangeRequest{Changes: []*pb.Entity{{Id: "a"}}})
+		if version != "" {
+			req.Header().Set(goclient.HeaderIfVersion, "a="+version)
+		}
+		_, err := w.Push(context.Background(), req)
+		return err
+	}
+
+	if err := push("0"); err != nil {
+		t.Fatalf("expected push of a new entity at version 0 to succeed: %v", err)
+	}
+	if err := push(""); err != nil {
+		t.Fatal(err)
+	}
+	if err := push("1"); connect.CodeOf(err) != connect.CodeAborted {
+		t.Fatalf("expected stale version to be aborted, got %v", err)
+	}
+	if err := push("2"); err != nil {
+		t.Fatalf("expected current version to succeed: %v", err)
+	}
+	if w.versions["a"] != 3 {
+		t.Errorf("expected version 3, got %d", w.versions["a"])
+	}
+}
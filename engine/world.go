@@ -3,10 +3,13 @@ package engine
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"net"
 	"net/http"
 	"os"
+	"runtime/debug"
 	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -14,6 +17,7 @@ import (
 
 	"github.com/fatih/color"
 	"github.com/projectqai/hydra/builtin"
+	"github.com/projectqai/hydra/goclient"
 	"github.com/projectqai/hydra/metrics"
 	"github.com/projectqai/hydra/policy"
 	"github.com/projectqai/hydra/version"
@@ -22,9 +26,9 @@ import (
 	"github.com/projectqai/proto/go/_goconnect"
 
 	"connectrpc.com/connect"
-	"github.com/rs/cors"
 	"golang.org/x/net/http2"
 	"golang.org/x/net/http2/h2c"
+	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
@@ -33,10 +37,13 @@ type WorldServer struct {
 
 	bus *Bus
 
-	// currently live, ordered by id
-	head  map[string]*pb.Entity
+	// currently live, see head.go
+	head  *head
 	store *Store
 
+	// clock is read wherever the engine needs the current time, see Clock
+	clock Clock
+
 	frozen   atomic.Bool
 	frozenAt time.Time
 
@@ -45,13 +52,87 @@ type WorldServer struct {
 
 	// policy is optional OPA policy engine for authorization
 	policy *policy.Engine
+
+	// cascadeExpiry expires children together with their parent in gc
+	cascadeExpiry bool
+
+	// estimateVelocity derives Kinematics from consecutive positions in Push
+	estimateVelocity bool
+
+	// merge folds entities reported by different feeds into one, aliases maps merged ids to their target
+	merge   *MergeConfig
+	aliases map[string]string
+
+	// conflict resolves pushes of an entity by different controllers, fieldTimes
+	// holds when each component of an entity was last set, see conflict.go
+	conflict   *ConflictConfig
+	fieldTimes map[string]map[protoreflect.FieldNumber]time.Time
+
+	// throttleConfig limits how often an entity is updated, throttled holds the
+	// latest deferred push per entity id, see throttle.go
+	throttleConfig *ThrottleConfig
+	throttled      map[string]throttledPush
+
+	// contents identify what was last pushed per entity id, unchanged counts the
+	// pushes that only refreshed the lifetime, see unchanged.go
+	contents  map[string]content
+	unchanged atomic.Uint64
+
+	// expiryJitter delays the expiry of each entity by up to this much, see expiresAt
+	expiryJitter time.Duration
+
+	// lastSeen is the wall clock time of the last push per entity id
+	lastSeen map[string]time.Time
+	// versions counts the pushes per entity id, see goclient.HeaderIfVersion
+	versions map[string]uint64
+	// confidence is the confidence entities were last pushed with, see confidence.go
+	confidence confidences
+	// seen counts the pushes of each entity since it was created, see seencount.go
+	seen seenCounts
+	// tombstones remember expired entities for tombstoneRetention, see tombstone.go
+	tombstones         map[string]tombstone
+	tombstoneRetention time.Duration
+	// epoch identifies the store sequence in resume tokens, see resume.go
+	epoch string
+	// pushed counts all entities received in Push, throttledCount those deferred by the throttle
+	pushed         atomic.Uint64
+	throttledCount atomic.Uint64
+
+	// slowConsumerTimeout disconnects watchers that stay behind for longer, zero disables it
+	slowConsumerTimeout time.Duration
+	// watchBufferSize disconnects watchers with more pending changes, zero is unlimited
+	watchBufferSize int
+
+	// maxEntities caps the head, evicted counts the entities expired to stay under it, see evict.go
+	maxEntities int
+	evicted     atomic.Uint64
+
+	// externalWatchers and builtinWatchers are drained on shutdown, see shutdown.go
+	externalWatchers *watcherGroup
+	builtinWatchers  *watcherGroup
 }
 
 func NewWorldServer() *WorldServer {
 	server := &WorldServer{
-		bus:   NewBus(),
-		head:  make(map[string]*pb.Entity),
-		store: NewStore(),
+		clock:    wallClock{},
+		bus:      NewBus(),
+		head:     newHead(),
+		store:    NewStore(),
+		aliases:  make(map[string]string),
+		lastSeen: make(map[string]time.Time),
+		versions: make(map[string]uint64),
+		epoch:    newEpoch(),
+
+		throttled: make(map[string]throttledPush),
+		contents:  make(map[string]content),
+
+		fieldTimes: make(map[string]map[protoreflect.FieldNumber]time.Time),
+
+		tombstones:         make(map[string]tombstone),
+		tombstoneRetention: DefaultTombstoneRetention,
+
+		externalWatchers: newWatcherGroup(),
+		builtinWatchers:  newWatcherGroup(),
 	}
 
 	// Start garbage collection ticker
@@ -66,27 +147,35 @@ func NewWorldServer() *WorldServer {
 	return server
 }
 
+// GetHead returns the current state of an entity, it doesn't wait for pushes of other entities
 func (s *WorldServer) GetHead(id string) *pb.Entity {
-	s.l.RLock()
-	defer s.l.RUnlock()
-	return s.head[id]
+	return s.head.Get(id)
 }
 
 func (s *WorldServer) ListEntities(ctx context.Context, req *connect.Request[pb.ListEntitiesRequest]) (*connect.Response[pb.ListEntitiesResponse], error) {
-	ability := policy.For(s.policy, req.Peer().Addr)
-
-	s.l.RLock()
-	defer s.l.RUnlock()
+	ability := policy.For(ctx, s.policy, req.Peer().Addr)
+	opts, err := parseRequestOptions(req.Header())
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+	if err := validateGeoFilter(req.Msg.Filter); err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+	now := s.now()
 
-	el := make([]*pb.Entity, 0, len(s.head))
-	for _, v := range s.head {
-		if !s.matchesListEntitiesRequest(v, req.Msg) {
+	// without the world lock, a push during the list shows up in some shards only
+	el := make([]*pb.Entity, 0, s.head.Len())
+	for v, marking := range s.head.Entries() {
+		if !s.matchesListEntitiesRequest(v, req.Msg, &opts) {
+			continue
+		}
+		if !opts.matches(v) {
 			continue
 		}
-		if !ability.CanRead(ctx, v) {
+		if !opts.cleared(marking) || !ability.CanRead(ctx, v, marking) {
 			continue
 		}
-		el = append(el, v)
+		el = append(el, opts.present(v, now))
 	}
 	slices.SortFunc(el, func(a, b *pb.Entity) int { return strings.Compare(a.Id, b.Id) })
 
@@ -97,34 +186,76 @@ func (s *WorldServer) ListEntities(ctx context.Context, req *connect.Request[pb.
 }
 
 func (s *WorldServer) GetEntity(ctx context.Context, req *connect.Request[pb.GetEntityRequest]) (*connect.Response[pb.GetEntityResponse], error) {
+	opts, err := parseRequestOptions(req.Header())
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+
 	s.l.RLock()
 	defer s.l.RUnlock()
 
-	entity, exists := s.head[req.Msg.Id]
+	entity, exists := s.head.Lookup(req.Msg.Id)
+	if !exists {
+		if target, ok := s.aliases[req.Msg.Id]; ok {
+			entity, exists = s.head.Lookup(target)
+		}
+	}
 	if !exists {
 		return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("entity with id %s not found", req.Msg.Id))
 	}
 
-	if !policy.For(s.policy, req.Peer().Addr).CanRead(ctx, entity) {
+	marking, classified := s.head.LookupMarking(entity.Id)
+	if !policy.For(ctx, s.policy, req.Peer().Addr).CanRead(ctx, entity, marking) {
 		return nil, connect.NewError(connect.CodePermissionDenied, fmt.Errorf("policy denied read"))
 	}
 
-	response := &pb.GetEntityResponse{
-		Entity: entity,
+	response := connect.NewResponse(&pb.GetEntityResponse{
+		Entity: opts.present(entity, s.now()),
+	})
+	for _, alias := range s.aliasesOf(entity.Id) {
+		response.Header().Add(goclient.HeaderAlias, alias)
 	}
-	return connect.NewResponse(response), nil
+	if classified {
+		response.Header().Set(goclient.HeaderClassification, marking.String())
+	}
+	response.Header().Set(goclient.HeaderVersion, strconv.FormatUint(s.versions[entity.Id], 10))
+	return response, nil
 }
 
 func (s *WorldServer) Push(ctx context.Context, req *connect.Request[pb.EntityChangeRequest]) (*connect.Response[pb.EntityChangeResponse], error) {
-	ability := policy.For(s.policy, req.Peer().Addr)
-	for _, e := range req.Msg.Changes {
-		if err := ability.AuthorizeWrite(ctx, e); err != nil {
-			return nil, err
+	ability := policy.For(ctx, s.policy, req.Peer().Addr)
+
+	var marking *policy.Marking
+	if v := req.Header().Get(goclient.HeaderClassification); v != "" {
+		m, err := policy.ParseMarking(v)
+		if err != nil {
+			return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("invalid %s: %w", goclient.HeaderClassification, err))
 		}
+		marking = &m
+	}
+	confidence := -1.0
+	if v := req.Header().Get(goclient.HeaderConfidence); v != "" {
+		c, err := parseConfidence(v)
+		if err != nil {
+			return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("invalid %s: %w", goclient.HeaderConfidence, err))
+		}
+		confidence = c
 	}
 
 	s.l.Lock()
 	defer s.l.Unlock()
+
+	// authorize all changes before applying any, policies see the entity being replaced
+	for _, e := range req.Msg.Changes {
+		if err := ability.AuthorizeWrite(ctx, e, s.head.Get(e.Id)); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.checkVersions(req.Header()); err != nil {
+		return nil, err
+	}
+	heartbeat := req.Header().Get(goclient.HeaderHeartbeat) == "true"
+	s.pushed.Add(uint64(len(req.Msg.Changes)))
 	for _, e := range req.Msg.Changes {
 
 		if e.Lifetime == nil {
@@ -132,15 +263,31 @@ func (s *WorldServer) Push(ctx context.Context, req *connect.Request[pb.EntityCh
 		}
 
 		if !e.Lifetime.From.IsValid() {
-			e.Lifetime.From = timestamppb.Now()
+			e.Lifetime.From = timestamppb.New(s.clock.Now())
 		}
 
-		s.store.Push(ctx, Event{Entity: e})
-		if !s.frozen.Load() {
-			s.head[e.Id] = e
-			s.bus.Dirty(e.Id, e, pb.EntityChange_EntityChangeUpdated)
+		s.resolveAlias(e)
+		created := !s.head.Has(e.Id)
+		s.seen.observe(e.Id, created)
+		if !s.resolveConflict(e) {
+			continue
+		}
+		if confidence >= 0 {
+			s.confidence.set(e.Id, confidence)
+		} else if created {
+			// the confidence of an expired entity doesn't carry over
+			s.confidence.delete(e.Id)
+		}
+
+		if !heartbeat && s.refreshUnchanged(e, marking) {
+			continue
+		}
+		if s.throttle(e, marking) {
+			continue
 		}
+		s.apply(ctx, e, marking)
 	}
+	s.evict()
 
 	response := &pb.EntityChangeResponse{
 		Accepted: true,
@@ -149,10 +296,162 @@ func (s *WorldServer) Push(ctx context.Context, req *connect.Request[pb.EntityCh
 	return connect.NewResponse(response), nil
 }
 
+// apply records a pushed entity and makes it the current state, a nil marking
+// keeps the classification it had. Caller must hold s.l.
+func (s *WorldServer) apply(ctx context.Context, e *pb.Entity, marking *policy.Marking) {
+	if s.estimateVelocity {
+		estimateVelocity(s.head.Get(e.Id), e)
+	}
+
+	event := Event{Entity: e, Received: s.clock.Now(), Marking: s.head.Marking(e.Id)}
+	if marking != nil {
+		event.Marking = *marking
+	}
+	s.store.Push(ctx, event)
+	if !s.frozen.Load() {
+		existed := s.head.Has(e.Id)
+		s.head.Put(e.Id, e, marking)
+		delete(s.tombstones, e.Id)
+		s.touch(e.Id)
+		if existed {
+			s.bus.Dirty(e.Id, e, pb.EntityChange_EntityChangeUpdated)
+		} else {
+			s.bus.Created(e.Id, e)
+		}
+	}
+}
+
+// compressMinBytes is the smallest message the engine compresses if the client accepts it
+const compressMinBytes = 512
+
+// asBuiltin marks requests as coming from builtins, so policies can tell them
+// from network peers regardless of the address the in-process transport reports
+func asBuiltin(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.RemoteAddr = policy.BuiltinAddr
+		h.ServeHTTP(w, r)
+	})
+}
+
 // EngineConfig holds configuration for starting the engine
 type EngineConfig struct {
 	WorldFile  string
 	PolicyFile string
+	// ConfigFile declares builtins to run, see ConfigFile
+	ConfigFile string
+
+	// CascadeExpire expires located and detected entities when their parent expires
+	CascadeExpire bool
+
+	// EstimateVelocity fills in Kinematics.VelocityEnu from consecutive position
+	// updates of entities that don't report kinematics themselves
+	EstimateVelocity bool
+
+	// Merge enables merging nearby entities from different feeds on ingest, nil disables it
+	Merge *MergeConfig
+
+	// Throttle limits how often the engine accepts updates of the same entity, nil disables it
+	Throttle *ThrottleConfig
+
+	// Conflict resolves pushes of the same entity by different controllers, nil lets the last writer win
+	Conflict *ConflictConfig
+
+	// SlowConsumerTimeout disconnects watch clients that have had unsent changes for
+	// longer than this, zero disables it. Watchers with a rate limit are exempt.
+	SlowConsumerTimeout time.Duration
+
+	// WatchBufferSize is the max number of pending changes per watch client before
+	// it is disconnected, zero is unlimited. Changes to the same entity coalesce, so
+	// without a limit a watcher holds at most one pending change per entity. A small
+	// buffer bounds memory per client on constrained devices but disconnects clients
+	// during bursts, e.g. when a large feed connects. A large one tolerates bursts at
+	// the cost of memory and of a lagging client seeing older state for longer.
+	WatchBufferSize int
+
+	// TombstoneRetention is how long expired entities are remembered, so watchers
+	// that reconnect within it still get the expiries they missed. Watchers that
+	// were away for longer get a full snapshot instead.
+	TombstoneRetention time.Duration
+
+	// ExpiryJitter delays the expiry of each entity by up to this much, by the
+	// same share for an id every time, so a batch pushed with the same
+	// Lifetime.Until disappears over a while instead of at once. Zero expires
+	// entities right at Until. Deletions, pushes with an Until that already
+	// passed, are never delayed.
+	ExpiryJitter time.Duration
+
+	// MaxEntities caps the number of entities, zero is unlimited. When a push
+	// exceeds it, the least recently updated entities are expired, Routine
+	// before Immediate. Flash entities and configurations are kept.
+	MaxEntities int
+
+	// ListenAddr is the host:port the server binds, e.g. "10.0.0.5:50051" to only
+	// serve one network. Empty binds all interfaces on $PORT, or 50051.
+	ListenAddr string
+
+	// AdminAddr is the host:port of a separate listener for /metrics, /healthz,
+	// /readyz and pprof, so they can be firewalled apart from the data API.
+	// Empty serves them on ListenAddr. /healthz stays on ListenAddr either way.
+	AdminAddr string
+
+	// Pprof mounts the net/http/pprof handlers at /debug/pprof/. They expose
+	// internals and can slow the engine down while profiling, so keep it off in production.
+	Pprof bool
+
+	// CORS sets which web pages of other origins may call the engine, nil
+	// allows pages on this machine only
+	CORS *CORSConfig
+
+	// Self publishes an own-ship entity at a fixed position, nil disables it
+	Self *SelfConfig
+
+	// StopBuiltins is called on shutdown once external clients are disconnected and
+	// builtin watches are drained, builtins can still use the in-process listener
+	// until it returns. Stopped is closed once the engine has shut down. Both are optional.
+	StopBuiltins func()
+	Stopped      chan struct{}
+}
+
+// handlerOptions are the options of all connect services of the engine
+func handlerOptions() []connect.HandlerOption {
+	return []connect.HandlerOption{
+		// gzip is supported by default, small messages are not worth compressing
+		connect.WithCompressMinBytes(compressMinBytes),
+		// policies see the bearer token of requests, see policy.Subject
+		connect.WithInterceptors(policy.TokenInterceptor()),
+		// a panicking call fails on its own instead of taking the engine down
+		connect.WithRecover(recoverHandler),
+	}
+}
+
+// recoverHandler turns a panic in a connect handler into an internal error
+func recoverHandler(ctx context.Context, spec connect.Spec, _ http.Header, r any) error {
+	slog.Error("handler panicked", "procedure", spec.Procedure, "panic", r, "stack", string(debug.Stack()))
+	return connect.NewError(connect.CodeInternal, fmt.Errorf("internal error: %v", r))
+}
+
+// handle registers the services and JSON endpoints of the world on mux
+func (s *WorldServer) handle(mux *http.ServeMux) {
+	options := handlerOptions()
+
+	worldPath, worldHandler := _goconnect.NewWorldServiceHandler(s, options...)
+	mux.Handle(worldPath, worldHandler)
+
+	timelinePath, timelineHandler := _goconnect.NewTimelineServiceHandler(s, options...)
+	mux.Handle(timelinePath, timelineHandler)
+
+	mux.HandleFunc("/healthz", handleHealth)
+
+	mux.HandleFunc("/nearest", s.handleNearest)
+	mux.HandleFunc("/clusters", s.handleClusters)
+	mux.HandleFunc("/bearing", s.handleBearing)
+	mux.HandleFunc("/entities/meta", s.handleEntityMeta)
+	mux.HandleFunc("/entities/tag", s.handleTag)
+	mux.HandleFunc("/events", s.handleEvents)
+	mux.HandleFunc("/firehose", s.handleFirehose)
+	mux.HandleFunc("/stats", s.handleStats)
+	mux.HandleFunc("/info", handleInfo)
+	mux.HandleFunc("/builtins", handleBuiltins)
 }
 
 // StartEngine starts the Hydra engine and returns the server address.
@@ -160,6 +459,16 @@ type EngineConfig struct {
 // and periodically flushes the current state back to the file.
 func StartEngine(ctx context.Context, cfg EngineConfig) (string, error) {
 	engine := NewWorldServer()
+	engine.cascadeExpiry = cfg.CascadeExpire
+	engine.estimateVelocity = cfg.EstimateVelocity
+	engine.merge = cfg.Merge
+	engine.throttleConfig = cfg.Throttle
+	engine.conflict = cfg.Conflict
+	engine.slowConsumerTimeout = cfg.SlowConsumerTimeout
+	engine.watchBufferSize = cfg.WatchBufferSize
+	engine.tombstoneRetention = cfg.TombstoneRetention
+	engine.maxEntities = cfg.MaxEntities
+	engine.expiryJitter = cfg.ExpiryJitter
 
 	// Set up world file persistence if specified
 	if cfg.WorldFile != "" {
@@ -174,6 +483,17 @@ func StartEngine(ctx context.Context, cfg EngineConfig) (string, error) {
 		engine.StartPeriodicFlush(10 * time.Second)
 	}
 
+	// Declare builtins after the world file, so the config file wins
+	if cfg.ConfigFile != "" {
+		if err := engine.LoadConfigFile(cfg.ConfigFile); err != nil {
+			return "", err
+		}
+	}
+
+	if cfg.Self != nil {
+		go engine.publishSelf(ctx, cfg.Self)
+	}
+
 	// Set up OPA policy engine if specified
 	if cfg.PolicyFile != "" {
 		policyEngine, err := policy.NewEngine(cfg.PolicyFile)
@@ -196,28 +516,40 @@ func StartEngine(ctx context.Context, cfg EngineConfig) (string, error) {
 	// Start metrics updater
 	StartMetricsUpdater(engine)
 
-	// Get port from environment or use default
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "50051"
+	// Listen on all interfaces unless told otherwise, the port may come from the environment
+	listenAddr := cfg.ListenAddr
+	if listenAddr == "" {
+		port := os.Getenv("PORT")
+		if port == "" {
+			port = "50051"
+		}
+		listenAddr = ":" + port
 	}
 
 	// Create HTTP handlers
 	mux := http.NewServeMux()
-
-	worldPath, worldHandler := _goconnect.NewWorldServiceHandler(engine)
-	mux.Handle(worldPath, worldHandler)
-
-	timelinePath, timelineHandler := _goconnect.NewTimelineServiceHandler(engine)
-	mux.Handle(timelinePath, timelineHandler)
-
-	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "text/plain")
-		w.Write([]byte("OK"))
-	})
-
-	// Prometheus metrics endpoint
-	mux.Handle("/metrics", promHandler)
+	engine.handle(mux)
+
+	// Metrics, readiness and profiles, on their own listener if configured
+	var adminServer *http.Server
+	if cfg.AdminAddr == "" {
+		engine.handleAdmin(mux, promHandler, cfg)
+	} else {
+		adminMux := http.NewServeMux()
+		adminMux.HandleFunc("/healthz", handleHealth)
+		engine.handleAdmin(adminMux, promHandler, cfg)
+		adminListener, err := net.Listen("tcp", cfg.AdminAddr)
+		if err != nil {
+			return "", fmt.Errorf("failed to listen on admin address %s: %v", cfg.AdminAddr, err)
+		}
+		adminServer = &http.Server{Handler: adminMux}
+		go func() {
+			if err := adminServer.Serve(adminListener); err != nil && err != http.ErrServerClosed {
+				fmt.Printf("Admin server error: %v\n", err)
+				os.Exit(1)
+			}
+		}()
+	}
 
 	webServer, err := view.NewWebServer()
 	if err != nil {
@@ -225,24 +557,18 @@ func StartEngine(ctx context.Context, cfg EngineConfig) (string, error) {
 	}
 	mux.Handle("/", webServer)
 
-	corsHandler := cors.New(cors.Options{
-		AllowedOrigins: []string{"*"},
-		AllowedMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders: []string{"*"},
-	})
-
 	httpServer := &http.Server{
-		Addr:    ":" + port,
-		Handler: h2c.NewHandler(corsHandler.Handler(mux), &http2.Server{}),
+		Addr:    listenAddr,
+		Handler: h2c.NewHandler(cfg.CORS.Handler(mux), &http2.Server{}),
 	}
 
 	// Create listener first to fail fast if port is in use
-	listener, err := net.Listen("tcp", ":"+port)
+	listener, err := net.Listen("tcp", listenAddr)
 	if err != nil {
-		return "", fmt.Errorf("failed to listen on port %s: %v", port, err)
+		return "", fmt.Errorf("failed to listen on %s: %v", listenAddr, err)
 	}
+	local, network := bannerURLs(listener.Addr().(*net.TCPAddr))
 
-	localIPs := getAllLocalIPs()
 	green := color.New(color.FgGreen)
 	cyan := color.New(color.FgCyan)
 	bold := color.New(color.Bold)
@@ -252,14 +578,16 @@ func StartEngine(ctx context.Context, cfg EngineConfig) (string, error) {
 	bold.Print("Hydra World Server ")
 	fmt.Printf("(%s)", version.Version)
 	fmt.Println(" running at:")
-	green.Print("  ➜ ")
-	fmt.Print("Local:   ")
-	cyan.Printf("http://localhost:%s\n", port)
+	if local != "" {
+		green.Print("  ➜ ")
+		fmt.Print("Local:   ")
+		cyan.Printf("http://%s\n", local)
+	}
 
-	for _, ip := range localIPs {
+	for _, addr := range network {
 		green.Print("  ➜ ")
 		fmt.Print("Network: ")
-		cyan.Printf("http://%s:%s\n", ip, port)
+		cyan.Printf("http://%s\n", addr)
 	}
 	fmt.Println()
 
@@ -272,7 +600,7 @@ func StartEngine(ctx context.Context, cfg EngineConfig) (string, error) {
 
 	// Start in-process server for builtin services
 	builtinServer := &http.Server{
-		Handler: h2c.NewHandler(mux, &http2.Server{}),
+		Handler: h2c.NewHandler(asBuiltin(mux), &http2.Server{}),
 	}
 	go func() {
 		if err := builtinServer.Serve(builtin.GetBuiltinListener()); err != nil && err != http.ErrServerClosed {
@@ -283,9 +611,11 @@ func StartEngine(ctx context.Context, cfg EngineConfig) (string, error) {
 
 	go func() {
 		<-ctx.Done()
-		httpServer.Shutdown(context.Background())
-		builtinServer.Shutdown(context.Background())
+		engine.shutdown(httpServer, builtinServer, adminServer, cfg)
 	}()
 
-	return "localhost:" + port, nil
+	if local != "" {
+		return local, nil
+	}
+	return network[0], nil
 }
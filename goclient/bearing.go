@@ -0,0 +1,11 @@
+package goclient
+
+// BearingResponse is served at the engine's /bearing
+type BearingResponse struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Bearing is the initial great-circle bearing from From to To in degrees clockwise from true north
+	Bearing float64 `json:"bearing"`
+	// Distance is the great-circle distance in meters
+	Distance float64 `json:"distance"`
+}
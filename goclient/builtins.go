@@ -0,0 +1,15 @@
+package goclient
+
+// BuiltinStatus is the state of a builtin registered in the engine process
+type BuiltinStatus struct {
+	Name string `json:"name"`
+	// State is one of "stopped", "running" or "restarting"
+	State     string `json:"state"`
+	Restarts  int    `json:"restarts"`
+	LastError string `json:"last_error,omitempty"`
+}
+
+// BuiltinsResponse is served at the engine's /builtins
+type BuiltinsResponse struct {
+	Builtins []BuiltinStatus `json:"builtins"`
+}
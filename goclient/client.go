@@ -4,6 +4,7 @@ import (
 	"context"
 	"io"
 	"log/slog"
+	"strings"
 	"time"
 
 	proto "github.com/projectqai/proto/go"
@@ -11,6 +12,7 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	_ "google.golang.org/grpc/encoding/gzip"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 )
@@ -19,6 +21,9 @@ import (
 type Connection struct {
 	*grpc.ClientConn
 	Tunnel *WireGuardTunnel // nil for non-WireGuard connections
+
+	// addr is the server address, used for the engine's plain HTTP endpoints
+	addr string
 }
 
 // Close closes the connection and tunnel if present
@@ -32,16 +37,25 @@ func (c *Connection) Close() error {
 	return nil
 }
 
+// dialOptions are used for all connections to an engine. Requests are sent
+// uncompressed, importing gzip only announces that the engine may gzip its
+// responses, which shrinks large ones like ListEntities. Watch streams barely
+// benefit as every event is compressed on its own. Errors are wrapped, see WrapError.
+func dialOptions(opts ...grpc.DialOption) []grpc.DialOption {
+	return append([]grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithChainUnaryInterceptor(UnaryErrorInterceptor),
+		grpc.WithChainStreamInterceptor(StreamErrorInterceptor),
+	}, opts...)
+}
+
 // Connect establishes a gRPC connection to the server
 func Connect(serverURL string) (*Connection, error) {
-	conn, err := grpc.NewClient(
-		serverURL,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-	)
+	conn, err := grpc.NewClient(serverURL, dialOptions()...)
 	if err != nil {
 		return nil, err
 	}
-	return &Connection{ClientConn: conn}, nil
+	return &Connection{ClientConn: conn, addr: serverURL}, nil
 }
 
 // ConnectWithWireGuard establishes a gRPC connection through a WireGuard tunnel
@@ -51,12 +65,18 @@ func ConnectWithWireGuard(serverAddr string, wgConfigPath string) (*Connection,
 		return nil, err
 	}
 
+	return ConnectWithWireGuardConfig(serverAddr, cfg)
+}
+
+// ConnectWithWireGuardConfig establishes a gRPC connection through a WireGuard tunnel
+// with a parsed config
+func ConnectWithWireGuardConfig(serverAddr string, cfg *WireGuardConfig) (*Connection, error) {
 	conn, tunnel, err := ConnectViaWireGuard(serverAddr, cfg)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Connection{ClientConn: conn, Tunnel: tunnel}, nil
+	return &Connection{ClientConn: conn, Tunnel: tunnel, addr: serverAddr}, nil
 }
 
 func isRetryableStreamError(err error) bool {
@@ -82,10 +102,18 @@ type resilientWatchEntitiesStream struct {
 	client  proto.WorldServiceClient
 	request *proto.ListEntitiesRequest
 	stream  proto.WorldService_WatchEntitiesClient
+
+	// token is the last resume token received, a reconnect only gets the changes since
+	token string
+	// shutdown is set when the engine announced it is shutting down
+	shutdown bool
 }
 
+// WatchEntitiesWithRetry watches entities and reconnects on transient errors.
+// After reconnecting the engine sends only the changes missed in between if it
+// still can, otherwise the full snapshot again.
 func WatchEntitiesWithRetry(ctx context.Context, client proto.WorldServiceClient, req *proto.ListEntitiesRequest) (proto.WorldService_WatchEntitiesClient, error) {
-	stream, err := client.WatchEntities(ctx, req)
+	stream, err := client.WatchEntities(metadata.AppendToOutgoingContext(ctx, HeaderResume, ResumeStart), req)
 	if err != nil {
 		return nil, err
 	}
@@ -103,6 +131,14 @@ func (r *resilientWatchEntitiesStream) Recv() (*proto.EntityChangeEvent, error)
 		slog.Debug("attempting to receive message from stream")
 		msg, err := r.stream.Recv()
 		if err == nil {
+			if token, ok := resumeToken(msg); ok {
+				r.token = token
+				continue
+			}
+			if isShutdown(msg) {
+				r.shutdown = true
+				continue
+			}
 			slog.Debug("received message successfully")
 			return msg, nil
 		}
@@ -127,6 +163,12 @@ func (r *resilientWatchEntitiesStream) Recv() (*proto.EntityChangeEvent, error)
 		retryStartTime := time.Now()
 		retryInterval := 1 * time.Second
 		maxRetryInterval := 30 * time.Second
+		if r.shutdown {
+			// the engine is restarting, don't hammer it while it comes back up
+			slog.Debug("world is shutting down, waiting before reconnecting")
+			retryInterval = shutdownRetryInterval
+			r.shutdown = false
+		}
 		attemptCount := 0
 
 		for {
@@ -139,7 +181,11 @@ func (r *resilientWatchEntitiesStream) Recv() (*proto.EntityChangeEvent, error)
 				return nil, r.ctx.Err()
 			}
 
-			stream, err := r.client.WatchEntities(r.ctx, r.request)
+			resume := ResumeStart
+			if r.token != "" {
+				resume = r.token
+			}
+			stream, err := r.client.WatchEntities(metadata.AppendToOutgoingContext(r.ctx, HeaderResume, resume), r.request)
 			if err != nil {
 				slog.Warn("reconnecting to world", "error", err, "attempt", attemptCount, "elapsed", time.Since(retryStartTime))
 				retryInterval = min(retryInterval*2, maxRetryInterval)
@@ -153,6 +199,22 @@ func (r *resilientWatchEntitiesStream) Recv() (*proto.EntityChangeEvent, error)
 	}
 }
 
+// shutdownRetryInterval is the first reconnect delay after the engine announced a shutdown
+const shutdownRetryInterval = 5 * time.Second
+
+// isShutdown reports whether msg announces the engine shutting down, see ShutdownEventID
+func isShutdown(msg *proto.EntityChangeEvent) bool {
+	return msg.T == proto.EntityChange_EntityChangeInvalid && msg.Entity != nil && msg.Entity.Id == ShutdownEventID
+}
+
+// resumeToken extracts the token of a checkpoint event, see ResumeTokenPrefix
+func resumeToken(msg *proto.EntityChangeEvent) (string, bool) {
+	if msg.T != proto.EntityChange_EntityChangeInvalid || msg.Entity == nil {
+		return "", false
+	}
+	return strings.CutPrefix(msg.Entity.Id, ResumeTokenPrefix)
+}
+
 func (r *resilientWatchEntitiesStream) Header() (metadata.MD, error) {
 	return r.stream.Header()
 }
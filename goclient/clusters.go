@@ -0,0 +1,34 @@
+package goclient
+
+import "encoding/json"
+
+// ClustersRequest asks for the entities in a map view, bucketed into a grid of
+// web mercator cells. Cells with at least MinCount entities are summarized as
+// a cluster, smaller cells return their entities, so a client can show clusters
+// when zoomed out and real entities when zoomed in.
+type ClustersRequest struct {
+	// Zoom is the web map zoom level from 0 to 20, cells are a quarter of a tile wide
+	Zoom int `json:"zoom"`
+	// Bounds limits the request to [minLon, minLat, maxLon, maxLat], the whole world if empty
+	Bounds []float64 `json:"bounds,omitempty"`
+	// MinCount is the smallest cell summarized as a cluster, 2 by default
+	MinCount int `json:"min_count,omitempty"`
+	// Filter is an EntityFilter in protojson encoding
+	Filter json.RawMessage `json:"filter,omitempty"`
+}
+
+type Cluster struct {
+	Count int `json:"count"`
+	// Lon and Lat are the centroid of the entities in the cluster
+	Lon float64 `json:"lon"`
+	Lat float64 `json:"lat"`
+	// Bounds of the entities as [minLon, minLat, maxLon, maxLat]
+	Bounds [4]float64 `json:"bounds"`
+}
+
+// ClustersResponse is served at the engine's /clusters
+type ClustersResponse struct {
+	Clusters []Cluster `json:"clusters"`
+	// Entities are in protojson encoding
+	Entities []json.RawMessage `json:"entities"`
+}
@@ -0,0 +1,91 @@
+package goclient
+
+import (
+	"math"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geo"
+	"github.com/paulmach/orb/planar"
+)
+
+// DistanceToGeometry is the great-circle distance in meters from p to the closest
+// point of g. It is 0 if p lies inside a polygon of g. Segments are evaluated in
+// a local flat projection around p, which is accurate for distances well below
+// the earth radius.
+func DistanceToGeometry(p orb.Point, g orb.Geometry) float64 {
+	switch g := g.(type) {
+	case orb.Point:
+		return geo.Distance(p, g)
+	case orb.MultiPoint:
+		return minDistance(len(g), func(i int) float64 { return geo.Distance(p, g[i]) })
+	case orb.LineString:
+		return distanceToPath(p, g)
+	case orb.MultiLineString:
+		return minDistance(len(g), func(i int) float64 { return distanceToPath(p, g[i]) })
+	case orb.Ring:
+		return distanceToPath(p, closedPath(g))
+	case orb.Polygon:
+		if planar.PolygonContains(g, p) {
+			return 0
+		}
+		return minDistance(len(g), func(i int) float64 { return distanceToPath(p, closedPath(g[i])) })
+	case orb.MultiPolygon:
+		return minDistance(len(g), func(i int) float64 { return DistanceToGeometry(p, g[i]) })
+	case orb.Collection:
+		return minDistance(len(g), func(i int) float64 { return DistanceToGeometry(p, g[i]) })
+	case orb.Bound:
+		return DistanceToGeometry(p, g.ToPolygon())
+	}
+	return math.Inf(1)
+}
+
+func minDistance(n int, distance func(i int) float64) float64 {
+	min := math.Inf(1)
+	for i := 0; i < n; i++ {
+		min = math.Min(min, distance(i))
+	}
+	return min
+}
+
+func closedPath(r orb.Ring) orb.LineString {
+	if len(r) > 1 && r[0] != r[len(r)-1] {
+		return append(orb.LineString(r[:len(r):len(r)]), r[0])
+	}
+	return orb.LineString(r)
+}
+
+func distanceToPath(p orb.Point, path orb.LineString) float64 {
+	switch len(path) {
+	case 0:
+		return math.Inf(1)
+	case 1:
+		return geo.Distance(p, path[0])
+	}
+	return minDistance(len(path)-1, func(i int) float64 {
+		return geo.Distance(p, closestOnSegment(p, path[i], path[i+1]))
+	})
+}
+
+// closestOnSegment projects p onto the segment a-b, scaling longitude by the
+// cosine of p's latitude so the projection is roughly metric
+func closestOnSegment(p, a, b orb.Point) orb.Point {
+	scale := math.Max(math.Cos(p[1]*math.Pi/180), 1e-9)
+	ax, ay := wrapLon(a[0]-p[0])*scale, a[1]-p[1]
+	bx, by := wrapLon(b[0]-p[0])*scale, b[1]-p[1]
+
+	dx, dy := bx-ax, by-ay
+	lengthSq := dx*dx + dy*dy
+	if lengthSq == 0 {
+		return a
+	}
+	t := -(ax*dx + ay*dy) / lengthSq
+	t = math.Max(0, math.Min(1, t))
+
+	x, y := ax+t*dx, ay+t*dy
+	return orb.Point{p[0] + x/scale, p[1] + y}
+}
+
+// wrapLon normalizes a longitude difference to [-180, 180)
+func wrapLon(d float64) float64 {
+	return math.Mod(d+540, 360) - 180
+}
@@ -0,0 +1,29 @@
+package goclient
+
+import (
+	"math"
+	"testing"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geo"
+)
+
+func TestDistanceToGeometry(t *testing.T) {
+	square := orb.Polygon{{{10, 50}, {10.1, 50}, {10.1, 50.1}, {10, 50.1}, {10, 50}}}
+
+	if d := DistanceToGeometry(orb.Point{10.05, 50.05}, square); d != 0 {
+		t.Errorf("expected 0 inside polygon, got %f", d)
+	}
+
+	// 0.01 degrees of latitude south of the bottom edge
+	if d := DistanceToGeometry(orb.Point{10.05, 49.99}, square); d < 1100 || d > 1125 {
+		t.Errorf("expected ~1112m to the edge, got %f", d)
+	}
+
+	// beyond the end of a line the closest point is its end
+	line := orb.LineString{{10, 50}, {10.1, 50}}
+	want := geo.Distance(orb.Point{10.2, 50}, orb.Point{10.1, 50})
+	if d := DistanceToGeometry(orb.Point{10.2, 50}, line); math.Abs(d-want) > 1 {
+		t.Errorf("expected %f to the line end, got %f", want, d)
+	}
+}
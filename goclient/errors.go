@@ -0,0 +1,99 @@
+package goclient
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Classes of errors returned by the engine, test for them with errors.Is.
+// Connections made by this package return errors that match them, other
+// connections can add UnaryErrorInterceptor and StreamErrorInterceptor.
+var (
+	// ErrUnavailable means the engine can't be reached or is not ready yet,
+	// e.g. it is restarting or the name doesn't resolve. Retry soon.
+	ErrUnavailable = errors.New("engine unavailable")
+
+	// ErrUnauthenticated means the engine rejected the client, e.g. by its
+	// policy. Retrying won't help until the policy or the credentials change,
+	// so back off long.
+	ErrUnauthenticated = errors.New("rejected by engine")
+
+	// ErrInvalid means the engine can't serve the request as sent, e.g. it
+	// doesn't know the call or an option is malformed. Give up.
+	ErrInvalid = errors.New("invalid request")
+)
+
+// StatusError is an error of the engine with its class, see ErrUnavailable,
+// ErrUnauthenticated and ErrInvalid. It still works with status.FromError.
+type StatusError struct {
+	Code codes.Code
+	err  error
+}
+
+func (e *StatusError) Error() string { return e.err.Error() }
+
+func (e *StatusError) Unwrap() error { return e.err }
+
+// GRPCStatus lets status.FromError and status.Code see through the wrapping
+func (e *StatusError) GRPCStatus() *status.Status {
+	return status.Convert(e.err)
+}
+
+func (e *StatusError) Is(target error) bool {
+	return target != nil && classOf(e.Code) == target
+}
+
+func classOf(code codes.Code) error {
+	switch code {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return ErrUnavailable
+	case codes.Unauthenticated, codes.PermissionDenied:
+		return ErrUnauthenticated
+	case codes.InvalidArgument, codes.Unimplemented:
+		return ErrInvalid
+	}
+	return nil
+}
+
+// WrapError wraps an error of a gRPC call into a StatusError. Other errors,
+// like io.EOF at the end of a stream, are returned as they are.
+func WrapError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var se *StatusError
+	if errors.As(err, &se) {
+		return err
+	}
+	st, ok := status.FromError(err)
+	if !ok || st.Code() == codes.OK {
+		return err
+	}
+	return &StatusError{Code: st.Code(), err: err}
+}
+
+// UnaryErrorInterceptor wraps the errors of unary calls, see WrapError
+func UnaryErrorInterceptor(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	return WrapError(invoker(ctx, method, req, reply, cc, opts...))
+}
+
+// StreamErrorInterceptor wraps the errors of streams, see WrapError
+func StreamErrorInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	s, err := streamer(ctx, desc, cc, method, opts...)
+	if err != nil {
+		return nil, WrapError(err)
+	}
+	return &errorStream{ClientStream: s}, nil
+}
+
+type errorStream struct {
+	grpc.ClientStream
+}
+
+func (s *errorStream) SendMsg(m any) error { return WrapError(s.ClientStream.SendMsg(m)) }
+
+func (s *errorStream) RecvMsg(m any) error { return WrapError(s.ClientStream.RecvMsg(m)) }
@@ -0,0 +1,127 @@
+package goclient
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/encoding/wkb"
+	"github.com/paulmach/orb/encoding/wkt"
+	"github.com/paulmach/orb/geo"
+	proto "github.com/projectqai/proto/go"
+)
+
+// PlanarToOrb converts a planar geometry, nil if it is empty
+func PlanarToOrb(planar *proto.PlanarGeometry) orb.Geometry {
+	if planar == nil {
+		return nil
+	}
+
+	switch p := planar.Plane.(type) {
+	case *proto.PlanarGeometry_Point:
+		if p.Point != nil {
+			return orb.Point{p.Point.Longitude, p.Point.Latitude}
+		}
+	case *proto.PlanarGeometry_Line:
+		if p.Line != nil && len(p.Line.Points) > 0 {
+			line := make(orb.LineString, len(p.Line.Points))
+			for i, pt := range p.Line.Points {
+				line[i] = orb.Point{pt.Longitude, pt.Latitude}
+			}
+			return line
+		}
+	case *proto.PlanarGeometry_Polygon:
+		if p.Polygon != nil && p.Polygon.Outer != nil && len(p.Polygon.Outer.Points) > 0 {
+			outer := make(orb.Ring, len(p.Polygon.Outer.Points))
+			for i, pt := range p.Polygon.Outer.Points {
+				outer[i] = orb.Point{pt.Longitude, pt.Latitude}
+			}
+			poly := orb.Polygon{outer}
+
+			// Add holes if present
+			for _, hole := range p.Polygon.Holes {
+				if len(hole.Points) > 0 {
+					holeRing := make(orb.Ring, len(hole.Points))
+					for i, pt := range hole.Points {
+						holeRing[i] = orb.Point{pt.Longitude, pt.Latitude}
+					}
+					poly = append(poly, holeRing)
+				}
+			}
+			return poly
+		}
+	}
+
+	return nil
+}
+
+// GeometryToOrb converts the planar geometry, or the WKB encoding if there is none.
+// WKB can express multi-polygons and collections that PlanarGeometry can not.
+func GeometryToOrb(g *proto.Geometry) orb.Geometry {
+	if g == nil {
+		return nil
+	}
+	if g.Planar != nil {
+		return PlanarToOrb(g.Planar)
+	}
+	if len(g.Wkb) > 0 {
+		geom, err := DecodeGeometry(g.Wkb)
+		if err != nil {
+			return nil
+		}
+		return geom
+	}
+	return nil
+}
+
+// DecodeGeometry reads WKB, or WKT text in its place, e.g. "POLYGON((...))".
+// WKB starts with its byte order 0 or 1, which WKT never does.
+func DecodeGeometry(b []byte) (orb.Geometry, error) {
+	if len(b) == 0 {
+		return nil, errors.New("empty geometry")
+	}
+	if b[0] > 1 {
+		return wkt.Unmarshal(string(bytes.TrimSpace(b)))
+	}
+	return wkb.Unmarshal(b)
+}
+
+// WKTGeometry parses WKT, e.g. "POLYGON((...))", into a geometry encoded as WKB
+func WKTGeometry(s string) (*proto.Geometry, error) {
+	g, err := wkt.Unmarshal(s)
+	if err != nil {
+		return nil, err
+	}
+	b, err := wkb.Marshal(g)
+	if err != nil {
+		return nil, err
+	}
+	return &proto.Geometry{Wkb: b}, nil
+}
+
+// EntityPath returns the line shape of an entity, e.g. a route or a track
+func EntityPath(entity *proto.Entity) (orb.MultiLineString, bool) {
+	if entity.Shape == nil || entity.Shape.Geometry == nil {
+		return nil, false
+	}
+	switch g := GeometryToOrb(entity.Shape.Geometry).(type) {
+	case orb.LineString:
+		return orb.MultiLineString{g}, len(g) > 0
+	case orb.MultiLineString:
+		return g, len(g) > 0
+	}
+	return nil, false
+}
+
+// PathLength is the great-circle length in meters of the line shape of an entity
+func PathLength(entity *proto.Entity) (float64, bool) {
+	path, ok := EntityPath(entity)
+	if !ok {
+		return 0, false
+	}
+	var length float64
+	for _, line := range path {
+		length += geo.Length(line)
+	}
+	return length, true
+}
@@ -0,0 +1,20 @@
+package goclient
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// HistoryEvent is a push the engine received or an expiry, as kept in the store
+type HistoryEvent struct {
+	Received time.Time `json:"received"`
+	// Entity is the pushed entity in protojson encoding, or the last state of an expired one
+	Entity json.RawMessage `json:"entity"`
+	// Expired is set when the entity was removed
+	Expired bool `json:"expired,omitempty"`
+}
+
+// HistoryResponse is served at the engine's /events
+type HistoryResponse struct {
+	Events []HistoryEvent `json:"events"`
+}
@@ -0,0 +1,99 @@
+package goclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// HTTPClient returns a client for the engine's plain HTTP endpoints,
+// routed through the WireGuard tunnel if the connection uses one.
+func (c *Connection) HTTPClient() *http.Client {
+	if c.Tunnel == nil {
+		return http.DefaultClient
+	}
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				return c.Tunnel.Dial(ctx, addr)
+			},
+		},
+	}
+}
+
+// URL returns the http url of path on the server
+func (c *Connection) URL(path string, query url.Values) string {
+	base := c.addr
+	if !strings.Contains(base, "://") {
+		base = "http://" + base
+	}
+	u := strings.TrimSuffix(base, "/") + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	return u
+}
+
+// GetJSON fetches path from the engine and decodes the json response into out
+func (c *Connection) GetJSON(ctx context.Context, path string, query url.Values, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.URL(path, query), nil)
+	if err != nil {
+		return err
+	}
+	return c.doJSON(req, out)
+}
+
+// PostJSON sends in as json body to path and decodes the json response into out
+func (c *Connection) PostJSON(ctx context.Context, path string, in any, out any) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL(path, nil), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return c.doJSON(req, out)
+}
+
+// HTTPError is a response of the engine's HTTP endpoints other than 200 OK
+type HTTPError struct {
+	Method     string
+	Path       string
+	StatusCode int
+	Message    string
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("%s %s: %d %s: %s", e.Method, e.Path, e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+func (c *Connection) doJSON(req *http.Request, out any) error {
+	resp, err := c.HTTPClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return &HTTPError{Method: req.Method, Path: req.URL.Path, StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(msg))}
+	}
+	// the web app answers paths the engine doesn't serve, e.g. endpoints added
+	// after the version it runs
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+		return &HTTPError{Method: req.Method, Path: req.URL.Path, StatusCode: http.StatusNotFound, Message: "served by the web app"}
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
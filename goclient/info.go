@@ -0,0 +1,67 @@
+package goclient
+
+import (
+	"context"
+	"fmt"
+	"slices"
+)
+
+// ProtocolVersion is bumped when engines of different builds can no longer
+// exchange entities, e.g. because the meaning of a field changed. Federation
+// refuses peers with another protocol.
+const ProtocolVersion = 1
+
+// Engine features announced in ServerInfo. A peer without one of them ignores
+// the corresponding request headers, so federation works with less fidelity.
+const (
+	// FeatureResume is support for HeaderResume
+	FeatureResume = "resume"
+	// FeatureTombstones is support for expiry events that carry the expired entity
+	FeatureTombstones = "tombstones"
+	// FeatureClassification is support for HeaderClassification and HeaderClearance
+	FeatureClassification = "classification"
+	// FeatureIfVersion is support for HeaderIfVersion
+	FeatureIfVersion = "if-version"
+)
+
+// Features lists the features of this build
+var Features = []string{FeatureResume, FeatureTombstones, FeatureClassification, FeatureIfVersion}
+
+// ServerInfo describes the build of an engine, served at /info
+type ServerInfo struct {
+	Version string `json:"version"`
+	// Proto is the version of the world proto module. Fields unknown to
+	// one side are dropped when entities pass through it.
+	Proto    string   `json:"proto"`
+	Protocol int      `json:"protocol"`
+	Features []string `json:"features"`
+}
+
+// ServerInfo fetches the build information of the engine
+func (c *Connection) ServerInfo(ctx context.Context) (ServerInfo, error) {
+	var info ServerInfo
+	err := c.GetJSON(ctx, "/info", nil, &info)
+	return info, err
+}
+
+// Compatible checks whether entities can be exchanged between engines with
+// the given infos. It fails if they can't, otherwise it returns the differences
+// that may lose data on the way.
+func Compatible(local, remote ServerInfo) (warnings []string, err error) {
+	if local.Protocol != remote.Protocol {
+		return nil, fmt.Errorf("incompatible protocol %d, expected %d (version %s, local %s)",
+			remote.Protocol, local.Protocol, remote.Version, local.Version)
+	}
+	if local.Version != remote.Version {
+		warnings = append(warnings, fmt.Sprintf("version %s differs from local %s", remote.Version, local.Version))
+	}
+	if local.Proto != remote.Proto {
+		warnings = append(warnings, fmt.Sprintf("proto %s differs from local %s, fields unknown to either side are dropped", remote.Proto, local.Proto))
+	}
+	for _, f := range local.Features {
+		if !slices.Contains(remote.Features, f) {
+			warnings = append(warnings, fmt.Sprintf("feature %s is not supported", f))
+		}
+	}
+	return warnings, nil
+}
@@ -0,0 +1,30 @@
+package goclient
+
+import "time"
+
+// EntityMeta is engine-side bookkeeping about an entity that is not part of the entity itself
+type EntityMeta struct {
+	// LastSeen is when the engine last received a push for the entity. Unlike
+	// Lifetime.From it can not be backdated by the producer.
+	LastSeen time.Time `json:"last_seen"`
+	// Age is the time since LastSeen in seconds
+	Age float64 `json:"age"`
+	// Version is the number of pushes of the entity, see HeaderIfVersion
+	Version uint64 `json:"version"`
+	// Classification is the marking banner of classified entities
+	Classification string `json:"classification,omitempty"`
+	// Confidence is set for entities pushed with one, see HeaderConfidence
+	Confidence *float64 `json:"confidence,omitempty"`
+	// SeenCount is how often the entity was pushed since it was created, see HeaderMinSeen
+	SeenCount uint64 `json:"seen_count"`
+	// Components is when each component was last set by a push, by its JSON name.
+	// It is the measurement time of the push, Detection.LastMeasured or else
+	// Lifetime.From, so with a conflict policy components of one entity can
+	// come from different pushes.
+	Components map[string]time.Time `json:"components,omitempty"`
+}
+
+// EntityMetaResponse is served at the engine's /entities/meta
+type EntityMetaResponse struct {
+	Entities map[string]EntityMeta `json:"entities"`
+}
@@ -0,0 +1,28 @@
+package goclient
+
+import "encoding/json"
+
+// NearestRequest is the body of a POST to the engine's /nearest, which returns
+// the entities closest to a point or geometry
+type NearestRequest struct {
+	Lon float64 `json:"lon"`
+	Lat float64 `json:"lat"`
+	// K is the number of results, 10 by default and at most 1000
+	K int `json:"k"`
+	// Sidc is a glob pattern matched against the MIL-STD-2525C symbol, e.g. "SF*"
+	Sidc string `json:"sidc,omitempty"`
+	// Filter is an EntityFilter in protojson encoding
+	Filter json.RawMessage `json:"filter,omitempty"`
+	// Geometry is a Geometry in protojson encoding. If set, distances are measured
+	// to its closest point instead of to Lon/Lat.
+	Geometry json.RawMessage `json:"geometry,omitempty"`
+}
+
+type NearestResult struct {
+	Distance float64         `json:"distance"`
+	Entity   json.RawMessage `json:"entity"`
+}
+
+type NearestResponse struct {
+	Results []NearestResult `json:"results"`
+}
@@ -0,0 +1,284 @@
+package goclient
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	proto "github.com/projectqai/proto/go"
+	"google.golang.org/grpc/metadata"
+)
+
+// Request options that have no field in the world proto are sent as request
+// metadata. The engine reads them from the request headers.
+const (
+	// HeaderParent limits list and watch requests to children of an entity
+	HeaderParent = "hydra-parent"
+	// HeaderDeadReckoning enables position extrapolation, the value is the max age as a duration, e.g. "30s"
+	HeaderDeadReckoning = "hydra-dead-reckoning"
+	// HeaderGeoUncertainty pads entities in geo filters by their position uncertainty,
+	// the value is the number of standard deviations, e.g. "2"
+	HeaderGeoUncertainty = "hydra-geo-uncertainty"
+	// HeaderAltitudeBand limits list and watch requests to entities with an altitude
+	// within "min,max" in meters, either side may be empty for an open bound
+	HeaderAltitudeBand = "hydra-altitude-band"
+	// HeaderClassification marks all entities of a push with a classification banner
+	// like "SECRET//REL TO DEU, FRA". It is kept until a later push sets another one.
+	// GetEntity responses carry it for classified entities.
+	HeaderClassification = "hydra-classification"
+	// HeaderConfidence sets how much the producer trusts all entities of a push,
+	// from 0 to 1. It is kept until a later push sets another one. Entities
+	// never pushed with one count as 1.
+	HeaderConfidence = "hydra-confidence"
+	// HeaderMinConfidence limits list and watch requests to entities with at
+	// least this confidence, see HeaderConfidence
+	HeaderMinConfidence = "hydra-min-confidence"
+	// HeaderMinSeen limits list and watch requests to entities pushed at least
+	// this many times since they were created, to drop tracks seen only once
+	HeaderMinSeen = "hydra-min-seen"
+	// HeaderSymbology asks list, get and watch requests to present symbols in
+	// Symbology2525D instead of the stored Symbology2525C, see SIDC2525D.
+	// Symbols without a 2525D equivalent are sent unchanged.
+	HeaderSymbology = "hydra-symbology"
+	// HeaderClearance limits list and watch requests to entities the reader may see,
+	// given as a banner like "CONFIDENTIAL//REL TO DEU"
+	HeaderClearance = "hydra-clearance"
+	// HeaderLiveOnly skips the initial snapshot of WatchEntities if set to "true"
+	HeaderLiveOnly = "hydra-live-only"
+	// HeaderWatchBurst is how many messages a rate limited watch may send at once
+	// after being idle, by default one second worth of WatchLimiter.MaxMessagesPerSecond
+	HeaderWatchBurst = "hydra-watch-burst"
+	// HeaderWatchPriorityRate gives priorities their own WatchEntities budget in messages
+	// per second, e.g. "routine=5,immediate=50". They don't count against
+	// WatchLimiter.MaxMessagesPerSecond, which still limits priorities without one.
+	HeaderWatchPriorityRate = "hydra-watch-priority-rate"
+	// HeaderWatchBoost raises the priority of changes by type in WatchEntities, e.g.
+	// "created=immediate,expired=immediate", so appearances and expiries get through
+	// while routine movement is throttled. Changes already above it are left alone.
+	HeaderWatchBoost = "hydra-watch-boost"
+	// HeaderResume asks WatchEntities for resume tokens. The value is ResumeStart
+	// for a new watch, or the last token received to get only the changes since.
+	// The engine sends a full snapshot if it can't resume from the token.
+	HeaderResume = "hydra-resume"
+	// HeaderAlias is set on GetEntity responses, once for every id merged into the entity
+	HeaderAlias = "hydra-alias"
+	// HeaderVersion is set on GetEntity responses to the version of the entity,
+	// which the engine bumps on every push of it
+	HeaderVersion = "hydra-version"
+	// HeaderIfVersion makes a push conditional on the current version of an entity,
+	// the value is "id=version". Version 0 matches entities not pushed since the engine started.
+	HeaderIfVersion = "hydra-if-version"
+	// HeaderHeartbeat set to "true" makes every entity of a push an update for
+	// watchers. Otherwise the engine only refreshes the lifetime of entities that
+	// are pushed again unchanged.
+	HeaderHeartbeat = "hydra-heartbeat"
+	// HeaderExpiryReason set to "true" makes WatchEntities tell why entities expired,
+	// see ExpiryReasonPrefix
+	HeaderExpiryReason = "hydra-expiry-reason"
+	// HeaderWatchTransitions set to "true" makes WatchEntities tell when entities
+	// start and stop matching its filter, see MatchEnterPrefix
+	HeaderWatchTransitions = "hydra-watch-transitions"
+)
+
+const (
+	// ResumeStart is the HeaderResume value of a watch without a token yet
+	ResumeStart = "start"
+	// ResumeTokenPrefix starts the id of the EntityChangeInvalid events that carry
+	// resume tokens, sent whenever the watcher caught up with all changes
+	ResumeTokenPrefix = "hydra-resume-token:"
+	// ShutdownEventID is the id of the EntityChangeInvalid event the engine sends
+	// to watchers before it shuts down. The stream then ends with CodeUnavailable,
+	// clients should wait a moment before reconnecting.
+	ShutdownEventID = "hydra-shutdown"
+	// ExpiryReasonPrefix starts the id of the EntityChangeInvalid events that tell
+	// why an entity expired, sent right before its EntityChangeExpired event if
+	// requested with HeaderExpiryReason. The id is the prefix, the reason, a colon
+	// and the id of the entity, e.g. "hydra-expiry-reason:evicted:ais-1234".
+	ExpiryReasonPrefix = "hydra-expiry-reason:"
+	// MatchEnterPrefix starts the id of the EntityChangeInvalid events that tell
+	// an entity started matching the filter of a watch, sent right before its
+	// EntityChangeUpdated event if requested with HeaderWatchTransitions. The id
+	// is the prefix and the id of the entity, e.g. "hydra-match-enter:ais-1234".
+	// Entities that stop matching are sent with EntityChangeUnobserved.
+	MatchEnterPrefix = "hydra-match-enter:"
+)
+
+// Reasons for entities to expire, see ExpiryReasonPrefix
+const (
+	// ExpiryLifetime is an entity that was not pushed again before its Lifetime.Until
+	ExpiryLifetime = "lifetime"
+	// ExpiryDeleted is an entity pushed with a Lifetime.Until that already passed
+	ExpiryDeleted = "deleted"
+	// ExpiryEvicted is an entity expired to keep the world under its max number of entities
+	ExpiryEvicted = "evicted"
+	// ExpiryCascade is an entity expired together with the entity it was located on or detected by
+	ExpiryCascade = "cascade"
+)
+
+// WithParent limits ListEntities and WatchEntities to entities related to parentID,
+// either located on it or detected by it.
+func WithParent(ctx context.Context, parentID string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, HeaderParent, parentID)
+}
+
+// WithDeadReckoning asks the engine to extrapolate the position of moving entities
+// in ListEntities and WatchEntities from their last position and velocity.
+// Entities last measured more than maxAge ago are returned as stored.
+func WithDeadReckoning(ctx context.Context, maxAge time.Duration) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, HeaderDeadReckoning, maxAge.String())
+}
+
+// WithGeoUncertainty makes geo filters in ListEntities and WatchEntities match
+// entities whose position is within sigma standard deviations of the filter,
+// based on their LocationUncertainty. Entities without uncertainty match as points.
+func WithGeoUncertainty(ctx context.Context, sigma float64) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, HeaderGeoUncertainty, strconv.FormatFloat(sigma, 'f', -1, 64))
+}
+
+// WithIfVersion makes Push fail with CodeAborted, without applying any change,
+// unless the entity is still at version, as returned in HeaderVersion by GetEntity.
+// It can be given multiple times for different entities.
+func WithIfVersion(ctx context.Context, id string, version uint64) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, HeaderIfVersion, id+"="+strconv.FormatUint(version, 10))
+}
+
+// WithHeartbeat makes pushes with ctx reach watchers even if nothing but the
+// lifetime of an entity changed, for feeds where the repetition itself matters.
+func WithHeartbeat(ctx context.Context) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, HeaderHeartbeat, "true")
+}
+
+// WithExpiryReason makes WatchEntities tell why entities expired, read it with ExpiryReason
+func WithExpiryReason(ctx context.Context) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, HeaderExpiryReason, "true")
+}
+
+// ExpiryReason returns the entity id and reason of an event that tells why an
+// entity expired, see ExpiryReasonPrefix
+func ExpiryReason(msg *proto.EntityChangeEvent) (id, reason string, ok bool) {
+	if msg.T != proto.EntityChange_EntityChangeInvalid || msg.Entity == nil {
+		return "", "", false
+	}
+	rest, ok := strings.CutPrefix(msg.Entity.Id, ExpiryReasonPrefix)
+	if !ok {
+		return "", "", false
+	}
+	reason, id, ok = strings.Cut(rest, ":")
+	return id, reason, ok
+}
+
+// WithWatchTransitions makes WatchEntities tell when entities start matching its
+// filter, read it with MatchEnter, and send EntityChangeUnobserved when they stop.
+// Entities matching when the watch starts don't count as starting to match.
+func WithWatchTransitions(ctx context.Context) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, HeaderWatchTransitions, "true")
+}
+
+// MatchEnter returns the id of the entity of an event that tells it started
+// matching the filter of the watch, see MatchEnterPrefix
+func MatchEnter(msg *proto.EntityChangeEvent) (id string, ok bool) {
+	if msg.T != proto.EntityChange_EntityChangeInvalid || msg.Entity == nil {
+		return "", false
+	}
+	return strings.CutPrefix(msg.Entity.Id, MatchEnterPrefix)
+}
+
+// WithToken authenticates the requests made with ctx with a bearer token or API
+// key, which the policy of the engine can authorize instead of the source address
+func WithToken(ctx context.Context, token string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+}
+
+// WithClassification marks the entities pushed with ctx, e.g. "SECRET//REL TO DEU".
+// Levels are UNCLASSIFIED, RESTRICTED, CONFIDENTIAL, SECRET and TOP SECRET.
+func WithClassification(ctx context.Context, marking string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, HeaderClassification, marking)
+}
+
+// WithConfidence sets the confidence of all entities pushed with ctx, from 0 to 1,
+// e.g. lower for a correlator's guesses than for a transponder feed
+func WithConfidence(ctx context.Context, confidence float64) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, HeaderConfidence, strconv.FormatFloat(confidence, 'f', -1, 64))
+}
+
+// WithMinConfidence drops entities from ListEntities and WatchEntities that were
+// pushed with a confidence below min, see WithConfidence
+func WithMinConfidence(ctx context.Context, min float64) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, HeaderMinConfidence, strconv.FormatFloat(min, 'f', -1, 64))
+}
+
+// WithMinSeen drops entities from ListEntities and WatchEntities that were pushed
+// fewer than min times since they were created, e.g. noise seen once by a radar
+func WithMinSeen(ctx context.Context, min uint64) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, HeaderMinSeen, strconv.FormatUint(min, 10))
+}
+
+// WithSymbology presents the symbols of entities read with ctx in symbology,
+// Symbology2525C or Symbology2525D
+func WithSymbology(ctx context.Context, symbology string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, HeaderSymbology, symbology)
+}
+
+// WithClearance drops entities from ListEntities and WatchEntities that are classified
+// above clearance or not releasable to it. This filters on request of a client,
+// a policy enforces what it may see regardless.
+func WithClearance(ctx context.Context, clearance string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, HeaderClearance, clearance)
+}
+
+// WithLiveOnly makes WatchEntities send only changes from now on, without the
+// current state of all matching entities first. For clients that already
+// have the state, e.g. from a ListEntities or an earlier watch.
+func WithLiveOnly(ctx context.Context) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, HeaderLiveOnly, "true")
+}
+
+// WithWatchBurst lets a WatchEntities rate limited by WatchLimiter.MaxMessagesPerSecond
+// send up to burst messages at once to catch up after being idle. The long term
+// rate stays at the limit.
+func WithWatchBurst(ctx context.Context, burst int) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, HeaderWatchBurst, strconv.Itoa(burst))
+}
+
+// WithWatchPriorityRate limits how many changes of priority WatchEntities sends per
+// second, with a budget of its own. This lets a link always deliver Immediate changes
+// promptly while Routine ones are throttled. It can be given once per priority,
+// Unspecified counts as Routine and Flash is never limited.
+func WithWatchPriorityRate(ctx context.Context, priority proto.Priority, perSecond float64) context.Context {
+	name := strings.ToLower(strings.TrimPrefix(priority.String(), "Priority"))
+	return metadata.AppendToOutgoingContext(ctx, HeaderWatchPriorityRate, name+"="+strconv.FormatFloat(perSecond, 'f', -1, 64))
+}
+
+// WithWatchBoost makes WatchEntities send entities that appear with at least priority
+// created and expiries with at least priority expired, regardless of the priority of
+// the entity. PriorityUnspecified leaves a change type as is. Boosted changes pass
+// WatchLimiter.MinPriority and use the rate budget of their new priority.
+func WithWatchBoost(ctx context.Context, created, expired proto.Priority) context.Context {
+	var boosts []string
+	for change, priority := range map[string]proto.Priority{"created": created, "expired": expired} {
+		if priority != proto.Priority_PriorityUnspecified {
+			boosts = append(boosts, change+"="+strings.ToLower(strings.TrimPrefix(priority.String(), "Priority")))
+		}
+	}
+	if len(boosts) == 0 {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, HeaderWatchBoost, strings.Join(boosts, ","))
+}
+
+// WithAltitudeBand limits ListEntities and WatchEntities to entities whose altitude
+// is between min and max meters. Combined with a geo filter this selects a volume,
+// e.g. an airspace. Use math.Inf for an open bound. Entities without altitude
+// don't match.
+func WithAltitudeBand(ctx context.Context, min, max float64) context.Context {
+	var lo, hi string
+	if !math.IsInf(min, 0) {
+		lo = strconv.FormatFloat(min, 'f', -1, 64)
+	}
+	if !math.IsInf(max, 0) {
+		hi = strconv.FormatFloat(max, 'f', -1, 64)
+	}
+	return metadata.AppendToOutgoingContext(ctx, HeaderAltitudeBand, lo+","+hi)
+}
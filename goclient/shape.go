@@ -0,0 +1,87 @@
+package goclient
+
+import (
+	"math"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geo"
+	proto "github.com/projectqai/proto/go"
+)
+
+// number of segments used to approximate a full circle
+const circleSegments = 72
+
+func orbToPlanarRing(ring []orb.Point) *proto.PlanarRing {
+	points := make([]*proto.PlanarPoint, len(ring))
+	for i, p := range ring {
+		points[i] = &proto.PlanarPoint{Longitude: p[0], Latitude: p[1]}
+	}
+	return &proto.PlanarRing{Points: points}
+}
+
+// ToPlanar converts points, lines and polygons to a planar geometry, other geometries return nil
+func ToPlanar(g orb.Geometry) *proto.PlanarGeometry {
+	switch g := g.(type) {
+	case orb.Point:
+		return &proto.PlanarGeometry{Plane: &proto.PlanarGeometry_Point{
+			Point: &proto.PlanarPoint{Longitude: g[0], Latitude: g[1]},
+		}}
+	case orb.LineString:
+		return &proto.PlanarGeometry{Plane: &proto.PlanarGeometry_Line{Line: orbToPlanarRing(g)}}
+	case orb.Ring:
+		return &proto.PlanarGeometry{Plane: &proto.PlanarGeometry_Line{Line: orbToPlanarRing(g)}}
+	case orb.Polygon:
+		if len(g) == 0 {
+			return nil
+		}
+		polygon := &proto.PlanarPolygon{Outer: orbToPlanarRing(g[0])}
+		for _, hole := range g[1:] {
+			polygon.Holes = append(polygon.Holes, orbToPlanarRing(hole))
+		}
+		return &proto.PlanarGeometry{Plane: &proto.PlanarGeometry_Polygon{Polygon: polygon}}
+	}
+	return nil
+}
+
+// Shape returns a shape of the points, lines or polygons of g, see ToPlanar
+func Shape(g orb.Geometry) *proto.GeoShapeComponent {
+	return &proto.GeoShapeComponent{Geometry: &proto.Geometry{Planar: ToPlanar(g)}}
+}
+
+// arc returns points at distance from center, clockwise from azimuth `from` to `to` in degrees
+func arc(center orb.Point, from, to, distance float64) []orb.Point {
+	segments := int(math.Ceil((to - from) / 360 * circleSegments))
+	if segments < 1 {
+		segments = 1
+	}
+	points := make([]orb.Point, 0, segments+1)
+	for i := 0; i <= segments; i++ {
+		bearing := from + (to-from)*float64(i)/float64(segments)
+		points = append(points, geo.PointAtBearingAndDistance(center, bearing, distance))
+	}
+	return points
+}
+
+// SectorShape is the coverage of a sensor at lon/lat looking towards azimuth,
+// width degrees wide, out to distance meters. A width of 0 or 360 or more is a full circle.
+func SectorShape(lon, lat, azimuth, width, distance float64) *proto.GeoShapeComponent {
+	center := orb.Point{lon, lat}
+
+	if width <= 0 || width >= 360 {
+		ring := orb.Ring(arc(center, 0, 360, distance))
+		ring[len(ring)-1] = ring[0]
+		return Shape(orb.Polygon{ring})
+	}
+
+	ring := orb.Ring{center}
+	ring = append(ring, arc(center, azimuth-width/2, azimuth+width/2, distance)...)
+	ring = append(ring, center)
+	return Shape(orb.Polygon{ring})
+}
+
+// RangeRingShape is a circle line around lon/lat with radius meters
+func RangeRingShape(lon, lat, radius float64) *proto.GeoShapeComponent {
+	ring := orb.Ring(arc(orb.Point{lon, lat}, 0, 360, radius))
+	ring[len(ring)-1] = ring[0]
+	return Shape(ring)
+}
@@ -0,0 +1,18 @@
+package goclient
+
+// Stats is a snapshot of engine load as served at the engine's /stats,
+// counters are totals since start
+type Stats struct {
+	// Version is the build version of the engine
+	Version  string `json:"version"`
+	Entities int    `json:"entities"`
+	Watchers int    `json:"watchers"`
+	// Pushed is the number of entities received in Push, diff two snapshots for a rate
+	Pushed uint64 `json:"pushed"`
+	// Throttled is the number of those deferred by the engine's update throttle
+	Throttled uint64 `json:"throttled"`
+	// Unchanged is the number of those that only refreshed the lifetime of an entity
+	Unchanged uint64 `json:"unchanged"`
+	// Evicted is the number of entities expired to stay under the engine's --max-entities
+	Evicted uint64 `json:"evicted"`
+}
@@ -0,0 +1,25 @@
+package goclient
+
+import (
+	"time"
+
+	proto "github.com/projectqai/proto/go"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// StickyUntil is the Lifetime.Until of sticky entities, the latest time a
+// protobuf timestamp can hold. The engine never expires sticky entities: not
+// by lifetime, not to make room under its max number of entities, and not
+// together with a parent. Push another lifetime to make them ordinary again.
+var StickyUntil = time.Date(9999, 12, 31, 23, 59, 59, 0, time.UTC)
+
+// StickyLifetime returns the lifetime of a sticky entity, valid from now
+func StickyLifetime() *proto.Lifetime {
+	return &proto.Lifetime{From: timestamppb.Now(), Until: timestamppb.New(StickyUntil)}
+}
+
+// IsSticky reports whether entity is sticky, see StickyUntil
+func IsSticky(entity *proto.Entity) bool {
+	until := entity.GetLifetime().GetUntil()
+	return until.IsValid() && !until.AsTime().Before(StickyUntil)
+}
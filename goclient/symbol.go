@@ -0,0 +1,38 @@
+package goclient
+
+import (
+	"strings"
+
+	proto "github.com/projectqai/proto/go"
+)
+
+// The symbol of an entity may reference an icon instead of a MIL-STD-2525C code,
+// for displays that don't use military symbology. The view renders the icon,
+// exporters to military systems like CoT treat the entity as having no symbol.
+const (
+	// IconPrefix is followed by the URL of an image, e.g. "icon:https://example.org/boat.png".
+	// data: URLs work offline, other URLs need to allow CORS to be shown on the map.
+	IconPrefix = "icon:"
+	// EmojiPrefix is followed by an emoji or a short text, e.g. "emoji:🚤"
+	EmojiPrefix = "emoji:"
+)
+
+// IconSymbol returns a symbol showing the image at url
+func IconSymbol(url string) *proto.SymbolComponent {
+	return &proto.SymbolComponent{MilStd2525C: IconPrefix + url}
+}
+
+// EmojiSymbol returns a symbol showing an emoji
+func EmojiSymbol(emoji string) *proto.SymbolComponent {
+	return &proto.SymbolComponent{MilStd2525C: EmojiPrefix + emoji}
+}
+
+// SIDC returns the MIL-STD-2525C code of a symbol, "" if it has none or
+// references an icon instead
+func SIDC(symbol *proto.SymbolComponent) string {
+	s := symbol.GetMilStd2525C()
+	if strings.HasPrefix(s, IconPrefix) || strings.HasPrefix(s, EmojiPrefix) {
+		return ""
+	}
+	return s
+}
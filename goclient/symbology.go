@@ -0,0 +1,127 @@
+package goclient
+
+import "strings"
+
+// Symbologies a client can ask the engine to present symbols in, see
+// HeaderSymbology. Entities are always stored with MIL-STD-2525C codes.
+const (
+	Symbology2525C = "2525c"
+	Symbology2525D = "2525d"
+)
+
+// SIDC2525D converts a 15 character MIL-STD-2525C warfighting code like
+// "SHGPUCI---*****" to the 20 digit MIL-STD-2525D identifier, e.g.
+// "10061000001211000000". Function IDs without a known 2525D entity map to
+// their nearest known parent, e.g. a bomber to a fixed wing aircraft, and at
+// least to the symbol set without an entity. ok is false for codes with no
+// 2525D equivalent, e.g. tactical graphics, icons and malformed codes.
+func SIDC2525D(sidc string) (string, bool) {
+	if len(sidc) < 10 || (sidc[0] != 'S' && sidc[0] != 's') {
+		return "", false
+	}
+	sidc = strings.ToUpper(sidc)
+
+	identity, ok := identities2525D[sidc[1]]
+	if !ok {
+		return "", false
+	}
+	status, ok := statuses2525D[sidc[3]]
+	if !ok {
+		return "", false
+	}
+	entity, ok := entity2525D(sidc[2], strings.ReplaceAll(sidc[4:10], "*", "-"))
+	if !ok {
+		return "", false
+	}
+
+	headquarters, echelon := byte('0'), "00"
+	if len(sidc) >= 12 && entity[:2] == "10" {
+		if hq, ok := headquarters2525D[sidc[10]]; ok {
+			headquarters = hq
+		}
+		if e, ok := echelons2525D[sidc[11]]; ok {
+			echelon = e
+		}
+	}
+
+	// version, context and identity, symbol set, status, headquarters, echelon, entity, modifiers
+	return "10" + identity + entity[:2] + string(status) + string(headquarters) + echelon + entity[2:] + "0000", true
+}
+
+// entity2525D returns the symbol set and entity code of a battle dimension and function ID
+func entity2525D(dimension byte, function string) (string, bool) {
+	for f := function; ; f = f[:len(f)-1] {
+		key := string(dimension) + strings.TrimRight(f, "-")
+		if code, ok := entities2525D[key]; ok {
+			return code, true
+		}
+		if f == "" {
+			return "", false
+		}
+	}
+}
+
+// identities2525D maps the affiliation of 2525C to the context and standard identity of 2525D
+var identities2525D = map[byte]string{
+	'P': "00", 'U': "01", 'A': "02", 'F': "03", 'N': "04", 'S': "05", 'H': "06",
+	'G': "10", 'W': "11", 'M': "12", 'D': "13", 'L': "14", 'J': "15", 'K': "16",
+}
+
+var statuses2525D = map[byte]byte{
+	'P': '0', 'A': '1', 'C': '2', 'D': '3', 'X': '4', 'F': '5',
+}
+
+// headquarters2525D maps the headquarters, task force and feint/dummy modifier of land units
+var headquarters2525D = map[byte]byte{
+	'F': '1', 'A': '2', 'C': '3', 'E': '4', 'G': '5', 'B': '6', 'D': '7',
+}
+
+var echelons2525D = map[byte]string{
+	'A': "11", 'B': "12", 'C': "13", 'D': "14", 'E': "15", 'F': "16", 'G': "17",
+	'H': "18", 'I': "21", 'J': "22", 'K': "23", 'L': "24", 'M': "25", 'N': "26",
+}
+
+// entities2525D maps a battle dimension and function ID, without trailing
+// dashes, to the symbol set and entity code of 2525D
+var entities2525D = map[string]string{
+	// space
+	"P": "05000000",
+	// air
+	"A":    "01000000",
+	"AM":   "01110000",
+	"AMF":  "01110100",
+	"AMFQ": "01110300",
+	"AMH":  "01110200",
+	"AML":  "01110500",
+	"AC":   "01120000",
+	"ACF":  "01120100",
+	"ACH":  "01120200",
+	"ACL":  "01120400",
+	// land units, equipment and installations
+	"G":     "10000000",
+	"GU":    "10000000",
+	"GUC":   "10000000",
+	"GUCA":  "10120500",
+	"GUCD":  "10130100",
+	"GUCF":  "10130300",
+	"GUCFM": "10130800",
+	"GUCI":  "10121100",
+	"GUCR":  "10121300",
+	"GE":    "15000000",
+	"GI":    "20000000",
+	// special operations forces
+	"F": "10121700",
+	// sea surface
+	"S":     "30000000",
+	"SC":    "30120000",
+	"SCL":   "30120200",
+	"SCLBB": "30120201",
+	"SCLCC": "30120202",
+	"SCLDD": "30120203",
+	"SCLFF": "30120204",
+	"SX":    "30140000",
+	"SXM":   "30140100",
+	"SXF":   "30140200",
+	// subsurface
+	"U": "35000000",
+}
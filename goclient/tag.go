@@ -0,0 +1,23 @@
+package goclient
+
+import "encoding/json"
+
+// TagRequest changes all entities matching Filter at once, e.g. to mark a group
+// as the red force during an exercise. Configurations are left alone.
+type TagRequest struct {
+	// Filter is an EntityFilter in protojson encoding, empty matches all entities
+	Filter json.RawMessage `json:"filter,omitempty"`
+	// LabelPrefix is put in front of labels that don't start with it yet.
+	// Entities without a label get the prefix and their id.
+	LabelPrefix string `json:"label_prefix,omitempty"`
+	// Controller sets the controller name
+	Controller string `json:"controller,omitempty"`
+	// DryRun only returns what would change
+	DryRun bool `json:"dry_run,omitempty"`
+}
+
+// TagResponse is served at the engine's /entities/tag
+type TagResponse struct {
+	// Tagged are the ids of the entities that changed
+	Tagged []string `json:"tagged"`
+}
@@ -0,0 +1,25 @@
+package goclient
+
+import (
+	"math"
+
+	proto "github.com/projectqai/proto/go"
+)
+
+// UncertaintyRadius is the 1-sigma semi-major axis in meters of the horizontal
+// position error ellipse, or 0 if the entity has no position covariance
+func UncertaintyRadius(entity *proto.Entity) float64 {
+	if entity.LocationUncertainty == nil || entity.LocationUncertainty.PositionEnuCov == nil {
+		return 0
+	}
+	cov := entity.LocationUncertainty.PositionEnuCov
+	xx, xy, yy := cov.GetMxx(), cov.GetMxy(), cov.GetMyy()
+
+	// largest eigenvalue of the 2x2 east/north covariance
+	mean := (xx + yy) / 2
+	lambda := mean + math.Sqrt((xx-yy)*(xx-yy)/4+xy*xy)
+	if lambda <= 0 || math.IsNaN(lambda) {
+		return 0
+	}
+	return math.Sqrt(lambda)
+}
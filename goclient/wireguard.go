@@ -19,7 +19,6 @@ import (
 	"golang.zx2c4.com/wireguard/tun/netstack"
 
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 )
 
 const (
@@ -336,11 +335,7 @@ func ConnectViaWireGuard(serverAddr string, wgCfg *WireGuardConfig) (*grpc.Clien
 		return nil, nil, fmt.Errorf("failed to create WireGuard tunnel: %w", err)
 	}
 
-	conn, err := grpc.NewClient(
-		serverAddr,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-		grpc.WithContextDialer(tunnel.Dial),
-	)
+	conn, err := grpc.NewClient(serverAddr, dialOptions(grpc.WithContextDialer(tunnel.Dial))...)
 	if err != nil {
 		tunnel.Close()
 		return nil, nil, err
@@ -12,6 +12,8 @@ import (
 type modulePrefixHandler struct {
 	handler slog.Handler
 	module  string
+	// attrs are kept for Recent, the handler has them already
+	attrs []slog.Attr
 }
 
 func (h *modulePrefixHandler) Enabled(ctx context.Context, level slog.Level) bool {
@@ -33,6 +35,7 @@ func (h *modulePrefixHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
 	return &modulePrefixHandler{
 		handler: h.handler.WithAttrs(otherAttrs),
 		module:  module,
+		attrs:   append(h.attrs[:len(h.attrs):len(h.attrs)], otherAttrs...),
 	}
 }
 
@@ -40,10 +43,13 @@ func (h *modulePrefixHandler) WithGroup(name string) slog.Handler {
 	return &modulePrefixHandler{
 		handler: h.handler.WithGroup(name),
 		module:  h.module,
+		attrs:   h.attrs,
 	}
 }
 
 func (h *modulePrefixHandler) Handle(ctx context.Context, r slog.Record) error {
+	Recent.add(newLine(h.module, h.attrs, r))
+
 	if h.module != "" {
 		newRecord := slog.NewRecord(r.Time, r.Level, "["+h.module+"] "+r.Message, r.PC)
 		r.Attrs(func(a slog.Attr) bool {
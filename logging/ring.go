@@ -0,0 +1,99 @@
+package logging
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Line is a captured log record
+type Line struct {
+	Time    time.Time         `json:"time"`
+	Level   string            `json:"level"`
+	Module  string            `json:"module,omitempty"`
+	Message string            `json:"message"`
+	Attrs   map[string]string `json:"attrs,omitempty"`
+}
+
+// Ring keeps the most recent log lines and passes new ones to followers
+type Ring struct {
+	mu        sync.Mutex
+	lines     []Line
+	next      int
+	full      bool
+	followers map[chan Line]struct{}
+}
+
+// Recent holds the last lines logged through the default logger
+var Recent = NewRing(2000)
+
+// followBuffer is how many lines a follower may lag behind before it misses lines
+const followBuffer = 256
+
+func NewRing(size int) *Ring {
+	return &Ring{lines: make([]Line, size), followers: make(map[chan Line]struct{})}
+}
+
+func (r *Ring) add(line Line) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lines[r.next] = line
+	r.next = (r.next + 1) % len(r.lines)
+	if r.next == 0 {
+		r.full = true
+	}
+	for ch := range r.followers {
+		// slow followers miss lines instead of blocking the logger
+		select {
+		case ch <- line:
+		default:
+		}
+	}
+}
+
+// Lines returns the captured lines, oldest first
+func (r *Ring) Lines() []Line {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.snapshot()
+}
+
+func (r *Ring) snapshot() []Line {
+	if !r.full {
+		return append([]Line(nil), r.lines[:r.next]...)
+	}
+	return append(append([]Line(nil), r.lines[r.next:]...), r.lines[:r.next]...)
+}
+
+// Follow returns the captured lines and a channel of the lines logged after
+// them, until stop is called
+func (r *Ring) Follow() (lines []Line, next <-chan Line, stop func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ch := make(chan Line, followBuffer)
+	r.followers[ch] = struct{}{}
+	return r.snapshot(), ch, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.followers, ch)
+	}
+}
+
+// newLine captures r with the attributes of the logger it was logged with
+func newLine(module string, attrs []slog.Attr, r slog.Record) Line {
+	line := Line{Time: r.Time, Level: r.Level.String(), Module: module, Message: r.Message}
+	if len(attrs) == 0 && r.NumAttrs() == 0 {
+		return line
+	}
+	line.Attrs = make(map[string]string, len(attrs)+r.NumAttrs())
+	for _, a := range attrs {
+		line.Attrs[a.Key] = a.Value.String()
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		line.Attrs[a.Key] = a.Value.String()
+		return true
+	})
+	return line
+}
@@ -0,0 +1,46 @@
+package logging
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRing_KeepsRecentAndFollows(t *testing.T) {
+	ring := NewRing(3)
+	for _, msg := range []string{"a", "b", "c", "d"} {
+		ring.add(Line{Message: msg})
+	}
+
+	messages := func(lines []Line) string {
+		var s string
+		for _, line := range lines {
+			s += line.Message
+		}
+		return s
+	}
+	if got := messages(ring.Lines()); got != "bcd" {
+		t.Fatalf("expected the last 3 lines oldest first, got %q", got)
+	}
+
+	lines, next, stop := ring.Follow()
+	if got := messages(lines); got != "bcd" {
+		t.Fatalf("expected follow to start with the captured lines, got %q", got)
+	}
+	ring.add(Line{Message: "e"})
+	select {
+	case line := <-next:
+		if line.Message != "e" {
+			t.Fatalf("expected the new line, got %q", line.Message)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the follower to get the new line")
+	}
+
+	stop()
+	ring.add(Line{Message: "f"})
+	select {
+	case line := <-next:
+		t.Fatalf("expected no lines after stop, got %q", line.Message)
+	default:
+	}
+}
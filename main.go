@@ -4,6 +4,9 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	_ "github.com/projectqai/hydra/logging"
 
@@ -14,8 +17,12 @@ import (
 	_ "github.com/projectqai/hydra/builtin/ais"
 	_ "github.com/projectqai/hydra/builtin/asterix"
 	_ "github.com/projectqai/hydra/builtin/federation"
+	_ "github.com/projectqai/hydra/builtin/geofence"
+	_ "github.com/projectqai/hydra/builtin/gps"
+	_ "github.com/projectqai/hydra/builtin/sim"
 	_ "github.com/projectqai/hydra/builtin/spacetrack"
 	_ "github.com/projectqai/hydra/builtin/tak"
+	_ "github.com/projectqai/hydra/builtin/webhook"
 	_ "github.com/projectqai/hydra/cli"
 	"github.com/projectqai/hydra/engine"
 	_ "github.com/projectqai/hydra/view"
@@ -27,32 +34,167 @@ import (
 func init() {
 	cmd.CMD.Flags().Bool("view", false, "open builtin webview")
 	cmd.CMD.Flags().StringP("world", "w", "", "world state file to load on startup and periodically flush to")
+	cmd.CMD.Flags().String("config", "", "YAML or JSON file declaring builtins to run, instead of pushing their config entities")
 	cmd.CMD.Flags().String("policy", "", "path to OPA policy file (.rego) for access control")
+	cmd.CMD.Flags().Bool("cascade-expire", false, "expire located and detected entities together with their parent")
+	cmd.CMD.Flags().Bool("estimate-velocity", false, "derive kinematics from consecutive positions of entities that don't report velocity")
+	cmd.CMD.Flags().Float64("merge-distance", 0, "merge new entities into an entity of another feed within this many meters (0 disables)")
+	cmd.CMD.Flags().Duration("merge-max-age", 10*time.Second, "max time between measurements of merged entities")
+	cmd.CMD.Flags().Duration("throttle", 0, "min time between accepted updates of the same entity, faster updates are merged (0 disables)")
+	cmd.CMD.Flags().StringToString("throttle-controllers", nil, "override --throttle for entities of these controllers, e.g. ais=1s,tak=200ms")
+	cmd.CMD.Flags().String("conflict", "last-writer", "when controllers push the same entity id: last-writer, priority (highest Priority wins) or field-newest (merge components, newest measurement wins)")
+	cmd.CMD.Flags().StringToString("conflict-ids", nil, "override --conflict for entity ids starting with a prefix, e.g. fed-=field-newest,ais-=priority")
+	cmd.CMD.Flags().Duration("slow-consumer-timeout", time.Minute, "disconnect watch clients that stay behind for longer than this (0 disables)")
+	cmd.CMD.Flags().Int("watch-buffer", 0, "max pending changes per watch client before it is disconnected (0 is unlimited, one per entity)")
+	cmd.CMD.Flags().Duration("tombstone-retention", engine.DefaultTombstoneRetention, "how long expired entities are remembered for watch clients that reconnect")
+	cmd.CMD.Flags().Duration("expiry-jitter", 0, "delay the expiry of each entity by up to this much, so batches with the same lifetime don't disappear at once (0 disables)")
+	cmd.CMD.Flags().Int("max-entities", 0, "max number of entities, the least recently updated are expired beyond it (0 is unlimited)")
+	cmd.CMD.Flags().StringSlice("skip-builtins", nil, "builtins not to start, e.g. because they run out of process with hydra builtins run")
+	cmd.CMD.Flags().Bool("pprof", false, "serve Go profiles at /debug/pprof/, e.g. for go tool pprof")
+	cmd.CMD.Flags().String("listen", "", "host:port to serve on, e.g. 10.0.0.5:50051 to only serve that network (default all interfaces on $PORT or 50051)")
+	cmd.CMD.Flags().String("admin-listen", "", "host:port to serve /metrics, /readyz and pprof on instead of the main port, e.g. 127.0.0.1:9090")
+	cmd.CMD.Flags().StringSlice("cors-origin", nil, "origins of web pages allowed to call the engine, e.g. https://*.example.org or * for any (default this machine only)")
+	cmd.CMD.Flags().StringSlice("cors-method", nil, "HTTP methods allowed from other origins (default GET,POST,PUT,DELETE,OPTIONS)")
+	cmd.CMD.Flags().StringSlice("cors-header", nil, "request headers allowed from other origins (default any)")
+	cmd.CMD.Flags().StringSlice("merge-controllers", nil, "controllers whose new entities may be merged, e.g. ais,adsblol")
+	cmd.CMD.Flags().Float64("self-lat", 0, "publish a self entity at this latitude, with --self-lon")
+	cmd.CMD.Flags().Float64("self-lon", 0, "publish a self entity at this longitude, with --self-lat")
+	cmd.CMD.Flags().Float64("self-alt", 0, "altitude of the self entity in meters")
+	cmd.CMD.Flags().String("self-sidc", "", "MIL-STD-2525C symbol of the self entity (default friendly ground unit)")
+	cmd.CMD.Flags().String("self-label", "", "label of the self entity (default \"Self\")")
 
 	cmd.CMD.RunE = func(cmd *cobra.Command, args []string) error {
 		all, _ := cmd.Flags().GetBool("all")
 		enableView, _ := cmd.Flags().GetBool("view")
 		worldFile, _ := cmd.Flags().GetString("world")
 		policyFile, _ := cmd.Flags().GetString("policy")
+		configFile, _ := cmd.Flags().GetString("config")
+		cascadeExpire, _ := cmd.Flags().GetBool("cascade-expire")
+		estimateVelocity, _ := cmd.Flags().GetBool("estimate-velocity")
+		mergeDistance, _ := cmd.Flags().GetFloat64("merge-distance")
+		mergeMaxAge, _ := cmd.Flags().GetDuration("merge-max-age")
+		mergeControllers, _ := cmd.Flags().GetStringSlice("merge-controllers")
+		throttleInterval, _ := cmd.Flags().GetDuration("throttle")
+		throttleControllers, _ := cmd.Flags().GetStringToString("throttle-controllers")
+		conflictPolicy, _ := cmd.Flags().GetString("conflict")
+		conflictIDs, _ := cmd.Flags().GetStringToString("conflict-ids")
+		slowConsumerTimeout, _ := cmd.Flags().GetDuration("slow-consumer-timeout")
+		watchBuffer, _ := cmd.Flags().GetInt("watch-buffer")
+		tombstoneRetention, _ := cmd.Flags().GetDuration("tombstone-retention")
+		maxEntities, _ := cmd.Flags().GetInt("max-entities")
+		expiryJitter, _ := cmd.Flags().GetDuration("expiry-jitter")
+		enablePprof, _ := cmd.Flags().GetBool("pprof")
+		listenAddr, _ := cmd.Flags().GetString("listen")
+		adminAddr, _ := cmd.Flags().GetString("admin-listen")
+		skipBuiltins, _ := cmd.Flags().GetStringSlice("skip-builtins")
 
-		ctx := context.Background()
+		corsConfig := &engine.CORSConfig{}
+		corsConfig.Origins, _ = cmd.Flags().GetStringSlice("cors-origin")
+		corsConfig.Methods, _ = cmd.Flags().GetStringSlice("cors-method")
+		corsConfig.Headers, _ = cmd.Flags().GetStringSlice("cors-header")
+
+		var self *engine.SelfConfig
+		if cmd.Flags().Changed("self-lat") || cmd.Flags().Changed("self-lon") {
+			if !cmd.Flags().Changed("self-lat") || !cmd.Flags().Changed("self-lon") {
+				return fmt.Errorf("--self-lat and --self-lon must be given together")
+			}
+			self = &engine.SelfConfig{}
+			self.Latitude, _ = cmd.Flags().GetFloat64("self-lat")
+			self.Longitude, _ = cmd.Flags().GetFloat64("self-lon")
+			if self.Latitude < -90 || self.Latitude > 90 || self.Longitude < -180 || self.Longitude > 180 {
+				return fmt.Errorf("invalid self position %v,%v", self.Latitude, self.Longitude)
+			}
+			if cmd.Flags().Changed("self-alt") {
+				alt, _ := cmd.Flags().GetFloat64("self-alt")
+				self.Altitude = &alt
+			}
+			self.SIDC, _ = cmd.Flags().GetString("self-sidc")
+			self.Label, _ = cmd.Flags().GetString("self-label")
+		}
+
+		var merge *engine.MergeConfig
+		if mergeDistance > 0 {
+			merge = &engine.MergeConfig{
+				Distance:    mergeDistance,
+				MaxAge:      mergeMaxAge,
+				Controllers: mergeControllers,
+			}
+		}
+
+		var throttle *engine.ThrottleConfig
+		if throttleInterval > 0 || len(throttleControllers) > 0 {
+			throttle = &engine.ThrottleConfig{
+				Interval:    throttleInterval,
+				Controllers: make(map[string]time.Duration),
+			}
+			for name, v := range throttleControllers {
+				d, err := time.ParseDuration(v)
+				if err != nil {
+					return fmt.Errorf("invalid --throttle-controllers interval for %s: %w", name, err)
+				}
+				throttle.Controllers[name] = d
+			}
+		}
+
+		policy, err := engine.ParseConflictPolicy(conflictPolicy)
+		if err != nil {
+			return fmt.Errorf("invalid --conflict: %w", err)
+		}
+		conflict := &engine.ConflictConfig{Policy: policy, IDs: make(map[string]engine.ConflictPolicy)}
+		for prefix, v := range conflictIDs {
+			p, err := engine.ParseConflictPolicy(v)
+			if err != nil {
+				return fmt.Errorf("invalid --conflict-ids policy for %s: %w", prefix, err)
+			}
+			conflict.IDs[prefix] = p
+		}
+
+		// On a signal the engine disconnects external clients first, then stops
+		// the builtins, so they can flush through the in-process listener
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		builtinCtx, stopBuiltins := context.WithCancel(context.Background())
+		stopped := make(chan struct{})
 
 		serverAddr, err := engine.StartEngine(ctx, engine.EngineConfig{
-			WorldFile:  worldFile,
-			PolicyFile: policyFile,
+			WorldFile:        worldFile,
+			PolicyFile:       policyFile,
+			ConfigFile:       configFile,
+			CascadeExpire:    cascadeExpire,
+			EstimateVelocity: estimateVelocity,
+			Merge:            merge,
+			Throttle:         throttle,
+			Conflict:         conflict,
+
+			SlowConsumerTimeout: slowConsumerTimeout,
+			WatchBufferSize:     watchBuffer,
+			TombstoneRetention:  tombstoneRetention,
+			MaxEntities:         maxEntities,
+			ExpiryJitter:        expiryJitter,
+			ListenAddr:          listenAddr,
+			AdminAddr:           adminAddr,
+			Pprof:               enablePprof,
+			CORS:                corsConfig,
+			Self:                self,
+			StopBuiltins:        stopBuiltins,
+			Stopped:             stopped,
 		})
 		if err != nil {
 			fmt.Fprintln(os.Stderr, err)
 			os.Exit(1)
 		}
 
-		builtin.StartAll(ctx, serverAddr)
+		builtin.StartAll(builtinCtx, serverAddr, skipBuiltins...)
 
 		if all || enableView {
 			browser.OpenURL("http://" + serverAddr)
 		}
 
-		select {}
+		// a second signal exits right away
+		<-ctx.Done()
+		stop()
+		<-stopped
+		return nil
 	}
 }
 
@@ -4,17 +4,41 @@ import (
 	"context"
 	"runtime"
 	"sync/atomic"
+	"time"
 
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
 )
 
+// ConsumerStats are the counters of one watch client since it connected
+type ConsumerStats struct {
+	Peer string
+	// Sent is the number of changes sent
+	Sent int64
+	// Coalesced is the number of changes merged into one still waiting to be sent
+	Coalesced int64
+	// RateLimited is the number of sends that waited for the rate limiter
+	RateLimited int64
+	// RateLimitWait is the total time spent waiting for the rate limiter
+	RateLimitWait time.Duration
+}
+
 var (
-	entityCount atomic.Int64
-	meter       metric.Meter
+	entityCount      atomic.Int64
+	droppedConsumers atomic.Int64
+	consumerStats    atomic.Pointer[func() []ConsumerStats]
+	meter            metric.Meter
 
 	// Application metrics
-	entityCountGauge metric.Int64ObservableGauge
+	entityCountGauge        metric.Int64ObservableGauge
+	droppedConsumersCounter metric.Int64ObservableCounter
+
+	// Per consumer metrics, labelled by peer
+	consumerSentCounter        metric.Int64ObservableCounter
+	consumerCoalescedCounter   metric.Int64ObservableCounter
+	consumerRateLimitedCounter metric.Int64ObservableCounter
+	consumerRateWaitCounter    metric.Float64ObservableCounter
 
 	// Go runtime metrics
 	goroutinesGauge     metric.Int64ObservableGauge
@@ -43,6 +67,51 @@ func Init() error {
 		return err
 	}
 
+	droppedConsumersCounter, err = meter.Int64ObservableCounter(
+		"hydra.consumers.dropped",
+		metric.WithDescription("Number of watch clients disconnected for being too slow"),
+		metric.WithUnit("{consumers}"),
+	)
+	if err != nil {
+		return err
+	}
+
+	consumerSentCounter, err = meter.Int64ObservableCounter(
+		"hydra.consumer.sent",
+		metric.WithDescription("Number of changes sent to a watch client"),
+		metric.WithUnit("{changes}"),
+	)
+	if err != nil {
+		return err
+	}
+
+	consumerCoalescedCounter, err = meter.Int64ObservableCounter(
+		"hydra.consumer.coalesced",
+		metric.WithDescription("Number of changes merged into an unsent change of the same entity"),
+		metric.WithUnit("{changes}"),
+	)
+	if err != nil {
+		return err
+	}
+
+	consumerRateLimitedCounter, err = meter.Int64ObservableCounter(
+		"hydra.consumer.rate_limited",
+		metric.WithDescription("Number of sends to a watch client that waited for its rate limit"),
+		metric.WithUnit("{changes}"),
+	)
+	if err != nil {
+		return err
+	}
+
+	consumerRateWaitCounter, err = meter.Float64ObservableCounter(
+		"hydra.consumer.rate_limit_wait",
+		metric.WithDescription("Time a watch client spent waiting for its rate limit"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return err
+	}
+
 	// Go runtime metrics
 	goroutinesGauge, err = meter.Int64ObservableGauge(
 		"go.goroutines",
@@ -140,6 +209,26 @@ func Init() error {
 			// Application metrics
 			count := GetEntityCount()
 			o.ObserveInt64(entityCountGauge, int64(count))
+			o.ObserveInt64(droppedConsumersCounter, droppedConsumers.Load())
+			if fn := consumerStats.Load(); fn != nil {
+				// consumers may share a peer, e.g. all builtins
+				byPeer := make(map[string]ConsumerStats)
+				for _, s := range (*fn)() {
+					sum := byPeer[s.Peer]
+					sum.Sent += s.Sent
+					sum.Coalesced += s.Coalesced
+					sum.RateLimited += s.RateLimited
+					sum.RateLimitWait += s.RateLimitWait
+					byPeer[s.Peer] = sum
+				}
+				for p, s := range byPeer {
+					peer := metric.WithAttributes(attribute.String("peer", p))
+					o.ObserveInt64(consumerSentCounter, s.Sent, peer)
+					o.ObserveInt64(consumerCoalescedCounter, s.Coalesced, peer)
+					o.ObserveInt64(consumerRateLimitedCounter, s.RateLimited, peer)
+					o.ObserveFloat64(consumerRateWaitCounter, s.RateLimitWait.Seconds(), peer)
+				}
+			}
 
 			// Runtime metrics
 			var m runtime.MemStats
@@ -159,6 +248,11 @@ func Init() error {
 			return nil
 		},
 		entityCountGauge,
+		droppedConsumersCounter,
+		consumerSentCounter,
+		consumerCoalescedCounter,
+		consumerRateLimitedCounter,
+		consumerRateWaitCounter,
 		goroutinesGauge,
 		memAllocGauge,
 		memTotalAllocGauge,
@@ -181,3 +275,12 @@ func SetEntityCount(count int) {
 func GetEntityCount() int {
 	return int(entityCount.Load())
 }
+
+func IncDroppedConsumers() {
+	droppedConsumers.Add(1)
+}
+
+// SetConsumerStatsSource sets the function that lists the stats of all connected watch clients
+func SetConsumerStatsSource(fn func() []ConsumerStats) {
+	consumerStats.Store(&fn)
+}
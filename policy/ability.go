@@ -2,42 +2,107 @@ package policy
 
 import (
 	"context"
+	"fmt"
 	"net"
 
+	"connectrpc.com/connect"
 	pb "github.com/projectqai/proto/go"
 )
 
+// BuiltinAddr is the remote address of requests from builtins. The engine sets it
+// on every request of the in-process listener, it can't be produced by a network peer.
+const BuiltinAddr = "builtin"
+
+// Actions checked by an Ability, as passed to policies in Input.Action
+const (
+	ActionRead     = "read"
+	ActionWrite    = "write"
+	ActionTimeline = "timeline"
+	ActionLogs     = "logs"
+)
+
+// Subject is the identity a request is made with. Policies should prefer Token,
+// SourceIP is the address of the last hop, e.g. a load balancer.
+type Subject struct {
+	SourceIP string `json:"source_ip"`
+	// Token is the bearer token or API key the request was made with, empty if none
+	Token string `json:"token,omitempty"`
+	// Builtin is set for connectors running inside the engine process
+	Builtin bool `json:"builtin"`
+}
+
+// Input is what a policy decides on
+type Input struct {
+	Action  string     `json:"action"`
+	Subject Subject    `json:"subject"`
+	Entity  *pb.Entity `json:"entity,omitempty"`
+	// Existing is the stored entity a write replaces, nil if the entity is new.
+	// Comparing its controller to the one of Entity lets a policy keep one
+	// connector from overwriting the entities of another.
+	Existing *pb.Entity `json:"existing,omitempty"`
+	// Marking is the classification of Entity for reads
+	Marking *Marking `json:"marking,omitempty"`
+}
+
 type Ability struct {
-	engine   *Engine
-	sourceIP string
-	builtin  bool
+	engine  *Engine
+	subject Subject
 }
 
-// Creates an Ability bound to a remote identity, like source ip for now
-func For(engine *Engine, remoteAddr string) *Ability {
+// Creates an Ability bound to a remote identity, the token in ctx if any, see
+// WithToken, and the source ip of remoteAddr
+func For(ctx context.Context, engine *Engine, remoteAddr string) *Ability {
 	host, _, err := net.SplitHostPort(remoteAddr)
 	if err != nil {
 		host = remoteAddr
 	}
 	return &Ability{
-		engine:   engine,
-		sourceIP: host,
-		builtin:  remoteAddr == "bufconn",
+		engine: engine,
+		subject: Subject{
+			SourceIP: host,
+			Token:    tokenFrom(ctx),
+			Builtin:  remoteAddr == BuiltinAddr,
+		},
 	}
 }
 
-func (a *Ability) CanRead(ctx context.Context, entity *pb.Entity) bool {
-	return true
+func (a *Ability) Subject() Subject {
+	return a.subject
+}
+
+// CanRead checks a read of entity, which is classified as marking
+func (a *Ability) CanRead(ctx context.Context, entity *pb.Entity, marking Marking) bool {
+	return a.can(ctx, Input{Action: ActionRead, Entity: entity, Marking: &marking})
 }
 
-func (a *Ability) AuthorizeWrite(ctx context.Context, entity *pb.Entity) error {
+// AuthorizeWrite checks a push of entity, existing is the stored entity with the same id or nil
+func (a *Ability) AuthorizeWrite(ctx context.Context, entity *pb.Entity, existing *pb.Entity) error {
+	if !a.can(ctx, Input{Action: ActionWrite, Entity: entity, Existing: existing}) {
+		return connect.NewError(connect.CodePermissionDenied, fmt.Errorf("policy denied write of %s", entity.Id))
+	}
 	return nil
 }
 
 func (a *Ability) AuthorizeTimeline(ctx context.Context) error {
+	if !a.can(ctx, Input{Action: ActionTimeline}) {
+		return connect.NewError(connect.CodePermissionDenied, fmt.Errorf("policy denied timeline access"))
+	}
+	return nil
+}
+
+// AuthorizeLogs checks reading the log lines of the engine, which can contain
+// secrets of builtin configurations like webhook urls
+func (a *Ability) AuthorizeLogs(ctx context.Context) error {
+	if !a.can(ctx, Input{Action: ActionLogs}) {
+		return connect.NewError(connect.CodePermissionDenied, fmt.Errorf("policy denied log access"))
+	}
 	return nil
 }
 
-func (a *Ability) can(ctx context.Context, action string, entity *pb.Entity) bool {
-	return true
+func (a *Ability) can(ctx context.Context, input Input) bool {
+	if a.engine == nil {
+		return true
+	}
+	input.Subject = a.subject
+	return a.engine.Allow(ctx, input)
 }
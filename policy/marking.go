@@ -0,0 +1,78 @@
+package policy
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Classification levels in ascending order, abbreviations are accepted when parsing
+var levels = []struct{ name, short string }{
+	{"UNCLASSIFIED", "U"},
+	{"RESTRICTED", "R"},
+	{"CONFIDENTIAL", "C"},
+	{"SECRET", "S"},
+	{"TOP SECRET", "TS"},
+}
+
+// Marking is the classification of an entity, or the clearance of a reader.
+// The zero value is unclassified and releasable to everyone.
+type Marking struct {
+	// Level is an index into the classification levels, higher is more restricted
+	Level int `json:"level"`
+	// ReleasableTo lists who an entity may be released to, empty means no restriction.
+	// For a clearance it lists who the reader is.
+	ReleasableTo []string `json:"releasable_to,omitempty"`
+}
+
+// ParseMarking reads a banner line like "SECRET//REL TO DEU, FRA" or "C"
+func ParseMarking(s string) (Marking, error) {
+	var m Marking
+	parts := strings.Split(s, "//")
+
+	level := strings.ToUpper(strings.TrimSpace(parts[0]))
+	m.Level = slices.IndexFunc(levels, func(l struct{ name, short string }) bool {
+		return l.name == level || l.short == level
+	})
+	if m.Level < 0 {
+		return Marking{}, fmt.Errorf("unknown classification level %q", parts[0])
+	}
+
+	for _, caveat := range parts[1:] {
+		caveat = strings.ToUpper(strings.TrimSpace(caveat))
+		rel, ok := strings.CutPrefix(caveat, "REL TO ")
+		if !ok {
+			return Marking{}, fmt.Errorf("unknown caveat %q", caveat)
+		}
+		for _, r := range strings.Split(rel, ",") {
+			if r = strings.TrimSpace(r); r != "" {
+				m.ReleasableTo = append(m.ReleasableTo, r)
+			}
+		}
+	}
+	return m, nil
+}
+
+func (m Marking) String() string {
+	s := levels[m.Level].name
+	if len(m.ReleasableTo) > 0 {
+		s += "//REL TO " + strings.Join(m.ReleasableTo, ", ")
+	}
+	return s
+}
+
+// Permits reports whether a reader with clearance may see data marked m
+func (m Marking) Permits(clearance Marking) bool {
+	if m.Level > clearance.Level {
+		return false
+	}
+	if len(m.ReleasableTo) == 0 {
+		return true
+	}
+	for _, r := range clearance.ReleasableTo {
+		if slices.Contains(m.ReleasableTo, r) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package policy
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"connectrpc.com/connect"
+)
+
+type tokenKey struct{}
+
+// WithToken returns ctx carrying the token a request was made with, see Subject.Token
+func WithToken(ctx context.Context, token string) context.Context {
+	if token == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, tokenKey{}, token)
+}
+
+func tokenFrom(ctx context.Context) string {
+	token, _ := ctx.Value(tokenKey{}).(string)
+	return token
+}
+
+// BearerToken returns the token of an "Authorization: Bearer <token>" header, "" if there is none
+func BearerToken(h http.Header) string {
+	scheme, token, ok := strings.Cut(h.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// ForHTTP creates an Ability for a plain HTTP request, with its source ip and bearer token
+func ForHTTP(engine *Engine, r *http.Request) *Ability {
+	return For(WithToken(r.Context(), BearerToken(r.Header)), engine, r.RemoteAddr)
+}
+
+// TokenInterceptor puts the bearer token of connect requests into their context, for For
+func TokenInterceptor() connect.Interceptor {
+	return tokenInterceptor{}
+}
+
+type tokenInterceptor struct{}
+
+func (tokenInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		return next(WithToken(ctx, BearerToken(req.Header())), req)
+	}
+}
+
+func (tokenInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+func (tokenInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		return next(WithToken(ctx, BearerToken(conn.RequestHeader())), conn)
+	}
+}
@@ -1,3 +1,26 @@
 package version
 
+import "runtime/debug"
+
 var Version = "dev"
+
+// protoModule is the module of the world proto, see Proto
+const protoModule = "github.com/projectqai/proto/go"
+
+// Proto returns the version of the world proto the binary was built with,
+// or "unknown" without build info
+func Proto() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == protoModule {
+			if dep.Replace != nil {
+				dep = dep.Replace
+			}
+			return dep.Version
+		}
+	}
+	return "unknown"
+}
@@ -30,6 +30,67 @@ export type AtlasStats = {
   isFull: boolean;
 };
 
+// Entities may reference an icon or an emoji instead of a MIL-STD-2525C symbol,
+// e.g. "icon:https://example.org/boat.png" or "emoji:🚤", see goclient/symbol.go
+const ICON_PREFIX = "icon:";
+const EMOJI_PREFIX = "emoji:";
+
+type RenderedSymbol = OverflowEntry & {
+  // external images need CORS, so they don't taint the atlas canvas
+  crossOrigin: boolean;
+};
+
+function svgDataUrl(svg: string): string {
+  const base64 = btoa(unescape(encodeURIComponent(svg)));
+  return `data:image/svg+xml;base64,${base64}`;
+}
+
+function escapeXml(text: string): string {
+  return text.replace(/[<>&"']/g, (c) => `&#${c.charCodeAt(0)};`);
+}
+
+function renderSymbol(sidc: string, size: number): RenderedSymbol {
+  const half = size / 2;
+  if (sidc.startsWith(ICON_PREFIX)) {
+    const url = sidc.slice(ICON_PREFIX.length);
+    return {
+      dataUrl: url,
+      width: size,
+      height: size,
+      anchorX: half,
+      anchorY: half,
+      crossOrigin: !url.startsWith("data:"),
+    };
+  }
+  if (sidc.startsWith(EMOJI_PREFIX)) {
+    const emoji = escapeXml(sidc.slice(EMOJI_PREFIX.length));
+    const svg =
+      `<svg xmlns="http://www.w3.org/2000/svg" width="${size}" height="${size}">` +
+      `<text x="50%" y="50%" font-size="${size * 0.8}" text-anchor="middle" dominant-baseline="central">${emoji}</text>` +
+      `</svg>`;
+    return {
+      dataUrl: svgDataUrl(svg),
+      width: size,
+      height: size,
+      anchorX: half,
+      anchorY: half,
+      crossOrigin: false,
+    };
+  }
+
+  const symbol = new ms.Symbol(sidc, { size });
+  const { width, height } = symbol.getSize();
+  const anchor = symbol.getAnchor();
+  return {
+    dataUrl: svgDataUrl(symbol.asSVG()),
+    width,
+    height,
+    anchorX: anchor.x,
+    anchorY: anchor.y,
+    crossOrigin: false,
+  };
+}
+
 function estimateCapacity(symbolSize: number): number {
   const avgSymbolWidth = symbolSize * 1.4;
   const avgSymbolHeight = symbolSize * 1.6;
@@ -100,9 +161,8 @@ function createSymbolAtlas(symbolSize = 32): SymbolAtlas {
     if (mapping.has(key)) return key;
     if (overflowSymbols.has(key)) return key;
 
-    const symbol = new ms.Symbol(sidc, { size: actualSize });
-    const { width, height } = symbol.getSize();
-    const anchor = symbol.getAnchor();
+    const rendered = renderSymbol(sidc, actualSize);
+    const { width, height } = rendered;
 
     if (currentX + width + PADDING > ATLAS_SIZE) {
       currentX = 0;
@@ -117,30 +177,18 @@ function createSymbolAtlas(symbolSize = 32): SymbolAtlas {
           `Symbol "${sidc}" using direct SVG rendering.`,
       );
 
-      const svgString = symbol.asSVG();
-      const base64 = btoa(unescape(encodeURIComponent(svgString)));
-      overflowSymbols.set(key, {
-        dataUrl: `data:image/svg+xml;base64,${base64}`,
-        width,
-        height,
-        anchorX: anchor.x,
-        anchorY: anchor.y,
-      });
+      overflowSymbols.set(key, rendered);
 
       return key;
     }
 
-    const svgString = symbol.asSVG();
-    const base64 = btoa(unescape(encodeURIComponent(svgString)));
-    const dataUrl = `data:image/svg+xml;base64,${base64}`;
-
     const entry: AtlasEntry = {
       x: currentX,
       y: currentY,
       width,
       height,
-      anchorX: anchor.x,
-      anchorY: anchor.y,
+      anchorX: rendered.anchorX,
+      anchorY: rendered.anchorY,
     };
 
     mapping.set(key, entry);
@@ -148,7 +196,7 @@ function createSymbolAtlas(symbolSize = 32): SymbolAtlas {
 
     const image = new Image();
     image.onload = () => {
-      ctx.drawImage(image, entry.x, entry.y);
+      ctx.drawImage(image, entry.x, entry.y, entry.width, entry.height);
       version++;
       pendingLoads--;
       if (pendingLoads === 0) notifyReady();
@@ -157,7 +205,8 @@ function createSymbolAtlas(symbolSize = 32): SymbolAtlas {
       pendingLoads--;
       if (pendingLoads === 0) notifyReady();
     };
-    image.src = dataUrl;
+    if (rendered.crossOrigin) image.crossOrigin = "anonymous";
+    image.src = rendered.dataUrl;
 
     currentX += width + PADDING;
     rowHeight = Math.max(rowHeight, height);
@@ -167,15 +216,14 @@ function createSymbolAtlas(symbolSize = 32): SymbolAtlas {
 
   const preload = async (sidcs: string[], size?: number): Promise<void> => {
     const actualSize = size ?? symbolSize;
-    const toLoad: { dataUrl: string; entry: AtlasEntry }[] = [];
+    const toLoad: { rendered: RenderedSymbol; entry: AtlasEntry }[] = [];
 
     for (const sidc of sidcs) {
       const key = getCacheKey(sidc, actualSize);
       if (mapping.has(key) || overflowSymbols.has(key)) continue;
 
-      const symbol = new ms.Symbol(sidc, { size: actualSize });
-      const { width, height } = symbol.getSize();
-      const anchor = symbol.getAnchor();
+      const rendered = renderSymbol(sidc, actualSize);
+      const { width, height } = rendered;
 
       if (currentX + width + PADDING > ATLAS_SIZE) {
         currentX = 0;
@@ -188,34 +236,22 @@ function createSymbolAtlas(symbolSize = 32): SymbolAtlas {
           `[SymbolAtlas] Atlas full during preload. Symbol "${sidc}" using direct SVG rendering.`,
         );
 
-        const svgString = symbol.asSVG();
-        const base64 = btoa(unescape(encodeURIComponent(svgString)));
-        overflowSymbols.set(key, {
-          dataUrl: `data:image/svg+xml;base64,${base64}`,
-          width,
-          height,
-          anchorX: anchor.x,
-          anchorY: anchor.y,
-        });
+        overflowSymbols.set(key, rendered);
 
         continue;
       }
 
-      const svgString = symbol.asSVG();
-      const base64 = btoa(unescape(encodeURIComponent(svgString)));
-      const dataUrl = `data:image/svg+xml;base64,${base64}`;
-
       const entry: AtlasEntry = {
         x: currentX,
         y: currentY,
         width,
         height,
-        anchorX: anchor.x,
-        anchorY: anchor.y,
+        anchorX: rendered.anchorX,
+        anchorY: rendered.anchorY,
       };
 
       mapping.set(key, entry);
-      toLoad.push({ dataUrl, entry });
+      toLoad.push({ rendered, entry });
 
       currentX += width + PADDING;
       rowHeight = Math.max(rowHeight, height);
@@ -223,15 +259,16 @@ function createSymbolAtlas(symbolSize = 32): SymbolAtlas {
 
     await Promise.all(
       toLoad.map(
-        ({ dataUrl, entry }) =>
+        ({ rendered, entry }) =>
           new Promise<void>((resolve) => {
             const img = new Image();
             img.onload = () => {
-              ctx.drawImage(img, entry.x, entry.y);
+              ctx.drawImage(img, entry.x, entry.y, entry.width, entry.height);
               resolve();
             };
             img.onerror = () => resolve();
-            img.src = dataUrl;
+            if (rendered.crossOrigin) img.crossOrigin = "anonymous";
+            img.src = rendered.dataUrl;
           }),
       ),
     );
@@ -287,8 +324,5 @@ export function getSymbolAtlas(size = 32): SymbolAtlas {
 }
 
 export function generateSymbol(sidc: string, size = 32): string {
-  const symbol = new ms.Symbol(sidc, { size });
-  const svgString = symbol.asSVG();
-  const base64 = btoa(unescape(encodeURIComponent(svgString)));
-  return `data:image/svg+xml;base64,${base64}`;
+  return renderSymbol(sidc, size).dataUrl;
 }